@@ -1,16 +1,24 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"log"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/keenchase/auth-center/internal/config"
 	"github.com/keenchase/auth-center/internal/handler"
 	"github.com/keenchase/auth-center/internal/middleware"
+	"github.com/keenchase/auth-center/internal/models"
 	"github.com/keenchase/auth-center/internal/repository"
+	"github.com/keenchase/auth-center/internal/scheduler"
+	"github.com/keenchase/auth-center/internal/service"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
+	"gorm.io/gorm"
 )
 
 func main() {
@@ -28,6 +36,78 @@ func main() {
 		log.Fatalf("数据库连接失败: %v", err)
 	}
 
+	// 初始化静态加密与 JWT 签名密钥管理（定期轮换）
+	if err := service.InitSecretBox(cfg); err != nil {
+		log.Fatalf("加密密钥初始化失败: %v", err)
+	}
+	if _, err := service.InitKeyManager(db, cfg); err != nil {
+		log.Fatalf("签名密钥加载失败: %v", err)
+	}
+	if err := service.InitSessionCache(cfg); err != nil {
+		log.Fatalf("会话缓存初始化失败: %v", err)
+	}
+
+	// 租户（按域名或 /t/:tenant 路径区分），修改后各副本热加载
+	if err := service.InitTenantRegistry(db, cfg); err != nil {
+		log.Fatalf("租户加载失败: %v", err)
+	}
+
+	// 各租户的初始超级管理员（仅当租户内还没有超级管理员时授予；默认租户沿用 ADMIN_WECHAT_OPENID）
+	if err := service.EnsureTenantAdmins(db, cfg); err != nil {
+		log.Printf("警告: 初始化超级管理员失败: %v", err)
+	}
+
+	// 已登记客户端（回调地址、CORS 来源），修改后各副本热加载
+	if err := service.InitClientRegistry(db, cfg); err != nil {
+		log.Fatalf("客户端加载失败: %v", err)
+	}
+
+	// 扫码关注登录：公众号事件与 PC 长轮询可能落在不同副本，通过 NOTIFY 唤醒等待方
+	service.InitQRLoginNotifier(cfg)
+
+	// 微信 / 企业微信接口客户端（基础地址、超时、重试次数）
+	service.InitWeChatClients(cfg)
+
+	// 后台任务（多副本通过 advisory lock 选出一个执行）
+	if cfg.JobsEnabled {
+		jobs := scheduler.New(db)
+		jobs.Register(scheduler.Job{
+			Name:     "session_reaper",
+			Interval: cfg.SessionReaperInterval,
+			Run: func(ctx context.Context, db *gorm.DB) (string, error) {
+				n, err := service.DeleteExpiredSessions(ctx, db)
+				return fmt.Sprintf("删除过期会话 %d 个", n), err
+			},
+		})
+		jobs.Register(scheduler.Job{
+			Name:     "oauth_cleanup",
+			Interval: time.Hour,
+			Run: func(ctx context.Context, db *gorm.DB) (string, error) {
+				n, err := service.DeleteExpiredOAuthArtifacts(ctx, db)
+				return fmt.Sprintf("删除过期授权请求/授权码/刷新令牌/登录 state %d 条", n), err
+			},
+		})
+		jobs.Register(scheduler.Job{
+			Name:     "wechat_credential_refresh",
+			Interval: 5 * time.Minute,
+			Run: func(ctx context.Context, db *gorm.DB) (string, error) {
+				n, err := service.RefreshWeChatCredentials(ctx, db, cfg)
+				return fmt.Sprintf("刷新公众号凭证 %d 个", n), err
+			},
+		})
+		if cfg.LoginLogRetention > 0 {
+			jobs.Register(scheduler.Job{
+				Name:     "login_log_retention",
+				Interval: 24 * time.Hour,
+				Run: func(ctx context.Context, db *gorm.DB) (string, error) {
+					n, err := service.DeleteLoginLogsBefore(ctx, db, time.Now().Add(-cfg.LoginLogRetention))
+					return fmt.Sprintf("删除 %s 前的登录流水 %d 条", cfg.LoginLogRetention, n), err
+				},
+			})
+		}
+		jobs.Start(context.Background())
+	}
+
 	// 设置 Gin 模式
 	if os.Getenv("GIN_MODE") == "release" {
 		gin.SetMode(gin.ReleaseMode)
@@ -36,9 +116,21 @@ func main() {
 	// 创建路由
 	r := gin.Default()
 
+	// 只信任配置的反向代理转发的客户端 IP（会话设备信息中的 IP）
+	var trustedProxies []string
+	for _, proxy := range strings.Split(cfg.TrustedProxies, ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			trustedProxies = append(trustedProxies, proxy)
+		}
+	}
+	if err := r.SetTrustedProxies(trustedProxies); err != nil {
+		log.Fatalf("TRUSTED_PROXIES 配置错误: %v", err)
+	}
+
 	// 全局中间件
-	r.Use(middleware.CORS(cfg))
+	r.Use(middleware.CORS(db, cfg))
 	r.Use(middleware.Logger())
+	r.Use(middleware.Tenant(db, cfg))
 
 	// 健康检查
 	r.GET("/health", func(c *gin.Context) {
@@ -48,35 +140,13 @@ func main() {
 		})
 	})
 
-	// API 路由组
-	api := r.Group("/api")
-	{
-		// 认证相关
-		auth := api.Group("/auth")
-		{
-			auth.GET("/wechat/login", handler.WeChatLogin(db))     // GET: 重定向到微信授权
-			auth.POST("/wechat/login", handler.WeChatLogin(db))    // POST: code 换 token
-			auth.GET("/wechat/callback", handler.WeChatCallback(db))
-			auth.GET("/wechat/mp-redirect", handler.WeChatMPRedirect(db))
-			auth.GET("/wechat/open-platform-redirect", handler.OpenPlatformRedirect(db))
-			auth.POST("/wechat/open-platform-callback", handler.OpenPlatformCallback(db))
-			auth.POST("/verify-token", handler.VerifyToken(db))
-			auth.GET("/user-info", middleware.Auth(db), handler.GetUserInfo(db))
-			auth.GET("/sessions", middleware.Auth(db), handler.GetSessions(db))
-			auth.POST("/password/login", handler.PasswordLogin(db))
-			auth.POST("/signout", handler.SignOut(db))
-		}
+	// OIDC 授权服务：默认租户在根路径，其他租户的 issuer 为 <AUTH_CENTER_ISSUER>/t/<租户>
+	registerOAuthRoutes(r.Group(""), db)
+	registerOAuthRoutes(r.Group("/t/:tenant"), db)
 
-		// 管理员功能
-		admin := api.Group("/admin")
-		admin.Use(middleware.Auth(db))
-		admin.Use(middleware.RequireAdmin(db))
-		{
-			admin.GET("/users", handler.GetUsers(db))
-			admin.POST("/set-phone-password", handler.SetPhonePassword(db))
-			admin.GET("/verify", handler.VerifyAdmin(db))
-		}
-	}
+	// API 路由组：默认按域名区分租户，也可通过 /t/:tenant/api 显式指定租户
+	registerAPIRoutes(r.Group("/api"), db)
+	registerAPIRoutes(r.Group("/t/:tenant/api"), db)
 
 	// 启动服务器
 	port := os.Getenv("PORT")
@@ -89,3 +159,158 @@ func main() {
 		log.Fatalf("服务器启动失败: %v", err)
 	}
 }
+
+// registerOAuthRoutes 注册 OIDC 路由（各租户共用同一套路由，租户由 Tenant 中间件解析）
+func registerOAuthRoutes(g *gin.RouterGroup, db *gorm.DB) {
+	g.GET("/.well-known/openid-configuration", handler.OpenIDConfiguration())
+	g.GET("/.well-known/jwks.json", handler.JWKS())
+	oauth := g.Group("/oauth")
+	{
+		oauth.GET("/authorize", handler.Authorize(db))
+		oauth.GET("/authorize/resume", handler.AuthorizeResume(db)) // 上游登录完成后回跳
+		oauth.POST("/token", handler.Token(db))
+		oauth.GET("/userinfo", handler.UserInfo(db))
+		oauth.POST("/userinfo", handler.UserInfo(db))
+		oauth.POST("/introspect", handler.Introspect(db))
+		oauth.POST("/revoke", handler.Revoke(db))
+	}
+}
+
+// registerAPIRoutes 注册 API 路由（各租户共用同一套路由，租户由 Tenant 中间件解析）
+func registerAPIRoutes(api *gin.RouterGroup, db *gorm.DB) {
+	// 认证相关
+	auth := api.Group("/auth")
+	{
+		auth.GET("/wechat/login", handler.WeChatLogin(db))  // GET: 重定向到微信授权
+		auth.POST("/wechat/login", handler.WeChatLogin(db)) // POST: code 换 token
+		auth.GET("/wechat/callback", handler.WeChatCallback(db))
+		auth.GET("/wechat/mp-redirect", handler.WeChatMPRedirect(db))
+		auth.GET("/wechat/open-platform-redirect", handler.OpenPlatformRedirect(db))
+		auth.POST("/wechat/open-platform-callback", handler.OpenPlatformCallback(db))
+		auth.GET("/wechat/wecom/login", handler.WeComLogin(db)) // 企业微信登录（企业微信内授权 / 扫码）
+		auth.GET("/wechat/wecom/redirect", handler.WeComRedirect(db))
+		auth.GET("/wechat/wecom/qr-redirect", handler.WeComQRRedirect(db))
+		auth.POST("/wechat/qr-login", handler.CreateQRLogin(db))                                  // 扫码关注公众号登录：创建二维码
+		auth.GET("/wechat/qr-login/:id", handler.WaitQRLogin(db))                                 // 长轮询扫码结果
+		auth.GET("/wechat/qr-login/:id/complete", handler.CompleteQRLogin(db))                    // 完成登录，带交换码回到业务系统
+		auth.POST("/wechat/miniapp/login", handler.MiniProgramLogin(db))                          // 小程序 wx.login code 换 token
+		auth.POST("/wechat/miniapp/decrypt", middleware.Auth(db), handler.MiniProgramDecrypt(db)) // 解密小程序 encryptedData
+		auth.POST("/verify-token", middleware.ClientAuth(db), handler.VerifyToken(db))            // 业务系统服务端调用，需客户端凭证
+		auth.POST("/refresh", handler.Refresh(db))
+		auth.POST("/exchange", handler.ExchangeCode(db)) // 一次性交换码换取令牌
+		auth.GET("/user-info", middleware.AuthAllowClients(db), handler.GetUserInfo(db))
+		auth.GET("/sessions", middleware.Auth(db), handler.GetSessions(db))
+		auth.POST("/sessions/revoke-others", middleware.Auth(db), handler.RevokeOtherSessions(db))
+		auth.DELETE("/sessions/:id", middleware.Auth(db), handler.RevokeSession(db))
+		auth.PATCH("/sessions/:id", middleware.Auth(db), handler.RenameSession(db))
+		auth.POST("/password/login", handler.PasswordLogin(db))
+		auth.POST("/signout", handler.SignOut(db))
+	}
+
+	// 公众号消息推送（服务器配置的 URL 填 /api/wechat/mp/events）
+	api.GET("/wechat/mp/events", handler.MPEventVerify())
+	api.POST("/wechat/mp/events", handler.MPEvent(db))
+
+	// 公众号中控服务：业务系统凭客户端凭证获取 access_token / jsapi_ticket，前端获取 JS-SDK 签名
+	clientAuth := middleware.ClientAuth(db)
+	api.GET("/wechat/mp/access-token", clientAuth, handler.MPAccessToken(db))
+	api.POST("/wechat/mp/access-token/refresh", clientAuth, handler.RefreshMPAccessToken(db)) // 上报失效的 access_token
+	api.GET("/wechat/mp/jsapi-ticket", clientAuth, handler.MPJSAPITicket(db))
+	api.POST("/wechat/mp/jssdk-signature", handler.JSSDKSignature(db))
+
+	// 组织（当前用户所在的组织；组织内 admin 及以上可管理成员与邀请）
+	orgs := api.Group("/orgs")
+	orgs.Use(middleware.Auth(db))
+	{
+		member := middleware.RequireOrgRole(db, models.OrgRoleMember)
+		orgAdmin := middleware.RequireOrgRole(db, models.OrgRoleAdmin)
+
+		orgs.GET("", handler.ListMyOrgs(db))
+		orgs.POST("/invitations/accept", handler.AcceptOrgInvitation(db))
+		orgs.GET("/:id", member, handler.GetOrg(db))
+		orgs.PUT("/:id", orgAdmin, handler.UpdateOrg(db))
+		orgs.GET("/:id/members", member, handler.ListOrgMembers(db))
+		orgs.PUT("/:id/members/:userId", orgAdmin, handler.UpdateOrgMember(db))
+		orgs.DELETE("/:id/members/:userId", member, handler.RemoveOrgMember(db)) // 管理员移除成员或成员自行退出
+		orgs.GET("/:id/invitations", orgAdmin, handler.ListOrgInvitations(db))
+		orgs.POST("/:id/invitations", orgAdmin, handler.CreateOrgInvitation(db))
+		orgs.DELETE("/:id/invitations/:invitationId", orgAdmin, handler.RevokeOrgInvitation(db))
+	}
+
+	// 业务系统服务端查询用户的角色与权限、按策略判定访问（客户端凭证认证）
+	authz := api.Group("/authz")
+	authz.Use(middleware.ClientAuth(db))
+	{
+		authz.GET("/users/:id", handler.GetUserAuthorization(db))
+		authz.POST("/permissions/check", handler.CheckPermission(db))
+		authz.POST("/check", handler.CheckAuthz(db))
+	}
+
+	// 管理员功能（按权限保护，权限通过角色授予）
+	admin := api.Group("/admin")
+	admin.Use(middleware.Auth(db))
+	{
+		perm := func(permission string) gin.HandlerFunc {
+			return middleware.RequirePermission(db, permission)
+		}
+		// 平台级功能只能在默认租户下使用；路径中的用户、客户端、策略、组织须属于当前租户
+		platform := middleware.RequireDefaultTenant()
+		tenantUser := middleware.InTenant(db, service.UserInTenant, service.ErrUserNotFound)
+		tenantClient := middleware.InTenant(db, service.ClientInTenant, service.ErrClientNotFound)
+		tenantPolicy := middleware.InTenant(db, service.PolicyInTenant, service.ErrPolicyNotFound)
+		tenantOrg := middleware.InTenant(db, service.OrgInTenant, service.ErrOrgNotFound)
+
+		admin.GET("/verify", handler.VerifyAdmin(db))
+		admin.GET("/users", perm(models.PermissionUsersRead), handler.GetUsers(db))
+		admin.POST("/set-phone-password", perm(models.PermissionUsersWrite), handler.SetPhonePassword(db))
+		admin.DELETE("/users/:id/sessions", perm(models.PermissionSessionsRevoke), tenantUser, handler.RevokeUserSessions(db))
+		admin.DELETE("/users/:id/sessions/:sessionId", perm(models.PermissionSessionsRevoke), tenantUser, handler.RevokeUserSessionByAdmin(db))
+		admin.GET("/keys", perm(models.PermissionKeysManage), platform, handler.ListSigningKeys(db))
+		admin.POST("/keys/rotate", perm(models.PermissionKeysManage), platform, handler.RotateSigningKey(db))
+		admin.GET("/jobs", perm(models.PermissionJobsRead), platform, handler.ListJobs(db))
+		admin.GET("/clients", perm(models.PermissionClientsManage), handler.ListClients(db))
+		admin.POST("/clients", perm(models.PermissionClientsManage), handler.CreateClient(db))
+		admin.GET("/clients/:id", perm(models.PermissionClientsManage), tenantClient, handler.GetClient(db))
+		admin.PUT("/clients/:id", perm(models.PermissionClientsManage), tenantClient, handler.UpdateClient(db))
+		admin.DELETE("/clients/:id", perm(models.PermissionClientsManage), tenantClient, handler.DeleteClient(db))
+		admin.POST("/clients/:id/rotate-secret", perm(models.PermissionClientsManage), tenantClient, handler.RotateClientSecret(db))
+		admin.GET("/clients/:id/permissions", perm(models.PermissionRolesManage), tenantClient, handler.ListPermissions(db))
+		admin.POST("/clients/:id/permissions", perm(models.PermissionRolesManage), tenantClient, handler.CreatePermission(db))
+		admin.DELETE("/clients/:id/permissions/:code", perm(models.PermissionRolesManage), tenantClient, handler.DeletePermission(db))
+		admin.GET("/clients/:id/roles", perm(models.PermissionRolesManage), tenantClient, handler.ListRoles(db))
+		admin.POST("/clients/:id/roles", perm(models.PermissionRolesManage), tenantClient, handler.CreateRole(db))
+		admin.GET("/permissions", perm(models.PermissionRolesManage), handler.ListPermissions(db))
+		admin.GET("/roles", perm(models.PermissionRolesManage), handler.ListRoles(db))
+		admin.POST("/roles", perm(models.PermissionRolesManage), platform, handler.CreateRole(db))
+		admin.PUT("/roles/:id", perm(models.PermissionRolesManage), handler.UpdateRole(db))
+		admin.DELETE("/roles/:id", perm(models.PermissionRolesManage), handler.DeleteRole(db))
+		admin.GET("/policies", perm(models.PermissionPoliciesManage), handler.ListPolicies(db))
+		admin.POST("/policies", perm(models.PermissionPoliciesManage), handler.CreatePolicy(db))
+		admin.GET("/policies/:id", perm(models.PermissionPoliciesManage), tenantPolicy, handler.GetPolicy(db))
+		admin.PUT("/policies/:id", perm(models.PermissionPoliciesManage), tenantPolicy, handler.UpdatePolicy(db))
+		admin.DELETE("/policies/:id", perm(models.PermissionPoliciesManage), tenantPolicy, handler.DeletePolicy(db))
+		admin.GET("/orgs", perm(models.PermissionOrgsManage), handler.ListOrgs(db))
+		admin.POST("/orgs", perm(models.PermissionOrgsManage), handler.CreateOrg(db))
+		// 平台管理员以组织所有者身份管理任意组织
+		adminOrg := admin.Group("/orgs/:id", perm(models.PermissionOrgsManage), tenantOrg, middleware.ActAsOrgOwner())
+		{
+			adminOrg.GET("", handler.GetOrg(db))
+			adminOrg.PUT("", handler.UpdateOrg(db))
+			adminOrg.DELETE("", handler.DeleteOrg(db))
+			adminOrg.GET("/members", handler.ListOrgMembers(db))
+			adminOrg.POST("/members", handler.AddOrgMember(db))
+			adminOrg.PUT("/members/:userId", handler.UpdateOrgMember(db))
+			adminOrg.DELETE("/members/:userId", handler.RemoveOrgMember(db))
+			adminOrg.GET("/invitations", handler.ListOrgInvitations(db))
+			adminOrg.POST("/invitations", handler.CreateOrgInvitation(db))
+			adminOrg.DELETE("/invitations/:invitationId", handler.RevokeOrgInvitation(db))
+		}
+		admin.GET("/tenants", perm(models.PermissionTenantsManage), platform, handler.ListTenants(db))
+		admin.POST("/tenants", perm(models.PermissionTenantsManage), platform, handler.CreateTenant(db))
+		admin.GET("/tenants/:id", perm(models.PermissionTenantsManage), platform, handler.GetTenant(db))
+		admin.PUT("/tenants/:id", perm(models.PermissionTenantsManage), platform, handler.UpdateTenant(db))
+		admin.GET("/users/:id/roles", perm(models.PermissionRolesManage), tenantUser, handler.GetUserRoles(db))
+		admin.POST("/users/:id/roles", perm(models.PermissionRolesManage), tenantUser, handler.AssignUserRole(db))
+		admin.DELETE("/users/:id/roles/:roleId", perm(models.PermissionRolesManage), tenantUser, handler.RevokeUserRole(db))
+	}
+}
@@ -0,0 +1,16 @@
+// Package cache 提供带过期时间的键值缓存（进程内 LRU / Redis）
+package cache
+
+import "time"
+
+// Cache 键值缓存
+// 缓存只用于加速，后端故障时 Get 视为未命中，调用方回源数据库
+type Cache interface {
+	Get(key string) (string, bool)
+	Set(key, value string, ttl time.Duration)
+	// Add 仅在键不存在（或已过期）时写入，返回是否写入；后端故障时返回 false
+	Add(key, value string, ttl time.Duration) bool
+	Delete(keys ...string)
+	// Purge 清空缓存（无法确认是否漏掉失效事件时使用）
+	Purge()
+}
@@ -0,0 +1,263 @@
+package cache
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	redisDialTimeout = 3 * time.Second
+	redisIOTimeout   = 2 * time.Second
+	redisMaxIdle     = 8
+)
+
+// ErrNil Redis 返回空值
+var ErrNil = errors.New("redis: nil")
+
+// Redis 最小化的 Redis 客户端（RESP2），实现 Cache 接口
+// 只覆盖本服务用到的命令，避免额外依赖
+type Redis struct {
+	addr     string
+	username string
+	password string
+	db       int
+	prefix   string
+
+	mu   sync.Mutex
+	idle []*redisConn
+}
+
+type redisConn struct {
+	conn net.Conn
+	rd   *bufio.Reader
+}
+
+// NewRedis 根据 redis://[user:password@]host:port/db 创建客户端，所有键加上 prefix 前缀
+func NewRedis(rawURL, prefix string) (*Redis, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("REDIS_URL 格式错误: %w", err)
+	}
+	if u.Scheme != "redis" {
+		return nil, fmt.Errorf("不支持的 Redis 协议: %s", u.Scheme)
+	}
+
+	r := &Redis{addr: u.Host, prefix: prefix}
+	if !strings.Contains(r.addr, ":") {
+		r.addr += ":6379"
+	}
+	if u.User != nil {
+		r.username = u.User.Username()
+		r.password, _ = u.User.Password()
+	}
+	if db := strings.TrimPrefix(u.Path, "/"); db != "" {
+		if r.db, err = strconv.Atoi(db); err != nil {
+			return nil, fmt.Errorf("REDIS_URL 数据库编号错误: %s", db)
+		}
+	}
+
+	// 启动时验证连通性
+	if _, err := r.Do("PING"); err != nil {
+		return nil, fmt.Errorf("连接 Redis 失败: %w", err)
+	}
+	return r, nil
+}
+
+// Get 读取键值，Redis 故障时视为未命中
+func (r *Redis) Get(key string) (string, bool) {
+	reply, err := r.Do("GET", r.prefix+key)
+	if err != nil {
+		if !errors.Is(err, ErrNil) {
+			log.Printf("警告: Redis GET 失败: %v", err)
+		}
+		return "", false
+	}
+	value, ok := reply.(string)
+	return value, ok
+}
+
+// Set 写入键值
+func (r *Redis) Set(key, value string, ttl time.Duration) {
+	ms := ttl.Milliseconds()
+	if ms <= 0 {
+		ms = 1
+	}
+	if _, err := r.Do("SET", r.prefix+key, value, "PX", strconv.FormatInt(ms, 10)); err != nil {
+		log.Printf("警告: Redis SET 失败: %v", err)
+	}
+}
+
+// Add 仅在键不存在时写入（SET NX）
+func (r *Redis) Add(key, value string, ttl time.Duration) bool {
+	ms := ttl.Milliseconds()
+	if ms <= 0 {
+		ms = 1
+	}
+	_, err := r.Do("SET", r.prefix+key, value, "PX", strconv.FormatInt(ms, 10), "NX")
+	if err != nil && !errors.Is(err, ErrNil) {
+		log.Printf("警告: Redis SET NX 失败: %v", err)
+	}
+	return err == nil
+}
+
+// Delete 删除键
+func (r *Redis) Delete(keys ...string) {
+	if len(keys) == 0 {
+		return
+	}
+	args := make([]string, 0, len(keys)+1)
+	args = append(args, "DEL")
+	for _, key := range keys {
+		args = append(args, r.prefix+key)
+	}
+	if _, err := r.Do(args...); err != nil {
+		log.Printf("警告: Redis DEL 失败: %v", err)
+	}
+}
+
+// Purge Redis 为多副本共享的缓存，失效事件直接作用于 Redis，无需清空
+func (r *Redis) Purge() {}
+
+// Do 执行命令，返回 string / int64 / []interface{}；空值返回 ErrNil
+func (r *Redis) Do(args ...string) (interface{}, error) {
+	c, err := r.getConn()
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := c.do(args...)
+	var redisErr redisError
+	if err != nil && !errors.Is(err, ErrNil) && !errors.As(err, &redisErr) {
+		// 网络错误，连接不再复用
+		c.conn.Close()
+		return nil, err
+	}
+	r.putConn(c)
+	return reply, err
+}
+
+func (r *Redis) getConn() (*redisConn, error) {
+	r.mu.Lock()
+	if n := len(r.idle); n > 0 {
+		c := r.idle[n-1]
+		r.idle = r.idle[:n-1]
+		r.mu.Unlock()
+		return c, nil
+	}
+	r.mu.Unlock()
+
+	conn, err := net.DialTimeout("tcp", r.addr, redisDialTimeout)
+	if err != nil {
+		return nil, err
+	}
+	c := &redisConn{conn: conn, rd: bufio.NewReader(conn)}
+
+	if r.password != "" {
+		auth := []string{"AUTH", r.password}
+		if r.username != "" {
+			auth = []string{"AUTH", r.username, r.password}
+		}
+		if _, err := c.do(auth...); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	if r.db != 0 {
+		if _, err := c.do("SELECT", strconv.Itoa(r.db)); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+func (r *Redis) putConn(c *redisConn) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.idle) >= redisMaxIdle {
+		c.conn.Close()
+		return
+	}
+	r.idle = append(r.idle, c)
+}
+
+// redisError Redis 返回的错误应答（连接仍可复用）
+type redisError string
+
+func (e redisError) Error() string { return "redis: " + string(e) }
+
+func (c *redisConn) do(args ...string) (interface{}, error) {
+	if err := c.conn.SetDeadline(time.Now().Add(redisIOTimeout)); err != nil {
+		return nil, err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := io.WriteString(c.conn, b.String()); err != nil {
+		return nil, err
+	}
+	return c.readReply()
+}
+
+func (c *redisConn) readReply() (interface{}, error) {
+	line, err := c.rd.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, errors.New("redis: 空应答")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, ErrNil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(c.rd, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, ErrNil
+		}
+		items := make([]interface{}, n)
+		for i := range items {
+			item, err := c.readReply()
+			if err != nil && !errors.Is(err, ErrNil) {
+				return nil, err
+			}
+			items[i] = item
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("redis: 无法解析的应答 %q", line)
+}
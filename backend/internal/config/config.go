@@ -2,33 +2,101 @@ package config
 
 import (
 	"os"
+	"strconv"
+	"time"
 )
 
+// DefaultTenantID 默认租户：未匹配到其他租户的请求、迁移前的全部数据都属于它
+const DefaultTenantID = "default"
+
 // Config 应用配置
 type Config struct {
+	// 所属租户（租户解析中间件按请求设置，见 service.TenantConfig；其余情况为默认租户）
+	TenantID string
+
 	// 数据库配置
 	DatabaseURL string
 
 	// JWT 密钥
 	JWTSecret string
 
-	// 微信开放平台配置
+	// JWT 非对称签名配置
+	JWTSigningAlg     string // RS256 | ES256 | EdDSA（仅在未提供私钥文件时用于生成临时密钥）
+	JWTPrivateKeyFile string // PEM 私钥文件路径
+	JWTLegacyHS256    bool   // 是否继续接受旧的 HS256 Token（迁移期，最晚到 service.LegacyHS256Sunset）
+
+	// 令牌有效期
+	AccessTokenTTL  time.Duration // 访问令牌（JWT）
+	RefreshTokenTTL time.Duration // 刷新令牌（会话空闲超时，每次刷新顺延）
+
+	// 签名密钥轮换
+	JWTKeyRotationInterval time.Duration // 自动轮换周期
+	JWTKeyGracePeriod      time.Duration // 旧密钥仅验签的宽限期（应大于 Token 有效期）
+
+	// 会话状态缓存（鉴权中间件检查会话是否已吊销）
+	SessionCache     string        // memory（进程内 LRU）| redis
+	SessionCacheTTL  time.Duration // 缓存条目有效期
+	SessionCacheSize int           // 进程内缓存最大条目数
+	RedisURL         string        // SESSION_CACHE=redis 时使用
+
+	// 后台任务
+	JobsEnabled           bool          // 是否在本副本运行后台任务调度器
+	SessionReaperInterval time.Duration // 过期会话清理周期
+	LoginLogRetention     time.Duration // 登录流水保留时长，0 表示永久保留
+
+	// 敏感数据静态加密密钥（base64 编码的 32 字节）
+	DataEncryptionKey string
+
+	// 微信开放平台配置（非默认租户使用租户自己登记的凭证）
 	WeChatAppID     string
 	WeChatAppSecret  string
 
 	// 微信公众号配置
 	WeChatMPAppID     string
 	WeChatMPSecret    string
+	WeChatMPToken          string // 服务器配置的令牌，用于校验消息推送签名
+	WeChatMPEncodingAESKey string // 服务器配置的消息加解密密钥（安全模式 / 兼容模式）
+
+	// 微信小程序配置
+	WeChatMiniAppID  string
+	WeChatMiniSecret string
+
+	// 企业微信自建应用配置（员工登录）
+	WeComCorpID          string
+	WeComAgentID         string
+	WeComSecret          string
+	WeComSyncDepartments bool // 登录时同步成员所属部门
 
-	// 管理员配置
+	// 微信 / 企业微信服务端接口（基础地址可指向本地模拟服务用于测试）
+	WeChatAPIBaseURL string
+	WeComAPIBaseURL  string
+	WeChatAPITimeout time.Duration // 单次请求超时
+	WeChatAPIRetries int           // 临时错误（网络错误、系统繁忙、频率限制）的重试次数
+
+	// 扫码关注登录：同一 IP 每分钟最多创建的二维码数（0 表示不限制）
+	QRLoginRateLimit int
+
+	// 初始超级管理员的微信 UnionID（仅在还没有任何超级管理员时授予）
 	AdminWeChatOpenID string
 
-	// CORS 白名单
+	// CORS 白名单（已废弃：改为在客户端上登记 allowed_origins，仅作迁移期兜底）
 	AllowedOrigins string
 
-	// 回调域名白名单
+	// 回调域名白名单（已废弃：改为在客户端上登记 redirect_uris，仅作迁移期兜底）
 	AllowedCallbackDomains string
 
+	// 回调地址未注册为客户端时，是否仍按旧方式在 URL 中携带 token（仅限迁移期临时开启，默认拒绝）
+	LegacyTokenRedirect bool
+
+	// 受信任的反向代理（逗号分隔的 IP / CIDR），仅信任这些来源的 X-Forwarded-For / X-Forwarded-Host
+	TrustedProxies string
+
+	// OIDC 签发者（对外访问的 auth-center 根地址）
+	Issuer string
+
+	// 登录页地址（OIDC 授权时用于密码登录）
+	LoginPageURL string
+
 	// 环境变量
 	Environment string
 }
@@ -36,15 +104,48 @@ type Config struct {
 // Load 从环境变量加载配置
 func Load() *Config {
 	return &Config{
+		TenantID:               DefaultTenantID,
 		DatabaseURL:      getEnv("DATABASE_URL", ""),
 		JWTSecret:        getEnv("AUTH_CENTER_SECRET", ""),
+		JWTSigningAlg:          getEnv("JWT_SIGNING_ALG", "RS256"),
+		JWTPrivateKeyFile:      getEnv("JWT_PRIVATE_KEY_FILE", ""),
+		JWTLegacyHS256:         getEnv("JWT_LEGACY_HS256", "false") == "true",
+		AccessTokenTTL:         getEnvDuration("ACCESS_TOKEN_TTL", 15*time.Minute),
+		RefreshTokenTTL:        getEnvDuration("REFRESH_TOKEN_TTL", 30*24*time.Hour),
+		JWTKeyRotationInterval: getEnvDuration("JWT_KEY_ROTATION_INTERVAL", 30*24*time.Hour),
+		JWTKeyGracePeriod:      getEnvDuration("JWT_KEY_GRACE_PERIOD", 8*24*time.Hour),
+		DataEncryptionKey:      getEnv("DATA_ENCRYPTION_KEY", ""),
+		SessionCache:           getEnv("SESSION_CACHE", "memory"),
+		SessionCacheTTL:        getEnvDuration("SESSION_CACHE_TTL", 30*time.Second),
+		SessionCacheSize:       getEnvInt("SESSION_CACHE_SIZE", 10000),
+		RedisURL:               getEnv("REDIS_URL", ""),
+		JobsEnabled:            getEnv("JOBS_ENABLED", "true") == "true",
+		SessionReaperInterval:  getEnvDuration("SESSION_REAPER_INTERVAL", time.Hour),
+		LoginLogRetention:      getEnvDuration("LOGIN_LOG_RETENTION", 180*24*time.Hour),
 		WeChatAppID:      getEnv("WECHAT_APP_ID", ""),
 		WeChatAppSecret:  getEnv("WECHAT_APP_SECRET", ""),
 		WeChatMPAppID:    getEnv("WECHAT_MP_APPID", ""),
 		WeChatMPSecret:   getEnv("WECHAT_MP_SECRET", ""),
+		WeChatMPToken:          getEnv("WECHAT_MP_TOKEN", ""),
+		WeChatMPEncodingAESKey: getEnv("WECHAT_MP_ENCODING_AES_KEY", ""),
+		WeChatMiniAppID:        getEnv("WECHAT_MINI_APPID", ""),
+		WeChatMiniSecret:       getEnv("WECHAT_MINI_SECRET", ""),
+		WeComCorpID:            getEnv("WECOM_CORP_ID", ""),
+		WeComAgentID:           getEnv("WECOM_AGENT_ID", ""),
+		WeComSecret:            getEnv("WECOM_SECRET", ""),
+		WeComSyncDepartments:   getEnv("WECOM_SYNC_DEPARTMENTS", "false") == "true",
+		WeChatAPIBaseURL:       getEnv("WECHAT_API_BASE_URL", "https://api.weixin.qq.com"),
+		WeComAPIBaseURL:        getEnv("WECOM_API_BASE_URL", "https://qyapi.weixin.qq.com"),
+		WeChatAPITimeout:       getEnvDuration("WECHAT_API_TIMEOUT", 5*time.Second),
+		WeChatAPIRetries:       getEnvInt("WECHAT_API_RETRIES", 2),
+		QRLoginRateLimit:       getEnvInt("QR_LOGIN_RATE_LIMIT", 10),
 		AdminWeChatOpenID: getEnv("ADMIN_WECHAT_OPENID", ""),
-		AllowedOrigins:   getEnv("ALLOWED_ORIGINS", "https://os.crazyaigc.com,https://pr.crazyaigc.com"),
-		AllowedCallbackDomains: getEnv("ALLOWED_CALLBACK_DOMAINS", "os.crazyaigc.com,pr.crazyaigc.com,pixel.crazyaigc.com,3xvs5r4nm4.coze.site"),
+		AllowedOrigins:         getEnv("ALLOWED_ORIGINS", ""),
+		AllowedCallbackDomains: getEnv("ALLOWED_CALLBACK_DOMAINS", ""),
+		LegacyTokenRedirect:    getEnv("LEGACY_TOKEN_REDIRECT", "false") == "true",
+		TrustedProxies:         getEnv("TRUSTED_PROXIES", "127.0.0.1,::1"),
+		Issuer:                 getEnv("AUTH_CENTER_ISSUER", "https://os.crazyaigc.com"),
+		LoginPageURL:           getEnv("AUTH_CENTER_LOGIN_PAGE_URL", "https://os.crazyaigc.com/login"),
 		Environment:      getEnv("NODE_ENV", "development"),
 	}
 }
@@ -56,3 +157,23 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+// getEnvDuration 获取时长类型的环境变量（如 15m、720h），解析失败时返回默认值
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if d, err := time.ParseDuration(value); err == nil {
+			return d
+		}
+	}
+	return defaultValue
+}
+
+// getEnvInt 获取整数类型的环境变量，解析失败时返回默认值
+func getEnvInt(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if n, err := strconv.Atoi(value); err == nil {
+			return n
+		}
+	}
+	return defaultValue
+}
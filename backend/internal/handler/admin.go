@@ -1,10 +1,14 @@
 package handler
 
 import (
+	"errors"
+	"log"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/keenchase/auth-center/internal/scheduler"
 	"github.com/keenchase/auth-center/internal/service"
 	"gorm.io/gorm"
 )
@@ -41,7 +45,7 @@ func GetUsers(db *gorm.DB) gin.HandlerFunc {
 		}
 
 		// 获取用户列表
-		users, total, err := service.GetUsers(db, page, pageSize)
+		users, total, err := service.GetUsers(db, c.GetString("tenantId"), page, pageSize)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, GetUsersResponse{
 				Success: false,
@@ -92,6 +96,22 @@ func SetPhonePassword(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
+		// 只能为当前租户的用户设置
+		if err := service.UserInTenant(db, c.GetString("tenantId"), req.UserID); err != nil {
+			if errors.Is(err, service.ErrUserNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{
+					"success": false,
+					"error":   "用户不存在",
+				})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"error":   "设置失败",
+			})
+			return
+		}
+
 		// 设置手机号和密码
 		if err := service.SetPhonePassword(db, req.UserID, req.PhoneNumber, req.Password); err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{
@@ -107,17 +127,206 @@ func SetPhonePassword(db *gorm.DB) gin.HandlerFunc {
 	}
 }
 
-// VerifyAdmin 验证管理员
-// 注意：此路由已被 RequireAdmin 中间件保护，能到达这里的都是管理员
+// VerifyAdmin 验证管理员，返回当前用户的角色与权限（前端据此展示可用功能）
+// 没有任何权限的用户返回 403
 func VerifyAdmin(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// 获取用户信息
 		userID := c.GetString("userId")
 
+		authz, err := service.UserAuthorization(db, "", userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"error":   "权限校验失败",
+			})
+			return
+		}
+		if len(authz.Permissions) == 0 {
+			c.JSON(http.StatusForbidden, gin.H{
+				"success": false,
+				"error":   "无管理员权限",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success":     true,
+			"isAdmin":     true,
+			"userId":      userID,
+			"roles":       authz.Roles,
+			"permissions": authz.Permissions,
+		})
+	}
+}
+
+// RevokeUserSessions 吊销某个用户的全部会话（强制下线）
+func RevokeUserSessions(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.Param("id")
+		if _, err := uuid.Parse(userID); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"success": false,
+				"error":   service.ErrUserNotFound.Error(),
+			})
+			return
+		}
+
+		count, err := service.RevokeAllSessions(db, userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"error":   "吊销会话失败",
+			})
+			return
+		}
+
+		log.Printf("管理员 %s 吊销了用户 %s 的全部会话（%d 个）", c.GetString("userId"), userID, count)
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data": gin.H{
+				"revoked": count,
+			},
+		})
+	}
+}
+
+// RevokeUserSessionByAdmin 吊销某个用户的指定会话
+func RevokeUserSessionByAdmin(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.Param("id")
+		sessionID := c.Param("sessionId")
+		if _, err := uuid.Parse(userID); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"success": false,
+				"error":   service.ErrSessionNotFound.Error(),
+			})
+			return
+		}
+		if _, err := uuid.Parse(sessionID); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"success": false,
+				"error":   service.ErrSessionNotFound.Error(),
+			})
+			return
+		}
+
+		if err := service.RevokeUserSession(db, userID, sessionID); err != nil {
+			if errors.Is(err, service.ErrSessionNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{
+					"success": false,
+					"error":   err.Error(),
+				})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"error":   "吊销会话失败",
+			})
+			return
+		}
+
+		log.Printf("管理员 %s 吊销了用户 %s 的会话 %s", c.GetString("userId"), userID, sessionID)
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+		})
+	}
+}
+
+// ListSigningKeys 列出 JWT 签名密钥（不含私钥）
+func ListSigningKeys(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		keys, err := service.DefaultKeyManager().ListKeys()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"error":   "获取签名密钥失败",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data": gin.H{
+				"keys": keys,
+			},
+		})
+	}
+}
+
+// RotateSigningKeyRequest 轮换签名密钥请求
+type RotateSigningKeyRequest struct {
+	// RevokePrevious 紧急轮换：旧密钥立即退役，其签发的 Token 全部失效
+	RevokePrevious bool `json:"revokePrevious"`
+}
+
+// RotateSigningKey 立即轮换 JWT 签名密钥
+func RotateSigningKey(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req RotateSigningKeyRequest
+		if c.Request.ContentLength > 0 {
+			if err := c.ShouldBindJSON(&req); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"success": false,
+					"error":   "无效的请求参数",
+				})
+				return
+			}
+		}
+
+		key, err := service.DefaultKeyManager().Rotate(req.RevokePrevious)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"error":   "轮换签名密钥失败",
+			})
+			return
+		}
+
+		log.Printf("管理员 %s 轮换了签名密钥: kid=%s revokePrevious=%v", c.GetString("userId"), key.Kid, req.RevokePrevious)
+
 		c.JSON(http.StatusOK, gin.H{
 			"success": true,
-			"isAdmin": true,
-			"userId":  userID,
+			"data": gin.H{
+				"kid":       key.Kid,
+				"algorithm": key.Algorithm,
+			},
+		})
+	}
+}
+
+// ListJobs 后台任务状态与最近一次运行结果
+func ListJobs(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		jobs := scheduler.Default()
+		if jobs == nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": true,
+				"data": gin.H{
+					"enabled": false,
+					"jobs":    []scheduler.JobStatus{},
+				},
+			})
+			return
+		}
+
+		status, err := jobs.Status()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"error":   "获取后台任务状态失败",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data": gin.H{
+				"enabled": true,
+				"jobs":    status,
+			},
 		})
 	}
 }
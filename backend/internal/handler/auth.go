@@ -1,15 +1,19 @@
 package handler
 
 import (
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
 	"net/url"
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/keenchase/auth-center/internal/config"
 	"github.com/keenchase/auth-center/internal/models"
 	"github.com/keenchase/auth-center/internal/service"
+	"github.com/keenchase/auth-center/internal/wechat"
 	"gorm.io/gorm"
 )
 
@@ -22,30 +26,46 @@ type LoginRequest struct {
 
 // LoginResponse 登录响应
 type LoginResponse struct {
-	Success bool   `json:"success"`
-	Token   string `json:"token,omitempty"`
-	UserID   string `json:"userId,omitempty"`
-	Error    string `json:"error,omitempty"`
+	Success      bool   `json:"success"`
+	Token        string `json:"token,omitempty"`
+	RefreshToken string `json:"refreshToken,omitempty"`
+	ExpiresIn    int    `json:"expiresIn,omitempty"`
+	UserID       string `json:"userId,omitempty"`
+	RedirectURL  string `json:"redirectUrl,omitempty"` // 回调地址为 auth-center 自身页面时，登录页跳转到此地址（带一次性交换码）
+	Error        string `json:"error,omitempty"`
 }
 
 // isWechatBrowser 检测是否在微信内置浏览器
 func isWechatBrowser(userAgent string) bool {
-	return containsIgnoreCase(userAgent, "MicroMessenger") ||
-		containsIgnoreCase(userAgent, "wxwork") ||
-		containsIgnoreCase(userAgent, "WeChat")
+	return service.IsWechatBrowser(userAgent)
 }
 
-func containsIgnoreCase(s, substr string) bool {
-	return len(s) >= len(substr) && (s == substr || len(s) > len(substr) && (s[:len(substr)] == substr || s[len(s)-len(substr):] == substr || indexOfIgnoreCase(s, substr) >= 0))
+// requestDeviceInfo 采集当前请求的设备信息（IP 按受信任代理列表解析）
+// app 为发起登录的业务系统，为空时取 Origin / Referer
+func requestDeviceInfo(c *gin.Context, app string) *models.DeviceInfo {
+	if app == "" {
+		app = c.GetHeader("Origin")
+	}
+	if app == "" {
+		app = c.Request.Referer()
+	}
+	return service.NewDeviceInfo(c.ClientIP(), c.Request.UserAgent(), app)
 }
 
-func indexOfIgnoreCase(s, substr string) int {
-	for i := 0; i <= len(s)-len(substr); i++ {
-		if s[i:i+len(substr)] == substr {
-			return i
-		}
-	}
-	return -1
+// wechatLoginTypes POST /api/auth/wechat/login 的 type 参数对应的登录方式
+var wechatLoginTypes = map[string]string{
+	"mp":       models.LoginMethodWeChatMP,
+	"open":     models.LoginMethodWeChatOpen,
+	"wecom":    models.LoginMethodWeCom,
+	"wecom_qr": models.LoginMethodWeComQR,
+}
+
+// providerRedirectPaths 各登录方式在上游授权后回跳的 auth-center 地址
+var providerRedirectPaths = map[string]string{
+	models.LoginMethodWeChatMP:   "/api/auth/wechat/mp-redirect",
+	models.LoginMethodWeChatOpen: "/api/auth/wechat/open-platform-redirect",
+	models.LoginMethodWeCom:      "/api/auth/wechat/wecom/redirect",
+	models.LoginMethodWeComQR:    "/api/auth/wechat/wecom/qr-redirect",
 }
 
 // WeChatLogin 微信登录（支持 POST 和 GET）
@@ -55,7 +75,7 @@ func WeChatLogin(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// GET 请求：重定向到微信授权页面
 		if c.Request.Method == "GET" {
-			handleWeChatLoginRedirect(c)
+			handleWeChatLoginRedirect(c, db)
 			return
 		}
 
@@ -70,72 +90,41 @@ func WeChatLogin(db *gorm.DB) gin.HandlerFunc {
 		}
 
 		// 获取配置
-		cfg := config.Load()
+		cfg := loadConfig(c)
 
-		// 获取微信 Access Token
-		isMP := req.Type == "mp"
-		wxResp, err := service.GetWeChatAccessToken(cfg, req.Code, isMP)
+		loginMethod, ok := wechatLoginTypes[req.Type]
+		if !ok {
+			loginMethod = models.LoginMethodWeChatOpen
+		}
+		provider, err := service.GetIdentityProvider(loginMethod)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, LoginResponse{
+			c.JSON(http.StatusBadRequest, LoginResponse{
 				Success: false,
-				Error:   "微信认证失败",
+				Error:   err.Error(),
 			})
 			return
 		}
 
-		// 获取用户信息（仅公众号需要调用此接口）
-		userInfo, err := service.GetWeChatUserInfo(wxResp.AccessToken, wxResp.OpenID, isMP)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, LoginResponse{
+		// 换取凭证、获取资料并关联账号
+		user, _, err := service.AuthenticateWithProvider(c.Request.Context(), db, cfg, provider, req.Code)
+		if errors.Is(err, service.ErrWeChatUnionIDMissing) || errors.Is(err, service.ErrAuthCodeInvalid) {
+			c.JSON(http.StatusBadRequest, LoginResponse{
 				Success: false,
-				Error:   "获取用户信息失败",
+				Error:   err.Error(),
 			})
 			return
 		}
-
-		// 提取 UnionID（注意：公众号和开放平台的 unionid 获取位置不同）
-		var unionID string
-		if isMP {
-			// 公众号：unionid 在 userinfo 响应中
-			unionID = service.GetStringValue(userInfo, "unionid")
-		} else {
-			// 开放平台：unionid 在 access_token 响应中
-			unionID = wxResp.UnionID
-		}
-
-		// 验证 unionID 不为空
-		if unionID == "" {
-			c.JSON(http.StatusBadRequest, LoginResponse{
+		if errors.Is(err, wechat.ErrCircuitOpen) {
+			c.JSON(http.StatusServiceUnavailable, LoginResponse{
 				Success: false,
-				Error:   "无法获取用户唯一标识，请确保应用已绑定到微信开放平台",
+				Error:   err.Error(),
 			})
 			return
 		}
-
-		// 获取或创建用户
-		appID := cfg.WeChatAppID
-		if isMP {
-			appID = cfg.WeChatMPAppID
-		}
-
-		accountType := "web"
-		if isMP {
-			accountType = "mp"
-		}
-
-		user, err := service.FindOrCreateUserByUnionID(
-			db,
-			unionID,
-			wxResp.OpenID,
-			appID,
-			accountType,
-			userInfo,
-		)
-
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, LoginResponse{
 				Success: false,
-				Error:   "用户创建失败",
+				Error:   "微信认证失败",
 			})
 			return
 		}
@@ -149,37 +138,11 @@ func WeChatLogin(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
-		// 构建账号列表
-		accounts := make([]map[string]interface{}, 0, len(user.Accounts))
-		for _, account := range user.Accounts {
-			accounts = append(accounts, map[string]interface{}{
-				"provider":   account.Provider,
-				"type":       account.Type,
-				"nickname":   account.Nickname,
-				"avatarUrl":  account.AvatarURL,
-				"createdAt":  account.CreatedAt,
-			})
-		}
-
-		// 从微信账号获取昵称和头像
-		var nickname, avatarUrl string
-		if len(user.Accounts) > 0 {
-			nickname = user.Accounts[0].Nickname
-			avatarUrl = user.Accounts[0].AvatarURL
-		}
-
-		// 生成 JWT Token
-		token, err := service.GenerateToken(user.UserID, cfg.JWTSecret)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, LoginResponse{
-				Success: false,
-				Error:   "生成Token失败",
-			})
-			return
-		}
-
-		// 创建会话
-		_, err = service.CreateSession(db, user.UserID, token, nil)
+		// 创建会话并签发令牌
+		tokens, err := service.IssueTokens(db, cfg, user.UserID, service.TokenOptions{
+			DeviceInfo:  requestDeviceInfo(c, req.CallbackURL),
+			LoginMethod: loginMethod,
+		})
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, LoginResponse{
 				Success: false,
@@ -189,49 +152,46 @@ func WeChatLogin(db *gorm.DB) gin.HandlerFunc {
 		}
 
 		// 记录登录流水
-		loginMethod := "wechat_open"
-		if req.Type == "mp" {
-			loginMethod = "wechat_mp"
-		}
-		_ = service.CreateLoginLog(db, user.UserID, req.CallbackURL, loginMethod)
-
-		// 更新最后登录时间
-		service.UpdateLastLogin(db, user.UserID)
-
-		// 构建完整的用户数据
-		userData := map[string]interface{}{
-			"userId":      user.UserID,
-			"unionId":     user.UnionID,
-			"phoneNumber": user.PhoneNumber,
-			"email":       user.Email,
-			"createdAt":   user.CreatedAt,
-			"lastLoginAt": user.LastLoginAt,
-			"profile": map[string]interface{}{
-				"nickname":  nickname,
-				"avatarUrl": avatarUrl,
-			},
-			"accounts": accounts,
-		}
+		_ = service.CreateLoginLog(db, cfg.TenantID, user.UserID, req.CallbackURL, loginMethod)
 
 		c.JSON(http.StatusOK, gin.H{
-			"success": true,
-			"token":    token,
-			"userId":   user.UserID,
-			"data":     userData,
+			"success":      true,
+			"token":        tokens.AccessToken,
+			"refreshToken": tokens.RefreshToken,
+			"expiresIn":    tokens.ExpiresIn,
+			"userId":       user.UserID,
+			"data":         userProfileData(user),
 		})
 	}
 }
 
 // handleWeChatLoginRedirect 处理微信登录重定向（智能检测）
-func handleWeChatLoginRedirect(c *gin.Context) {
-	cfg := config.Load()
+func handleWeChatLoginRedirect(c *gin.Context, db *gorm.DB) {
+	cfg := loadConfig(c)
+	userAgent := c.GetHeader("User-Agent")
+
+	// 企业微信内使用企业微信网页授权（未配置企业微信时沿用公众号授权），
+	// 微信内置浏览器使用公众号授权，PC 浏览器使用开放平台扫码登录
+	loginMethod := models.LoginMethodWeChatOpen
+	switch {
+	case service.IsWeComBrowser(userAgent) && cfg.WeComCorpID != "":
+		loginMethod = models.LoginMethodWeCom
+	case isWechatBrowser(userAgent):
+		loginMethod = models.LoginMethodWeChatMP
+	}
+	startProviderLogin(c, db, loginMethod)
+}
+
+// startProviderLogin 校验 callbackUrl 后跳转到上游登录方式的授权页
+func startProviderLogin(c *gin.Context, db *gorm.DB, loginMethod string) {
+	cfg := loadConfig(c)
 	callbackURL := c.Query("callbackUrl")
 	if callbackURL == "" {
 		callbackURL = "/"
 	}
 
-	// 验证回调 URL（简单验证：必须以 https://os.crazyaigc.com 开头）
-	if !isValidCallbackURL(callbackURL) {
+	// 验证回调 URL：auth-center 自身页面或已登记客户端的回调地址
+	if !isValidCallbackURL(db, cfg, callbackURL) {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"success": false,
 			"error": gin.H{
@@ -242,10 +202,10 @@ func handleWeChatLoginRedirect(c *gin.Context) {
 		return
 	}
 
-	// 获取 Host（优先使用 X-Forwarded-Host，因为可能有 Nginx 代理）
-	host := c.GetHeader("X-Forwarded-Host")
+	// 获取 Host（Tenant 中间件只信任 TRUSTED_PROXIES 转发的 X-Forwarded-Host）
+	host := c.GetString("requestHost")
 	if host == "" {
-		host = c.GetHeader("Host")
+		host = c.Request.Host
 	}
 	// 移除端口号
 	if idx := len(host) - 1; idx >= 0 && host[idx] >= '0' && host[idx] <= '9' {
@@ -260,59 +220,63 @@ func handleWeChatLoginRedirect(c *gin.Context) {
 		host = "os.crazyaigc.com"
 	}
 
-	// 检测是否在微信内置浏览器
-	userAgent := c.GetHeader("User-Agent")
-	isInWeChat := isWechatBrowser(userAgent)
-
-	if isInWeChat {
-		// 微信内置浏览器：使用公众号授权
-		if cfg.WeChatMPAppID == "" {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"success": false,
-				"error":   "公众号配置缺失",
-			})
-			return
-		}
+	redirectToProvider(c, db, cfg, loginMethod, host, callbackURL)
+}
 
-		redirectURI := fmt.Sprintf("https://%s/api/auth/wechat/mp-redirect", host)
-		state := callbackURL
-		authURL := fmt.Sprintf(
-			"https://open.weixin.qq.com/connect/oauth2/authorize?appid=%s&redirect_uri=%s&response_type=code&scope=snsapi_userinfo&state=%s#wechat_redirect",
-			cfg.WeChatMPAppID,
-			redirectURI,
-			state,
-		)
-		c.Redirect(http.StatusFound, authURL)
-	} else {
-		// PC 浏览器：使用开放平台扫码登录
-		if cfg.WeChatAppID == "" {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"success": false,
-				"error":   "微信开放平台配置缺失",
-			})
-			return
-		}
+// redirectToProvider 签发 state 并跳转到上游登录方式的授权页
+func redirectToProvider(c *gin.Context, db *gorm.DB, cfg *config.Config, loginMethod, host, callbackURL string) {
+	provider, err := service.GetIdentityProvider(loginMethod)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+	appID := provider.AppID(cfg)
+	if appID == "" {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   service.ErrIdentityProviderNotConfigured.Error(),
+		})
+		return
+	}
+	if !allowsLoginMethod(db, cfg, callbackURL, loginMethod) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"success": false,
+			"error":   "该应用不允许此登录方式",
+		})
+		return
+	}
 
-		redirectURI := fmt.Sprintf("https://%s/api/auth/wechat/open-platform-redirect", host)
-		state := callbackURL
-		authURL := fmt.Sprintf(
-			"https://open.weixin.qq.com/connect/qrconnect?appid=%s&redirect_uri=%s&response_type=code&scope=snsapi_login&state=%s#wechat_redirect",
-			cfg.WeChatAppID,
-			redirectURI,
-			state,
-		)
-		c.Redirect(http.StatusFound, authURL)
+	redirectURI := fmt.Sprintf("https://%s%s%s", host, c.GetString("tenantBasePath"), providerRedirectPaths[loginMethod])
+	state, err := issueLoginState(c, db, cfg, loginMethod, appID, callbackURL)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "创建登录状态失败",
+		})
+		return
 	}
+	c.Redirect(http.StatusFound, provider.AuthURL(cfg, redirectURI, state))
 }
 
 // isValidCallbackURL 验证回调 URL
-func isValidCallbackURL(callbackURL string) bool {
-	// 允许根路径
-	if callbackURL == "/" {
+// 允许 auth-center 自身页面与当前租户已登记客户端的回调地址；ALLOWED_CALLBACK_DOMAINS 仅作为默认租户迁移期兜底
+func isValidCallbackURL(db *gorm.DB, cfg *config.Config, callbackURL string) bool {
+	// auth-center 自身（根路径、OIDC 授权流程回跳、管理后台）
+	if isInternalCallbackURL(cfg, callbackURL) {
 		return true
 	}
 
-	cfg := config.Load()
+	// 已登记客户端的回调地址
+	if _, err := service.FindClientByCallbackURL(db, cfg.TenantID, callbackURL); err == nil {
+		return true
+	}
+
+	if cfg.AllowedCallbackDomains == "" {
+		return false
+	}
 
 	// 解析 URL
 	parsedURL, err := url.Parse(callbackURL)
@@ -355,33 +319,103 @@ func isValidCallbackURL(callbackURL string) bool {
 	return false
 }
 
-// WeChatCallback 微信公众号回调
-func WeChatCallback(db *gorm.DB) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		code := c.Query("code")
-		state := c.Query("state")
+// allowsLoginMethod 回调地址所属客户端是否允许该登录方式（未登记为客户端的回调地址不限制）
+func allowsLoginMethod(db *gorm.DB, cfg *config.Config, callbackURL, method string) bool {
+	client, err := service.FindClientByCallbackURL(db, cfg.TenantID, callbackURL)
+	if err != nil {
+		return true
+	}
+	return client.AllowsLoginMethod(method)
+}
 
-		if code == "" {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"success": false,
-				"error":   "缺少授权码",
-			})
-			return
-		}
+// isInternalCallbackURL 回调地址是否为 auth-center 自身页面（与租户的 issuer 同源）
+func isInternalCallbackURL(cfg *config.Config, callbackURL string) bool {
+	if callbackURL == "/" {
+		return true
+	}
 
-		// 重定向到前端，带上授权码
-		callbackURL := state // 从 state 参数获取回调 URL
-		if callbackURL == "" {
-			callbackURL = "http://localhost:3000/auth-complete"
+	parsedURL, err := url.Parse(callbackURL)
+	if err != nil {
+		return false
+	}
+	issuerURL, err := url.Parse(cfg.Issuer)
+	return err == nil && issuerURL.Host != "" &&
+		parsedURL.Host == issuerURL.Host && parsedURL.Scheme == issuerURL.Scheme
+}
+
+// isAuthorizeResumeURL 回调地址是否为当前租户的 OIDC 授权续接地址（<issuer>/oauth/authorize/resume）
+// 只有这一地址使用不绑定客户端的交换码，交换码绑定浏览器 nonce，由 AuthorizeResume 兑换
+func isAuthorizeResumeURL(cfg *config.Config, callbackURL string) bool {
+	parsedURL, err := url.Parse(callbackURL)
+	if err != nil || parsedURL.User != nil || parsedURL.Opaque != "" || parsedURL.Host == "" {
+		return false
+	}
+	return parsedURL.Scheme+"://"+parsedURL.Host+parsedURL.Path == strings.TrimSuffix(cfg.Issuer, "/")+"/oauth/authorize/resume"
+}
+
+// loginNonceCookieName 绑定登录流程（上游登录 state、OIDC 授权请求与续接交换码）的浏览器 Cookie
+const loginNonceCookieName = "auth_center_login_nonce"
+
+// loginNonce 返回当前浏览器的登录 nonce（没有时生成），并刷新 Cookie 有效期
+// 同一浏览器复用已有的 nonce，多个标签页并发登录互不影响
+func loginNonce(c *gin.Context, cfg *config.Config) (string, error) {
+	nonce, err := c.Cookie(loginNonceCookieName)
+	if err != nil || nonce == "" {
+		if nonce, err = service.GenerateOAuthNonce(); err != nil {
+			return "", err
 		}
+	}
 
-		redirectURL := callbackURL + "?code=" + code + "&type=mp"
-		c.Redirect(http.StatusFound, redirectURL)
+	// 上游回跳与业务系统发起授权都是跨站顶级导航，SameSite=Lax 的 Cookie 会被带上
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(loginNonceCookieName, nonce, int(service.OAuthStateExpiration.Seconds()), c.GetString("tenantBasePath")+"/", "", cfg.Environment == "production", true)
+	return nonce, nil
+}
+
+// issueLoginState 签发绑定当前浏览器的上游登录 state（flow 为登录方式）
+func issueLoginState(c *gin.Context, db *gorm.DB, cfg *config.Config, loginMethod, appID, callbackURL string) (string, error) {
+	nonce, err := loginNonce(c, cfg)
+	if err != nil {
+		return "", err
 	}
+	return service.IssueOAuthState(db, nonce, loginMethod, appID, callbackURL)
 }
 
-// OpenPlatformCallback 开放平台回调
+// consumeLoginState 校验并作废上游回传的 state，失败时直接写入错误响应
+// 回调地址在使用前按当前白名单再校验一次
+func consumeLoginState(c *gin.Context, db *gorm.DB, cfg *config.Config, provider service.IdentityProvider, state string) (*models.OAuthState, bool) {
+	nonce, _ := c.Cookie(loginNonceCookieName)
+	record, err := service.ConsumeOAuthState(db, state, nonce, provider.Name(), provider.AppID(cfg))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   service.ErrInvalidOAuthState.Error(),
+		})
+		return nil, false
+	}
+
+	if !isValidCallbackURL(db, cfg, record.CallbackURL) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "回调 URL 不在允许的域名列表中",
+		})
+		return nil, false
+	}
+	return record, true
+}
+
+// WeChatCallback 微信公众号回调（旧流程：带着微信授权码回到前端，由前端 POST /wechat/login 兑换）
+func WeChatCallback(db *gorm.DB) gin.HandlerFunc {
+	return forwardProviderCode(db, models.LoginMethodWeChatMP, "mp")
+}
+
+// OpenPlatformCallback 开放平台回调（旧流程，同 WeChatCallback）
 func OpenPlatformCallback(db *gorm.DB) gin.HandlerFunc {
+	return forwardProviderCode(db, models.LoginMethodWeChatOpen, "open")
+}
+
+// forwardProviderCode 校验 state 后带着上游授权码重定向到签发时绑定的回调地址
+func forwardProviderCode(db *gorm.DB, loginMethod, loginType string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// 尝试从多个地方获取code参数
 		code := c.Query("code")
@@ -396,8 +430,6 @@ func OpenPlatformCallback(db *gorm.DB) gin.HandlerFunc {
 			}
 		}
 
-		state := c.Query("state")
-
 		if code == "" {
 			c.JSON(http.StatusBadRequest, gin.H{
 				"success": false,
@@ -406,19 +438,52 @@ func OpenPlatformCallback(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
-		// 重定向到前端，带上授权码
-		callbackURL := state
-		if callbackURL == "" {
-			callbackURL = "/admin/dashboard"
+		provider, err := service.GetIdentityProvider(loginMethod)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error":   err.Error(),
+			})
+			return
+		}
+
+		// 校验 state，回调地址取自签发时绑定的值
+		record, ok := consumeLoginState(c, db, loadConfig(c), provider, c.Query("state"))
+		if !ok {
+			return
 		}
 
-		redirectURL := callbackURL + "?code=" + code + "&type=open"
+		// 重定向到前端，带上授权码
+		redirectURL := appendQuery(record.CallbackURL, map[string]string{
+			"code": code,
+			"type": loginType,
+		})
 		c.Redirect(http.StatusFound, redirectURL)
 	}
 }
 
+// wechatLoginType 登录方式对应的 POST /api/auth/wechat/login type 参数
+func wechatLoginType(loginMethod string) string {
+	for loginType, method := range wechatLoginTypes {
+		if method == loginMethod {
+			return loginType
+		}
+	}
+	return ""
+}
+
 // WeChatMPRedirect 公众号授权重定向（接收微信回调）
 func WeChatMPRedirect(db *gorm.DB) gin.HandlerFunc {
+	return ProviderRedirect(db, models.LoginMethodWeChatMP)
+}
+
+// OpenPlatformRedirect 开放平台授权重定向（接收微信回调）
+func OpenPlatformRedirect(db *gorm.DB) gin.HandlerFunc {
+	return ProviderRedirect(db, models.LoginMethodWeChatOpen)
+}
+
+// ProviderRedirect 上游授权后的回跳入口：校验 state，完成登录流水线，带一次性交换码回到业务系统
+func ProviderRedirect(db *gorm.DB, loginMethod string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		code := c.Query("code")
 		state := c.Query("state")
@@ -431,92 +496,233 @@ func WeChatMPRedirect(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
-		// 完成微信登录流程
-		cfg := config.Load()
-		result, err := service.CompleteWeChatLogin(db, cfg, code, true) // isMP = true
+		provider, err := service.GetIdentityProvider(loginMethod)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
+			c.JSON(http.StatusBadRequest, gin.H{
 				"success": false,
-				"error":   "登录失败: " + err.Error(),
+				"error":   err.Error(),
 			})
 			return
 		}
 
-		callbackURL := state
-		_ = service.CreateLoginLog(db, result.UserID, callbackURL, "wechat_mp")
+		// 校验 state（CSRF 防护），回调地址取自签发时绑定的值
+		cfg := loadConfig(c)
+		record, ok := consumeLoginState(c, db, cfg, provider, state)
+		if !ok {
+			return
+		}
 
-		// 重定向到业务系统，带上 userId 和 token
-		if callbackURL == "" {
-			callbackURL = "/admin/dashboard"
+		// auth-center 自身页面（管理后台等）不使用交换码：带着上游授权码回去，由页面 POST /wechat/login 兑换
+		if isInternalCallbackURL(cfg, record.CallbackURL) && !isAuthorizeResumeURL(cfg, record.CallbackURL) {
+			c.Redirect(http.StatusFound, appendQuery(record.CallbackURL, map[string]string{
+				"code": code,
+				"type": wechatLoginType(loginMethod),
+			}))
+			return
 		}
 
-		redirectURL := callbackURL + "?userId=" + result.UserID + "&token=" + result.Token
-		c.Redirect(http.StatusFound, redirectURL)
+		// 完成上游登录流程
+		user, _, err := service.AuthenticateWithProvider(c.Request.Context(), db, cfg, provider, code)
+		if err != nil {
+			writeProviderLoginError(c, provider.Name(), err)
+			return
+		}
+
+		// 重定向到业务系统，带上一次性交换码
+		redirectWithExchangeCode(c, db, cfg, user.UserID, record.CallbackURL, provider.Name())
 	}
 }
 
-// OpenPlatformRedirect 开放平台授权重定向（接收微信回调）
-func OpenPlatformRedirect(db *gorm.DB) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		code := c.Query("code")
-		state := c.Query("state")
+// writeProviderLoginError 按错误类型返回上游登录失败的错误
+// 面向用户的错误原样返回，其他错误（上游接口、数据库等）只记录日志，不把内部细节返回给浏览器
+func writeProviderLoginError(c *gin.Context, loginMethod string, err error) {
+	switch {
+	case errors.Is(err, service.ErrWeChatUnionIDMissing),
+		errors.Is(err, service.ErrAuthCodeInvalid),
+		errors.Is(err, service.ErrWeComNotMember):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+	case errors.Is(err, wechat.ErrCircuitOpen):
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+	default:
+		log.Printf("%s 登录失败: %v", loginMethod, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "登录失败，请稍后重试",
+		})
+	}
+}
 
-		if code == "" {
+// redirectWithExchangeCode 登录完成后带着一次性交换码回到业务系统
+// 令牌不出现在 URL 中：注册为机密客户端的业务系统由服务端凭密钥兑换，OIDC 授权续接的交换码绑定当前浏览器
+// 回调地址未注册为客户端时，迁移期内按旧方式携带 token（LEGACY_TOKEN_REDIRECT）
+func redirectWithExchangeCode(c *gin.Context, db *gorm.DB, cfg *config.Config, userID, callbackURL, loginMethod string) {
+	device := requestDeviceInfo(c, callbackURL)
+	_ = service.CreateLoginLog(db, cfg.TenantID, userID, callbackURL, loginMethod)
+
+	clientID, ok := "", isAuthorizeResumeURL(cfg, callbackURL)
+	if !ok {
+		clientID, ok = exchangeCodeClientID(db, cfg, callbackURL)
+	}
+	if ok {
+		code, err := issueCallbackExchangeCode(c, db, clientID, userID, callbackURL, loginMethod, device)
+		if errors.Is(err, service.ErrInvalidOAuthState) {
 			c.JSON(http.StatusBadRequest, gin.H{
 				"success": false,
-				"error":   "缺少授权码",
+				"error":   err.Error(),
+			})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"error":   "签发交换码失败",
 			})
 			return
 		}
+		c.Redirect(http.StatusFound, appendQuery(callbackURL, map[string]string{
+			"exchangeCode": code,
+		}))
+		return
+	}
 
-		// 验证回调URL是否在白名单中
-		callbackURL := state
-		if callbackURL == "" {
+	if !cfg.LegacyTokenRedirect {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "回调地址未注册为客户端，请联系管理员",
+		})
+		return
+	}
+
+	log.Printf("警告: 回调地址 %s 未注册为客户端，令牌通过 URL 传递", callbackURL)
+	tokens, err := service.IssueTokens(db, cfg, userID, service.TokenOptions{DeviceInfo: device, LoginMethod: loginMethod})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "创建会话失败",
+		})
+		return
+	}
+	c.Redirect(http.StatusFound, appendQuery(callbackURL, map[string]string{
+		"userId":       userID,
+		"token":        tokens.AccessToken,
+		"refreshToken": tokens.RefreshToken,
+	}))
+}
+
+// exchangeCodeClientID 确定交换码的兑换方：注册了该回调地址的机密客户端
+func exchangeCodeClientID(db *gorm.DB, cfg *config.Config, callbackURL string) (string, bool) {
+	client, err := service.FindClientByCallbackURL(db, cfg.TenantID, callbackURL)
+	if err != nil || client.IsPublic() {
+		return "", false
+	}
+	return client.ClientID, true
+}
+
+// issueCallbackExchangeCode 签发交换码：clientID 为空时（OIDC 授权续接）绑定当前浏览器的登录 nonce
+// 浏览器没有 nonce Cookie 时返回 ErrInvalidOAuthState
+func issueCallbackExchangeCode(c *gin.Context, db *gorm.DB, clientID, userID, callbackURL, loginMethod string, device *models.DeviceInfo) (string, error) {
+	if clientID != "" {
+		return service.IssueExchangeCode(db, clientID, userID, callbackURL, loginMethod, device)
+	}
+	nonce, _ := c.Cookie(loginNonceCookieName)
+	if nonce == "" {
+		return "", service.ErrInvalidOAuthState
+	}
+	return service.IssueBrowserExchangeCode(db, nonce, userID, callbackURL, loginMethod, device)
+}
+
+// ExchangeCodeRequest 交换码兑换请求
+type ExchangeCodeRequest struct {
+	Code         string `json:"code" binding:"required"`
+	ClientID     string `json:"clientId"` // 也可使用 HTTP Basic 认证
+	ClientSecret string `json:"clientSecret"`
+}
+
+// ExchangeCode 兑换登录交换码，返回令牌与用户信息
+// 业务系统服务端调用，必须提供机密客户端凭证
+func ExchangeCode(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Cache-Control", "no-store")
+
+		var req ExchangeCodeRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{
 				"success": false,
-				"error":   "缺少回调地址",
+				"error":   "无效的请求参数",
 			})
 			return
 		}
 
-		if !isValidCallbackURL(callbackURL) {
-			c.JSON(http.StatusBadRequest, gin.H{
+		clientID, clientSecret := req.ClientID, req.ClientSecret
+		if id, secret, ok := c.Request.BasicAuth(); ok {
+			clientID, _ = url.QueryUnescape(id)
+			clientSecret, _ = url.QueryUnescape(secret)
+		}
+		tenantID := c.GetString("tenantId")
+		if clientID == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{
 				"success": false,
-				"error":   "回调 URL 不在允许的域名列表中",
+				"error":   "缺少客户端凭证",
+			})
+			return
+		}
+		client, err := service.AuthenticateClient(db, tenantID, clientID, clientSecret)
+		if err != nil || client.IsPublic() {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"error":   "客户端认证失败",
 			})
 			return
 		}
 
-		// 完成微信登录流程，用 code 换 token（统一为 token 模式）
-		cfg := config.Load()
-		result, err := service.CompleteWeChatLogin(db, cfg, code, false) // isMP = false (开放平台)
+		exchangeCode, err := service.RedeemExchangeCode(db, clientID, req.Code)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
+			c.JSON(http.StatusBadRequest, gin.H{
 				"success": false,
-				"error":   "登录失败: " + err.Error(),
+				"error":   err.Error(),
 			})
 			return
 		}
 
-		// 记录登录流水
-		_ = service.CreateLoginLog(db, result.UserID, callbackURL, "wechat_open")
+		var user models.User
+		if err := db.Preload("Accounts").Where("user_id = ? AND tenant_id = ?", exchangeCode.UserID, tenantID).First(&user).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"success": false,
+				"error":   "用户不存在",
+			})
+			return
+		}
 
-		// 重定向到业务系统，带上 token
-		u, err := url.Parse(callbackURL)
+		// 创建会话并签发令牌（会话归属兑换方客户端）
+		cfg := loadConfig(c)
+		tokens, err := service.IssueTokens(db, cfg, user.UserID, service.TokenOptions{
+			ClientID:    clientID,
+			DeviceInfo:  service.ParseDeviceInfo(exchangeCode.DeviceInfo),
+			LoginMethod: exchangeCode.LoginMethod,
+			AuthTime:    exchangeCode.CreatedAt,
+		})
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{
 				"success": false,
-				"error":   "无效的回调 URL",
+				"error":   "创建会话失败",
 			})
 			return
 		}
 
-		// 添加 token 参数
-		query := u.Query()
-		query.Set("token", result.Token)
-		u.RawQuery = query.Encode()
-
-		c.Redirect(http.StatusFound, u.String())
+		c.JSON(http.StatusOK, gin.H{
+			"success":      true,
+			"token":        tokens.AccessToken,
+			"refreshToken": tokens.RefreshToken,
+			"expiresIn":    tokens.ExpiresIn,
+			"userId":       user.UserID,
+			"data":         userProfileData(&user),
+		})
 	}
 }
 
@@ -525,7 +731,8 @@ type VerifyTokenRequest struct {
 	Token string `json:"token" binding:"required"`
 }
 
-// VerifyToken 验证 Token
+// VerifyToken 验证 Token，返回用户及其在调用方业务系统内的角色与权限（实时查询）
+// 业务系统服务端凭客户端凭证调用（ClientAuth），只能验证第一方令牌或签发给自己的令牌
 func VerifyToken(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var req VerifyTokenRequest
@@ -537,11 +744,9 @@ func VerifyToken(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
-		cfg := config.Load()
-
-		// 验证 Token
-		_, err := service.ValidateToken(req.Token, cfg.JWTSecret)
-		if err != nil {
+		// 验证 Token（只接受当前租户签发的令牌）
+		claims, err := service.ValidateToken(req.Token)
+		if err != nil || claims.Tenant() != c.GetString("tenantId") {
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"success": false,
 				"error":   "无效的Token",
@@ -550,7 +755,7 @@ func VerifyToken(db *gorm.DB) gin.HandlerFunc {
 		}
 
 		// 检查会话是否有效
-		user, err := service.GetUserByToken(db, req.Token)
+		session, err := service.GetSessionByToken(db, req.Token)
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"success": false,
@@ -559,12 +764,55 @@ func VerifyToken(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
+		// 签发给其他业务系统的令牌不能由调用方验证
+		clientID := c.GetString("clientId")
+		if session.ClientID != "" && session.ClientID != clientID {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"error":   "无效的Token",
+			})
+			return
+		}
+		var user models.User
+		if err := db.Where("user_id = ?", session.UserID).First(&user).Error; err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"error":   "会话已过期",
+			})
+			return
+		}
+
+		orgs, err := service.UserOrgClaims(db, user.UserID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"error":   "获取用户组织失败",
+			})
+			return
+		}
+
+		data := gin.H{
+			"userId":  user.UserID,
+			"unionId": user.UnionID,
+			"orgs":    orgs,
+		}
+
+		// 角色与权限取调用方业务系统的
+		authz, err := service.UserAuthorization(db, clientID, user.UserID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"error":   "获取用户权限失败",
+			})
+			return
+		}
+		data["clientId"] = authz.ClientID
+		data["roles"] = authz.Roles
+		data["permissions"] = authz.Permissions
+
 		c.JSON(http.StatusOK, gin.H{
 			"success": true,
-			"data": gin.H{
-				"userId": user.UserID,
-				"unionId": user.UnionID,
-			},
+			"data":    data,
 		})
 	}
 }
@@ -584,45 +832,56 @@ func GetUserInfo(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
-		// 构建账号列表（包含昵称和头像）
-		accounts := make([]map[string]interface{}, 0, len(user.Accounts))
-		for _, account := range user.Accounts {
-			accounts = append(accounts, map[string]interface{}{
-				"provider":   account.Provider,
-				"type":       account.Type,
-				"nickname":   account.Nickname,
-				"avatarUrl":  account.AvatarURL,
-				"createdAt":  account.CreatedAt,
-			})
-		}
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data":    userProfileData(&user),
+		})
+	}
+}
 
-		// 从微信账号获取昵称和头像（优先使用最近登录的账号）
-		var nickname, avatarUrl string
-		if len(user.Accounts) > 0 {
-			// 使用第一个账号（通常是最近登录的）
-			nickname = user.Accounts[0].Nickname
-			avatarUrl = user.Accounts[0].AvatarURL
+// userProfileData 构建返回给业务系统的用户数据（需预加载 Accounts）
+func userProfileData(user *models.User) map[string]interface{} {
+	// 构建账号列表（包含昵称和头像）
+	accounts := make([]map[string]interface{}, 0, len(user.Accounts))
+	for _, account := range user.Accounts {
+		item := map[string]interface{}{
+			"provider":  account.Provider,
+			"type":      account.Type,
+			"nickname":  account.Nickname,
+			"avatarUrl": account.AvatarURL,
+			"createdAt": account.CreatedAt,
 		}
-
-		// 构建返回数据
-		data := map[string]interface{}{
-			"userId":      user.UserID,
-			"unionId":     user.UnionID,
-			"phoneNumber": user.PhoneNumber,
-			"email":       user.Email,
-			"createdAt":   user.CreatedAt,
-			"lastLoginAt": user.LastLoginAt,
-			"profile": map[string]interface{}{
-				"nickname":  nickname,
-				"avatarUrl": avatarUrl,
-			},
-			"accounts": accounts,
+		// 企业微信同步的部门
+		if departments := account.DepartmentList(); departments != nil {
+			item["departments"] = departments
+		}
+		// 公众号关注状态
+		if account.Type == "mp" {
+			item["subscribed"] = account.Subscribed
 		}
+		accounts = append(accounts, item)
+	}
 
-		c.JSON(http.StatusOK, gin.H{
-			"success": true,
-			"data":    data,
-		})
+	// 从微信账号获取昵称和头像（优先使用最近登录的账号）
+	var nickname, avatarUrl string
+	if len(user.Accounts) > 0 {
+		// 使用第一个账号（通常是最近登录的）
+		nickname = user.Accounts[0].Nickname
+		avatarUrl = user.Accounts[0].AvatarURL
+	}
+
+	return map[string]interface{}{
+		"userId":      user.UserID,
+		"unionId":     user.UnionID,
+		"phoneNumber": user.PhoneNumber,
+		"email":       user.Email,
+		"createdAt":   user.CreatedAt,
+		"lastLoginAt": user.LastLoginAt,
+		"profile": map[string]interface{}{
+			"nickname":  nickname,
+			"avatarUrl": avatarUrl,
+		},
+		"accounts": accounts,
 	}
 }
 
@@ -630,9 +889,11 @@ func GetUserInfo(db *gorm.DB) gin.HandlerFunc {
 type PasswordLoginRequest struct {
 	PhoneNumber string `json:"phoneNumber" binding:"required"`
 	Password    string `json:"password" binding:"required"`
+	CallbackURL string `json:"callbackUrl"` // 可选，发起登录的业务系统，记录到会话设备信息
 }
 
 // PasswordLogin 密码登录
+// callbackUrl 为 auth-center 自身页面（如 OIDC 授权的 resume 地址）时不返回令牌，而是返回带一次性交换码的 redirectUrl
 func PasswordLogin(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var req PasswordLoginRequest
@@ -644,8 +905,17 @@ func PasswordLogin(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
+		cfg := loadConfig(c)
+		if req.CallbackURL != "" && !allowsLoginMethod(db, cfg, req.CallbackURL, models.LoginMethodPassword) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"success": false,
+				"error":   "该应用不允许此登录方式",
+			})
+			return
+		}
+
 		// 验证密码
-		user, err := service.VerifyPassword(db, req.PhoneNumber, req.Password)
+		user, err := service.VerifyPassword(db, cfg.TenantID, req.PhoneNumber, req.Password)
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"success": false,
@@ -662,19 +932,37 @@ func PasswordLogin(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
-		// 生成 JWT Token
-		cfg := config.Load()
-		token, err := service.GenerateToken(user.UserID, cfg.JWTSecret)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"success": false,
-				"error":   "生成Token失败",
+		// OIDC 授权续接：令牌不经过登录页与 URL，带绑定当前浏览器的交换码回到 /oauth/authorize/resume
+		if isAuthorizeResumeURL(cfg, req.CallbackURL) {
+			code, err := issueCallbackExchangeCode(c, db, "", user.UserID, req.CallbackURL, models.LoginMethodPassword, requestDeviceInfo(c, req.CallbackURL))
+			if errors.Is(err, service.ErrInvalidOAuthState) {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"success": false,
+					"error":   err.Error(),
+				})
+				return
+			}
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"success": false,
+					"error":   "签发交换码失败",
+				})
+				return
+			}
+			service.UpdateLastLogin(db, user.UserID)
+			c.JSON(http.StatusOK, LoginResponse{
+				Success:     true,
+				UserID:      user.UserID,
+				RedirectURL: appendQuery(req.CallbackURL, map[string]string{"exchangeCode": code}),
 			})
 			return
 		}
 
-		// 创建会话
-		_, err = service.CreateSession(db, user.UserID, token, nil)
+		// 创建会话并签发令牌
+		tokens, err := service.IssueTokens(db, cfg, user.UserID, service.TokenOptions{
+			DeviceInfo:  requestDeviceInfo(c, req.CallbackURL),
+			LoginMethod: models.LoginMethodPassword,
+		})
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{
 				"success": false,
@@ -687,9 +975,52 @@ func PasswordLogin(db *gorm.DB) gin.HandlerFunc {
 		service.UpdateLastLogin(db, user.UserID)
 
 		c.JSON(http.StatusOK, LoginResponse{
-			Success: true,
-			Token:   token,
-			UserID:  user.UserID,
+			Success:      true,
+			Token:        tokens.AccessToken,
+			RefreshToken: tokens.RefreshToken,
+			ExpiresIn:    tokens.ExpiresIn,
+			UserID:       user.UserID,
+		})
+	}
+}
+
+// RefreshRequest 刷新令牌请求
+type RefreshRequest struct {
+	RefreshToken string `json:"refreshToken" binding:"required"`
+}
+
+// Refresh 使用刷新令牌换取新的访问令牌（刷新令牌同时轮换，旧令牌作废）
+func Refresh(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req RefreshRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error":   "无效的请求参数",
+			})
+			return
+		}
+
+		cfg := loadConfig(c)
+		tokens, err := service.RefreshTokens(db, cfg, req.RefreshToken, "")
+		if err != nil {
+			message := "刷新令牌无效或已过期"
+			if errors.Is(err, service.ErrRefreshTokenReused) {
+				message = "刷新令牌已失效，请重新登录"
+			}
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"error":   message,
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, LoginResponse{
+			Success:      true,
+			Token:        tokens.AccessToken,
+			RefreshToken: tokens.RefreshToken,
+			ExpiresIn:    tokens.ExpiresIn,
+			UserID:       tokens.UserID,
 		})
 	}
 }
@@ -731,7 +1062,7 @@ func GetSessions(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		userID := c.GetString("userId")
 
-		sessions, err := service.GetSessions(db, userID)
+		sessions, err := service.ListSessions(db, userID, c.GetString("sessionId"))
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{
 				"success": false,
@@ -749,3 +1080,115 @@ func GetSessions(db *gorm.DB) gin.HandlerFunc {
 	}
 }
 
+// RevokeSession 吊销当前用户的某个会话（在其他设备上登出）
+func RevokeSession(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.GetString("userId")
+		sessionID := c.Param("id")
+
+		if _, err := uuid.Parse(sessionID); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"success": false,
+				"error":   service.ErrSessionNotFound.Error(),
+			})
+			return
+		}
+
+		if err := service.RevokeUserSession(db, userID, sessionID); err != nil {
+			if errors.Is(err, service.ErrSessionNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{
+					"success": false,
+					"error":   err.Error(),
+				})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"error":   "吊销会话失败",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+		})
+	}
+}
+
+// RevokeOtherSessions 吊销当前用户除本会话外的全部会话
+func RevokeOtherSessions(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.GetString("userId")
+
+		count, err := service.RevokeOtherSessions(db, userID, c.GetString("sessionId"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"error":   "吊销会话失败",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data": gin.H{
+				"revoked": count,
+			},
+		})
+	}
+}
+
+// RenameSessionRequest 设备重命名请求
+type RenameSessionRequest struct {
+	Name string `json:"name"`
+}
+
+// RenameSession 为当前用户的某个会话设置设备名称
+func RenameSession(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.GetString("userId")
+		sessionID := c.Param("id")
+
+		var req RenameSessionRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error":   "无效的请求参数",
+			})
+			return
+		}
+
+		if _, err := uuid.Parse(sessionID); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"success": false,
+				"error":   service.ErrSessionNotFound.Error(),
+			})
+			return
+		}
+
+		if err := service.RenameSession(db, userID, sessionID, req.Name); err != nil {
+			switch {
+			case errors.Is(err, service.ErrSessionNotFound):
+				c.JSON(http.StatusNotFound, gin.H{
+					"success": false,
+					"error":   err.Error(),
+				})
+			case errors.Is(err, service.ErrInvalidSessionName):
+				c.JSON(http.StatusBadRequest, gin.H{
+					"success": false,
+					"error":   err.Error(),
+				})
+			default:
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"success": false,
+					"error":   "重命名设备失败",
+				})
+			}
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+		})
+	}
+}
@@ -0,0 +1,167 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/keenchase/auth-center/internal/models"
+	"github.com/keenchase/auth-center/internal/service"
+	"gorm.io/gorm"
+)
+
+// GetUserAuthorization 业务系统查询用户在本系统内的角色与权限
+func GetUserAuthorization(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.Param("id")
+		if _, err := uuid.Parse(userID); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"success": false,
+				"error":   service.ErrUserNotFound.Error(),
+			})
+			return
+		}
+
+		authz, err := service.UserAuthorization(db, c.GetString("clientId"), userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"error":   "获取用户权限失败",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data": gin.H{
+				"userId":      userID,
+				"clientId":    authz.ClientID,
+				"roles":       authz.Roles,
+				"permissions": authz.Permissions,
+			},
+		})
+	}
+}
+
+// CheckPermissionRequest 权限检查请求
+type CheckPermissionRequest struct {
+	UserID     string `json:"userId" binding:"required"`
+	Permission string `json:"permission" binding:"required"`
+}
+
+// CheckPermission 业务系统检查用户在本系统内是否拥有指定权限
+func CheckPermission(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req CheckPermissionRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error":   "无效的请求参数",
+			})
+			return
+		}
+		if _, err := uuid.Parse(req.UserID); err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": true,
+				"data": gin.H{
+					"allowed": false,
+				},
+			})
+			return
+		}
+
+		allowed, err := service.UserHasPermission(db, c.GetString("clientId"), req.UserID, req.Permission)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"error":   "权限检查失败",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data": gin.H{
+				"allowed": allowed,
+			},
+		})
+	}
+}
+
+// AuthzCheckRequest 策略判定请求，token 与 userId 二选一
+type AuthzCheckRequest struct {
+	Token      string                 `json:"token"`  // 用户的访问令牌，可使用会话属性（登录方式、认证时间等）
+	UserID     string                 `json:"userId"` // 仅按用户判定，session.* 属性不存在
+	Resource   string                 `json:"resource" binding:"required"`
+	Action     string                 `json:"action" binding:"required"`
+	Attributes map[string]interface{} `json:"attributes"` // 资源属性，条件中以 resource.<name> 引用
+}
+
+// CheckAuthz 按本系统的访问策略判定用户能否对资源执行操作，返回 allow/deny 及依据
+func CheckAuthz(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req AuthzCheckRequest
+		if err := c.ShouldBindJSON(&req); err != nil || (req.Token == "") == (req.UserID == "") {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error":   "无效的请求参数",
+			})
+			return
+		}
+
+		clientID := c.GetString("clientId")
+		authzReq := service.AuthzRequest{
+			ClientID:   clientID,
+			Resource:   req.Resource,
+			Action:     req.Action,
+			Attributes: req.Attributes,
+		}
+
+		userID := req.UserID
+		if req.Token != "" {
+			if _, err := service.ValidateToken(req.Token); err != nil {
+				c.JSON(http.StatusUnauthorized, gin.H{
+					"success": false,
+					"error":   "无效的Token",
+				})
+				return
+			}
+			// 只接受签发给本系统或第一方登录的令牌
+			session, err := service.GetSessionByToken(db, req.Token)
+			if err != nil || session.ClientID != "" && session.ClientID != clientID {
+				c.JSON(http.StatusUnauthorized, gin.H{
+					"success": false,
+					"error":   "会话已过期",
+				})
+				return
+			}
+			authzReq.Session = session
+			userID = session.UserID
+		}
+
+		var user models.User
+		if _, err := uuid.Parse(userID); err != nil ||
+			db.Preload("Accounts").Where("user_id = ? AND tenant_id = ?", userID, c.GetString("tenantId")).First(&user).Error != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"success": false,
+				"error":   service.ErrUserNotFound.Error(),
+			})
+			return
+		}
+		authzReq.User = &user
+
+		decision, err := service.Authorize(db, authzReq)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"error":   "策略判定失败",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data":    decision,
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package handler
+
+import (
+	"testing"
+
+	"github.com/keenchase/auth-center/internal/config"
+	"github.com/keenchase/auth-center/internal/models"
+)
+
+func TestIsValidCallbackURL(t *testing.T) {
+	db := testDB(t)
+	cfg := &config.Config{TenantID: config.DefaultTenantID, Issuer: "https://os.crazyaigc.com"}
+	client := models.Client{
+		ClientID:     "test-callback-client",
+		TenantID:     config.DefaultTenantID,
+		Name:         "测试应用",
+		RedirectURIs: "https://app.example.com/auth/callback",
+		Enabled:      true,
+	}
+	if err := db.Create(&client).Error; err != nil {
+		t.Fatalf("创建测试客户端失败: %v", err)
+	}
+	other := models.Client{
+		ClientID:     "test-callback-other",
+		TenantID:     "acme-test",
+		Name:         "其他租户的应用",
+		RedirectURIs: "https://acme.example.com/auth/callback",
+		Enabled:      true,
+	}
+	if err := db.Create(&models.Tenant{ID: "acme-test", Name: "测试租户", Enabled: true}).Error; err != nil {
+		t.Fatalf("创建测试租户失败: %v", err)
+	}
+	if err := db.Create(&other).Error; err != nil {
+		t.Fatalf("创建测试客户端失败: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		callbackURL string
+		want        bool
+	}{
+		{"auth-center 根路径", "/", true},
+		{"auth-center 自身页面", "https://os.crazyaigc.com/oauth/authorize/resume", true},
+		{"已登记的回调地址", "https://app.example.com/auth/callback", true},
+		{"已登记的回调地址附带查询参数", "https://app.example.com/auth/callback?next=%2Fhome", true},
+		{"外部域名", "https://evil.com/auth/callback", false},
+		{"相同后缀的域名", "https://evil-os.crazyaigc.com/", false},
+		{"userinfo 伪装主机", "https://os.crazyaigc.com@evil.com/", false},
+		{"auth-center 协议降级", "http://os.crazyaigc.com/", false},
+		{"已登记回调协议降级", "http://app.example.com/auth/callback", false},
+		{"已登记域名的其他路径", "https://app.example.com/other", false},
+		{"其他租户登记的回调地址", "https://acme.example.com/auth/callback", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isValidCallbackURL(db, cfg, tt.callbackURL); got != tt.want {
+				t.Fatalf("isValidCallbackURL(%q) = %v, want %v", tt.callbackURL, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsValidCallbackURLAllowedDomains(t *testing.T) {
+	db := testDB(t)
+	cfg := &config.Config{
+		TenantID:               config.DefaultTenantID,
+		Issuer:                 "https://os.crazyaigc.com",
+		AllowedCallbackDomains: "legacy.example.com, *.example.org, localhost",
+	}
+
+	tests := []struct {
+		name        string
+		callbackURL string
+		want        bool
+	}{
+		{"精确匹配", "https://legacy.example.com/callback", true},
+		{"通配符子域名", "https://app.example.org/callback", true},
+		{"通配符相同后缀", "https://evilexample.org/callback", false},
+		{"协议降级", "http://legacy.example.com/callback", false},
+		{"localhost 允许 HTTP", "http://localhost:3000/callback", true},
+		{"未列出的域名", "https://other.example.com/callback", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isValidCallbackURL(db, cfg, tt.callbackURL); got != tt.want {
+				t.Fatalf("isValidCallbackURL(%q) = %v, want %v", tt.callbackURL, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsAuthorizeResumeURL(t *testing.T) {
+	cfg := &config.Config{Issuer: "https://os.crazyaigc.com/t/acme"}
+	tests := []struct {
+		name        string
+		callbackURL string
+		want        bool
+	}{
+		{"续接地址", "https://os.crazyaigc.com/t/acme/oauth/authorize/resume?request_id=abc", true},
+		{"其他自身页面", "https://os.crazyaigc.com/t/acme/admin/dashboard", false},
+		{"根路径", "/", false},
+		{"相对路径", "/t/acme/oauth/authorize/resume", false},
+		{"其他租户", "https://os.crazyaigc.com/oauth/authorize/resume", false},
+		{"协议降级", "http://os.crazyaigc.com/t/acme/oauth/authorize/resume", false},
+		{"外部域名", "https://evil.com/t/acme/oauth/authorize/resume", false},
+		{"包含用户信息", "https://os.crazyaigc.com@evil.com/t/acme/oauth/authorize/resume", false},
+		{"路径前缀", "https://os.crazyaigc.com/t/acme/oauth/authorize/resume/extra", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isAuthorizeResumeURL(cfg, tt.callbackURL); got != tt.want {
+				t.Errorf("isAuthorizeResumeURL(%q) = %v, want %v", tt.callbackURL, got, tt.want)
+			}
+		})
+	}
+}
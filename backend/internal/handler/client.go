@@ -0,0 +1,164 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/keenchase/auth-center/internal/service"
+	"gorm.io/gorm"
+)
+
+// ListClients 列出已登记的客户端（业务系统）
+func ListClients(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		clients, err := service.ListClients(db, c.GetString("tenantId"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"error":   "获取客户端列表失败",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data": gin.H{
+				"clients": clients,
+			},
+		})
+	}
+}
+
+// GetClient 获取客户端详情
+func GetClient(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		client, err := service.GetClientView(db, c.Param("id"))
+		if err != nil {
+			writeClientError(c, err, "获取客户端失败")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data":    client,
+		})
+	}
+}
+
+// CreateClient 登记客户端，机密客户端的密钥只在此返回一次
+func CreateClient(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req service.ClientInput
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error":   "无效的请求参数",
+			})
+			return
+		}
+
+		client, secret, err := service.CreateClient(db, c.GetString("tenantId"), req)
+		if err != nil {
+			writeClientError(c, err, "创建客户端失败")
+			return
+		}
+
+		log.Printf("管理员 %s 登记了客户端: %s", c.GetString("userId"), client.ClientID)
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data": gin.H{
+				"client":       client,
+				"clientSecret": secret,
+			},
+		})
+	}
+}
+
+// UpdateClient 更新客户端配置
+func UpdateClient(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req service.ClientInput
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error":   "无效的请求参数",
+			})
+			return
+		}
+
+		client, err := service.UpdateClient(db, c.Param("id"), req)
+		if err != nil {
+			writeClientError(c, err, "更新客户端失败")
+			return
+		}
+
+		log.Printf("管理员 %s 更新了客户端: %s", c.GetString("userId"), client.ClientID)
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data":    client,
+		})
+	}
+}
+
+// DeleteClient 删除客户端，该客户端的会话全部吊销
+func DeleteClient(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		clientID := c.Param("id")
+		if err := service.DeleteClient(db, clientID); err != nil {
+			writeClientError(c, err, "删除客户端失败")
+			return
+		}
+
+		log.Printf("管理员 %s 删除了客户端: %s", c.GetString("userId"), clientID)
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+		})
+	}
+}
+
+// RotateClientSecret 重新生成客户端密钥，旧密钥立即失效
+func RotateClientSecret(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		clientID := c.Param("id")
+		secret, err := service.RotateClientSecret(db, clientID)
+		if err != nil {
+			writeClientError(c, err, "重新生成密钥失败")
+			return
+		}
+
+		log.Printf("管理员 %s 重新生成了客户端密钥: %s", c.GetString("userId"), clientID)
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data": gin.H{
+				"clientSecret": secret,
+			},
+		})
+	}
+}
+
+// writeClientError 按错误类型返回客户端管理接口的错误
+func writeClientError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, service.ErrClientNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+	case errors.Is(err, service.ErrInvalidClientInput):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   message,
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestExchangeCodeRequiresClient(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	// 缺少客户端凭证时在兑换交换码之前拒绝，不需要数据库
+	router.POST("/api/auth/exchange", ExchangeCode(nil))
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/api/auth/exchange", strings.NewReader(`{"code":"abc"}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	var resp struct {
+		Success bool   `json:"success"`
+		Error   string `json:"error"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if w.Code != http.StatusUnauthorized || resp.Success || resp.Error != "缺少客户端凭证" {
+		t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+	}
+}
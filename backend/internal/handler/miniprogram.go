@@ -0,0 +1,138 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/keenchase/auth-center/internal/models"
+	"github.com/keenchase/auth-center/internal/service"
+	"github.com/keenchase/auth-center/internal/wechat"
+	"gorm.io/gorm"
+)
+
+// MiniProgramLoginRequest 小程序登录请求
+type MiniProgramLoginRequest struct {
+	Code          string `json:"code" binding:"required"` // wx.login 返回的 code
+	EncryptedData string `json:"encryptedData"`           // 可选，jscode2session 不返回 unionid 时用于获取 unionId、昵称、头像
+	IV            string `json:"iv"`
+}
+
+// MiniProgramLogin 小程序登录：wx.login 的 code 换取令牌
+func MiniProgramLogin(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req MiniProgramLoginRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error":   "无效的请求参数",
+			})
+			return
+		}
+
+		cfg := loadConfig(c)
+		user, err := service.LoginMiniProgram(c.Request.Context(), db, cfg, service.MiniProgramLoginInput{
+			Code:          req.Code,
+			EncryptedData: req.EncryptedData,
+			IV:            req.IV,
+		})
+		if err != nil {
+			writeMiniProgramError(c, err, "小程序登录失败")
+			return
+		}
+
+		// 重新查询用户信息，包含 Accounts
+		if err := db.Preload("Accounts").Where("user_id = ?", user.UserID).First(user).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"error":   "查询用户信息失败",
+			})
+			return
+		}
+
+		tokens, err := service.IssueTokens(db, cfg, user.UserID, service.TokenOptions{
+			DeviceInfo:  requestDeviceInfo(c, ""),
+			LoginMethod: models.LoginMethodWeChatMini,
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"error":   "创建会话失败",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success":      true,
+			"token":        tokens.AccessToken,
+			"refreshToken": tokens.RefreshToken,
+			"expiresIn":    tokens.ExpiresIn,
+			"userId":       user.UserID,
+			"data":         userProfileData(user),
+		})
+	}
+}
+
+// MiniProgramDecryptRequest 小程序加密数据解密请求
+type MiniProgramDecryptRequest struct {
+	EncryptedData string `json:"encryptedData" binding:"required"`
+	IV            string `json:"iv" binding:"required"`
+}
+
+// MiniProgramDecrypt 用当前用户的小程序 session_key 解密 encryptedData（用户信息、手机号等）
+func MiniProgramDecrypt(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req MiniProgramDecryptRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error":   "无效的请求参数",
+			})
+			return
+		}
+
+		data, err := service.DecryptUserMiniProgramData(db, loadConfig(c), c.GetString("userId"), req.EncryptedData, req.IV)
+		if err != nil {
+			writeMiniProgramError(c, err, "解密失败")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data":    data,
+		})
+	}
+}
+
+// writeMiniProgramError 按错误类型返回小程序接口的错误
+func writeMiniProgramError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, service.ErrMiniProgramDataInvalid),
+		errors.Is(err, service.ErrMiniProgramSessionNotFound),
+		errors.Is(err, service.ErrAuthCodeInvalid):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+	case errors.Is(err, service.ErrUnionIDConflict):
+		c.JSON(http.StatusConflict, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+	case errors.Is(err, wechat.ErrCircuitOpen):
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+	case errors.Is(err, service.ErrIdentityProviderNotConfigured):
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "小程序配置缺失",
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   message,
+		})
+	}
+}
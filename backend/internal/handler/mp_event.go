@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/keenchase/auth-center/internal/service"
+	"gorm.io/gorm"
+)
+
+// mpMessageMaxBytes 公众号推送消息体的大小上限
+const mpMessageMaxBytes = 64 << 10
+
+// MPEventVerify 公众号服务器地址校验：签名正确时原样返回 echostr
+func MPEventVerify() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		cfg := loadConfig(c)
+		if !service.CheckMPSignature(cfg.WeChatMPToken, c.Query("signature"), c.Query("timestamp"), c.Query("nonce")) {
+			c.String(http.StatusForbidden, "invalid signature")
+			return
+		}
+		c.String(http.StatusOK, c.Query("echostr"))
+	}
+}
+
+// MPEvent 接收公众号推送的消息与事件（明文模式、兼容模式与安全模式），分发给订阅者
+// 微信要求 5 秒内响应，无需回复时返回 success；加密推送的回复同样加密；处理失败时返回 500，由微信重试
+func MPEvent(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		cfg := loadConfig(c)
+		params := service.MPPushParams{
+			Signature:    c.Query("signature"),
+			Timestamp:    c.Query("timestamp"),
+			Nonce:        c.Query("nonce"),
+			EncryptType:  c.Query("encrypt_type"),
+			MsgSignature: c.Query("msg_signature"),
+		}
+
+		body, err := io.ReadAll(io.LimitReader(c.Request.Body, mpMessageMaxBytes))
+		if err != nil {
+			c.String(http.StatusBadRequest, "invalid body")
+			return
+		}
+		msg, err := service.ReadMPMessage(cfg, params, body)
+		if err != nil {
+			log.Printf("拒绝公众号推送: %v", err)
+			c.String(http.StatusForbidden, "invalid message")
+			return
+		}
+
+		content, err := service.HandleMPMessage(c.Request.Context(), db, cfg, msg)
+		if err != nil {
+			// 不回复 success，微信会重试同一推送
+			log.Printf("处理公众号推送失败: %v", err)
+			c.String(http.StatusInternalServerError, "failed")
+			return
+		}
+		if content == "" {
+			c.String(http.StatusOK, "success")
+			return
+		}
+		reply, err := service.MPTextReply(msg, content)
+		if err == nil && params.Encrypted() {
+			reply, err = service.SealMPReply(cfg, params, reply)
+		}
+		if err != nil {
+			log.Printf("构造公众号回复失败: %v", err)
+			c.String(http.StatusOK, "success")
+			return
+		}
+		c.Data(http.StatusOK, "application/xml; charset=utf-8", reply)
+	}
+}
@@ -0,0 +1,530 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/keenchase/auth-center/internal/config"
+	"github.com/keenchase/auth-center/internal/models"
+	"github.com/keenchase/auth-center/internal/service"
+	"gorm.io/gorm"
+)
+
+// ssoCookieName auth-center 自身的登录态 Cookie（仅用于 /oauth、/t/<租户>/oauth 路径下的免登录授权）
+const ssoCookieName = "auth_center_sso"
+
+// OpenIDConfiguration OIDC 发现文档（各租户的 issuer 不同，见 service.TenantConfig）
+func OpenIDConfiguration() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		issuer := loadConfig(c).Issuer
+
+		c.JSON(http.StatusOK, gin.H{
+			"issuer":                                        issuer,
+			"authorization_endpoint":                        issuer + "/oauth/authorize",
+			"token_endpoint":                                issuer + "/oauth/token",
+			"userinfo_endpoint":                             issuer + "/oauth/userinfo",
+			"introspection_endpoint":                        issuer + "/oauth/introspect",
+			"revocation_endpoint":                           issuer + "/oauth/revoke",
+			"jwks_uri":                                      issuer + "/.well-known/jwks.json",
+			"response_types_supported":                      []string{"code"},
+			"response_modes_supported":                      []string{"query"},
+			"grant_types_supported":                         []string{"authorization_code", "refresh_token"},
+			"subject_types_supported":                       []string{"public"},
+			"id_token_signing_alg_values_supported":         signingAlgorithms(),
+			"scopes_supported":                              []string{"openid", "profile", "phone", "email"},
+			"token_endpoint_auth_methods_supported":         []string{"client_secret_basic", "client_secret_post", "none"},
+			"introspection_endpoint_auth_methods_supported": []string{"client_secret_basic", "client_secret_post"},
+			"revocation_endpoint_auth_methods_supported":    []string{"client_secret_basic", "client_secret_post", "none"},
+			"code_challenge_methods_supported":              []string{"S256"},
+			"claims_supported": []string{
+				"sub", "iss", "aud", "exp", "iat", "auth_time", "nonce", "at_hash",
+				"name", "nickname", "picture", "unionid", "phone_number", "email",
+			},
+		})
+	}
+}
+
+// JWKS 公布验签公钥（/.well-known/jwks.json）
+func JWKS() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Cache-Control", "public, max-age=300")
+		c.JSON(http.StatusOK, service.DefaultKeySet().JWKS())
+	}
+}
+
+// signingAlgorithms 当前可验签密钥使用的算法
+func signingAlgorithms() []string {
+	seen := make(map[string]bool)
+	algs := []string{}
+	for _, k := range service.DefaultKeySet().Keys() {
+		if !seen[k.Algorithm] {
+			seen[k.Algorithm] = true
+			algs = append(algs, k.Algorithm)
+		}
+	}
+	return algs
+}
+
+// Authorize OIDC 授权端点（授权码 + PKCE）
+// 已有 auth-center 登录态时直接签发授权码，否则先跳转到微信/密码登录
+func Authorize(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		cfg := loadConfig(c)
+
+		clientID := c.Query("client_id")
+		redirectURI := c.Query("redirect_uri")
+		state := c.Query("state")
+
+		// client_id / redirect_uri 无效时不能重定向，直接返回错误
+		client, err := service.GetClient(db, cfg.TenantID, clientID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":             "invalid_request",
+				"error_description": "客户端不存在",
+			})
+			return
+		}
+		if !client.HasRedirectURI(redirectURI) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":             "invalid_request",
+				"error_description": "redirect_uri 未注册",
+			})
+			return
+		}
+
+		if c.Query("response_type") != "code" {
+			redirectAuthorizeError(c, redirectURI, state, "unsupported_response_type", "仅支持授权码模式")
+			return
+		}
+
+		scope := c.Query("scope")
+		if !service.HasScope(scope, "openid") {
+			redirectAuthorizeError(c, redirectURI, state, "invalid_scope", "scope 必须包含 openid")
+			return
+		}
+
+		codeChallenge := c.Query("code_challenge")
+		codeChallengeMethod := c.Query("code_challenge_method")
+		if codeChallenge != "" && codeChallengeMethod != "S256" {
+			redirectAuthorizeError(c, redirectURI, state, "invalid_request", "code_challenge_method 仅支持 S256")
+			return
+		}
+		if codeChallenge == "" && client.IsPublic() {
+			redirectAuthorizeError(c, redirectURI, state, "invalid_request", "公共客户端必须使用 PKCE")
+			return
+		}
+		maxAge, err := parseMaxAge(c.Query("max_age"))
+		if err != nil {
+			redirectAuthorizeError(c, redirectURI, state, "invalid_request", "max_age 无效")
+			return
+		}
+
+		// 授权请求与续接时的交换码都绑定当前浏览器的登录 nonce（密码登录页不经过上游 state，也由此下发）
+		browserNonce, err := loginNonce(c, cfg)
+		if err != nil {
+			redirectAuthorizeError(c, redirectURI, state, "server_error", "创建登录状态失败")
+			return
+		}
+		req := models.AuthorizeRequest{
+			ClientID:            client.ClientID,
+			RedirectURI:         redirectURI,
+			Scope:               scope,
+			State:               state,
+			Nonce:               c.Query("nonce"),
+			CodeChallenge:       codeChallenge,
+			CodeChallengeMethod: codeChallengeMethod,
+		}
+		if err := service.CreateAuthorizeRequest(db, &req, browserNonce); err != nil {
+			redirectAuthorizeError(c, redirectURI, state, "server_error", "保存授权请求失败")
+			return
+		}
+
+		// 已登录 auth-center，且登录方式与登录时间满足本次授权要求：直接签发授权码
+		prompt := c.Query("prompt")
+		if prompt != "login" {
+			if session, ok := currentSSOLogin(c, db, cfg); ok && reusableSSOLogin(client, session, maxAge, time.Now()) {
+				completeAuthorize(c, db, &req, session.UserID, session.LoginMethod, session.AuthenticatedAt())
+				return
+			}
+		}
+
+		if prompt == "none" {
+			redirectAuthorizeError(c, redirectURI, state, "login_required", "用户未登录")
+			return
+		}
+
+		// 跳转到上游登录，完成后回到 /oauth/authorize/resume
+		passwordLogin := c.Query("login_method") == "password"
+		if passwordLogin && !client.AllowsLoginMethod(models.LoginMethodPassword) ||
+			!passwordLogin && !client.AllowsLoginMethod(models.LoginMethodWeChatMP) && !client.AllowsLoginMethod(models.LoginMethodWeChatOpen) &&
+				!client.AllowsLoginMethod(models.LoginMethodWeCom) {
+			redirectAuthorizeError(c, redirectURI, state, "access_denied", "该应用不允许此登录方式")
+			return
+		}
+		resumeURL := appendQuery(cfg.Issuer+"/oauth/authorize/resume", map[string]string{
+			"request_id": req.ID,
+		})
+		if passwordLogin {
+			c.Redirect(http.StatusFound, appendQuery(cfg.LoginPageURL, map[string]string{
+				"callbackUrl": resumeURL,
+			}))
+			return
+		}
+		c.Redirect(http.StatusFound, c.GetString("tenantBasePath")+"/api/auth/wechat/login?callbackUrl="+url.QueryEscape(resumeURL))
+	}
+}
+
+// AuthorizeResume 上游登录（微信公众号/开放平台/企业微信/密码）完成后继续授权
+// 各登录方式都以 ?exchangeCode=<一次性交换码> 回跳到此处，令牌不出现在 URL 中；交换码只能由发起登录的浏览器兑换
+func AuthorizeResume(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		cfg := loadConfig(c)
+
+		// 授权请求与交换码都须由发起授权的同一浏览器续接，他人的登录结果无法注入当前浏览器
+		nonce, _ := c.Cookie(loginNonceCookieName)
+		req, err := service.GetAuthorizeRequest(db, c.Query("request_id"), nonce)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":             "invalid_request",
+				"error_description": "授权请求不存在或已过期",
+			})
+			return
+		}
+
+		exchangeCode, err := service.RedeemBrowserExchangeCode(db, nonce, c.Query("exchangeCode"))
+		if err == nil {
+			err = service.UserInTenant(db, cfg.TenantID, exchangeCode.UserID)
+		}
+		if err != nil {
+			redirectAuthorizeError(c, req.RedirectURI, req.State, "access_denied", "登录失败")
+			return
+		}
+		client, err := service.GetClient(db, cfg.TenantID, req.ClientID)
+		if err != nil || !client.AllowsLoginMethod(exchangeCode.LoginMethod) {
+			redirectAuthorizeError(c, req.RedirectURI, req.State, "access_denied", "该应用不允许此登录方式")
+			return
+		}
+
+		// 创建 auth-center 自身的登录态
+		tokens, err := service.IssueTokens(db, cfg, exchangeCode.UserID, service.TokenOptions{
+			DeviceInfo:  service.ParseDeviceInfo(exchangeCode.DeviceInfo),
+			LoginMethod: exchangeCode.LoginMethod,
+			AuthTime:    exchangeCode.CreatedAt,
+		})
+		if err != nil {
+			redirectAuthorizeError(c, req.RedirectURI, req.State, "server_error", "创建会话失败")
+			return
+		}
+
+		setSSOCookie(c, cfg, tokens.AccessToken)
+		completeAuthorize(c, db, req, exchangeCode.UserID, exchangeCode.LoginMethod, exchangeCode.CreatedAt)
+	}
+}
+
+// Token OIDC 令牌端点
+func Token(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Cache-Control", "no-store")
+		c.Header("Pragma", "no-cache")
+
+		cfg := loadConfig(c)
+
+		clientID, clientSecret := clientCredentials(c)
+		client, err := service.AuthenticateClient(db, c.GetString("tenantId"), clientID, clientSecret)
+		if err != nil {
+			writeOAuthError(c, err)
+			return
+		}
+
+		switch c.PostForm("grant_type") {
+		case "authorization_code":
+			exchangeAuthorizationCode(c, db, cfg, client)
+		case "refresh_token":
+			exchangeRefreshToken(c, db, cfg, client)
+		default:
+			writeOAuthError(c, service.NewOAuthError("unsupported_grant_type", "不支持的 grant_type"))
+		}
+	}
+}
+
+// exchangeAuthorizationCode 授权码换取令牌
+func exchangeAuthorizationCode(c *gin.Context, db *gorm.DB, cfg *config.Config, client *models.Client) {
+	authCode, err := service.RedeemAuthorizationCode(
+		db,
+		client,
+		c.PostForm("code"),
+		c.PostForm("redirect_uri"),
+		c.PostForm("code_verifier"),
+	)
+	if err != nil {
+		writeOAuthError(c, err)
+		return
+	}
+
+	// 创建会话并签发令牌
+	tokens, err := service.IssueTokens(db, cfg, authCode.UserID, service.TokenOptions{
+		ClientID:    client.ClientID,
+		Scope:       authCode.Scope,
+		DeviceInfo:  service.ParseDeviceInfo(authCode.DeviceInfo),
+		LoginMethod: authCode.LoginMethod,
+		AuthTime:    authCode.AuthTime,
+	})
+	if err != nil {
+		writeOAuthError(c, service.NewOAuthError("server_error", "创建会话失败"))
+		return
+	}
+	// 授权码重复使用时按绑定的会话吊销令牌；绑定失败则无法吊销，作废刚签发的令牌
+	if err := service.BindAuthorizationCodeSession(db, authCode, tokens.SessionID); err != nil {
+		log.Printf("记录授权码会话失败: %v", err)
+		if err := service.RevokeSessions(db, tokens.SessionID); err != nil {
+			log.Printf("警告: 吊销会话失败: %v", err)
+		}
+		writeOAuthError(c, service.NewOAuthError("server_error", "创建会话失败"))
+		return
+	}
+
+	idToken, err := service.GenerateIDToken(db, cfg, authCode, tokens.AccessToken)
+	if err != nil {
+		writeOAuthError(c, service.NewOAuthError("server_error", "生成 ID Token 失败"))
+		return
+	}
+
+	_ = service.CreateLoginLog(db, cfg.TenantID, authCode.UserID, authCode.RedirectURI, "oidc")
+	service.UpdateLastLogin(db, authCode.UserID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"access_token":  tokens.AccessToken,
+		"token_type":    "Bearer",
+		"expires_in":    tokens.ExpiresIn,
+		"refresh_token": tokens.RefreshToken,
+		"id_token":      idToken,
+		"scope":         authCode.Scope,
+	})
+}
+
+// exchangeRefreshToken 刷新令牌换取新令牌（刷新令牌同时轮换）
+func exchangeRefreshToken(c *gin.Context, db *gorm.DB, cfg *config.Config, client *models.Client) {
+	tokens, err := service.RefreshTokens(db, cfg, c.PostForm("refresh_token"), client.ClientID)
+	if err != nil {
+		writeOAuthError(c, service.NewOAuthError("invalid_grant", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"access_token":  tokens.AccessToken,
+		"token_type":    "Bearer",
+		"expires_in":    tokens.ExpiresIn,
+		"refresh_token": tokens.RefreshToken,
+		"scope":         tokens.Scope,
+	})
+}
+
+// Introspect RFC 7662 令牌内省端点（需客户端凭证，供网关/资源服务器校验令牌）
+func Introspect(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Cache-Control", "no-store")
+
+		cfg := loadConfig(c)
+
+		clientID, clientSecret := clientCredentials(c)
+		client, err := service.AuthenticateClient(db, c.GetString("tenantId"), clientID, clientSecret)
+		if err != nil {
+			writeOAuthError(c, err)
+			return
+		}
+		if client.IsPublic() {
+			writeOAuthError(c, service.NewOAuthError("invalid_client", "公开客户端不能调用内省端点"))
+			return
+		}
+
+		c.JSON(http.StatusOK, service.IntrospectToken(db, cfg, c.PostForm("token"), c.PostForm("token_type_hint")))
+	}
+}
+
+// Revoke RFC 7009 令牌吊销端点
+// 令牌无效、已吊销或不属于该客户端时同样返回 200
+func Revoke(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Cache-Control", "no-store")
+
+		clientID, clientSecret := clientCredentials(c)
+		client, err := service.AuthenticateClient(db, c.GetString("tenantId"), clientID, clientSecret)
+		if err != nil {
+			writeOAuthError(c, err)
+			return
+		}
+
+		if c.PostForm("token") == "" {
+			writeOAuthError(c, service.NewOAuthError("invalid_request", "缺少 token 参数"))
+			return
+		}
+		if err := service.RevokeToken(db, client, c.PostForm("token"), c.PostForm("token_type_hint")); err != nil {
+			writeOAuthError(c, err)
+			return
+		}
+
+		c.Status(http.StatusOK)
+	}
+}
+
+// UserInfo OIDC UserInfo 端点
+func UserInfo(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := bearerToken(c)
+		claims, err := service.ValidateToken(token)
+		if err != nil || claims.Tenant() != c.GetString("tenantId") {
+			c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid_token"})
+			return
+		}
+		if _, err := service.GetUserByToken(db, token); err != nil {
+			c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid_token"})
+			return
+		}
+
+		// 非 OIDC 签发的第一方 Token 没有 scope，按基础资料返回
+		scope := claims.Scope
+		if scope == "" {
+			scope = "openid profile"
+		}
+
+		info, err := service.GetUserInfoClaims(db, claims.UserID, scope)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "invalid_token"})
+			return
+		}
+
+		c.JSON(http.StatusOK, info)
+	}
+}
+
+// completeAuthorize 签发授权码并重定向回客户端
+func completeAuthorize(c *gin.Context, db *gorm.DB, req *models.AuthorizeRequest, userID, loginMethod string, authTime time.Time) {
+	code, err := service.IssueAuthorizationCode(db, req, userID, loginMethod, authTime, requestDeviceInfo(c, req.ClientID))
+	if err != nil {
+		redirectAuthorizeError(c, req.RedirectURI, req.State, "server_error", "签发授权码失败")
+		return
+	}
+
+	params := map[string]string{"code": code}
+	if req.State != "" {
+		params["state"] = req.State
+	}
+	c.Redirect(http.StatusFound, appendQuery(req.RedirectURI, params))
+}
+
+// currentSSOLogin 读取 auth-center 在当前租户的登录态
+func currentSSOLogin(c *gin.Context, db *gorm.DB, cfg *config.Config) (*models.Session, bool) {
+	token, err := c.Cookie(ssoCookieName)
+	if err != nil || token == "" {
+		return nil, false
+	}
+
+	// 访问令牌本身很快过期，登录态以会话是否存活为准
+	session, err := service.GetSessionByToken(db, token)
+	if err != nil || session.TenantID != cfg.TenantID {
+		return nil, false
+	}
+	return session, true
+}
+
+// reusableSSOLogin 已有登录态能否直接用于本次授权：登录方式须为客户端允许的，且登录时间满足 max_age（-1 表示不限制）
+func reusableSSOLogin(client *models.Client, session *models.Session, maxAge int, now time.Time) bool {
+	if !client.AllowsLoginMethod(session.LoginMethod) {
+		return false
+	}
+	return maxAge < 0 || now.Sub(session.AuthenticatedAt()) <= time.Duration(maxAge)*time.Second
+}
+
+// parseMaxAge 解析 OIDC max_age 参数（秒），未提供时返回 -1
+func parseMaxAge(raw string) (int, error) {
+	if raw == "" {
+		return -1, nil
+	}
+	maxAge, err := strconv.Atoi(raw)
+	if err != nil || maxAge < 0 {
+		return 0, errors.New("max_age 无效")
+	}
+	return maxAge, nil
+}
+
+// setSSOCookie 写入 auth-center 登录态
+func setSSOCookie(c *gin.Context, cfg *config.Config, token string) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(ssoCookieName, token, int(cfg.RefreshTokenTTL.Seconds()), c.GetString("tenantBasePath")+"/oauth", "", cfg.Environment == "production", true)
+}
+
+// redirectAuthorizeError 按 RFC 6749 4.1.2.1 将错误重定向回客户端
+func redirectAuthorizeError(c *gin.Context, redirectURI, state, code, description string) {
+	params := map[string]string{
+		"error":             code,
+		"error_description": description,
+	}
+	if state != "" {
+		params["state"] = state
+	}
+	c.Redirect(http.StatusFound, appendQuery(redirectURI, params))
+}
+
+// writeOAuthError 按 RFC 6749 5.2 返回错误
+func writeOAuthError(c *gin.Context, err error) {
+	var oauthErr *service.OAuthError
+	if !errors.As(err, &oauthErr) {
+		oauthErr = service.NewOAuthError("server_error", "服务器内部错误")
+	}
+
+	status := http.StatusBadRequest
+	switch oauthErr.Code {
+	case "invalid_client":
+		status = http.StatusUnauthorized
+		if _, _, ok := c.Request.BasicAuth(); ok {
+			c.Header("WWW-Authenticate", `Basic realm="auth-center"`)
+		}
+	case "server_error":
+		status = http.StatusInternalServerError
+	}
+
+	c.JSON(status, gin.H{
+		"error":             oauthErr.Code,
+		"error_description": oauthErr.Description,
+	})
+}
+
+// clientCredentials 读取客户端凭证（client_secret_basic 优先，其次 client_secret_post）
+func clientCredentials(c *gin.Context) (string, string) {
+	if clientID, clientSecret, ok := c.Request.BasicAuth(); ok {
+		id, _ := url.QueryUnescape(clientID)
+		secret, _ := url.QueryUnescape(clientSecret)
+		return id, secret
+	}
+	return c.PostForm("client_id"), c.PostForm("client_secret")
+}
+
+// bearerToken 提取 Authorization: Bearer 令牌
+func bearerToken(c *gin.Context) string {
+	token := c.GetHeader("Authorization")
+	if len(token) > 7 && token[:7] == "Bearer " {
+		return token[7:]
+	}
+	return ""
+}
+
+// appendQuery 在 URL 上追加查询参数（保留已有参数）
+func appendQuery(rawURL string, params map[string]string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	query := u.Query()
+	for k, v := range params {
+		query.Set(k, v)
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
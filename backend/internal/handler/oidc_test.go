@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/keenchase/auth-center/internal/models"
+)
+
+func TestReusableSSOLogin(t *testing.T) {
+	now := time.Now()
+	authTime := now.Add(-10 * time.Minute)
+	passwordOnly := &models.Client{AllowedLoginMethods: models.LoginMethodPassword}
+	unrestricted := &models.Client{}
+
+	tests := []struct {
+		name        string
+		client      *models.Client
+		loginMethod string
+		maxAge      int
+		want        bool
+	}{
+		{"不限登录方式", unrestricted, models.LoginMethodWeChatMP, -1, true},
+		{"登录方式不被允许", passwordOnly, models.LoginMethodWeChatMP, -1, false},
+		{"登录方式允许", passwordOnly, models.LoginMethodPassword, -1, true},
+		{"满足 max_age", unrestricted, models.LoginMethodWeChatMP, 3600, true},
+		{"超过 max_age", unrestricted, models.LoginMethodWeChatMP, 60, false},
+		{"max_age=0 强制重新登录", unrestricted, models.LoginMethodWeChatMP, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := &models.Session{LoginMethod: tt.loginMethod, AuthTime: &authTime}
+			if got := reusableSSOLogin(tt.client, session, tt.maxAge, now); got != tt.want {
+				t.Errorf("reusableSSOLogin = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseMaxAge(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    int
+		wantErr bool
+	}{
+		{"", -1, false},
+		{"0", 0, false},
+		{"3600", 3600, false},
+		{"-1", 0, true},
+		{"abc", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := parseMaxAge(tt.raw)
+		if (err != nil) != tt.wantErr || !tt.wantErr && got != tt.want {
+			t.Errorf("parseMaxAge(%q) = %d, %v", tt.raw, got, err)
+		}
+	}
+}
@@ -0,0 +1,455 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/keenchase/auth-center/internal/service"
+	"gorm.io/gorm"
+)
+
+// ListMyOrgs 列出当前用户所在的组织及其角色
+func ListMyOrgs(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		orgs, err := service.ListUserOrgs(db, c.GetString("userId"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"error":   "获取组织列表失败",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data": gin.H{
+				"orgs": orgs,
+			},
+		})
+	}
+}
+
+// AcceptInvitationRequest 兑换邀请码请求
+type AcceptInvitationRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// AcceptOrgInvitation 当前用户兑换邀请码加入组织（登录后调用）
+func AcceptOrgInvitation(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req AcceptInvitationRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error":   "无效的请求参数",
+			})
+			return
+		}
+
+		userID := c.GetString("userId")
+		membership, err := service.AcceptInvitation(db, userID, req.Code)
+		if err != nil {
+			writeOrgError(c, err, "加入组织失败")
+			return
+		}
+
+		log.Printf("用户 %s 通过邀请加入了组织 %s（%s）", userID, membership.Slug, membership.Role)
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data":    membership,
+		})
+	}
+}
+
+// ListOrgs 列出全部组织（平台管理员）
+func ListOrgs(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		orgs, err := service.ListOrgs(db, c.GetString("tenantId"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"error":   "获取组织列表失败",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data": gin.H{
+				"orgs": orgs,
+			},
+		})
+	}
+}
+
+// CreateOrg 创建组织并指定初始所有者（平台管理员）
+func CreateOrg(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req service.OrgInput
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error":   "无效的请求参数",
+			})
+			return
+		}
+		if _, err := uuid.Parse(req.OwnerUserID); err != nil {
+			writeOrgError(c, service.ErrUserNotFound, "")
+			return
+		}
+
+		org, err := service.CreateOrg(db, c.GetString("tenantId"), req)
+		if err != nil {
+			writeOrgError(c, err, "创建组织失败")
+			return
+		}
+
+		log.Printf("管理员 %s 创建了组织 %s，所有者 %s", c.GetString("userId"), org.Slug, req.OwnerUserID)
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data":    org,
+		})
+	}
+}
+
+// GetOrg 获取组织详情
+func GetOrg(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		orgID, ok := orgIDParam(c)
+		if !ok {
+			return
+		}
+
+		org, err := service.GetOrg(db, orgID)
+		if err != nil {
+			writeOrgError(c, err, "获取组织失败")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data":    service.OrgMembership{Organization: *org, Role: c.GetString("orgRole")},
+		})
+	}
+}
+
+// UpdateOrg 更新组织名称与描述
+func UpdateOrg(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		orgID, ok := orgIDParam(c)
+		if !ok {
+			return
+		}
+
+		var req service.OrgInput
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error":   "无效的请求参数",
+			})
+			return
+		}
+
+		org, err := service.UpdateOrg(db, orgID, req)
+		if err != nil {
+			writeOrgError(c, err, "更新组织失败")
+			return
+		}
+
+		log.Printf("用户 %s 更新了组织 %s", c.GetString("userId"), org.Slug)
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data":    org,
+		})
+	}
+}
+
+// DeleteOrg 删除组织（平台管理员）
+func DeleteOrg(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		orgID, ok := orgIDParam(c)
+		if !ok {
+			return
+		}
+
+		if err := service.DeleteOrg(db, orgID); err != nil {
+			writeOrgError(c, err, "删除组织失败")
+			return
+		}
+
+		log.Printf("管理员 %s 删除了组织 %s", c.GetString("userId"), orgID)
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+		})
+	}
+}
+
+// ListOrgMembers 列出组织成员
+func ListOrgMembers(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		orgID, ok := orgIDParam(c)
+		if !ok {
+			return
+		}
+
+		members, err := service.ListOrgMembers(db, orgID)
+		if err != nil {
+			writeOrgError(c, err, "获取成员列表失败")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data": gin.H{
+				"members": members,
+			},
+		})
+	}
+}
+
+// OrgMemberRequest 添加成员/变更角色请求
+type OrgMemberRequest struct {
+	UserID string `json:"userId"` // 仅添加成员时使用
+	Role   string `json:"role" binding:"required"`
+}
+
+// AddOrgMember 直接将用户加入组织（平台管理员）
+func AddOrgMember(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		orgID, ok := orgIDParam(c)
+		if !ok {
+			return
+		}
+
+		var req OrgMemberRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error":   "无效的请求参数",
+			})
+			return
+		}
+		if _, err := uuid.Parse(req.UserID); err != nil {
+			writeOrgError(c, service.ErrUserNotFound, "")
+			return
+		}
+
+		if err := service.AddOrgMember(db, orgID, req.UserID, req.Role); err != nil {
+			writeOrgError(c, err, "添加成员失败")
+			return
+		}
+
+		log.Printf("管理员 %s 将用户 %s 加入组织 %s（%s）", c.GetString("userId"), req.UserID, orgID, req.Role)
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+		})
+	}
+}
+
+// UpdateOrgMember 变更成员在组织内的角色
+func UpdateOrgMember(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		orgID, ok := orgIDParam(c)
+		if !ok {
+			return
+		}
+		memberID, ok := orgMemberParam(c)
+		if !ok {
+			return
+		}
+
+		var req OrgMemberRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error":   "无效的请求参数",
+			})
+			return
+		}
+
+		if err := service.SetOrgMemberRole(db, orgID, c.GetString("orgRole"), memberID, req.Role); err != nil {
+			writeOrgError(c, err, "变更成员角色失败")
+			return
+		}
+
+		log.Printf("用户 %s 将组织 %s 的成员 %s 设为 %s", c.GetString("userId"), orgID, memberID, req.Role)
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+		})
+	}
+}
+
+// RemoveOrgMember 将成员移出组织（成员也可以自行退出）
+func RemoveOrgMember(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		orgID, ok := orgIDParam(c)
+		if !ok {
+			return
+		}
+		memberID, ok := orgMemberParam(c)
+		if !ok {
+			return
+		}
+
+		actorID := c.GetString("userId")
+		if err := service.RemoveOrgMember(db, orgID, actorID, c.GetString("orgRole"), memberID); err != nil {
+			writeOrgError(c, err, "移除成员失败")
+			return
+		}
+
+		log.Printf("用户 %s 将成员 %s 移出了组织 %s", actorID, memberID, orgID)
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+		})
+	}
+}
+
+// ListOrgInvitations 列出组织的邀请
+func ListOrgInvitations(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		orgID, ok := orgIDParam(c)
+		if !ok {
+			return
+		}
+
+		invitations, err := service.ListInvitations(db, orgID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"error":   "获取邀请列表失败",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data": gin.H{
+				"invitations": invitations,
+			},
+		})
+	}
+}
+
+// CreateOrgInvitation 创建邀请，邀请码只在此返回一次
+func CreateOrgInvitation(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		orgID, ok := orgIDParam(c)
+		if !ok {
+			return
+		}
+
+		var req service.InvitationInput
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error":   "无效的请求参数",
+			})
+			return
+		}
+
+		actorID := c.GetString("userId")
+		invitation, code, err := service.CreateInvitation(db, orgID, actorID, c.GetString("orgRole"), req)
+		if err != nil {
+			writeOrgError(c, err, "创建邀请失败")
+			return
+		}
+
+		log.Printf("用户 %s 创建了组织 %s 的邀请 %s（%s）", actorID, orgID, invitation.ID, invitation.Role)
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data": gin.H{
+				"invitation": invitation,
+				"code":       code,
+			},
+		})
+	}
+}
+
+// RevokeOrgInvitation 撤销邀请
+func RevokeOrgInvitation(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		orgID, ok := orgIDParam(c)
+		if !ok {
+			return
+		}
+		invitationID := c.Param("invitationId")
+		if _, err := uuid.Parse(invitationID); err != nil {
+			writeOrgError(c, service.ErrInvalidInvitation, "")
+			return
+		}
+
+		if err := service.RevokeInvitation(db, orgID, invitationID); err != nil {
+			writeOrgError(c, err, "撤销邀请失败")
+			return
+		}
+
+		log.Printf("用户 %s 撤销了组织 %s 的邀请 %s", c.GetString("userId"), orgID, invitationID)
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+		})
+	}
+}
+
+// orgIDParam 读取并校验路径中的组织 ID，无效时直接写入 404
+func orgIDParam(c *gin.Context) (string, bool) {
+	orgID := c.Param("id")
+	if _, err := uuid.Parse(orgID); err != nil {
+		writeOrgError(c, service.ErrOrgNotFound, "")
+		return "", false
+	}
+	return orgID, true
+}
+
+// orgMemberParam 读取并校验路径中的成员用户 ID，无效时直接写入 404
+func orgMemberParam(c *gin.Context) (string, bool) {
+	userID := c.Param("userId")
+	if _, err := uuid.Parse(userID); err != nil {
+		writeOrgError(c, service.ErrOrgMemberNotFound, "")
+		return "", false
+	}
+	return userID, true
+}
+
+// writeOrgError 按错误类型返回组织接口的错误
+func writeOrgError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, service.ErrOrgNotFound), errors.Is(err, service.ErrOrgMemberNotFound),
+		errors.Is(err, service.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+	case errors.Is(err, service.ErrInvalidOrgInput), errors.Is(err, service.ErrInvalidInvitation):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+	case errors.Is(err, service.ErrOrgForbidden):
+		c.JSON(http.StatusForbidden, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+	case errors.Is(err, service.ErrLastOrgOwner):
+		c.JSON(http.StatusConflict, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   message,
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/keenchase/auth-center/internal/service"
+	"gorm.io/gorm"
+)
+
+// ListPolicies 列出访问策略（?clientId= 按业务系统过滤）
+func ListPolicies(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		policies, err := service.ListPolicies(db, c.GetString("tenantId"), c.Query("clientId"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"error":   "获取策略列表失败",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data": gin.H{
+				"policies": policies,
+			},
+		})
+	}
+}
+
+// GetPolicy 获取策略详情
+func GetPolicy(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := policyIDParam(c)
+		if !ok {
+			return
+		}
+
+		policy, err := service.GetPolicy(db, id)
+		if err != nil {
+			writePolicyError(c, err, "获取策略失败")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data":    policy,
+		})
+	}
+}
+
+// CreatePolicy 为业务系统创建访问策略
+func CreatePolicy(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req service.PolicyInput
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error":   "无效的请求参数",
+			})
+			return
+		}
+
+		policy, err := service.CreatePolicy(db, c.GetString("tenantId"), req)
+		if err != nil {
+			writePolicyError(c, err, "创建策略失败")
+			return
+		}
+
+		log.Printf("管理员 %s 为客户端 %s 创建了策略: %s", c.GetString("userId"), policy.ClientID, policy.Name)
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data":    policy,
+		})
+	}
+}
+
+// UpdatePolicy 更新访问策略
+func UpdatePolicy(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := policyIDParam(c)
+		if !ok {
+			return
+		}
+
+		var req service.PolicyInput
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error":   "无效的请求参数",
+			})
+			return
+		}
+
+		policy, err := service.UpdatePolicy(db, id, req)
+		if err != nil {
+			writePolicyError(c, err, "更新策略失败")
+			return
+		}
+
+		log.Printf("管理员 %s 更新了客户端 %s 的策略: %s", c.GetString("userId"), policy.ClientID, policy.Name)
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data":    policy,
+		})
+	}
+}
+
+// DeletePolicy 删除访问策略
+func DeletePolicy(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := policyIDParam(c)
+		if !ok {
+			return
+		}
+
+		if err := service.DeletePolicy(db, id); err != nil {
+			writePolicyError(c, err, "删除策略失败")
+			return
+		}
+
+		log.Printf("管理员 %s 删除了策略: %s", c.GetString("userId"), id)
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+		})
+	}
+}
+
+// policyIDParam 读取并校验路径中的策略 ID，无效时直接写入 404
+func policyIDParam(c *gin.Context) (string, bool) {
+	id := c.Param("id")
+	if _, err := uuid.Parse(id); err != nil {
+		writePolicyError(c, service.ErrPolicyNotFound, "")
+		return "", false
+	}
+	return id, true
+}
+
+// writePolicyError 按错误类型返回策略管理接口的错误
+func writePolicyError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, service.ErrPolicyNotFound), errors.Is(err, service.ErrClientNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+	case errors.Is(err, service.ErrInvalidPolicyInput):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   message,
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/keenchase/auth-center/internal/models"
+	"github.com/keenchase/auth-center/internal/service"
+	"github.com/keenchase/auth-center/internal/wechat"
+	"gorm.io/gorm"
+)
+
+// qrLoginWaitTimeout 长轮询单次等待的最长时间（低于常见代理的 30 秒空闲超时）
+const qrLoginWaitTimeout = 25 * time.Second
+
+// CreateQRLoginRequest 创建扫码关注登录请求
+type CreateQRLoginRequest struct {
+	CallbackURL string `json:"callbackUrl"`
+}
+
+// CreateQRLogin 创建扫码关注公众号登录，返回二维码与轮询令牌
+func CreateQRLogin(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req CreateQRLoginRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error":   "无效的请求参数",
+			})
+			return
+		}
+		if req.CallbackURL == "" {
+			req.CallbackURL = "/"
+		}
+
+		cfg := loadConfig(c)
+		if !isValidCallbackURL(db, cfg, req.CallbackURL) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error":   "回调 URL 不在允许的域名列表中",
+			})
+			return
+		}
+		if !allowsLoginMethod(db, cfg, req.CallbackURL, models.LoginMethodWeChatMPQR) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"success": false,
+				"error":   "该应用不允许此登录方式",
+			})
+			return
+		}
+
+		// 扫码登录绑定当前浏览器，只有同一浏览器能完成登录
+		nonce, err := loginNonce(c, cfg)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"error":   "创建登录状态失败",
+			})
+			return
+		}
+
+		ticket, err := service.CreateQRLogin(c.Request.Context(), db, cfg, req.CallbackURL, c.ClientIP(), nonce)
+		if errors.Is(err, service.ErrQRLoginRateLimited) {
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"success": false,
+				"error":   err.Error(),
+			})
+			return
+		}
+		if errors.Is(err, service.ErrIdentityProviderNotConfigured) {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"error":   "公众号配置缺失",
+			})
+			return
+		}
+		if errors.Is(err, wechat.ErrCircuitOpen) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"success": false,
+				"error":   err.Error(),
+			})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"error":   "创建二维码失败",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data":    ticket,
+		})
+	}
+}
+
+// WaitQRLogin 长轮询扫码结果；已扫码时返回完成登录的地址，PC 页面跳转过去即可
+func WaitQRLogin(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, pollToken := c.Param("id"), c.Query("pollToken")
+		status, err := service.WaitQRLogin(c.Request.Context(), db, c.GetString("tenantId"), id, pollToken, qrLoginWaitTimeout)
+		if errors.Is(err, service.ErrQRLoginNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"success": false,
+				"error":   err.Error(),
+			})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"error":   "查询扫码状态失败",
+			})
+			return
+		}
+
+		data := gin.H{"status": status}
+		if status == models.QRLoginConfirmed {
+			data["completeUrl"] = appendQuery(c.GetString("tenantBasePath")+"/api/auth/wechat/qr-login/"+id+"/complete", map[string]string{
+				"pollToken": pollToken,
+			})
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data":    data,
+		})
+	}
+}
+
+// CompleteQRLogin 完成扫码关注登录：作废扫码登录，带一次性交换码回到业务系统（须为创建二维码的浏览器）
+func CompleteQRLogin(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		cfg := loadConfig(c)
+		nonce, _ := c.Cookie(loginNonceCookieName)
+		record, err := service.ConsumeQRLogin(db, cfg.TenantID, c.Param("id"), c.Query("pollToken"), nonce)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error":   service.ErrQRLoginNotFound.Error(),
+			})
+			return
+		}
+
+		// 回调地址在使用前按当前白名单再校验一次
+		if !isValidCallbackURL(db, cfg, record.CallbackURL) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error":   "回调 URL 不在允许的域名列表中",
+			})
+			return
+		}
+
+		redirectWithExchangeCode(c, db, cfg, *record.UserID, record.CallbackURL, models.LoginMethodWeChatMPQR)
+	}
+}
@@ -0,0 +1,319 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/keenchase/auth-center/internal/service"
+	"gorm.io/gorm"
+)
+
+// ListPermissions 列出 auth-center 或业务系统的全部权限
+func ListPermissions(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		permissions, err := service.ListPermissions(db, roleClientID(c))
+		if err != nil {
+			writeRoleError(c, err, "获取权限列表失败")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data": gin.H{
+				"permissions": permissions,
+			},
+		})
+	}
+}
+
+// CreatePermissionRequest 定义业务系统权限请求
+type CreatePermissionRequest struct {
+	Code        string `json:"code" binding:"required"`
+	Description string `json:"description"`
+}
+
+// CreatePermission 为业务系统定义权限
+func CreatePermission(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req CreatePermissionRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error":   "无效的请求参数",
+			})
+			return
+		}
+
+		clientID := roleClientID(c)
+		permission, err := service.CreatePermission(db, clientID, req.Code, req.Description)
+		if err != nil {
+			writeRoleError(c, err, "创建权限失败")
+			return
+		}
+
+		log.Printf("管理员 %s 为客户端 %s 定义了权限: %s", c.GetString("userId"), clientID, permission.Code)
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data":    permission,
+		})
+	}
+}
+
+// DeletePermission 删除业务系统的权限
+func DeletePermission(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		clientID, code := roleClientID(c), c.Param("code")
+		if err := service.DeletePermission(db, clientID, code); err != nil {
+			writeRoleError(c, err, "删除权限失败")
+			return
+		}
+
+		log.Printf("管理员 %s 删除了客户端 %s 的权限: %s", c.GetString("userId"), clientID, code)
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+		})
+	}
+}
+
+// ListRoles 列出 auth-center 或业务系统的全部角色及其权限
+func ListRoles(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		roles, err := service.ListRoles(db, roleClientID(c))
+		if err != nil {
+			writeRoleError(c, err, "获取角色列表失败")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data": gin.H{
+				"roles": roles,
+			},
+		})
+	}
+}
+
+// CreateRole 在 auth-center 或业务系统内创建角色
+func CreateRole(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req service.RoleInput
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error":   "无效的请求参数",
+			})
+			return
+		}
+
+		role, err := service.CreateRole(db, roleClientID(c), req)
+		if err != nil {
+			writeRoleError(c, err, "创建角色失败")
+			return
+		}
+
+		log.Printf("管理员 %s 创建了角色: %s %v", c.GetString("userId"), role.Name, role.Permissions)
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data":    role,
+		})
+	}
+}
+
+// UpdateRole 更新角色（权限全量替换）
+func UpdateRole(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		roleID, ok := roleIDParam(c, "id")
+		if !ok {
+			return
+		}
+
+		var req service.RoleInput
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error":   "无效的请求参数",
+			})
+			return
+		}
+
+		role, err := service.UpdateRole(db, c.GetString("tenantId"), roleID, req)
+		if err != nil {
+			writeRoleError(c, err, "更新角色失败")
+			return
+		}
+
+		log.Printf("管理员 %s 更新了角色: %s %v", c.GetString("userId"), role.Name, role.Permissions)
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data":    role,
+		})
+	}
+}
+
+// DeleteRole 删除角色
+func DeleteRole(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		roleID, ok := roleIDParam(c, "id")
+		if !ok {
+			return
+		}
+
+		if err := service.DeleteRole(db, c.GetString("tenantId"), roleID); err != nil {
+			writeRoleError(c, err, "删除角色失败")
+			return
+		}
+
+		log.Printf("管理员 %s 删除了角色: %s", c.GetString("userId"), roleID)
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+		})
+	}
+}
+
+// GetUserRoles 获取用户的角色
+func GetUserRoles(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.Param("id")
+		if _, err := uuid.Parse(userID); err != nil {
+			writeRoleError(c, service.ErrUserNotFound, "")
+			return
+		}
+
+		roles, err := service.ListUserRoles(db, userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"error":   "获取用户角色失败",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data": gin.H{
+				"roles": roles,
+			},
+		})
+	}
+}
+
+// AssignRoleRequest 授予角色请求
+type AssignRoleRequest struct {
+	RoleID string `json:"roleId" binding:"required"`
+}
+
+// AssignUserRole 为用户授予角色
+func AssignUserRole(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.Param("id")
+		if _, err := uuid.Parse(userID); err != nil {
+			writeRoleError(c, service.ErrUserNotFound, "")
+			return
+		}
+
+		var req AssignRoleRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error":   "无效的请求参数",
+			})
+			return
+		}
+		if _, err := uuid.Parse(req.RoleID); err != nil {
+			writeRoleError(c, service.ErrRoleNotFound, "")
+			return
+		}
+
+		if err := service.AssignRole(db, c.GetString("tenantId"), userID, req.RoleID, c.GetString("userId")); err != nil {
+			writeRoleError(c, err, "授予角色失败")
+			return
+		}
+
+		log.Printf("管理员 %s 为用户 %s 授予了角色 %s", c.GetString("userId"), userID, req.RoleID)
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+		})
+	}
+}
+
+// RevokeUserRole 移除用户的角色
+func RevokeUserRole(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.Param("id")
+		if _, err := uuid.Parse(userID); err != nil {
+			writeRoleError(c, service.ErrUserNotFound, "")
+			return
+		}
+		roleID, ok := roleIDParam(c, "roleId")
+		if !ok {
+			return
+		}
+
+		if err := service.RevokeRole(db, c.GetString("tenantId"), userID, roleID); err != nil {
+			writeRoleError(c, err, "移除角色失败")
+			return
+		}
+
+		log.Printf("管理员 %s 移除了用户 %s 的角色 %s", c.GetString("userId"), userID, roleID)
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+		})
+	}
+}
+
+// roleClientID 角色与权限所属的客户端：挂在 /clients/:id 下时为该业务系统，否则为 auth-center 自身
+func roleClientID(c *gin.Context) string {
+	return c.Param("id")
+}
+
+// roleIDParam 读取并校验路径中的角色 ID，无效时直接写入 404
+func roleIDParam(c *gin.Context, name string) (string, bool) {
+	roleID := c.Param(name)
+	if _, err := uuid.Parse(roleID); err != nil {
+		writeRoleError(c, service.ErrRoleNotFound, "")
+		return "", false
+	}
+	return roleID, true
+}
+
+// writeRoleError 按错误类型返回角色管理接口的错误
+func writeRoleError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, service.ErrUserNotFound), errors.Is(err, service.ErrRoleNotFound),
+		errors.Is(err, service.ErrPermissionNotFound), errors.Is(err, service.ErrClientNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+	case errors.Is(err, service.ErrInvalidRoleInput), errors.Is(err, service.ErrInvalidPermissionInput):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+	case errors.Is(err, service.ErrSharedRole):
+		c.JSON(http.StatusForbidden, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+	case errors.Is(err, service.ErrSystemRole), errors.Is(err, service.ErrLastSuperAdmin):
+		c.JSON(http.StatusConflict, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   message,
+		})
+	}
+}
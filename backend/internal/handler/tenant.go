@@ -0,0 +1,132 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/keenchase/auth-center/internal/config"
+	"github.com/keenchase/auth-center/internal/service"
+	"gorm.io/gorm"
+)
+
+// loadConfig 当前请求所属租户的配置（由 Tenant 中间件写入，未经过该中间件时为全局配置）
+func loadConfig(c *gin.Context) *config.Config {
+	if cfg, ok := c.Get("tenantConfig"); ok {
+		return cfg.(*config.Config)
+	}
+	return config.Load()
+}
+
+// ListTenants 列出全部租户
+func ListTenants(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tenants, err := service.ListTenants(db)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"error":   "获取租户列表失败",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data": gin.H{
+				"tenants": tenants,
+			},
+		})
+	}
+}
+
+// GetTenant 获取租户详情（不含微信密钥）
+func GetTenant(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tenant, err := service.GetTenantView(db, c.Param("id"))
+		if err != nil {
+			writeTenantError(c, err, "获取租户失败")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data":    tenant,
+		})
+	}
+}
+
+// CreateTenant 创建租户
+func CreateTenant(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req service.TenantInput
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error":   "无效的请求参数",
+			})
+			return
+		}
+
+		tenant, err := service.CreateTenant(db, req)
+		if err != nil {
+			writeTenantError(c, err, "创建租户失败")
+			return
+		}
+
+		log.Printf("管理员 %s 创建了租户: %s", c.GetString("userId"), tenant.ID)
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data":    tenant,
+		})
+	}
+}
+
+// UpdateTenant 更新租户（域名、微信凭证、初始管理员、启停）
+func UpdateTenant(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req service.TenantInput
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error":   "无效的请求参数",
+			})
+			return
+		}
+
+		tenant, err := service.UpdateTenant(db, c.Param("id"), req)
+		if err != nil {
+			writeTenantError(c, err, "更新租户失败")
+			return
+		}
+
+		log.Printf("管理员 %s 更新了租户: %s", c.GetString("userId"), tenant.ID)
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data":    tenant,
+		})
+	}
+}
+
+// writeTenantError 按错误类型返回租户管理接口的错误
+func writeTenantError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, service.ErrTenantNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+	case errors.Is(err, service.ErrInvalidTenantInput):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   message,
+		})
+	}
+}
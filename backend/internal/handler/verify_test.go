@@ -0,0 +1,131 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/keenchase/auth-center/internal/config"
+	"github.com/keenchase/auth-center/internal/models"
+	"github.com/keenchase/auth-center/internal/service"
+)
+
+// useTestSigningKey 用新生成的签名密钥替换进程内的密钥集合，测试结束后恢复
+func useTestSigningKey(t *testing.T) {
+	t.Helper()
+	key, err := service.GenerateSigningKey(service.AlgES256)
+	if err != nil {
+		t.Fatalf("GenerateSigningKey: %v", err)
+	}
+	keySet := service.DefaultKeySet()
+	previous := keySet.Keys()
+	keySet.Replace(key)
+	t.Cleanup(func() {
+		if len(previous) == 0 {
+			keySet.Replace(nil)
+			return
+		}
+		keySet.Replace(previous[0], previous[1:]...)
+	})
+}
+
+func TestVerifyTokenRejectsOtherTenantToken(t *testing.T) {
+	useTestSigningKey(t)
+	token, err := service.GenerateAccessToken("user-1", "session-1", "acme", "", "", nil, nil, time.Minute)
+	if err != nil {
+		t.Fatalf("GenerateAccessToken: %v", err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) { c.Set("tenantId", config.DefaultTenantID) })
+	// 租户校验在查询会话之前，不需要数据库
+	router.POST("/api/auth/verify-token", VerifyToken(nil))
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/api/auth/verify-token", strings.NewReader(`{"token":"`+token+`"}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	var resp struct {
+		Success bool   `json:"success"`
+		Error   string `json:"error"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if w.Code != http.StatusUnauthorized || resp.Success || resp.Error != "无效的Token" {
+		t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+	}
+}
+
+// serveVerifyToken 以 clientID 的身份（ClientAuth 已通过）调用 verify-token
+func serveVerifyToken(db *gorm.DB, clientID, token string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("tenantId", config.DefaultTenantID)
+		c.Set("clientId", clientID)
+	})
+	router.POST("/api/auth/verify-token", VerifyToken(db))
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/api/auth/verify-token", strings.NewReader(`{"token":"`+token+`"}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestVerifyTokenClientBinding(t *testing.T) {
+	db := testDB(t)
+	useTestSigningKey(t)
+	cfg := &config.Config{TenantID: config.DefaultTenantID, AccessTokenTTL: time.Minute, RefreshTokenTTL: time.Hour}
+
+	user := models.User{TenantID: config.DefaultTenantID}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("创建测试用户失败: %v", err)
+	}
+	clientTokens, err := service.IssueTokens(db, cfg, user.UserID, service.TokenOptions{ClientID: "verify-client-a"})
+	if err != nil {
+		t.Fatalf("IssueTokens: %v", err)
+	}
+	firstPartyTokens, err := service.IssueTokens(db, cfg, user.UserID, service.TokenOptions{})
+	if err != nil {
+		t.Fatalf("IssueTokens: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		clientID   string
+		token      string
+		wantStatus int
+	}{
+		{"签发给调用方的令牌", "verify-client-a", clientTokens.AccessToken, http.StatusOK},
+		{"签发给其他客户端的令牌", "verify-client-b", clientTokens.AccessToken, http.StatusUnauthorized},
+		{"第一方令牌", "verify-client-b", firstPartyTokens.AccessToken, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serveVerifyToken(db, tt.clientID, tt.token)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var resp struct {
+				Data struct {
+					ClientID string `json:"clientId"`
+				} `json:"data"`
+			}
+			json.Unmarshal(w.Body.Bytes(), &resp)
+			if resp.Data.ClientID != tt.clientID {
+				t.Fatalf("clientId = %q, want %q（角色与权限取调用方的）", resp.Data.ClientID, tt.clientID)
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/keenchase/auth-center/internal/service"
+	"github.com/keenchase/auth-center/internal/wechat"
+	"gorm.io/gorm"
+)
+
+// MPAccessToken 业务系统获取公众号 access_token（中控服务，客户端凭证认证）
+func MPAccessToken(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !requireWeChatTokenAccess(c, db) {
+			return
+		}
+		credential, err := service.MPAccessToken(c.Request.Context(), db, loadConfig(c))
+		if err != nil {
+			writeWeChatCredentialError(c, err, "获取 access_token 失败")
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data": gin.H{
+				"appId":       credential.AppID,
+				"accessToken": credential.Value,
+				"expiresIn":   credential.ExpiresIn,
+			},
+		})
+	}
+}
+
+// RefreshMPAccessTokenRequest 上报失效的 access_token
+type RefreshMPAccessTokenRequest struct {
+	InvalidToken string `json:"invalidToken" binding:"required"` // 调用微信接口返回 40001 / 42001 的 access_token
+}
+
+// RefreshMPAccessToken 业务系统上报 access_token 已失效，中控服务确认后重新获取
+func RefreshMPAccessToken(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !requireWeChatTokenAccess(c, db) {
+			return
+		}
+		var req RefreshMPAccessTokenRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error":   "无效的请求参数",
+			})
+			return
+		}
+
+		credential, err := service.RefreshMPAccessToken(c.Request.Context(), db, loadConfig(c), req.InvalidToken)
+		if err != nil {
+			writeWeChatCredentialError(c, err, "刷新 access_token 失败")
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data": gin.H{
+				"appId":       credential.AppID,
+				"accessToken": credential.Value,
+				"expiresIn":   credential.ExpiresIn,
+			},
+		})
+	}
+}
+
+// MPJSAPITicket 业务系统获取公众号 jsapi_ticket（中控服务，客户端凭证认证）
+func MPJSAPITicket(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !requireWeChatTokenAccess(c, db) {
+			return
+		}
+		credential, err := service.MPJSAPITicket(c.Request.Context(), db, loadConfig(c))
+		if err != nil {
+			writeWeChatCredentialError(c, err, "获取 jsapi_ticket 失败")
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data": gin.H{
+				"appId":     credential.AppID,
+				"ticket":    credential.Value,
+				"expiresIn": credential.ExpiresIn,
+			},
+		})
+	}
+}
+
+// JSSDKSignatureRequest JS-SDK 签名请求
+type JSSDKSignatureRequest struct {
+	URL       string `json:"url" binding:"required"` // 当前网页地址（# 及其后部分会被去掉）
+	NonceStr  string `json:"nonceStr"`               // 可选，为空时自动生成
+	Timestamp int64  `json:"timestamp"`              // 可选，为空时取当前时间
+}
+
+// JSSDKSignature 为业务系统前端生成 wx.config 参数
+// 无需登录，但网页地址必须属于本租户已登记客户端的 CORS 来源或回调域名
+func JSSDKSignature(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req JSSDKSignatureRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error":   "无效的请求参数",
+			})
+			return
+		}
+
+		cfg := loadConfig(c)
+		if _, err := service.FindClientByPageURL(db, cfg.TenantID, req.URL); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error":   "网页地址不属于已登记的应用",
+			})
+			return
+		}
+
+		config, err := service.SignJSSDK(c.Request.Context(), db, cfg, req.URL, req.NonceStr, req.Timestamp)
+		if err != nil {
+			writeWeChatCredentialError(c, err, "生成签名失败")
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data":    config,
+		})
+	}
+}
+
+// requireWeChatTokenAccess 要求已认证的客户端登记了获取公众号凭证的权限
+func requireWeChatTokenAccess(c *gin.Context, db *gorm.DB) bool {
+	if service.ClientHasWeChatTokenAccess(db, c.GetString("clientId")) {
+		return true
+	}
+	c.JSON(http.StatusForbidden, gin.H{
+		"success": false,
+		"error":   "该应用未开通公众号凭证访问",
+	})
+	return false
+}
+
+// writeWeChatCredentialError 按错误类型返回中控服务接口的错误
+func writeWeChatCredentialError(c *gin.Context, err error, message string) {
+	if errors.Is(err, service.ErrIdentityProviderNotConfigured) {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "公众号配置缺失",
+		})
+		return
+	}
+	if errors.Is(err, wechat.ErrCircuitOpen) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{
+		"success": false,
+		"error":   message,
+	})
+}
@@ -0,0 +1,40 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/keenchase/auth-center/internal/config"
+)
+
+func TestJSSDKSignatureRejectsUnregisteredURL(t *testing.T) {
+	db := testDB(t)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("tenantConfig", &config.Config{TenantID: config.DefaultTenantID, WeChatMPAppID: "wx-test"})
+	})
+	router.POST("/api/wechat/mp/jssdk-signature", JSSDKSignature(db))
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/api/wechat/mp/jssdk-signature",
+		strings.NewReader(`{"url":"https://unregistered.example.com/page"}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	var resp struct {
+		Success bool   `json:"success"`
+		Error   string `json:"error"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("解析响应: %v", err)
+	}
+	if w.Code != http.StatusBadRequest || resp.Success || resp.Error != "网页地址不属于已登记的应用" {
+		t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,29 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/keenchase/auth-center/internal/models"
+	"github.com/keenchase/auth-center/internal/service"
+	"gorm.io/gorm"
+)
+
+// WeComLogin 企业微信登录：企业微信内走网页授权，其他浏览器走扫码登录
+func WeComLogin(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		loginMethod := models.LoginMethodWeComQR
+		if service.IsWeComBrowser(c.GetHeader("User-Agent")) {
+			loginMethod = models.LoginMethodWeCom
+		}
+		startProviderLogin(c, db, loginMethod)
+	}
+}
+
+// WeComRedirect 企业微信网页授权重定向（接收企业微信回调）
+func WeComRedirect(db *gorm.DB) gin.HandlerFunc {
+	return ProviderRedirect(db, models.LoginMethodWeCom)
+}
+
+// WeComQRRedirect 企业微信扫码登录重定向（接收企业微信回调）
+func WeComQRRedirect(db *gorm.DB) gin.HandlerFunc {
+	return ProviderRedirect(db, models.LoginMethodWeComQR)
+}
@@ -1,23 +1,30 @@
 package middleware
 
 import (
+	"errors"
 	"net/http"
+	"net/url"
 	"strings"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
-	"github.com/keenchase/auth-center/internal/config"
+	"github.com/google/uuid"
+	"github.com/keenchase/auth-center/internal/models"
+	"github.com/keenchase/auth-center/internal/service"
 	"gorm.io/gorm"
 )
 
-// Claims JWT 声明
-type Claims struct {
-	UserID string `json:"userId"`
-	jwt.RegisteredClaims
+// Auth JWT 认证中间件（auth-center 自身的接口，只接受第一方令牌）
+func Auth(db *gorm.DB) gin.HandlerFunc {
+	return authenticate(db, false)
 }
 
-// Auth JWT 认证中间件
-func Auth(db *gorm.DB) gin.HandlerFunc {
+// AuthAllowClients 同 Auth，但也接受签发给业务系统客户端的令牌（仅用于业务系统会调用的只读接口，如 user-info）
+func AuthAllowClients(db *gorm.DB) gin.HandlerFunc {
+	return authenticate(db, true)
+}
+
+// authenticate 校验 Bearer 令牌与会话，allowClients 为 false 时拒绝签发给业务系统客户端的令牌
+func authenticate(db *gorm.DB, allowClients bool) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// 获取 Token
 		authHeader := c.GetHeader("Authorization")
@@ -43,16 +50,9 @@ func Auth(db *gorm.DB) gin.HandlerFunc {
 
 		tokenString := parts[1]
 
-		// 加载配置获取 JWT Secret
-		cfg := config.Load()
-		secret := cfg.JWTSecret
-
-		claims := &Claims{}
-		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-			return []byte(secret), nil
-		})
-
-		if err != nil || !token.Valid {
+		// 按 kid 选择公钥验签，且令牌须由当前租户签发
+		claims, err := service.ValidateToken(tokenString)
+		if err != nil || claims.Tenant() != c.GetString("tenantId") {
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"success": false,
 				"error":   "无效的令牌",
@@ -60,17 +60,45 @@ func Auth(db *gorm.DB) gin.HandlerFunc {
 			c.Abort()
 			return
 		}
+		if !allowClients && !claims.FirstParty() {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"error":   "该令牌不能用于此接口",
+			})
+			c.Abort()
+			return
+		}
+
+		// 检查会话是否已登出或吊销（优先读缓存），且会话必须属于令牌中的用户
+		sessionID, sessionUserID := claims.SessionID, ""
+		if sessionID != "" {
+			sessionUserID, err = service.CheckSession(db, sessionID)
+		} else {
+			// 迁移期：不带 sid 的旧 Token 按 token 列查会话
+			var session *models.Session
+			if session, err = service.GetSessionByToken(db, tokenString); err == nil {
+				sessionID, sessionUserID = session.ID, session.UserID
+			}
+		}
+		if err != nil || sessionUserID != claims.UserID {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"error":   "会话已失效",
+			})
+			c.Abort()
+			return
+		}
 
 		// 将用户信息存储到上下文
 		c.Set("userId", claims.UserID)
+		c.Set("sessionId", sessionID)
 
 		c.Next()
 	}
 }
 
-// RequireAdmin 要求管理员权限
-// 通过验证用户的微信 UnionID 是否匹配配置中的管理员 UnionID
-func RequireAdmin(db *gorm.DB) gin.HandlerFunc {
+// RequirePermission 要求当前用户拥有 auth-center 的指定权限（通过角色授予，* 表示全部权限）
+func RequirePermission(db *gorm.DB, permission string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		userID := c.GetString("userId")
 		if userID == "" {
@@ -82,59 +110,97 @@ func RequireAdmin(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
-		// 加载配置获取管理员 UnionID
-		cfg := config.Load()
-		adminUnionID := cfg.AdminWeChatOpenID
-
-		if adminUnionID == "" {
+		allowed, err := service.UserHasPermission(db, "", userID, permission)
+		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{
 				"success": false,
-				"error":   "管理员配置缺失",
+				"error":   "权限校验失败",
 			})
 			c.Abort()
 			return
 		}
+		if !allowed {
+			c.JSON(http.StatusForbidden, gin.H{
+				"success": false,
+				"error":   "权限不足：需要 " + permission,
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
 
-		// 查询用户的 UnionID
-		var user struct {
-			UnionID string `gorm:"column:union_id"`
+// ClientAuth 业务系统服务端认证中间件（HTTP Basic，client_id:client_secret）
+// 只接受当前租户的机密客户端，认证通过后将 clientId 存入上下文
+func ClientAuth(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, secret, ok := c.Request.BasicAuth()
+		if ok {
+			id, _ = url.QueryUnescape(id)
+			secret, _ = url.QueryUnescape(secret)
 		}
-		err := db.Table("users").
-			Select("union_id").
-			Where("user_id = ?", userID).
-			First(&user).Error
 
-		if err != nil {
-			c.JSON(http.StatusForbidden, gin.H{
+		client, err := service.AuthenticateClient(db, c.GetString("tenantId"), id, secret)
+		if !ok || err != nil || client.IsPublic() {
+			c.Header("WWW-Authenticate", `Basic realm="auth-center"`)
+			c.JSON(http.StatusUnauthorized, gin.H{
 				"success": false,
-				"error":   "无管理员权限",
-				"debug": gin.H{
-					"userID":    userID,
-					"error":     err.Error(),
-				},
+				"error":   "客户端认证失败",
 			})
 			c.Abort()
 			return
 		}
 
-		// 添加调试日志
-		c.Header("X-User-UnionID", user.UnionID)
-		c.Header("X-Admin-UnionID", adminUnionID)
+		c.Set("clientId", client.ClientID)
+		c.Next()
+	}
+}
 
-		// 验证 UnionID 是否匹配管理员
-		if user.UnionID != adminUnionID {
+// RequireOrgRole 要求当前用户是路径中 :id 组织的成员，且组织内角色不低于 minRole
+// 通过后将组织内角色存入上下文 orgRole；非成员一律返回 404，不暴露组织是否存在
+func RequireOrgRole(db *gorm.DB, minRole string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		orgID := c.Param("id")
+		role, err := "", service.ErrOrgNotFound
+		if _, perr := uuid.Parse(orgID); perr == nil {
+			role, err = service.OrgMemberRole(db, orgID, c.GetString("userId"))
+		}
+		if errors.Is(err, service.ErrOrgNotFound) || errors.Is(err, service.ErrOrgMemberNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"success": false,
+				"error":   service.ErrOrgNotFound.Error(),
+			})
+			c.Abort()
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"error":   "权限校验失败",
+			})
+			c.Abort()
+			return
+		}
+		if models.OrgRoleRank(role) < models.OrgRoleRank(minRole) {
 			c.JSON(http.StatusForbidden, gin.H{
 				"success": false,
-				"error":   "无管理员权限",
-				"debug": gin.H{
-					"userUnionID": user.UnionID,
-					"adminUnionID": adminUnionID,
-				},
+				"error":   service.ErrOrgForbidden.Error(),
 			})
 			c.Abort()
 			return
 		}
 
+		c.Set("orgRole", role)
+		c.Next()
+	}
+}
+
+// ActAsOrgOwner 平台管理员以所有者身份管理任意组织（需放在 RequirePermission 之后）
+func ActAsOrgOwner() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set("orgRole", models.OrgRoleOwner)
 		c.Next()
 	}
 }
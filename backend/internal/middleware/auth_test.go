@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/keenchase/auth-center/internal/config"
+	"github.com/keenchase/auth-center/internal/service"
+)
+
+// useTestSigningKey 用新生成的签名密钥替换进程内的密钥集合，测试结束后恢复
+func useTestSigningKey(t *testing.T) {
+	t.Helper()
+	key, err := service.GenerateSigningKey(service.AlgES256)
+	if err != nil {
+		t.Fatalf("GenerateSigningKey: %v", err)
+	}
+	keySet := service.DefaultKeySet()
+	previous := keySet.Keys()
+	keySet.Replace(key)
+	t.Cleanup(func() {
+		if len(previous) == 0 {
+			keySet.Replace(nil)
+			return
+		}
+		keySet.Replace(previous[0], previous[1:]...)
+	})
+}
+
+func TestAuthRejectsOtherTenantToken(t *testing.T) {
+	useTestSigningKey(t)
+	token, err := service.GenerateAccessToken("user-1", "session-1", "acme", "", "", nil, nil, time.Minute)
+	if err != nil {
+		t.Fatalf("GenerateAccessToken: %v", err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) { c.Set("tenantId", config.DefaultTenantID) })
+	// 租户校验在查询会话之前，不需要数据库
+	router.GET("/api/auth/me", Auth(nil), func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/auth/me", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	router.ServeHTTP(w, req)
+
+	var resp struct {
+		Error string `json:"error"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if w.Code != http.StatusUnauthorized || resp.Error != "无效的令牌" {
+		t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+	}
+}
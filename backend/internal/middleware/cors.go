@@ -1,19 +1,27 @@
 package middleware
 
 import (
+	"net/url"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/keenchase/auth-center/internal/config"
+	"github.com/keenchase/auth-center/internal/service"
+	"gorm.io/gorm"
 )
 
 // CORS 跨域中间件（带白名单验证）
-func CORS(cfg *config.Config) gin.HandlerFunc {
+// 允许 auth-center 自身与已登记客户端的 CORS 来源；ALLOWED_ORIGINS 仅作为迁移期兜底
+func CORS(db *gorm.DB, cfg *config.Config) gin.HandlerFunc {
 	// 解析白名单
-	allowedOrigins := strings.Split(cfg.AllowedOrigins, ",")
 	originMap := make(map[string]bool)
-	for _, origin := range allowedOrigins {
-		originMap[strings.TrimSpace(origin)] = true
+	if issuerURL, err := url.Parse(cfg.Issuer); err == nil && issuerURL.Host != "" {
+		originMap[issuerURL.Scheme+"://"+issuerURL.Host] = true
+	}
+	for _, origin := range strings.Split(cfg.AllowedOrigins, ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			originMap[origin] = true
+		}
 	}
 
 	return func(c *gin.Context) {
@@ -21,7 +29,7 @@ func CORS(cfg *config.Config) gin.HandlerFunc {
 
 		// 验证 Origin 是否在白名单中
 		if origin != "" {
-			if !originMap[origin] {
+			if !originMap[origin] && !service.IsAllowedOrigin(db, origin) {
 				c.JSON(403, gin.H{
 					"success": false,
 					"error":   "域名未在白名单中",
@@ -31,11 +39,12 @@ func CORS(cfg *config.Config) gin.HandlerFunc {
 			}
 			// 设置具体的 Origin（不能是 *，因为要支持 credentials）
 			c.Writer.Header().Set("Access-Control-Allow-Origin", origin)
+			c.Writer.Header().Add("Vary", "Origin")
 		}
 
 		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
 		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE")
+		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, PATCH, DELETE")
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
@@ -0,0 +1,143 @@
+package middleware
+
+import (
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/keenchase/auth-center/internal/config"
+	"github.com/keenchase/auth-center/internal/service"
+	"gorm.io/gorm"
+)
+
+// Tenant 解析请求所属的租户（路径 /t/:tenant 优先，其次按域名，否则为默认租户）
+// 将 tenantId、租户配置 tenantConfig、请求域名 requestHost 存入上下文；路径指定租户时 tenantBasePath 为 /t/<id>，用于拼接回跳地址
+func Tenant(db *gorm.DB, cfg *config.Config) gin.HandlerFunc {
+	proxies := parseTrustedProxies(cfg.TrustedProxies)
+
+	return func(c *gin.Context) {
+		// 只有受信任的反向代理（TRUSTED_PROXIES）转发的请求才使用 X-Forwarded-Host
+		host := c.Request.Host
+		if forwarded := c.GetHeader("X-Forwarded-Host"); forwarded != "" && fromTrustedProxy(c, proxies) {
+			host, _, _ = strings.Cut(forwarded, ",")
+			host = strings.TrimSpace(host)
+		}
+
+		tenant, err := service.ResolveTenant(db, c.Param("tenant"), host)
+		if errors.Is(err, service.ErrTenantNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"success": false,
+				"error":   err.Error(),
+			})
+			c.Abort()
+			return
+		}
+		if err != nil {
+			log.Printf("解析租户失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"error":   "解析租户失败",
+			})
+			c.Abort()
+			return
+		}
+
+		tenantCfg, err := service.LoadTenantConfig(db, tenant)
+		if err != nil {
+			log.Printf("加载租户 %s 配置失败: %v", tenant.ID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"error":   "加载租户配置失败",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Set("tenantId", tenant.ID)
+		c.Set("tenantConfig", tenantCfg)
+		c.Set("requestHost", host)
+		if c.Param("tenant") != "" {
+			c.Set("tenantBasePath", "/t/"+tenant.ID)
+		}
+		c.Next()
+	}
+}
+
+// parseTrustedProxies 解析逗号分隔的 IP / CIDR 列表
+func parseTrustedProxies(list string) []*net.IPNet {
+	var nets []*net.IPNet
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			if ip := net.ParseIP(entry); ip != nil && ip.To4() != nil {
+				entry += "/32"
+			} else {
+				entry += "/128"
+			}
+		}
+		if _, ipNet, err := net.ParseCIDR(entry); err == nil {
+			nets = append(nets, ipNet)
+		}
+	}
+	return nets
+}
+
+// fromTrustedProxy 请求是否直接来自受信任的反向代理
+func fromTrustedProxy(c *gin.Context, proxies []*net.IPNet) bool {
+	ip := net.ParseIP(c.RemoteIP())
+	if ip == nil {
+		return false
+	}
+	for _, proxy := range proxies {
+		if proxy.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// RequireDefaultTenant 平台级功能（签名密钥、后台任务、租户管理等）只能在默认租户下使用
+func RequireDefaultTenant() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString("tenantId") != config.DefaultTenantID {
+			c.JSON(http.StatusForbidden, gin.H{
+				"success": false,
+				"error":   "仅默认租户可使用此功能",
+			})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// InTenant 要求路径中 :id 指向的资源属于当前租户
+// belongs 在资源不存在或属于其他租户时返回 notFound，两种情况一律 404，不暴露其他租户的资源
+func InTenant(db *gorm.DB, belongs func(db *gorm.DB, tenantID, id string) error, notFound error) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		err := belongs(db, c.GetString("tenantId"), c.Param("id"))
+		if errors.Is(err, notFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"success": false,
+				"error":   notFound.Error(),
+			})
+			c.Abort()
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"error":   "权限校验失败",
+			})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
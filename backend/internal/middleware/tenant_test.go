@@ -0,0 +1,154 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/keenchase/auth-center/internal/config"
+	"github.com/keenchase/auth-center/internal/models"
+)
+
+// serveTenantID 经 Tenant 中间件处理请求，返回状态码与解析出的租户
+func serveTenantID(t *testing.T, handler gin.HandlerFunc, req *http.Request) (int, string) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	echo := func(c *gin.Context) { c.String(http.StatusOK, c.GetString("tenantId")) }
+	router.GET("/api/whoami", handler, echo)
+	router.GET("/t/:tenant/api/whoami", handler, echo)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w.Code, w.Body.String()
+}
+
+func TestTenantHostAndPath(t *testing.T) {
+	db := testDB(t)
+	if err := db.Create(&models.Tenant{ID: "acme-test", Name: "测试租户", Hosts: "acme-test.example.com", Enabled: true}).Error; err != nil {
+		t.Fatalf("创建测试租户失败: %v", err)
+	}
+	handler := Tenant(db, &config.Config{TrustedProxies: "10.0.0.1"})
+
+	tests := []struct {
+		name   string
+		target string
+		want   string
+	}{
+		{"按域名", "http://acme-test.example.com/api/whoami", "acme-test"},
+		{"按路径", "http://os.crazyaigc.com/t/acme-test/api/whoami", "acme-test"},
+		{"路径优先于域名", "http://acme-test.example.com/t/default/api/whoami", config.DefaultTenantID},
+		{"未登记的域名", "http://os.crazyaigc.com/api/whoami", config.DefaultTenantID},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, tenantID := serveTenantID(t, handler, httptest.NewRequest(http.MethodGet, tt.target, nil))
+			if code != http.StatusOK || tenantID != tt.want {
+				t.Fatalf("status = %d, tenant = %q, want %q", code, tenantID, tt.want)
+			}
+		})
+	}
+
+	code, _ := serveTenantID(t, handler, httptest.NewRequest(http.MethodGet, "http://os.crazyaigc.com/t/unknown/api/whoami", nil))
+	if code != http.StatusNotFound {
+		t.Fatalf("不存在的租户 status = %d, want 404", code)
+	}
+}
+
+func TestTenantForwardedHost(t *testing.T) {
+	db := testDB(t)
+	if err := db.Create(&models.Tenant{ID: "acme-test", Name: "测试租户", Hosts: "acme-test.example.com", Enabled: true}).Error; err != nil {
+		t.Fatalf("创建测试租户失败: %v", err)
+	}
+	handler := Tenant(db, &config.Config{TrustedProxies: "10.0.0.1"})
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		want       string
+	}{
+		{"受信任的代理", "10.0.0.1:40000", "acme-test"},
+		{"直接访问的客户端", "203.0.113.7:40000", config.DefaultTenantID},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "http://os.crazyaigc.com/api/whoami", nil)
+			req.RemoteAddr = tt.remoteAddr
+			req.Header.Set("X-Forwarded-Host", "acme-test.example.com")
+			code, tenantID := serveTenantID(t, handler, req)
+			if code != http.StatusOK || tenantID != tt.want {
+				t.Fatalf("status = %d, tenant = %q, want %q", code, tenantID, tt.want)
+			}
+		})
+	}
+}
+
+func TestFromTrustedProxy(t *testing.T) {
+	proxies := parseTrustedProxies("127.0.0.1, 10.0.0.0/8, ::1, invalid")
+	tests := []struct {
+		remoteAddr string
+		want       bool
+	}{
+		{"127.0.0.1:1234", true},
+		{"10.20.30.40:1234", true},
+		{"[::1]:1234", true},
+		{"203.0.113.7:1234", false},
+		{"[2001:db8::1]:1234", false},
+	}
+	for _, tt := range tests {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+		c.Request.RemoteAddr = tt.remoteAddr
+		if got := fromTrustedProxy(c, proxies); got != tt.want {
+			t.Errorf("fromTrustedProxy(%s) = %v, want %v", tt.remoteAddr, got, tt.want)
+		}
+	}
+}
+
+func TestInTenant(t *testing.T) {
+	errNotFound := errors.New("资源不存在")
+	owners := map[string]string{"doc-1": "acme", "doc-2": config.DefaultTenantID}
+	belongs := func(_ *gorm.DB, tenantID, id string) error {
+		if owners[id] != tenantID {
+			return errNotFound
+		}
+		return nil
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) { c.Set("tenantId", config.DefaultTenantID) })
+	router.GET("/api/docs/:id", InTenant(nil, belongs, errNotFound), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	tests := []struct {
+		id   string
+		want int
+	}{
+		{"doc-2", http.StatusNoContent},
+		{"doc-1", http.StatusNotFound}, // 其他租户的资源
+		{"doc-3", http.StatusNotFound}, // 不存在的资源
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/docs/"+tt.id, nil))
+		if w.Code != tt.want {
+			t.Fatalf("%s status = %d, want %d", tt.id, w.Code, tt.want)
+		}
+		if tt.want == http.StatusNotFound {
+			var resp struct {
+				Error string `json:"error"`
+			}
+			json.Unmarshal(w.Body.Bytes(), &resp)
+			if resp.Error != errNotFound.Error() {
+				t.Fatalf("%s error = %q, 其他租户的资源应与不存在的资源返回相同的错误", tt.id, resp.Error)
+			}
+		}
+	}
+}
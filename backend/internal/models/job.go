@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+)
+
+// 后台任务运行状态
+const (
+	JobStatusSuccess = "success"
+	JobStatusFailed  = "failed"
+)
+
+// ScheduledJob 后台任务最近一次运行记录
+type ScheduledJob struct {
+	Name           string     `gorm:"primaryKey;column:name;type:varchar(100)" json:"name"`
+	LastStartedAt  *time.Time `gorm:"column:last_started_at;type:timestamp with time zone" json:"lastStartedAt,omitempty"`
+	LastFinishedAt *time.Time `gorm:"column:last_finished_at;type:timestamp with time zone" json:"lastFinishedAt,omitempty"`
+	LastStatus     string     `gorm:"column:last_status;type:varchar(20)" json:"lastStatus,omitempty"`
+	LastResult     string     `gorm:"column:last_result;type:text" json:"lastResult,omitempty"`
+	LastError      string     `gorm:"column:last_error;type:text" json:"lastError,omitempty"`
+	LastDurationMs int64      `gorm:"column:last_duration_ms" json:"lastDurationMs"`
+	LastInstance   string     `gorm:"column:last_instance;type:varchar(255)" json:"lastInstance,omitempty"`
+	UpdatedAt      time.Time  `gorm:"column:updated_at;type:timestamp with time zone" json:"updatedAt"`
+}
+
+// TableName 指定表名
+func (ScheduledJob) TableName() string {
+	return "scheduled_jobs"
+}
@@ -0,0 +1,192 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// 客户端可限制的登录方式（与登录流水的 login_method 一致）
+const (
+	LoginMethodWeChatMP   = "wechat_mp"
+	LoginMethodWeChatOpen = "wechat_open"
+	LoginMethodWeChatMini = "wechat_miniapp"
+	LoginMethodWeCom      = "wecom"        // 企业微信内网页授权
+	LoginMethodWeComQR    = "wecom_qr"     // 企业微信扫码登录
+	LoginMethodWeChatMPQR = "wechat_mp_qr" // 扫码关注公众号登录
+	LoginMethodPassword   = "password"
+)
+
+// Client 已注册的 OAuth/OIDC 客户端（业务系统）
+type Client struct {
+	ClientID            string    `gorm:"primaryKey;column:client_id;type:varchar(100)" json:"clientId"`
+	TenantID            string    `gorm:"column:tenant_id;type:varchar(50);not null" json:"-"`
+	ClientSecretHash    string    `gorm:"column:client_secret_hash;type:varchar(255)" json:"-"`
+	Name                string    `gorm:"column:name;type:varchar(255);not null" json:"name"`
+	RedirectURIs        string    `gorm:"column:redirect_uris;type:text;not null" json:"-"`         // 空格分隔
+	AllowedOrigins      string    `gorm:"column:allowed_origins;type:text;not null" json:"-"`       // 空格分隔
+	AllowedLoginMethods string    `gorm:"column:allowed_login_methods;type:text;not null" json:"-"` // 空格分隔，空表示不限
+	AccessTokenTTL      *int      `gorm:"column:access_token_ttl" json:"-"`                         // 秒
+	RefreshTokenTTL     *int      `gorm:"column:refresh_token_ttl" json:"-"`                        // 秒
+	LogoURL             *string   `gorm:"column:logo_url;type:text" json:"-"`
+	WeChatTokenAccess   bool      `gorm:"column:wechat_token_access;not null" json:"-"` // 允许获取公众号接口凭证（中控服务）
+	Enabled             bool      `gorm:"column:enabled;not null" json:"enabled"`
+	CreatedAt           time.Time `gorm:"column:created_at;type:timestamp with time zone" json:"createdAt"`
+	UpdatedAt           time.Time `gorm:"column:updated_at;type:timestamp with time zone" json:"updatedAt"`
+}
+
+// TableName 指定表名
+func (Client) TableName() string {
+	return "clients"
+}
+
+// IsPublic 是否为公共客户端（无密钥，必须使用 PKCE）
+func (c *Client) IsPublic() bool {
+	return c.ClientSecretHash == ""
+}
+
+// RedirectURIList 返回已注册的回调地址列表
+func (c *Client) RedirectURIList() []string {
+	return strings.Fields(c.RedirectURIs)
+}
+
+// HasRedirectURI 回调地址是否已注册（精确匹配）
+func (c *Client) HasRedirectURI(uri string) bool {
+	for _, registered := range c.RedirectURIList() {
+		if registered == uri {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowedOriginList 返回允许跨域访问的来源列表
+func (c *Client) AllowedOriginList() []string {
+	return strings.Fields(c.AllowedOrigins)
+}
+
+// AllowedLoginMethodList 返回允许的登录方式列表，空表示不限
+func (c *Client) AllowedLoginMethodList() []string {
+	return strings.Fields(c.AllowedLoginMethods)
+}
+
+// AllowsLoginMethod 是否允许该登录方式
+func (c *Client) AllowsLoginMethod(method string) bool {
+	methods := c.AllowedLoginMethodList()
+	if len(methods) == 0 {
+		return true
+	}
+	for _, m := range methods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+// AuthorizeRequest 待完成上游登录的授权请求
+type AuthorizeRequest struct {
+	ID                  string    `gorm:"primaryKey;column:id;type:uuid;default:gen_random_uuid()"`
+	ClientID            string    `gorm:"column:client_id;type:varchar(100);not null"`
+	RedirectURI         string    `gorm:"column:redirect_uri;type:text;not null"`
+	Scope               string    `gorm:"column:scope;type:varchar(500);not null"`
+	State               string    `gorm:"column:state;type:varchar(500)"`
+	Nonce               string    `gorm:"column:nonce;type:varchar(500)"`
+	CodeChallenge       string    `gorm:"column:code_challenge;type:varchar(128)"`
+	CodeChallengeMethod string    `gorm:"column:code_challenge_method;type:varchar(10)"`
+	BrowserNonceHash    string    `gorm:"column:browser_nonce_hash;type:varchar(64)"` // 发起授权的浏览器 nonce 哈希，续接时校验
+	ExpiresAt           time.Time `gorm:"column:expires_at;type:timestamp with time zone;not null"`
+	CreatedAt           time.Time `gorm:"column:created_at;type:timestamp with time zone"`
+}
+
+// TableName 指定表名
+func (AuthorizeRequest) TableName() string {
+	return "oauth_authorize_requests"
+}
+
+// AuthorizationCode 授权码（仅保存哈希）
+type AuthorizationCode struct {
+	CodeHash            string     `gorm:"primaryKey;column:code_hash;type:varchar(64)"`
+	ClientID            string     `gorm:"column:client_id;type:varchar(100);not null"`
+	UserID              string     `gorm:"column:user_id;type:uuid;not null"`
+	RedirectURI         string     `gorm:"column:redirect_uri;type:text;not null"`
+	Scope               string     `gorm:"column:scope;type:varchar(500);not null"`
+	Nonce               string     `gorm:"column:nonce;type:varchar(500)"`
+	CodeChallenge       string     `gorm:"column:code_challenge;type:varchar(128)"`
+	CodeChallengeMethod string     `gorm:"column:code_challenge_method;type:varchar(10)"`
+	AuthTime            time.Time  `gorm:"column:auth_time;type:timestamp with time zone;not null"`
+	LoginMethod         string     `gorm:"column:login_method;type:varchar(20);not null"`
+	SessionID           *string    `gorm:"column:session_id;type:uuid"`
+	DeviceInfo          *string    `gorm:"column:device_info;type:jsonb"` // 授权时用户浏览器的设备信息
+	ExpiresAt           time.Time  `gorm:"column:expires_at;type:timestamp with time zone;not null"`
+	UsedAt              *time.Time `gorm:"column:used_at;type:timestamp with time zone"`
+	CreatedAt           time.Time  `gorm:"column:created_at;type:timestamp with time zone"`
+}
+
+// TableName 指定表名
+func (AuthorizationCode) TableName() string {
+	return "oauth_authorization_codes"
+}
+
+// OAuthState 上游登录 state（仅保存哈希，一次性使用）
+type OAuthState struct {
+	StateHash   string     `gorm:"primaryKey;column:state_hash;type:varchar(64)"`
+	NonceHash   string     `gorm:"column:nonce_hash;type:varchar(64);not null"`
+	Flow        string     `gorm:"column:flow;type:varchar(20);not null"` // 登录方式，见 LoginMethod*
+	AppID       string     `gorm:"column:app_id;type:varchar(100);not null"`
+	CallbackURL string     `gorm:"column:callback_url;type:text;not null"`
+	ExpiresAt   time.Time  `gorm:"column:expires_at;type:timestamp with time zone;not null"`
+	UsedAt      *time.Time `gorm:"column:used_at;type:timestamp with time zone"`
+	CreatedAt   time.Time  `gorm:"column:created_at;type:timestamp with time zone"`
+}
+
+// TableName 指定表名
+func (OAuthState) TableName() string {
+	return "oauth_states"
+}
+
+// ExchangeCode 登录交换码（仅保存哈希，一次性使用）
+type ExchangeCode struct {
+	CodeHash         string     `gorm:"primaryKey;column:code_hash;type:varchar(64)"`
+	ClientID         string     `gorm:"column:client_id;type:varchar(100);not null"` // 空字符串表示由 auth-center 自身（OIDC 授权续接）兑换
+	BrowserNonceHash string     `gorm:"column:browser_nonce_hash;type:varchar(64)"`  // ClientID 为空时绑定的浏览器 nonce 哈希
+	UserID           string     `gorm:"column:user_id;type:uuid;not null"`
+	CallbackURL      string     `gorm:"column:callback_url;type:text;not null"`
+	LoginMethod      string     `gorm:"column:login_method;type:varchar(50);not null"`
+	DeviceInfo       *string    `gorm:"column:device_info;type:jsonb"`
+	ExpiresAt        time.Time  `gorm:"column:expires_at;type:timestamp with time zone;not null"`
+	UsedAt           *time.Time `gorm:"column:used_at;type:timestamp with time zone"`
+	CreatedAt        time.Time  `gorm:"column:created_at;type:timestamp with time zone"`
+}
+
+// TableName 指定表名
+func (ExchangeCode) TableName() string {
+	return "exchange_codes"
+}
+
+// 扫码关注登录状态
+const (
+	QRLoginPending   = "pending"   // 等待扫码
+	QRLoginConfirmed = "confirmed" // 已扫码，等待 PC 页面完成登录
+	QRLoginConsumed  = "consumed"  // 已完成登录
+)
+
+// QRLogin 扫码关注公众号登录（带参数二维码，仅保存轮询令牌的哈希）
+type QRLogin struct {
+	ID               string     `gorm:"primaryKey;column:id;type:uuid;default:gen_random_uuid()"`
+	TenantID         string     `gorm:"column:tenant_id;type:varchar(50);not null"`
+	PollTokenHash    string     `gorm:"column:poll_token_hash;type:varchar(64);not null"`
+	BrowserNonceHash string     `gorm:"column:browser_nonce_hash;type:varchar(64)"` // 创建扫码登录的浏览器 nonce 哈希，完成登录时校验
+	ClientIP         string     `gorm:"column:client_ip;type:varchar(45)"`          // 创建者 IP（按 IP 限流）
+	AppID            string     `gorm:"column:app_id;type:varchar(100);not null"`   // 公众号 AppID
+	CallbackURL      string     `gorm:"column:callback_url;type:text;not null"`
+	Status           string     `gorm:"column:status;type:varchar(20);not null"` // 见 QRLogin* 常量
+	UserID           *string    `gorm:"column:user_id;type:uuid"`                // 扫码后识别出的用户
+	ExpiresAt        time.Time  `gorm:"column:expires_at;type:timestamp with time zone;not null"`
+	ScannedAt        *time.Time `gorm:"column:scanned_at;type:timestamp with time zone"`
+	CreatedAt        time.Time  `gorm:"column:created_at;type:timestamp with time zone"`
+}
+
+// TableName 指定表名
+func (QRLogin) TableName() string {
+	return "wechat_qr_logins"
+}
@@ -0,0 +1,71 @@
+package models
+
+import "time"
+
+// 组织内角色
+const (
+	OrgRoleOwner  = "owner"
+	OrgRoleAdmin  = "admin"
+	OrgRoleMember = "member"
+)
+
+// OrgRoleRank 组织角色的级别，数值越大权限越高，未知角色为 0
+func OrgRoleRank(role string) int {
+	switch role {
+	case OrgRoleOwner:
+		return 3
+	case OrgRoleAdmin:
+		return 2
+	case OrgRoleMember:
+		return 1
+	}
+	return 0
+}
+
+// Organization 组织
+type Organization struct {
+	ID          string    `gorm:"primaryKey;column:id;type:uuid;default:gen_random_uuid()" json:"id"`
+	TenantID    string    `gorm:"column:tenant_id;type:varchar(50);not null" json:"-"`
+	Slug        string    `gorm:"column:slug;type:varchar(50);not null" json:"slug"` // 租户内唯一
+	Name        string    `gorm:"column:name;type:varchar(100);not null" json:"name"`
+	Description string    `gorm:"column:description;type:varchar(255);not null" json:"description"`
+	CreatedAt   time.Time `gorm:"column:created_at;type:timestamp with time zone" json:"createdAt"`
+	UpdatedAt   time.Time `gorm:"column:updated_at;type:timestamp with time zone" json:"updatedAt"`
+}
+
+// TableName 指定表名
+func (Organization) TableName() string {
+	return "organizations"
+}
+
+// OrganizationMember 组织成员
+type OrganizationMember struct {
+	OrgID     string    `gorm:"primaryKey;column:org_id;type:uuid"`
+	UserID    string    `gorm:"primaryKey;column:user_id;type:uuid"`
+	Role      string    `gorm:"column:role;type:varchar(20);not null"`
+	CreatedAt time.Time `gorm:"column:created_at;type:timestamp with time zone"`
+}
+
+// TableName 指定表名
+func (OrganizationMember) TableName() string {
+	return "organization_members"
+}
+
+// OrganizationInvitation 组织邀请（邀请码仅保存哈希）
+type OrganizationInvitation struct {
+	ID        string     `gorm:"primaryKey;column:id;type:uuid;default:gen_random_uuid()" json:"id"`
+	OrgID     string     `gorm:"column:org_id;type:uuid;not null" json:"orgId"`
+	CodeHash  string     `gorm:"column:code_hash;type:varchar(64);not null" json:"-"`
+	Role      string     `gorm:"column:role;type:varchar(20);not null" json:"role"`
+	MaxUses   *int       `gorm:"column:max_uses" json:"maxUses,omitempty"` // 为空表示不限次数
+	Uses      int        `gorm:"column:uses;not null" json:"uses"`
+	CreatedBy *string    `gorm:"column:created_by;type:uuid" json:"createdBy,omitempty"`
+	ExpiresAt time.Time  `gorm:"column:expires_at;type:timestamp with time zone;not null" json:"expiresAt"`
+	RevokedAt *time.Time `gorm:"column:revoked_at;type:timestamp with time zone" json:"revokedAt,omitempty"`
+	CreatedAt time.Time  `gorm:"column:created_at;type:timestamp with time zone" json:"createdAt"`
+}
+
+// TableName 指定表名
+func (OrganizationInvitation) TableName() string {
+	return "organization_invitations"
+}
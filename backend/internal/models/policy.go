@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// 策略效果
+const (
+	PolicyEffectAllow = "allow"
+	PolicyEffectDeny  = "deny"
+)
+
+// Policy 业务系统的访问策略：资源与操作匹配且条件全部满足时生效
+type Policy struct {
+	ID          string    `gorm:"primaryKey;column:id;type:uuid;default:gen_random_uuid()"`
+	ClientID    string    `gorm:"column:client_id;type:varchar(100);not null"`
+	Name        string    `gorm:"column:name;type:varchar(100);not null"`
+	Description string    `gorm:"column:description;type:varchar(255);not null"`
+	Resource    string    `gorm:"column:resource;type:varchar(255);not null"` // * 结尾表示前缀匹配
+	Action      string    `gorm:"column:action;type:varchar(100);not null"`   // * 结尾表示前缀匹配
+	Effect      string    `gorm:"column:effect;type:varchar(10);not null"`
+	Conditions  string    `gorm:"column:conditions;type:jsonb;not null"` // []PolicyCondition
+	Enabled     bool      `gorm:"column:enabled;not null"`
+	CreatedAt   time.Time `gorm:"column:created_at;type:timestamp with time zone"`
+	UpdatedAt   time.Time `gorm:"column:updated_at;type:timestamp with time zone"`
+}
+
+// TableName 指定表名
+func (Policy) TableName() string {
+	return "policies"
+}
+
+// PolicyCondition 策略条件：属性 运算符 值（或另一个属性）
+type PolicyCondition struct {
+	Attribute string      `json:"attribute"`           // 如 session.loginMethod、user.roles、resource.ownerId
+	Operator  string      `json:"operator"`            // eq | ne | in | not_in | contains | gt | gte | lt | lte | exists
+	Value     interface{} `json:"value,omitempty"`     // 常量值
+	ValueFrom string      `json:"valueFrom,omitempty"` // 与另一个属性比较（与 value 二选一）
+}
@@ -0,0 +1,74 @@
+package models
+
+import "time"
+
+// 内置权限
+const (
+	PermissionAll            = "*"
+	PermissionUsersRead      = "users:read"
+	PermissionUsersWrite     = "users:write"
+	PermissionSessionsRevoke = "sessions:revoke"
+	PermissionClientsManage  = "clients:manage"
+	PermissionRolesManage    = "roles:manage"
+	PermissionKeysManage     = "keys:manage"
+	PermissionJobsRead       = "jobs:read"
+	PermissionPoliciesManage = "policies:manage"
+	PermissionOrgsManage     = "orgs:manage"
+	PermissionTenantsManage  = "tenants:manage"
+)
+
+// RoleSuperAdmin 内置超级管理员角色（拥有全部权限）
+const RoleSuperAdmin = "super_admin"
+
+// Permission 权限，ClientID 为空表示 auth-center 自身的权限
+type Permission struct {
+	ClientID    string `gorm:"primaryKey;column:client_id;type:varchar(100)" json:"clientId"`
+	Code        string `gorm:"primaryKey;column:code;type:varchar(100)" json:"code"`
+	Description string `gorm:"column:description;type:varchar(255);not null" json:"description"`
+}
+
+// TableName 指定表名
+func (Permission) TableName() string {
+	return "permissions"
+}
+
+// Role 角色，ClientID 为空表示 auth-center 自身的角色
+type Role struct {
+	ID          string    `gorm:"primaryKey;column:id;type:uuid;default:gen_random_uuid()" json:"id"`
+	ClientID    string    `gorm:"column:client_id;type:varchar(100);not null" json:"clientId"`
+	Name        string    `gorm:"column:name;type:varchar(100);not null" json:"name"`
+	Description string    `gorm:"column:description;type:varchar(255);not null" json:"description"`
+	IsSystem    bool      `gorm:"column:is_system;not null" json:"isSystem"`
+	CreatedAt   time.Time `gorm:"column:created_at;type:timestamp with time zone" json:"createdAt"`
+	UpdatedAt   time.Time `gorm:"column:updated_at;type:timestamp with time zone" json:"updatedAt"`
+}
+
+// TableName 指定表名
+func (Role) TableName() string {
+	return "roles"
+}
+
+// RolePermission 角色拥有的权限
+type RolePermission struct {
+	RoleID     string `gorm:"primaryKey;column:role_id;type:uuid"`
+	ClientID   string `gorm:"column:client_id;type:varchar(100);not null"`
+	Permission string `gorm:"primaryKey;column:permission;type:varchar(100)"`
+}
+
+// TableName 指定表名
+func (RolePermission) TableName() string {
+	return "role_permissions"
+}
+
+// UserRole 用户拥有的角色
+type UserRole struct {
+	UserID    string    `gorm:"primaryKey;column:user_id;type:uuid"`
+	RoleID    string    `gorm:"primaryKey;column:role_id;type:uuid"`
+	GrantedBy *string   `gorm:"column:granted_by;type:uuid"`
+	CreatedAt time.Time `gorm:"column:created_at;type:timestamp with time zone"`
+}
+
+// TableName 指定表名
+func (UserRole) TableName() string {
+	return "user_roles"
+}
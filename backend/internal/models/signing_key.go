@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+)
+
+// 签名密钥状态
+const (
+	SigningKeyActive  = "active"  // 当前用于签名
+	SigningKeyVerify  = "verify"  // 仅验签（宽限期内）
+	SigningKeyRetired = "retired" // 已退役
+)
+
+// SigningKey JWT 签名密钥表
+type SigningKey struct {
+	Kid           string     `gorm:"primaryKey;column:kid;type:varchar(64)" json:"kid"`
+	Algorithm     string     `gorm:"column:algorithm;type:varchar(10);not null" json:"algorithm"`
+	PrivateKeyEnc string     `gorm:"column:private_key_enc;type:text;not null" json:"-"`
+	Status        string     `gorm:"column:status;type:varchar(20);not null" json:"status"`
+	ActivatedAt   time.Time  `gorm:"column:activated_at;type:timestamp with time zone;not null" json:"activatedAt"`
+	RotatedAt     *time.Time `gorm:"column:rotated_at;type:timestamp with time zone" json:"rotatedAt,omitempty"`
+	RetireAt      *time.Time `gorm:"column:retire_at;type:timestamp with time zone" json:"retireAt,omitempty"`
+	RetiredAt     *time.Time `gorm:"column:retired_at;type:timestamp with time zone" json:"retiredAt,omitempty"`
+	CreatedAt     time.Time  `gorm:"column:created_at;type:timestamp with time zone" json:"createdAt"`
+}
+
+// TableName 指定表名
+func (SigningKey) TableName() string {
+	return "signing_keys"
+}
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// Tenant 租户：独立的微信凭证、客户端、用户池与会话，同一部署服务多个品牌
+type Tenant struct {
+	ID               string    `gorm:"primaryKey;column:id;type:varchar(50)"`
+	Name             string    `gorm:"column:name;type:varchar(255);not null"`
+	Hosts            string    `gorm:"column:hosts;type:text;not null"` // 空格分隔
+	WeChatAppID      string    `gorm:"column:wechat_app_id;type:varchar(100);not null"`
+	WeChatAppSecret  string    `gorm:"column:wechat_app_secret;type:text;not null"` // 加密存储
+	WeChatMPAppID    string    `gorm:"column:wechat_mp_app_id;type:varchar(100);not null"`
+	WeChatMPSecret   string    `gorm:"column:wechat_mp_secret;type:text;not null"`           // 加密存储
+	WeChatMPToken    string    `gorm:"column:wechat_mp_token;type:text;not null"`            // 消息推送令牌，加密存储
+	WeChatMPAESKey   string    `gorm:"column:wechat_mp_encoding_aes_key;type:text;not null"` // 消息加解密密钥，加密存储
+	WeChatMiniAppID  string    `gorm:"column:wechat_mini_app_id;type:varchar(100);not null"`
+	WeChatMiniSecret string    `gorm:"column:wechat_mini_secret;type:text;not null"` // 加密存储
+	WeComCorpID      string    `gorm:"column:wecom_corp_id;type:varchar(100);not null"`
+	WeComAgentID     string    `gorm:"column:wecom_agent_id;type:varchar(50);not null"`
+	WeComSecret      string    `gorm:"column:wecom_secret;type:text;not null"` // 加密存储
+	AdminUnionID     string    `gorm:"column:admin_union_id;type:varchar(255);not null"`
+	Enabled          bool      `gorm:"column:enabled;not null"`
+	CreatedAt        time.Time `gorm:"column:created_at;type:timestamp with time zone"`
+	UpdatedAt        time.Time `gorm:"column:updated_at;type:timestamp with time zone"`
+}
+
+// TableName 指定表名
+func (Tenant) TableName() string {
+	return "tenants"
+}
+
+// HostList 返回租户登记的域名列表
+func (t *Tenant) HostList() []string {
+	return strings.Fields(t.Hosts)
+}
@@ -1,16 +1,18 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 )
 
 // User 用户表
 type User struct {
 	UserID       string         `gorm:"primaryKey;column:user_id;type:uuid;default:gen_random_uuid()" json:"userId"`
-	UnionID      string         `gorm:"uniqueIndex;column:union_id;type:varchar(255)" json:"unionId"`
-	PhoneNumber  *string        `gorm:"uniqueIndex;column:phone_number;type:varchar(255)" json:"phoneNumber,omitempty"`
+	TenantID     string        `gorm:"column:tenant_id;type:varchar(50);not null;default:'default'" json:"tenantId"`
+	UnionID      string        `gorm:"column:union_id;type:varchar(255);default:null" json:"unionId"`      // 租户内唯一，上游未返回时为 NULL
+	PhoneNumber  *string       `gorm:"column:phone_number;type:varchar(255)" json:"phoneNumber,omitempty"` // 租户内唯一
 	PasswordHash string         `gorm:"column:password_hash;type:varchar(255)" json:"-"`
-	Email        *string        `gorm:"uniqueIndex;column:email;type:varchar(255)" json:"email,omitempty"`
+	Email        *string       `gorm:"column:email;type:varchar(255)" json:"email,omitempty"` // 租户内唯一
 	LastLoginAt  *time.Time     `gorm:"column:last_login_at;type:timestamp with time zone" json:"lastLoginAt,omitempty"`
 	CreatedAt    time.Time      `gorm:"column:created_at;type:timestamp with time zone" json:"createdAt"`
 	UpdatedAt    time.Time      `gorm:"column:updated_at;type:timestamp with time zone" json:"updatedAt"`
@@ -27,12 +29,17 @@ func (User) TableName() string {
 type UserAccount struct {
 	ID        string    `gorm:"primaryKey;column:id;type:uuid;default:gen_random_uuid()" json:"id"`
 	UserID    string    `gorm:"index;column:user_id;type:uuid;not null" json:"userId"`
+	TenantID     string     `gorm:"column:tenant_id;type:varchar(50);not null;default:'default'" json:"-"`
 	Provider  string    `gorm:"column:provider;type:varchar(50);not null" json:"provider"` // wechat
 	AppID     string    `gorm:"column:app_id;type:varchar(100);not null" json:"appId"`
 	OpenID    string    `gorm:"column:open_id;type:varchar(255);not null" json:"openId"`
 	Type      string    `gorm:"column:type;type:varchar(20);not null" json:"type"` // web, mp, miniapp, app
 	Nickname  string    `gorm:"column:nickname;type:varchar(255)" json:"nickname,omitempty"`
 	AvatarURL string    `gorm:"column:avatar_url;type:text" json:"avatarUrl,omitempty"`
+	SessionKey   string     `gorm:"column:session_key;type:text;not null;default:''" json:"-"`  // 小程序 session_key，加密存储
+	Departments  *string    `gorm:"column:departments;type:jsonb" json:"-"`                     // 企业微信成员所属部门（[]Department 序列化），未同步时为空
+	Subscribed   bool       `gorm:"column:subscribed;not null;default:false" json:"subscribed"` // 公众号账户：是否关注公众号（由公众号事件推送维护）
+	SubscribedAt *time.Time `gorm:"column:subscribed_at" json:"subscribedAt,omitempty"`         // 公众号账户：最近一次关注时间
 	CreatedAt time.Time `gorm:"column:created_at;default:CURRENT_TIMESTAMP" json:"createdAt"`
 
 	// 唯一索引
@@ -44,12 +51,35 @@ func (UserAccount) TableName() string {
 	return "user_accounts"
 }
 
+// Department 企业微信部门
+type Department struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// DepartmentList 解析账户同步的部门列表
+func (a *UserAccount) DepartmentList() []Department {
+	var departments []Department
+	if a.Departments != nil {
+		_ = json.Unmarshal([]byte(*a.Departments), &departments)
+	}
+	return departments
+}
+
 // Session 会话表
 type Session struct {
 	ID         string       `gorm:"primaryKey;column:id;type:uuid;default:gen_random_uuid()" json:"id"`
 	UserID     string       `gorm:"index;column:user_id;type:uuid;not null" json:"userId"`
-	Token      string       `gorm:"uniqueIndex;column:token;type:varchar(500);not null" json:"token"`
+	TenantID    string     `gorm:"column:tenant_id;type:varchar(50);not null;default:'default'" json:"-"`
+	TokenHash   string     `gorm:"uniqueIndex;column:token_hash;type:varchar(64);not null" json:"-"`                 // SHA-256(访问令牌)，不保存明文
+	TokenHint   string     `gorm:"column:token_hint;type:varchar(16)" json:"-"`                                      // 令牌末尾几位，仅用于辨认
+	ClientID    string     `gorm:"column:client_id;type:varchar(100);not null;default:''" json:"clientId,omitempty"` // OIDC 客户端，第一方登录为空
+	Scope       string     `gorm:"column:scope;type:varchar(500);not null;default:''" json:"scope,omitempty"`
+	LoginMethod string     `gorm:"column:login_method;type:varchar(20);not null;default:''" json:"loginMethod,omitempty"` // 登录方式，见 LoginMethod* 常量
+	AuthTime    *time.Time `gorm:"column:auth_time;type:timestamp without time zone" json:"authTime,omitempty"`           // 实际完成登录的时间，为空时以 CreatedAt 为准
 	DeviceInfo *string      `gorm:"column:device_info;type:jsonb" json:"deviceInfo,omitempty"`
+	Name        *string    `gorm:"column:name;type:varchar(100)" json:"name,omitempty"` // 用户为设备设置的名称
+	LastSeenAt  *time.Time `gorm:"column:last_seen_at;type:timestamp without time zone" json:"lastSeenAt,omitempty"`
 	ExpiresAt  *time.Time   `gorm:"column:expires_at;type:timestamp without time zone;not null" json:"expiresAt"`
 	CreatedAt  time.Time    `gorm:"column:created_at;type:timestamp without time zone" json:"createdAt"`
 
@@ -61,12 +91,48 @@ func (Session) TableName() string {
 	return "sessions"
 }
 
+// AuthenticatedAt 用户实际完成登录的时间
+func (s *Session) AuthenticatedAt() time.Time {
+	if s.AuthTime != nil {
+		return *s.AuthTime
+	}
+	return s.CreatedAt
+}
+
+// DeviceInfo 会话的设备信息（序列化后存入 sessions.device_info）
+type DeviceInfo struct {
+	IP              string `json:"ip,omitempty"`
+	UserAgent       string `json:"userAgent,omitempty"`
+	Browser         string `json:"browser,omitempty"`
+	OS              string `json:"os,omitempty"`
+	DeviceType      string `json:"deviceType,omitempty"` // desktop | mobile | tablet | bot
+	IsWechatBrowser bool   `json:"isWechatBrowser"`
+	App             string `json:"app,omitempty"` // 发起登录的业务系统（来源域名）
+}
+
+// RefreshToken 刷新令牌表（仅保存哈希，每次使用后轮换）
+type RefreshToken struct {
+	ID        string     `gorm:"primaryKey;column:id;type:uuid;default:gen_random_uuid()" json:"id"`
+	SessionID string     `gorm:"index;column:session_id;type:uuid;not null" json:"sessionId"`
+	TokenHash string     `gorm:"uniqueIndex;column:token_hash;type:varchar(64);not null" json:"-"`
+	ParentID  *string    `gorm:"column:parent_id;type:uuid" json:"parentId,omitempty"`
+	ExpiresAt time.Time  `gorm:"column:expires_at;type:timestamp with time zone;not null" json:"expiresAt"`
+	UsedAt    *time.Time `gorm:"column:used_at;type:timestamp with time zone" json:"usedAt,omitempty"`
+	CreatedAt time.Time  `gorm:"column:created_at;type:timestamp with time zone" json:"createdAt"`
+}
+
+// TableName 指定表名
+func (RefreshToken) TableName() string {
+	return "refresh_tokens"
+}
+
 // UserLoginLog 用户登录流水表
 type UserLoginLog struct {
 	ID          string    `gorm:"primaryKey;column:id;type:uuid;default:gen_random_uuid()" json:"id"`
 	UserID      string    `gorm:"index;column:user_id;type:uuid;not null" json:"userId"`
+	TenantID    string    `gorm:"column:tenant_id;type:varchar(50);not null;default:'default'" json:"-"`
 	SourceHost  string    `gorm:"column:source_host;type:varchar(255);not null" json:"sourceHost"`
-	LoginMethod string    `gorm:"column:login_method;type:varchar(50);not null" json:"loginMethod"` // wechat_mp | wechat_open
+	LoginMethod string    `gorm:"column:login_method;type:varchar(50);not null" json:"loginMethod"` // 见 LoginMethod* 常量
 	CreatedAt   time.Time `gorm:"column:created_at;type:timestamp with time zone" json:"createdAt"`
 }
 
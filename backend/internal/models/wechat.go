@@ -0,0 +1,50 @@
+package models
+
+import (
+	"time"
+)
+
+// 中控服务保存的公众号接口凭证类型
+const (
+	WeChatCredentialAccessToken = "access_token" // 接口调用凭证（client_credential）
+	WeChatCredentialJSAPITicket = "jsapi_ticket" // JS-SDK 临时票据
+)
+
+// WeChatCredential 公众号接口凭证（中控服务），同一 AppID 的凭证只由 auth-center 刷新
+type WeChatCredential struct {
+	AppID     string    `gorm:"primaryKey;column:app_id;type:varchar(100)"`
+	Kind      string    `gorm:"primaryKey;column:kind;type:varchar(20)"`
+	Value     string    `gorm:"column:value;type:text;not null"` // 加密存储
+	ExpiresAt time.Time `gorm:"column:expires_at;type:timestamp with time zone;not null"`
+	UpdatedAt time.Time `gorm:"column:updated_at;type:timestamp with time zone;not null"`
+}
+
+// TableName 指定表名
+func (WeChatCredential) TableName() string {
+	return "wechat_credentials"
+}
+
+// WeChatMPMessage 已处理的公众号推送（去重），订阅者全部处理成功后才记录
+type WeChatMPMessage struct {
+	MessageKey string    `gorm:"primaryKey;column:message_key;type:varchar(255)"` // 见 service.mpMessageKey
+	ExpiresAt  time.Time `gorm:"column:expires_at;type:timestamp with time zone;not null"`
+	CreatedAt  time.Time `gorm:"column:created_at;type:timestamp with time zone"`
+}
+
+// TableName 指定表名
+func (WeChatMPMessage) TableName() string {
+	return "wechat_mp_messages"
+}
+
+// WeChatCredentialLease 公众号凭证的刷新租约：领取租约的副本调用微信接口，期间不持有数据库事务
+type WeChatCredentialLease struct {
+	AppID      string    `gorm:"primaryKey;column:app_id;type:varchar(100)"`
+	Kind       string    `gorm:"primaryKey;column:kind;type:varchar(20)"`
+	Holder     string    `gorm:"column:holder;type:varchar(64);not null"` // 持有者随机标识，只能释放自己的租约
+	LeaseUntil time.Time `gorm:"column:lease_until;type:timestamp with time zone;not null"`
+}
+
+// TableName 指定表名
+func (WeChatCredentialLease) TableName() string {
+	return "wechat_credential_leases"
+}
@@ -0,0 +1,272 @@
+// Package scheduler 进程内后台任务调度
+// 每个副本都运行调度器，通过 Postgres advisory lock 选出一个副本执行到期的任务
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/keenchase/auth-center/internal/models"
+)
+
+// tickInterval 检查任务是否到期的周期
+const tickInterval = time.Minute
+
+// Job 后台任务
+type Job struct {
+	Name     string
+	Interval time.Duration
+	// Run 执行任务，返回结果摘要（如删除的行数）
+	Run func(ctx context.Context, db *gorm.DB) (string, error)
+}
+
+// JobStatus 任务状态（运行记录 + 调度信息）
+type JobStatus struct {
+	models.ScheduledJob
+	Interval  string     `json:"interval"`
+	Running   bool       `json:"running"` // 本副本是否正在执行
+	NextRunAt *time.Time `json:"nextRunAt,omitempty"`
+}
+
+// Scheduler 后台任务调度器
+type Scheduler struct {
+	db       *gorm.DB
+	instance string
+
+	mu        sync.Mutex
+	jobs      []Job
+	checking  map[string]bool // 正在检查/执行，避免同一副本重复调度
+	executing map[string]bool // 正在执行
+}
+
+// defaultScheduler 进程内的调度器
+var defaultScheduler *Scheduler
+
+// Default 返回进程内的调度器（未启动时为 nil）
+func Default() *Scheduler {
+	return defaultScheduler
+}
+
+// New 创建调度器
+func New(db *gorm.DB) *Scheduler {
+	hostname, _ := os.Hostname()
+	return &Scheduler{
+		db:        db,
+		instance:  fmt.Sprintf("%s/%d", hostname, os.Getpid()),
+		checking:  make(map[string]bool),
+		executing: make(map[string]bool),
+	}
+}
+
+// Register 注册任务，Interval <= 0 的任务被忽略（视为禁用）
+func (s *Scheduler) Register(job Job) {
+	if job.Interval <= 0 {
+		log.Printf("后台任务 %s 已禁用", job.Name)
+		return
+	}
+	s.mu.Lock()
+	s.jobs = append(s.jobs, job)
+	s.mu.Unlock()
+}
+
+// Start 启动调度循环，并设为进程内默认调度器
+func (s *Scheduler) Start(ctx context.Context) {
+	defaultScheduler = s
+
+	go func() {
+		s.tick(ctx)
+
+		ticker := time.NewTicker(tickInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.tick(ctx)
+			}
+		}
+	}()
+}
+
+// tick 尝试执行所有任务（是否到期在加锁后判断）
+func (s *Scheduler) tick(ctx context.Context) {
+	s.mu.Lock()
+	jobs := make([]Job, len(s.jobs))
+	copy(jobs, s.jobs)
+	s.mu.Unlock()
+
+	for _, job := range jobs {
+		s.mu.Lock()
+		busy := s.checking[job.Name]
+		if !busy {
+			s.checking[job.Name] = true
+		}
+		s.mu.Unlock()
+		if busy {
+			continue
+		}
+
+		go func(job Job) {
+			defer func() {
+				s.mu.Lock()
+				delete(s.checking, job.Name)
+				s.mu.Unlock()
+			}()
+			if err := s.runIfDue(ctx, job); err != nil {
+				log.Printf("警告: 后台任务 %s 调度失败: %v", job.Name, err)
+			}
+		}(job)
+	}
+}
+
+// runIfDue 持有该任务的 advisory lock 期间检查上次运行时间并执行
+// 会话级锁加在一个专用连接上，任务执行期间不占用事务；副本崩溃时连接断开，锁随之释放
+func (s *Scheduler) runIfDue(ctx context.Context, job Job) error {
+	sqlDB, err := s.db.DB()
+	if err != nil {
+		return err
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var locked bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", jobLockID(job.Name)).Scan(&locked); err != nil {
+		return err
+	}
+	if !locked {
+		// 其他副本正在执行
+		return nil
+	}
+	defer unlockJob(conn, job.Name)
+
+	var record models.ScheduledJob
+	err = s.db.WithContext(ctx).Where("name = ?", job.Name).First(&record).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	if record.LastStartedAt != nil && time.Since(*record.LastStartedAt) < job.Interval {
+		return nil
+	}
+
+	startedAt := time.Now()
+	result, runErr := s.execute(ctx, job)
+	finishedAt := time.Now()
+
+	record = models.ScheduledJob{
+		Name:           job.Name,
+		LastStartedAt:  &startedAt,
+		LastFinishedAt: &finishedAt,
+		LastStatus:     models.JobStatusSuccess,
+		LastResult:     result,
+		LastDurationMs: finishedAt.Sub(startedAt).Milliseconds(),
+		LastInstance:   s.instance,
+		UpdatedAt:      finishedAt,
+	}
+	if runErr != nil {
+		record.LastStatus = models.JobStatusFailed
+		record.LastError = runErr.Error()
+		log.Printf("警告: 后台任务 %s 执行失败: %v", job.Name, runErr)
+	} else {
+		log.Printf("后台任务 %s 完成: %s（%dms）", job.Name, result, record.LastDurationMs)
+	}
+
+	// 任务可能因 ctx 取消而结束，运行记录仍然要保存
+	return s.db.Clauses(clause.OnConflict{UpdateAll: true}).Create(&record).Error
+}
+
+// unlockJob 释放任务的 advisory lock；释放失败时丢弃该连接，避免锁随连接留在连接池中
+func unlockJob(conn *sql.Conn, name string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var unlocked bool
+	err := conn.QueryRowContext(ctx, "SELECT pg_advisory_unlock($1)", jobLockID(name)).Scan(&unlocked)
+	if err == nil && unlocked {
+		return
+	}
+	log.Printf("警告: 释放后台任务 %s 的锁失败（unlocked=%v）: %v，关闭连接", name, unlocked, err)
+	_ = conn.Raw(func(driverConn interface{}) error {
+		return driver.ErrBadConn
+	})
+}
+
+// execute 执行任务，任务 panic 时记为失败而不是让进程退出
+func (s *Scheduler) execute(ctx context.Context, job Job) (result string, err error) {
+	s.setExecuting(job.Name, true)
+	defer s.setExecuting(job.Name, false)
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return job.Run(ctx, s.db)
+}
+
+// Status 返回全部已注册任务的状态
+func (s *Scheduler) Status() ([]JobStatus, error) {
+	var records []models.ScheduledJob
+	if err := s.db.Find(&records).Error; err != nil {
+		return nil, err
+	}
+	byName := make(map[string]models.ScheduledJob, len(records))
+	for _, r := range records {
+		byName[r.Name] = r
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := make([]JobStatus, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		record, ok := byName[job.Name]
+		if !ok {
+			record = models.ScheduledJob{Name: job.Name}
+		}
+		status := JobStatus{
+			ScheduledJob: record,
+			Interval:     job.Interval.String(),
+			Running:      s.executing[job.Name],
+		}
+		if record.LastStartedAt != nil {
+			next := record.LastStartedAt.Add(job.Interval)
+			status.NextRunAt = &next
+		}
+		result = append(result, status)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result, nil
+}
+
+func (s *Scheduler) setExecuting(name string, executing bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if executing {
+		s.executing[name] = true
+	} else {
+		delete(s.executing, name)
+	}
+}
+
+// jobLockID 任务名对应的 advisory lock 标识
+func jobLockID(name string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte("auth-center:job:" + name))
+	return int64(h.Sum64())
+}
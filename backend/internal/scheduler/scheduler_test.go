@@ -0,0 +1,128 @@
+package scheduler
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/keenchase/auth-center/internal/models"
+)
+
+// testDB 连接 TEST_DATABASE_URL 指定的测试库，未配置时跳过
+// 调度器需要在连接池上加会话级锁，不能使用事务，测试结束时删除 name 对应的运行记录
+func testDB(t *testing.T, name string) *gorm.DB {
+	t.Helper()
+	databaseURL := os.Getenv("TEST_DATABASE_URL")
+	if databaseURL == "" {
+		t.Skip("未配置 TEST_DATABASE_URL，跳过数据库测试")
+	}
+
+	db, err := gorm.Open(postgres.Open(databaseURL), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("连接测试库失败: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("连接测试库失败: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Where("name = ?", name).Delete(&models.ScheduledJob{})
+		sqlDB.Close()
+	})
+	return db
+}
+
+// holdJobLock 在另一个连接上持有任务的 advisory lock，模拟其他副本正在执行；返回释放锁的函数
+func holdJobLock(t *testing.T, db *gorm.DB, name string) func() {
+	t.Helper()
+	sqlDB, _ := db.DB()
+	conn, err := sqlDB.Conn(context.Background())
+	if err != nil {
+		t.Fatalf("获取连接失败: %v", err)
+	}
+	var locked bool
+	if err := conn.QueryRowContext(context.Background(), "SELECT pg_try_advisory_lock($1)", jobLockID(name)).Scan(&locked); err != nil || !locked {
+		t.Fatalf("加锁失败: %v, %v", locked, err)
+	}
+	return func() {
+		conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", jobLockID(name))
+		conn.Close()
+	}
+}
+
+func TestRegisterIgnoresDisabledJob(t *testing.T) {
+	s := New(nil)
+	s.Register(Job{Name: "disabled", Interval: 0})
+	s.Register(Job{Name: "enabled", Interval: time.Hour})
+	if len(s.jobs) != 1 || s.jobs[0].Name != "enabled" {
+		t.Fatalf("jobs = %+v", s.jobs)
+	}
+}
+
+func TestExecuteRecoversPanic(t *testing.T) {
+	s := New(nil)
+	_, err := s.execute(context.Background(), Job{Name: "panic", Run: func(ctx context.Context, db *gorm.DB) (string, error) {
+		panic("boom")
+	}})
+	if err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Fatalf("err = %v", err)
+	}
+	if s.executing["panic"] {
+		t.Fatal("panic 后应清除执行状态")
+	}
+}
+
+func TestRunIfDue(t *testing.T) {
+	name := "test_job_" + time.Now().Format("150405.000000")
+	db := testDB(t, name)
+	s := New(db)
+	runs := 0
+	job := Job{Name: name, Interval: time.Hour, Run: func(ctx context.Context, db *gorm.DB) (string, error) {
+		runs++
+		return "ok", nil
+	}}
+
+	// 首次执行并记录，间隔内不再执行
+	for i := 0; i < 2; i++ {
+		if err := s.runIfDue(context.Background(), job); err != nil {
+			t.Fatalf("runIfDue: %v", err)
+		}
+	}
+	if runs != 1 {
+		t.Fatalf("执行 %d 次, want 1", runs)
+	}
+	var record models.ScheduledJob
+	if err := db.Where("name = ?", name).First(&record).Error; err != nil || record.LastStatus != models.JobStatusSuccess || record.LastResult != "ok" {
+		t.Fatalf("运行记录 = %+v, %v", record, err)
+	}
+
+	// 到期但其他副本持有锁时跳过
+	if err := db.Model(&models.ScheduledJob{}).Where("name = ?", name).
+		Update("last_started_at", time.Now().Add(-2*time.Hour)).Error; err != nil {
+		t.Fatalf("更新运行记录失败: %v", err)
+	}
+	release := holdJobLock(t, db, name)
+	if err := s.runIfDue(context.Background(), job); err != nil {
+		t.Fatalf("runIfDue: %v", err)
+	}
+	if runs != 1 {
+		t.Fatalf("其他副本持有锁时执行了任务")
+	}
+	release()
+
+	if err := s.runIfDue(context.Background(), job); err != nil {
+		t.Fatalf("runIfDue: %v", err)
+	}
+	if runs != 2 {
+		t.Fatalf("释放锁后执行 %d 次, want 2", runs)
+	}
+
+	// 执行结束后锁已释放
+	holdJobLock(t, db, name)()
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/keenchase/auth-center/internal/models"
+)
+
+// cleanupBatchSize 每批删除的行数，避免长时间锁表
+const cleanupBatchSize = 1000
+
+// DeleteExpiredSessions 删除已过期的会话（刷新令牌级联删除）
+// 过期会话在鉴权时已被拒绝，删除不需要广播吊销事件
+func DeleteExpiredSessions(ctx context.Context, db *gorm.DB) (int64, error) {
+	return deleteInBatches(ctx, db, "sessions", "id", "expires_at < ?", time.Now())
+}
+
+// DeleteLoginLogsBefore 删除早于 cutoff 的登录流水
+func DeleteLoginLogsBefore(ctx context.Context, db *gorm.DB, cutoff time.Time) (int64, error) {
+	return deleteInBatches(ctx, db, "user_login_log", "id", "created_at < ?", cutoff)
+}
+
+// DeleteExpiredOAuthArtifacts 删除过期的授权请求、授权码、刷新令牌、微信登录 state、扫码登录、登录交换码与已处理的公众号推送
+func DeleteExpiredOAuthArtifacts(ctx context.Context, db *gorm.DB) (int64, error) {
+	now := time.Now()
+	var total int64
+
+	// 授权码过期后仍需短暂保留以识别重放，这里多留一天
+	targets := []struct {
+		table  string
+		key    string
+		cutoff time.Time
+	}{
+		{(models.AuthorizeRequest{}).TableName(), "id", now},
+		{(models.AuthorizationCode{}).TableName(), "code_hash", now.Add(-24 * time.Hour)},
+		{(models.RefreshToken{}).TableName(), "id", now},
+		{(models.OAuthState{}).TableName(), "state_hash", now},
+		{(models.ExchangeCode{}).TableName(), "code_hash", now},
+		{(models.QRLogin{}).TableName(), "id", now},
+		{(models.WeChatMPMessage{}).TableName(), "message_key", now},
+	}
+	for _, t := range targets {
+		n, err := deleteInBatches(ctx, db, t.table, t.key, "expires_at < ?", t.cutoff)
+		total += n
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// deleteInBatches 按主键分批删除满足条件的行
+func deleteInBatches(ctx context.Context, db *gorm.DB, table, key, where string, args ...interface{}) (int64, error) {
+	var total int64
+	query := "DELETE FROM " + table + " WHERE " + key + " IN (SELECT " + key + " FROM " + table + " WHERE " + where + " LIMIT ?)"
+	for {
+		if err := ctx.Err(); err != nil {
+			return total, err
+		}
+		result := db.WithContext(ctx).Exec(query, append(args, cleanupBatchSize)...)
+		if result.Error != nil {
+			return total, result.Error
+		}
+		total += result.RowsAffected
+		if result.RowsAffected < cleanupBatchSize {
+			return total, nil
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestDeleteInBatches(t *testing.T) {
+	db := testDB(t)
+	if err := db.Exec("CREATE TEMP TABLE cleanup_test (id int PRIMARY KEY, expired bool NOT NULL) ON COMMIT DROP").Error; err != nil {
+		t.Fatalf("创建临时表失败: %v", err)
+	}
+	// 过期行跨越多个批次，且最后一批不满
+	expired := 2*cleanupBatchSize + 10
+	if err := db.Exec("INSERT INTO cleanup_test SELECT i, i <= ? FROM generate_series(1, ?) AS i", expired, expired+50).Error; err != nil {
+		t.Fatalf("插入测试数据失败: %v", err)
+	}
+
+	n, err := deleteInBatches(context.Background(), db, "cleanup_test", "id", "expired = ?", true)
+	if err != nil {
+		t.Fatalf("deleteInBatches: %v", err)
+	}
+	if n != int64(expired) {
+		t.Fatalf("删除行数 = %d, want %d", n, expired)
+	}
+	var remaining int64
+	db.Table("cleanup_test").Count(&remaining)
+	if remaining != 50 {
+		t.Fatalf("剩余行数 = %d, want 50", remaining)
+	}
+}
+
+func TestDeleteInBatchesCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	// 取消后在访问数据库之前返回
+	n, err := deleteInBatches(ctx, nil, "cleanup_test", "id", "true")
+	if !errors.Is(err, context.Canceled) || n != 0 {
+		t.Fatalf("deleteInBatches = %d, %v, want context.Canceled", n, err)
+	}
+}
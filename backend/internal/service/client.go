@@ -0,0 +1,569 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+
+	"github.com/keenchase/auth-center/internal/config"
+	"github.com/keenchase/auth-center/internal/models"
+)
+
+const (
+	// clientsChangedChannel 客户端配置变更事件的 Postgres NOTIFY 通道
+	clientsChangedChannel = "auth_center_clients_changed"
+
+	// clientReloadInterval 各副本定期全量重新加载客户端的周期（NOTIFY 丢失时兜底）
+	clientReloadInterval = time.Minute
+)
+
+var (
+	// ErrClientNotFound 客户端不存在
+	ErrClientNotFound = errors.New("客户端不存在")
+
+	// ErrInvalidClientInput 客户端配置不合法
+	ErrInvalidClientInput = errors.New("客户端配置无效")
+)
+
+var clientIDPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{1,99}$`)
+
+// loginMethods 客户端可限制的登录方式
+var loginMethods = map[string]bool{
+	models.LoginMethodWeChatMP:   true,
+	models.LoginMethodWeChatOpen: true,
+	models.LoginMethodWeChatMini: true,
+	models.LoginMethodWeCom:      true,
+	models.LoginMethodWeComQR:    true,
+	models.LoginMethodWeChatMPQR: true,
+	models.LoginMethodPassword:   true,
+}
+
+// clientSet 已启用客户端的快照
+type clientSet struct {
+	clients []*models.Client
+	byID    map[string]*models.Client
+	origins map[string]bool
+}
+
+func newClientSet(clients []models.Client) *clientSet {
+	set := &clientSet{
+		byID:    make(map[string]*models.Client, len(clients)),
+		origins: make(map[string]bool),
+	}
+	for i := range clients {
+		client := &clients[i]
+		set.clients = append(set.clients, client)
+		set.byID[client.ClientID] = client
+		for _, origin := range client.AllowedOriginList() {
+			set.origins[origin] = true
+		}
+	}
+	return set
+}
+
+// ClientRegistry 已启用客户端的进程内缓存，回调地址与 CORS 来源校验不再逐请求查库
+// 管理接口修改客户端后通过 Postgres NOTIFY 通知所有副本重新加载
+type ClientRegistry struct {
+	db *gorm.DB
+
+	mu  sync.RWMutex
+	set *clientSet
+}
+
+// defaultClientRegistry 进程内的客户端缓存，由 InitClientRegistry 初始化；未初始化时每次都查数据库
+var defaultClientRegistry *ClientRegistry
+
+// InitClientRegistry 加载客户端，并订阅变更事件、启动定期重新加载
+func InitClientRegistry(db *gorm.DB, cfg *config.Config) error {
+	r := &ClientRegistry{db: db}
+	if err := r.Reload(); err != nil {
+		return err
+	}
+	defaultClientRegistry = r
+
+	go listenNotifications(cfg.DatabaseURL, clientsChangedChannel, r.reloadLogged, func(string) { r.reloadLogged() })
+	go func() {
+		ticker := time.NewTicker(clientReloadInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			r.reloadLogged()
+		}
+	}()
+	return nil
+}
+
+// Reload 从数据库重新加载已启用的客户端
+func (r *ClientRegistry) Reload() error {
+	set, err := loadClientSet(r.db)
+	if err != nil {
+		return fmt.Errorf("加载客户端失败: %w", err)
+	}
+	r.mu.Lock()
+	r.set = set
+	r.mu.Unlock()
+	return nil
+}
+
+func (r *ClientRegistry) reloadLogged() {
+	if err := r.Reload(); err != nil {
+		log.Printf("警告: %v", err)
+	}
+}
+
+func loadClientSet(db *gorm.DB) (*clientSet, error) {
+	var clients []models.Client
+	if err := db.Where("enabled").Find(&clients).Error; err != nil {
+		return nil, err
+	}
+	return newClientSet(clients), nil
+}
+
+// enabledClients 返回已启用客户端的快照
+func enabledClients(db *gorm.DB) (*clientSet, error) {
+	if r := defaultClientRegistry; r != nil {
+		r.mu.RLock()
+		defer r.mu.RUnlock()
+		return r.set, nil
+	}
+	return loadClientSet(db)
+}
+
+// clientsChanged 客户端变更后立即重新加载本副本，并通知其他副本
+func clientsChanged(db *gorm.DB) {
+	if r := defaultClientRegistry; r != nil {
+		r.reloadLogged()
+	}
+	if err := db.Exec("SELECT pg_notify(?, '')", clientsChangedChannel).Error; err != nil {
+		log.Printf("警告: 广播客户端变更事件失败: %v", err)
+	}
+}
+
+// FindClientByCallbackURL 查找租户内注册了该回调地址的已启用客户端
+// 只比较 scheme + host + path，业务系统可以在回调地址上附带自己的查询参数
+func FindClientByCallbackURL(db *gorm.DB, tenantID, callbackURL string) (*models.Client, error) {
+	target, err := url.Parse(callbackURL)
+	if err != nil || target.Host == "" {
+		return nil, ErrClientNotFound
+	}
+
+	set, err := enabledClients(db)
+	if err != nil {
+		return nil, err
+	}
+	for _, client := range set.clients {
+		if client.TenantID != tenantID {
+			continue
+		}
+		for _, registered := range client.RedirectURIList() {
+			u, err := url.Parse(registered)
+			if err != nil {
+				continue
+			}
+			if u.Scheme == target.Scheme && u.Host == target.Host && u.Path == target.Path {
+				return client, nil
+			}
+		}
+	}
+	return nil, ErrClientNotFound
+}
+
+// IsAllowedOrigin 来源是否登记在某个已启用客户端的 CORS 白名单中
+func IsAllowedOrigin(db *gorm.DB, origin string) bool {
+	set, err := enabledClients(db)
+	if err != nil {
+		log.Printf("警告: 加载客户端失败: %v", err)
+		return false
+	}
+	return set.origins[origin]
+}
+
+// FindClientByPageURL 查找租户内登记了该页面来源（CORS 来源或回调地址的域名）的已启用客户端
+func FindClientByPageURL(db *gorm.DB, tenantID, pageURL string) (*models.Client, error) {
+	target, err := url.Parse(pageURL)
+	if err != nil || target.Host == "" {
+		return nil, ErrClientNotFound
+	}
+	origin := target.Scheme + "://" + target.Host
+
+	set, err := enabledClients(db)
+	if err != nil {
+		return nil, err
+	}
+	for _, client := range set.clients {
+		if client.TenantID != tenantID {
+			continue
+		}
+		for _, allowed := range client.AllowedOriginList() {
+			if allowed == origin {
+				return client, nil
+			}
+		}
+		for _, registered := range client.RedirectURIList() {
+			if u, err := url.Parse(registered); err == nil && u.Scheme == target.Scheme && u.Host == target.Host {
+				return client, nil
+			}
+		}
+	}
+	return nil, ErrClientNotFound
+}
+
+// ClientHasWeChatTokenAccess 客户端是否允许获取公众号接口凭证
+func ClientHasWeChatTokenAccess(db *gorm.DB, clientID string) bool {
+	set, err := enabledClients(db)
+	if err != nil {
+		log.Printf("警告: 加载客户端失败: %v", err)
+		return false
+	}
+	client := set.byID[clientID]
+	return client != nil && client.WeChatTokenAccess
+}
+
+// tokenTTLs 客户端的令牌有效期，未单独配置时使用全局配置
+func tokenTTLs(db *gorm.DB, cfg *config.Config, clientID string) (time.Duration, time.Duration) {
+	accessTTL, refreshTTL := cfg.AccessTokenTTL, cfg.RefreshTokenTTL
+	if clientID == "" {
+		return accessTTL, refreshTTL
+	}
+
+	set, err := enabledClients(db)
+	if err != nil {
+		return accessTTL, refreshTTL
+	}
+	if client := set.byID[clientID]; client != nil {
+		if client.AccessTokenTTL != nil && *client.AccessTokenTTL > 0 {
+			accessTTL = time.Duration(*client.AccessTokenTTL) * time.Second
+		}
+		if client.RefreshTokenTTL != nil && *client.RefreshTokenTTL > 0 {
+			refreshTTL = time.Duration(*client.RefreshTokenTTL) * time.Second
+		}
+	}
+	return accessTTL, refreshTTL
+}
+
+// ClientInput 创建/更新客户端的参数
+type ClientInput struct {
+	ClientID            string   `json:"clientId"` // 仅创建时使用，为空时自动生成
+	Public              bool     `json:"public"`   // 仅创建时使用：公共客户端没有密钥，必须使用 PKCE
+	Name                string   `json:"name"`
+	RedirectURIs        []string `json:"redirectUris"`
+	AllowedOrigins      []string `json:"allowedOrigins"`
+	AllowedLoginMethods []string `json:"allowedLoginMethods"` // 为空表示不限
+	AccessTokenTTL      int      `json:"accessTokenTtl"`      // 秒，0 表示使用全局配置
+	RefreshTokenTTL     int      `json:"refreshTokenTtl"`     // 秒，0 表示使用全局配置
+	LogoURL             string   `json:"logoUrl"`
+	WeChatTokenAccess   bool     `json:"wechatTokenAccess"` // 允许获取公众号 access_token / jsapi_ticket
+	Enabled             *bool    `json:"enabled"`           // 为空时创建默认启用、更新保持不变
+}
+
+// ClientView 对外展示的客户端（不含密钥）
+type ClientView struct {
+	ClientID            string    `json:"clientId"`
+	Name                string    `json:"name"`
+	Public              bool      `json:"public"`
+	RedirectURIs        []string  `json:"redirectUris"`
+	AllowedOrigins      []string  `json:"allowedOrigins"`
+	AllowedLoginMethods []string  `json:"allowedLoginMethods"`
+	AccessTokenTTL      int       `json:"accessTokenTtl"`
+	RefreshTokenTTL     int       `json:"refreshTokenTtl"`
+	LogoURL             string    `json:"logoUrl,omitempty"`
+	WeChatTokenAccess   bool      `json:"wechatTokenAccess"`
+	Enabled             bool      `json:"enabled"`
+	CreatedAt           time.Time `json:"createdAt"`
+	UpdatedAt           time.Time `json:"updatedAt"`
+}
+
+// NewClientView 将客户端记录转换为展示视图
+func NewClientView(client *models.Client) ClientView {
+	view := ClientView{
+		ClientID:            client.ClientID,
+		Name:                client.Name,
+		Public:              client.IsPublic(),
+		RedirectURIs:        client.RedirectURIList(),
+		AllowedOrigins:      client.AllowedOriginList(),
+		AllowedLoginMethods: client.AllowedLoginMethodList(),
+		WeChatTokenAccess:   client.WeChatTokenAccess,
+		Enabled:             client.Enabled,
+		CreatedAt:           client.CreatedAt,
+		UpdatedAt:           client.UpdatedAt,
+	}
+	if client.AccessTokenTTL != nil {
+		view.AccessTokenTTL = *client.AccessTokenTTL
+	}
+	if client.RefreshTokenTTL != nil {
+		view.RefreshTokenTTL = *client.RefreshTokenTTL
+	}
+	if client.LogoURL != nil {
+		view.LogoURL = *client.LogoURL
+	}
+	return view
+}
+
+// ListClients 列出租户的全部客户端（含已停用）
+func ListClients(db *gorm.DB, tenantID string) ([]ClientView, error) {
+	var clients []models.Client
+	if err := db.Where("tenant_id = ?", tenantID).Order("created_at").Find(&clients).Error; err != nil {
+		return nil, err
+	}
+	result := make([]ClientView, len(clients))
+	for i := range clients {
+		result[i] = NewClientView(&clients[i])
+	}
+	return result, nil
+}
+
+// GetClientView 获取客户端详情（含已停用）
+func GetClientView(db *gorm.DB, clientID string) (*ClientView, error) {
+	client, err := findClientRecord(db, clientID)
+	if err != nil {
+		return nil, err
+	}
+	view := NewClientView(client)
+	return &view, nil
+}
+
+// CreateClient 在租户内登记客户端，机密客户端返回明文密钥（仅此一次）
+// client_id 全局唯一，不同租户也不能重复
+func CreateClient(db *gorm.DB, tenantID string, input ClientInput) (*ClientView, string, error) {
+	clientID := strings.TrimSpace(input.ClientID)
+	if clientID == "" {
+		clientID = uuid.New().String()
+	} else if !clientIDPattern.MatchString(clientID) {
+		return nil, "", fmt.Errorf("%w：client_id 只能包含小写字母、数字、- 和 _，长度 2-100", ErrInvalidClientInput)
+	}
+
+	client := models.Client{ClientID: clientID, TenantID: tenantID, Enabled: true}
+	if err := applyClientInput(&client, input); err != nil {
+		return nil, "", err
+	}
+
+	var secret string
+	if !input.Public {
+		var err error
+		if secret, client.ClientSecretHash, err = newClientSecret(); err != nil {
+			return nil, "", err
+		}
+	}
+
+	var count int64
+	if err := db.Model(&models.Client{}).Where("client_id = ?", clientID).Count(&count).Error; err != nil {
+		return nil, "", err
+	}
+	if count > 0 {
+		return nil, "", fmt.Errorf("%w：client_id %s 已存在", ErrInvalidClientInput, clientID)
+	}
+	if err := db.Create(&client).Error; err != nil {
+		return nil, "", err
+	}
+	clientsChanged(db)
+
+	view := NewClientView(&client)
+	return &view, secret, nil
+}
+
+// UpdateClient 更新客户端配置（client_id 与密钥不变）
+func UpdateClient(db *gorm.DB, clientID string, input ClientInput) (*ClientView, error) {
+	client, err := findClientRecord(db, clientID)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyClientInput(client, input); err != nil {
+		return nil, err
+	}
+
+	if err := db.Select("name", "redirect_uris", "allowed_origins", "allowed_login_methods",
+		"access_token_ttl", "refresh_token_ttl", "logo_url", "wechat_token_access", "enabled", "updated_at").
+		Save(client).Error; err != nil {
+		return nil, err
+	}
+	clientsChanged(db)
+
+	view := NewClientView(client)
+	return &view, nil
+}
+
+// RotateClientSecret 重新生成机密客户端的密钥，旧密钥立即失效
+func RotateClientSecret(db *gorm.DB, clientID string) (string, error) {
+	client, err := findClientRecord(db, clientID)
+	if err != nil {
+		return "", err
+	}
+	if client.IsPublic() {
+		return "", fmt.Errorf("%w：公共客户端没有密钥", ErrInvalidClientInput)
+	}
+
+	secret, hash, err := newClientSecret()
+	if err != nil {
+		return "", err
+	}
+	if err := db.Model(client).Updates(map[string]interface{}{
+		"client_secret_hash": hash,
+		"updated_at":         time.Now(),
+	}).Error; err != nil {
+		return "", err
+	}
+	return secret, nil
+}
+
+// DeleteClient 删除客户端及其角色与权限，并吊销该客户端的全部会话
+func DeleteClient(db *gorm.DB, clientID string) error {
+	if _, err := findClientRecord(db, clientID); err != nil {
+		return err
+	}
+
+	var sessionIDs []string
+	if err := db.Model(&models.Session{}).Where("client_id = ?", clientID).Pluck("id", &sessionIDs).Error; err != nil {
+		return err
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// 角色删除时用户角色、角色权限级联删除
+		if err := tx.Where("client_id = ?", clientID).Delete(&models.Role{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("client_id = ?", clientID).Delete(&models.Permission{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("client_id = ?", clientID).Delete(&models.Client{}).Error
+	})
+	if err != nil {
+		return err
+	}
+	clientsChanged(db)
+	return RevokeSessions(db, sessionIDs...)
+}
+
+// ClientInTenant 客户端是否属于该租户（含已停用），不属于时返回 ErrClientNotFound
+func ClientInTenant(db *gorm.DB, tenantID, clientID string) error {
+	client, err := findClientRecord(db, clientID)
+	if err != nil {
+		return err
+	}
+	if client.TenantID != tenantID {
+		return ErrClientNotFound
+	}
+	return nil
+}
+
+// findClientRecord 按 client_id 获取客户端（含已停用）
+func findClientRecord(db *gorm.DB, clientID string) (*models.Client, error) {
+	var client models.Client
+	err := db.Where("client_id = ?", clientID).First(&client).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrClientNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &client, nil
+}
+
+// newClientSecret 生成客户端密钥，返回明文与 bcrypt 哈希
+func newClientSecret() (string, string, error) {
+	secret, err := generateRandomToken(32)
+	if err != nil {
+		return "", "", err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(secret), bcrypt.DefaultCost)
+	if err != nil {
+		return "", "", err
+	}
+	return secret, string(hash), nil
+}
+
+// applyClientInput 校验并写入可编辑字段
+func applyClientInput(client *models.Client, input ClientInput) error {
+	name := strings.TrimSpace(input.Name)
+	if name == "" || len(name) > 255 {
+		return fmt.Errorf("%w：名称不能为空且不超过 255 个字符", ErrInvalidClientInput)
+	}
+
+	if len(input.RedirectURIs) == 0 {
+		return fmt.Errorf("%w：至少需要一个回调地址", ErrInvalidClientInput)
+	}
+	redirectURIs := make([]string, 0, len(input.RedirectURIs))
+	for _, raw := range input.RedirectURIs {
+		u, err := parseClientURL(raw)
+		if err != nil {
+			return fmt.Errorf("%w：回调地址 %s %v", ErrInvalidClientInput, raw, err)
+		}
+		if u.Fragment != "" {
+			return fmt.Errorf("%w：回调地址 %s 不能包含 #", ErrInvalidClientInput, raw)
+		}
+		redirectURIs = append(redirectURIs, u.String())
+	}
+
+	origins := make([]string, 0, len(input.AllowedOrigins))
+	for _, raw := range input.AllowedOrigins {
+		u, err := parseClientURL(raw)
+		if err != nil {
+			return fmt.Errorf("%w：CORS 来源 %s %v", ErrInvalidClientInput, raw, err)
+		}
+		if (u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.Fragment != "" {
+			return fmt.Errorf("%w：CORS 来源 %s 只能包含协议、域名和端口", ErrInvalidClientInput, raw)
+		}
+		origins = append(origins, u.Scheme+"://"+u.Host)
+	}
+
+	for _, method := range input.AllowedLoginMethods {
+		if !loginMethods[method] {
+			return fmt.Errorf("%w：不支持的登录方式 %s", ErrInvalidClientInput, method)
+		}
+	}
+
+	if input.AccessTokenTTL < 0 || input.RefreshTokenTTL < 0 {
+		return fmt.Errorf("%w：令牌有效期不能为负数", ErrInvalidClientInput)
+	}
+
+	var logoURL *string
+	if logo := strings.TrimSpace(input.LogoURL); logo != "" {
+		if _, err := parseClientURL(logo); err != nil {
+			return fmt.Errorf("%w：Logo 地址 %v", ErrInvalidClientInput, err)
+		}
+		logoURL = &logo
+	}
+
+	client.Name = name
+	client.RedirectURIs = strings.Join(redirectURIs, " ")
+	client.AllowedOrigins = strings.Join(origins, " ")
+	client.AllowedLoginMethods = strings.Join(input.AllowedLoginMethods, " ")
+	client.AccessTokenTTL = optionalSeconds(input.AccessTokenTTL)
+	client.RefreshTokenTTL = optionalSeconds(input.RefreshTokenTTL)
+	client.LogoURL = logoURL
+	client.WeChatTokenAccess = input.WeChatTokenAccess
+	if input.Enabled != nil {
+		client.Enabled = *input.Enabled
+	}
+	client.UpdatedAt = time.Now()
+	return nil
+}
+
+// parseClientURL 解析客户端登记的地址：必须是绝对地址，localhost 以外必须使用 HTTPS
+func parseClientURL(raw string) (*url.URL, error) {
+	raw = strings.TrimSpace(raw)
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" || strings.ContainsAny(raw, " \t\n") {
+		return nil, errors.New("不是有效的绝对地址")
+	}
+	switch {
+	case u.Scheme == "https":
+	case u.Scheme == "http" && (u.Hostname() == "localhost" || u.Hostname() == "127.0.0.1"):
+	default:
+		return nil, errors.New("必须使用 https（localhost 除外）")
+	}
+	return u, nil
+}
+
+func optionalSeconds(seconds int) *int {
+	if seconds <= 0 {
+		return nil
+	}
+	return &seconds
+}
@@ -0,0 +1,61 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/keenchase/auth-center/internal/config"
+	"github.com/keenchase/auth-center/internal/models"
+)
+
+// useTestClients 用给定的客户端替换进程内的客户端缓存，测试结束后恢复
+func useTestClients(t *testing.T, clients ...models.Client) {
+	t.Helper()
+	previous := defaultClientRegistry
+	defaultClientRegistry = &ClientRegistry{set: newClientSet(clients)}
+	t.Cleanup(func() { defaultClientRegistry = previous })
+}
+
+func TestFindClientByCallbackURL(t *testing.T) {
+	useTestClients(t, models.Client{
+		ClientID:     "client-1",
+		TenantID:     config.DefaultTenantID,
+		RedirectURIs: "https://app.crazyaigc.com/auth/callback http://localhost:3000/callback",
+		Enabled:      true,
+	})
+
+	tests := []struct {
+		name        string
+		callbackURL string
+		found       bool
+	}{
+		{"完全一致", "https://app.crazyaigc.com/auth/callback", true},
+		{"附带查询参数", "https://app.crazyaigc.com/auth/callback?next=%2Fhome", true},
+		{"本地开发地址", "http://localhost:3000/callback", true},
+		{"外部域名", "https://evil.com/auth/callback", false},
+		{"相同后缀的域名", "https://evil-app.crazyaigc.com/auth/callback", false},
+		{"userinfo 伪装主机", "https://app.crazyaigc.com@evil.com/auth/callback", false},
+		{"协议降级", "http://app.crazyaigc.com/auth/callback", false},
+		{"端口不同", "https://app.crazyaigc.com:8443/auth/callback", false},
+		{"路径不同", "https://app.crazyaigc.com/auth/callback/other", false},
+		{"相对地址", "/auth/callback", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := FindClientByCallbackURL(nil, config.DefaultTenantID, tt.callbackURL)
+			if tt.found {
+				if err != nil || client.ClientID != "client-1" {
+					t.Fatalf("FindClientByCallbackURL(%q) = %v, %v", tt.callbackURL, client, err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrClientNotFound) {
+				t.Fatalf("FindClientByCallbackURL(%q) err = %v, want ErrClientNotFound", tt.callbackURL, err)
+			}
+		})
+	}
+
+	if _, err := FindClientByCallbackURL(nil, "other-tenant", "https://app.crazyaigc.com/auth/callback"); !errors.Is(err, ErrClientNotFound) {
+		t.Fatalf("其他租户 err = %v", err)
+	}
+}
@@ -0,0 +1,39 @@
+package service
+
+import (
+	"os"
+	"testing"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// testDB 连接 TEST_DATABASE_URL 指定的测试库（须已建表并执行 migrations），未配置时跳过
+// 返回的连接处于事务中，测试结束时回滚
+func testDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	databaseURL := os.Getenv("TEST_DATABASE_URL")
+	if databaseURL == "" {
+		t.Skip("未配置 TEST_DATABASE_URL，跳过数据库测试")
+	}
+
+	db, err := gorm.Open(postgres.Open(databaseURL), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("连接测试库失败: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("连接测试库失败: %v", err)
+	}
+
+	tx := db.Begin()
+	if tx.Error != nil {
+		t.Fatalf("开启事务失败: %v", tx.Error)
+	}
+	t.Cleanup(func() {
+		tx.Rollback()
+		sqlDB.Close()
+	})
+	return tx
+}
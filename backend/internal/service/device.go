@@ -0,0 +1,151 @@
+package service
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+
+	"github.com/keenchase/auth-center/internal/models"
+)
+
+// maxUserAgentLength 保存的 User-Agent 最大长度
+const maxUserAgentLength = 512
+
+// browserPatterns 浏览器识别规则（按顺序匹配，内置浏览器和基于 Chromium 的浏览器需排在 Chrome 之前）
+var browserPatterns = []struct {
+	name    string
+	pattern *regexp.Regexp
+}{
+	{"WeCom", regexp.MustCompile(`wxwork/([\d.]+)`)},
+	{"WeChat", regexp.MustCompile(`MicroMessenger/([\d.]+)`)},
+	{"QQ", regexp.MustCompile(`\bQQ/([\d.]+)`)},
+	{"DingTalk", regexp.MustCompile(`DingTalk/([\d.]+)`)},
+	{"Feishu", regexp.MustCompile(`(?:Lark|Feishu)/([\d.]+)`)},
+	{"Edge", regexp.MustCompile(`Edg(?:e|A|iOS)?/([\d.]+)`)},
+	{"Opera", regexp.MustCompile(`OPR/([\d.]+)`)},
+	{"QQ Browser", regexp.MustCompile(`MQQBrowser/([\d.]+)|QQBrowser/([\d.]+)`)},
+	{"UC Browser", regexp.MustCompile(`UCBrowser/([\d.]+)`)},
+	{"Samsung Internet", regexp.MustCompile(`SamsungBrowser/([\d.]+)`)},
+	{"Firefox", regexp.MustCompile(`(?:Firefox|FxiOS)/([\d.]+)`)},
+	{"Chrome", regexp.MustCompile(`(?:Chrome|CriOS)/([\d.]+)`)},
+	{"Safari", regexp.MustCompile(`Version/([\d.]+).*Safari/`)},
+}
+
+// osPatterns 操作系统识别规则
+var osPatterns = []struct {
+	name    string
+	pattern *regexp.Regexp
+}{
+	{"HarmonyOS", regexp.MustCompile(`HarmonyOS(?:[ /]([\d.]+))?`)},
+	{"iOS", regexp.MustCompile(`(?:iPhone|iPad|iPod).*? OS ([\d_]+)`)},
+	{"Android", regexp.MustCompile(`Android ([\d.]+)`)},
+	{"Windows", regexp.MustCompile(`Windows NT ([\d.]+)`)},
+	{"macOS", regexp.MustCompile(`Mac OS X ([\d_.]+)`)},
+	{"Chrome OS", regexp.MustCompile(`CrOS \S+ ([\d.]+)`)},
+	{"Linux", regexp.MustCompile(`Linux()`)},
+}
+
+var botPattern = regexp.MustCompile(`(?i)bot|spider|crawler|curl|wget|python-requests|Go-http-client`)
+
+// IsWechatBrowser 检测是否在微信（含企业微信）内置浏览器
+func IsWechatBrowser(userAgent string) bool {
+	ua := strings.ToLower(userAgent)
+	return strings.Contains(ua, "micromessenger") || strings.Contains(ua, "wxwork") || strings.Contains(ua, "wechat")
+}
+
+// IsWeComBrowser 检测是否在企业微信内置浏览器
+func IsWeComBrowser(userAgent string) bool {
+	return strings.Contains(strings.ToLower(userAgent), "wxwork")
+}
+
+// ParseUserAgent 解析 User-Agent，返回浏览器、操作系统与设备类型
+func ParseUserAgent(userAgent string) (browser, os, deviceType string) {
+	for _, p := range browserPatterns {
+		if m := p.pattern.FindStringSubmatch(userAgent); m != nil {
+			browser = withMajorVersion(p.name, firstNonEmpty(m[1:]))
+			break
+		}
+	}
+
+	for _, p := range osPatterns {
+		if m := p.pattern.FindStringSubmatch(userAgent); m != nil {
+			version := strings.ReplaceAll(firstNonEmpty(m[1:]), "_", ".")
+			if p.name == "Windows" {
+				// Windows 10 与 11 的 UA 均为 NT 10.0，无法区分
+				version = map[string]string{"10.0": "10/11", "6.3": "8.1", "6.2": "8", "6.1": "7"}[version]
+			}
+			os = strings.TrimSpace(p.name + " " + version)
+			break
+		}
+	}
+
+	switch {
+	case userAgent == "":
+		deviceType = ""
+	case botPattern.MatchString(userAgent):
+		deviceType = "bot"
+	case strings.Contains(userAgent, "iPad") || strings.Contains(userAgent, "Tablet") ||
+		(strings.Contains(userAgent, "Android") && !strings.Contains(userAgent, "Mobile")):
+		deviceType = "tablet"
+	case strings.Contains(userAgent, "Mobile") || strings.Contains(userAgent, "iPhone") || strings.Contains(userAgent, "Android"):
+		deviceType = "mobile"
+	default:
+		deviceType = "desktop"
+	}
+	return browser, os, deviceType
+}
+
+// NewDeviceInfo 根据请求信息构建会话的设备信息，app 为发起登录的业务系统（回调地址或来源域名）
+func NewDeviceInfo(ip, userAgent, app string) *models.DeviceInfo {
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+	}
+	browser, os, deviceType := ParseUserAgent(userAgent)
+
+	if host := parseHostFromCallbackURL(app); host != "" {
+		app = host
+	}
+
+	return &models.DeviceInfo{
+		IP:              ip,
+		UserAgent:       userAgent,
+		Browser:         browser,
+		OS:              os,
+		DeviceType:      deviceType,
+		IsWechatBrowser: IsWechatBrowser(userAgent),
+		App:             app,
+	}
+}
+
+// marshalDeviceInfo 序列化设备信息，用于写入 jsonb 列
+func marshalDeviceInfo(device *models.DeviceInfo) *string {
+	if device == nil {
+		return nil
+	}
+	data, err := json.Marshal(device)
+	if err != nil {
+		return nil
+	}
+	s := string(data)
+	return &s
+}
+
+// withMajorVersion 名称加主版本号，如 "Chrome 120"
+func withMajorVersion(name, version string) string {
+	if version == "" {
+		return name
+	}
+	if i := strings.Index(version, "."); i > 0 {
+		version = version[:i]
+	}
+	return name + " " + version
+}
+
+func firstNonEmpty(values []string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
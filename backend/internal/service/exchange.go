@@ -0,0 +1,108 @@
+package service
+
+import (
+	"crypto/subtle"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/keenchase/auth-center/internal/models"
+)
+
+// ExchangeCodeExpiration 登录交换码有效期
+const ExchangeCodeExpiration = time.Minute
+
+// ErrInvalidExchangeCode 交换码无效、已使用、已过期或不属于该客户端
+var ErrInvalidExchangeCode = errors.New("交换码无效或已过期")
+
+// IssueExchangeCode 签发由机密客户端凭密钥兑换的登录交换码
+func IssueExchangeCode(db *gorm.DB, clientID, userID, callbackURL, loginMethod string, device *models.DeviceInfo) (string, error) {
+	if clientID == "" {
+		return "", errors.New("交换码必须绑定客户端")
+	}
+	return issueExchangeCode(db, models.ExchangeCode{
+		ClientID:    clientID,
+		UserID:      userID,
+		CallbackURL: callbackURL,
+		LoginMethod: loginMethod,
+		DeviceInfo:  marshalDeviceInfo(device),
+	})
+}
+
+// IssueBrowserExchangeCode 签发由 auth-center 自身（OIDC 授权续接）兑换的登录交换码
+// 交换码绑定发起登录的浏览器 nonce，只有同一浏览器带着 nonce Cookie 才能兑换
+func IssueBrowserExchangeCode(db *gorm.DB, nonce, userID, callbackURL, loginMethod string, device *models.DeviceInfo) (string, error) {
+	if nonce == "" {
+		return "", errors.New("交换码必须绑定浏览器")
+	}
+	return issueExchangeCode(db, models.ExchangeCode{
+		BrowserNonceHash: hashToken(nonce),
+		UserID:           userID,
+		CallbackURL:      callbackURL,
+		LoginMethod:      loginMethod,
+		DeviceInfo:       marshalDeviceInfo(device),
+	})
+}
+
+// issueExchangeCode 生成交换码并保存其哈希
+func issueExchangeCode(db *gorm.DB, record models.ExchangeCode) (string, error) {
+	code, err := generateRandomToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	record.CodeHash = hashToken(code)
+	record.ExpiresAt = time.Now().Add(ExchangeCodeExpiration)
+	if err := db.Create(&record).Error; err != nil {
+		return "", err
+	}
+	return code, nil
+}
+
+// RedeemExchangeCode 兑换登录交换码（一次性），clientID 必须与签发时一致
+func RedeemExchangeCode(db *gorm.DB, clientID, code string) (*models.ExchangeCode, error) {
+	record, err := redeemExchangeCode(db, code)
+	if err != nil {
+		return nil, err
+	}
+	if clientID == "" || record.ClientID != clientID {
+		return nil, ErrInvalidExchangeCode
+	}
+	return record, nil
+}
+
+// RedeemBrowserExchangeCode 兑换绑定浏览器的登录交换码（一次性），nonce 必须与签发时一致
+func RedeemBrowserExchangeCode(db *gorm.DB, nonce, code string) (*models.ExchangeCode, error) {
+	record, err := redeemExchangeCode(db, code)
+	if err != nil {
+		return nil, err
+	}
+	if nonce == "" || record.ClientID != "" || record.BrowserNonceHash == "" ||
+		subtle.ConstantTimeCompare([]byte(record.BrowserNonceHash), []byte(hashToken(nonce))) != 1 {
+		return nil, ErrInvalidExchangeCode
+	}
+	return record, nil
+}
+
+// redeemExchangeCode 原子地作废交换码（无论后续校验是否通过），泄露的交换码最多被尝试一次
+func redeemExchangeCode(db *gorm.DB, code string) (*models.ExchangeCode, error) {
+	if code == "" {
+		return nil, ErrInvalidExchangeCode
+	}
+
+	var record models.ExchangeCode
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("code_hash = ? AND used_at IS NULL", hashToken(code)).
+			First(&record).Error; err != nil {
+			return err
+		}
+		return tx.Model(&record).Update("used_at", time.Now()).Error
+	})
+	if err != nil || time.Now().After(record.ExpiresAt) {
+		return nil, ErrInvalidExchangeCode
+	}
+	return &record, nil
+}
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/keenchase/auth-center/internal/models"
+)
+
+func TestExchangeCodeSingleUse(t *testing.T) {
+	db := testDB(t)
+	userID := createTestUser(t, db)
+
+	code, err := IssueExchangeCode(db, "client-1", userID, "https://app.example.com/callback", models.LoginMethodPassword, nil)
+	if err != nil {
+		t.Fatalf("IssueExchangeCode: %v", err)
+	}
+
+	record, err := RedeemExchangeCode(db, "client-1", code)
+	if err != nil || record.UserID != userID {
+		t.Fatalf("RedeemExchangeCode = %v, %v", record, err)
+	}
+	if _, err := RedeemExchangeCode(db, "client-1", code); !errors.Is(err, ErrInvalidExchangeCode) {
+		t.Fatalf("重复兑换 err = %v", err)
+	}
+}
+
+func TestExchangeCodeWrongClient(t *testing.T) {
+	db := testDB(t)
+	userID := createTestUser(t, db)
+
+	code, err := IssueExchangeCode(db, "client-1", userID, "https://app.example.com/callback", models.LoginMethodPassword, nil)
+	if err != nil {
+		t.Fatalf("IssueExchangeCode: %v", err)
+	}
+	if _, err := RedeemExchangeCode(db, "client-2", code); !errors.Is(err, ErrInvalidExchangeCode) {
+		t.Fatalf("其他客户端兑换 err = %v", err)
+	}
+	// 被其他客户端尝试过的交换码已作废
+	if _, err := RedeemExchangeCode(db, "client-1", code); !errors.Is(err, ErrInvalidExchangeCode) {
+		t.Fatalf("作废后兑换 err = %v", err)
+	}
+}
+
+func TestExchangeCodeExpired(t *testing.T) {
+	db := testDB(t)
+	userID := createTestUser(t, db)
+
+	code, err := IssueExchangeCode(db, "client-1", userID, "https://app.example.com/callback", models.LoginMethodPassword, nil)
+	if err != nil {
+		t.Fatalf("IssueExchangeCode: %v", err)
+	}
+	db.Model(&models.ExchangeCode{}).Where("code_hash = ?", hashToken(code)).Update("expires_at", time.Now().Add(-time.Second))
+
+	if _, err := RedeemExchangeCode(db, "client-1", code); !errors.Is(err, ErrInvalidExchangeCode) {
+		t.Fatalf("err = %v", err)
+	}
+	if _, err := RedeemExchangeCode(db, "client-1", ""); !errors.Is(err, ErrInvalidExchangeCode) {
+		t.Fatalf("空交换码 err = %v", err)
+	}
+}
+
+func TestIssueExchangeCodeRequiresBinding(t *testing.T) {
+	// 未绑定客户端或浏览器的交换码在写库之前被拒绝
+	if _, err := IssueExchangeCode(nil, "", "user-1", "https://app.example.com/callback", models.LoginMethodPassword, nil); err == nil {
+		t.Fatal("clientID 为空时应拒绝签发")
+	}
+	if _, err := IssueBrowserExchangeCode(nil, "", "user-1", "https://auth.example.com/oauth/authorize/resume", models.LoginMethodPassword, nil); err == nil {
+		t.Fatal("nonce 为空时应拒绝签发")
+	}
+}
+
+func TestBrowserExchangeCode(t *testing.T) {
+	db := testDB(t)
+	userID := createTestUser(t, db)
+	resumeURL := "https://auth.example.com/oauth/authorize/resume?request_id=abc"
+
+	code, err := IssueBrowserExchangeCode(db, "browser-nonce", userID, resumeURL, models.LoginMethodPassword, nil)
+	if err != nil {
+		t.Fatalf("IssueBrowserExchangeCode: %v", err)
+	}
+	record, err := RedeemBrowserExchangeCode(db, "browser-nonce", code)
+	if err != nil || record.UserID != userID {
+		t.Fatalf("RedeemBrowserExchangeCode = %v, %v", record, err)
+	}
+	if _, err := RedeemBrowserExchangeCode(db, "browser-nonce", code); !errors.Is(err, ErrInvalidExchangeCode) {
+		t.Fatalf("重复兑换 err = %v", err)
+	}
+}
+
+func TestBrowserExchangeCodeRejects(t *testing.T) {
+	db := testDB(t)
+	userID := createTestUser(t, db)
+	resumeURL := "https://auth.example.com/oauth/authorize/resume?request_id=abc"
+
+	tests := []struct {
+		name   string
+		redeem func(code string) error
+	}{
+		{"其他浏览器", func(code string) error {
+			_, err := RedeemBrowserExchangeCode(db, "other-nonce", code)
+			return err
+		}},
+		{"没有 nonce", func(code string) error {
+			_, err := RedeemBrowserExchangeCode(db, "", code)
+			return err
+		}},
+		{"不带客户端凭证", func(code string) error {
+			_, err := RedeemExchangeCode(db, "", code)
+			return err
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, err := IssueBrowserExchangeCode(db, "browser-nonce", userID, resumeURL, models.LoginMethodPassword, nil)
+			if err != nil {
+				t.Fatalf("IssueBrowserExchangeCode: %v", err)
+			}
+			if err := tt.redeem(code); !errors.Is(err, ErrInvalidExchangeCode) {
+				t.Fatalf("err = %v", err)
+			}
+			// 尝试失败的交换码已作废
+			if _, err := RedeemBrowserExchangeCode(db, "browser-nonce", code); !errors.Is(err, ErrInvalidExchangeCode) {
+				t.Fatalf("作废后兑换 err = %v", err)
+			}
+		})
+	}
+
+	// 签发给客户端的交换码不能由浏览器兑换
+	code, err := IssueExchangeCode(db, "client-1", userID, "https://app.example.com/callback", models.LoginMethodPassword, nil)
+	if err != nil {
+		t.Fatalf("IssueExchangeCode: %v", err)
+	}
+	if _, err := RedeemBrowserExchangeCode(db, "browser-nonce", code); !errors.Is(err, ErrInvalidExchangeCode) {
+		t.Fatalf("客户端交换码由浏览器兑换 err = %v", err)
+	}
+}
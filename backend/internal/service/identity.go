@@ -0,0 +1,270 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+
+	"gorm.io/gorm"
+
+	"github.com/keenchase/auth-center/internal/config"
+	"github.com/keenchase/auth-center/internal/models"
+	"github.com/keenchase/auth-center/internal/wechat"
+)
+
+var (
+	// ErrUnknownIdentityProvider 未注册的登录方式
+	ErrUnknownIdentityProvider = errors.New("不支持的登录方式")
+
+	// ErrIdentityProviderNotConfigured 登录方式在当前租户未配置（缺少 AppID 等凭证）
+	ErrIdentityProviderNotConfigured = errors.New("登录方式未配置")
+
+	// ErrUnionIDConflict UnionID 已属于租户内的其他用户
+	ErrUnionIDConflict = errors.New("该微信身份已关联其他用户")
+
+	// ErrAuthCodeInvalid 授权码无效或已被使用（用户重复提交、刷新回调页等）
+	ErrAuthCodeInvalid = errors.New("授权码无效或已使用，请重新登录")
+)
+
+// ExternalIdentity 上游身份提供方返回的统一身份，与具体提供方无关
+type ExternalIdentity struct {
+	Provider    string                 // 账号平台，如 wechat
+	AppID       string                 // 提供方的应用标识（微信 AppID）
+	Subject     string                 // 用户在该应用下的标识（微信 openid）
+	UnionID     string                 // 跨应用的统一标识（微信 unionid），为空时只按 Subject 关联账号
+	AccountType string                 // 账号类型：web / mp / miniapp / app
+	Nickname    string                 // 昵称
+	AvatarURL   string                 // 头像
+	Departments []models.Department    // 所属部门（企业微信），nil 表示未同步
+	Raw         map[string]interface{} // 提供方返回的原始资料
+}
+
+// ProviderToken 授权码换得的上游凭证
+type ProviderToken struct {
+	AccessToken string
+	Subject     string
+	UnionID     string
+	Raw         map[string]interface{}
+}
+
+// IdentityProvider 上游登录方式（微信开放平台、公众号……）
+// 所有上游登录共用同一条流水线：构造授权地址 → 授权码换凭证 → 获取资料 → 关联账号
+type IdentityProvider interface {
+	// Name 登录方式标识，同时作为会话的登录方式（见 models.LoginMethod*）
+	Name() string
+	// AppID 当前租户配置的应用标识，为空表示未配置
+	AppID(cfg *config.Config) string
+	// AuthURL 跳转到提供方的授权地址
+	AuthURL(cfg *config.Config, redirectURI, state string) string
+	// ExchangeCode 用授权码换取上游凭证
+	ExchangeCode(ctx context.Context, cfg *config.Config, code string) (*ProviderToken, error)
+	// FetchIdentity 获取用户资料并转换为统一身份
+	FetchIdentity(ctx context.Context, cfg *config.Config, token *ProviderToken) (*ExternalIdentity, error)
+}
+
+var (
+	identityProvidersMu sync.RWMutex
+	identityProviders   = make(map[string]IdentityProvider)
+)
+
+// RegisterIdentityProvider 注册登录方式（通常在 init 中调用，重复注册同名登录方式会 panic）
+func RegisterIdentityProvider(provider IdentityProvider) {
+	identityProvidersMu.Lock()
+	defer identityProvidersMu.Unlock()
+	if _, exists := identityProviders[provider.Name()]; exists {
+		panic("登录方式重复注册: " + provider.Name())
+	}
+	identityProviders[provider.Name()] = provider
+}
+
+// GetIdentityProvider 按名称获取已注册的登录方式
+func GetIdentityProvider(name string) (IdentityProvider, error) {
+	identityProvidersMu.RLock()
+	defer identityProvidersMu.RUnlock()
+	provider, ok := identityProviders[name]
+	if !ok {
+		return nil, fmt.Errorf("%w：%s", ErrUnknownIdentityProvider, name)
+	}
+	return provider, nil
+}
+
+// IdentityProviderNames 已注册的全部登录方式
+func IdentityProviderNames() []string {
+	identityProvidersMu.RLock()
+	defer identityProvidersMu.RUnlock()
+	names := make([]string, 0, len(identityProviders))
+	for name := range identityProviders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// AuthenticateWithProvider 上游登录流水线：授权码换凭证 → 获取资料 → 关联账号 → 更新最后登录时间（不签发令牌）
+func AuthenticateWithProvider(ctx context.Context, db *gorm.DB, cfg *config.Config, provider IdentityProvider, code string) (*models.User, *ExternalIdentity, error) {
+	if provider.AppID(cfg) == "" {
+		return nil, nil, fmt.Errorf("%w：%s", ErrIdentityProviderNotConfigured, provider.Name())
+	}
+
+	token, err := exchangeProviderCode(ctx, cfg, provider, code)
+	if err != nil {
+		return nil, nil, err
+	}
+	identity, err := provider.FetchIdentity(ctx, cfg, token)
+	if err != nil {
+		return nil, nil, fmt.Errorf("获取用户资料失败: %w", err)
+	}
+
+	user, err := LinkExternalIdentity(db, cfg, identity)
+	if err != nil {
+		return nil, nil, fmt.Errorf("获取或创建用户失败: %w", err)
+	}
+
+	if err := UpdateLastLogin(db, user.UserID); err != nil {
+		// 记录错误但不中断登录流程
+		log.Printf("警告: 更新最后登录时间失败: %v", err)
+	}
+	return user, identity, nil
+}
+
+// exchangeProviderCode 用授权码换取上游凭证，授权码无效或已使用时返回 ErrAuthCodeInvalid
+func exchangeProviderCode(ctx context.Context, cfg *config.Config, provider IdentityProvider, code string) (*ProviderToken, error) {
+	token, err := provider.ExchangeCode(ctx, cfg, code)
+	if wechat.IsCode(err, wechat.CodeInvalidCode, wechat.CodeCodeUsed) {
+		return nil, fmt.Errorf("%w（%v）", ErrAuthCodeInvalid, err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("授权码换取凭证失败: %w", err)
+	}
+	return token, nil
+}
+
+// LinkExternalIdentity 在 cfg 所属租户内按上游身份查找或创建用户，并登记/更新对应的登录账户
+// 已登记的账户直接归属其用户；否则按 UnionID 关联到已有用户，都没有时创建新用户
+func LinkExternalIdentity(db *gorm.DB, cfg *config.Config, identity *ExternalIdentity) (*models.User, error) {
+	var user models.User
+	var account models.UserAccount
+	err := db.Where("tenant_id = ? AND provider = ? AND app_id = ? AND open_id = ?",
+		cfg.TenantID, identity.Provider, identity.AppID, identity.Subject).
+		First(&account).Error
+	switch {
+	case err == nil:
+		if err := db.Where("user_id = ?", account.UserID).First(&user).Error; err != nil {
+			return nil, fmt.Errorf("查询用户失败: %w", err)
+		}
+		if err := updateAccountProfile(db, &account, identity); err != nil {
+			return nil, err
+		}
+		if user.UnionID == "" && identity.UnionID != "" {
+			// UnionID 已属于其他用户时仍按账户登录，不合并用户
+			if err := attachUnionID(db, cfg.TenantID, user.UserID, identity.UnionID); err != nil {
+				log.Printf("警告: 补全用户 %s 的 UnionID 失败: %v", user.UserID, err)
+			} else {
+				user.UnionID = identity.UnionID
+			}
+		}
+		return &user, nil
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return nil, fmt.Errorf("查询用户账户失败: %w", err)
+	}
+
+	err = gorm.ErrRecordNotFound
+	if identity.UnionID != "" {
+		err = db.Where("tenant_id = ? AND union_id = ?", cfg.TenantID, identity.UnionID).First(&user).Error
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// 让数据库自动生成 UUID（通过 gen_random_uuid()）
+		user = models.User{
+			TenantID: cfg.TenantID,
+			UnionID:  identity.UnionID,
+		}
+		if err := db.Create(&user).Error; err != nil {
+			return nil, fmt.Errorf("创建用户失败: %w", err)
+		}
+
+		// 租户初始超级管理员（默认租户为 ADMIN_WECHAT_OPENID）首次登录时授予
+		if adminUnionID := cfg.AdminWeChatOpenID; adminUnionID != "" && adminUnionID == identity.UnionID {
+			if err := EnsureBootstrapAdmin(db, cfg.TenantID, adminUnionID); err != nil {
+				log.Printf("警告: 初始化超级管理员失败: %v", err)
+			}
+		}
+	} else if err != nil {
+		return nil, fmt.Errorf("查询用户失败: %w", err)
+	}
+
+	account = models.UserAccount{
+		UserID:      user.UserID,
+		TenantID:    cfg.TenantID,
+		Provider:    identity.Provider,
+		AppID:       identity.AppID,
+		OpenID:      identity.Subject,
+		Type:        identity.AccountType,
+		Nickname:    identity.Nickname,
+		AvatarURL:   identity.AvatarURL,
+		Departments: departmentsJSON(identity.Departments),
+	}
+	if err := db.Create(&account).Error; err != nil {
+		return nil, fmt.Errorf("创建用户账户失败: %w", err)
+	}
+	return &user, nil
+}
+
+// attachUnionID 为还没有 UnionID 的用户补全 UnionID（已属于其他用户时返回 ErrUnionIDConflict）
+func attachUnionID(db *gorm.DB, tenantID, userID, unionID string) error {
+	var owner models.User
+	err := db.Where("tenant_id = ? AND union_id = ?", tenantID, unionID).First(&owner).Error
+	if err == nil {
+		if owner.UserID != userID {
+			return ErrUnionIDConflict
+		}
+		return nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("查询用户失败: %w", err)
+	}
+
+	if err := db.Model(&models.User{}).
+		Where("user_id = ? AND tenant_id = ? AND union_id IS NULL", userID, tenantID).
+		Update("union_id", unionID).Error; err != nil {
+		return fmt.Errorf("更新用户失败: %w", err)
+	}
+	return nil
+}
+
+// updateAccountProfile 用上游最新资料更新账户的昵称、头像和部门
+func updateAccountProfile(db *gorm.DB, account *models.UserAccount, identity *ExternalIdentity) error {
+	updates := map[string]interface{}{}
+	if identity.Nickname != "" {
+		updates["nickname"] = identity.Nickname
+	}
+	if identity.AvatarURL != "" {
+		updates["avatar_url"] = identity.AvatarURL
+	}
+	if identity.Departments != nil {
+		updates["departments"] = departmentsJSON(identity.Departments)
+	}
+	if len(updates) == 0 {
+		return nil
+	}
+	if err := db.Model(account).Updates(updates).Error; err != nil {
+		return fmt.Errorf("更新用户账户失败: %w", err)
+	}
+	return nil
+}
+
+// departmentsJSON 序列化部门列表，nil 表示未同步
+func departmentsJSON(departments []models.Department) *string {
+	if departments == nil {
+		return nil
+	}
+	data, err := json.Marshal(departments)
+	if err != nil {
+		return nil
+	}
+	encoded := string(data)
+	return &encoded
+}
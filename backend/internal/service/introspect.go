@@ -0,0 +1,161 @@
+package service
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/keenchase/auth-center/internal/config"
+	"github.com/keenchase/auth-center/internal/models"
+)
+
+// 令牌类型提示（RFC 7662 / RFC 7009 token_type_hint）
+const (
+	TokenTypeHintAccessToken  = "access_token"
+	TokenTypeHintRefreshToken = "refresh_token"
+)
+
+// Introspection RFC 7662 令牌内省结果
+type Introspection struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	Exp       int64  `json:"exp,omitempty"`
+	Iat       int64  `json:"iat,omitempty"`
+	Sub       string `json:"sub,omitempty"`
+	Iss       string `json:"iss,omitempty"`
+	Jti       string `json:"jti,omitempty"`
+	SessionID string `json:"sid,omitempty"`
+}
+
+// IntrospectToken 查询令牌状态（只认当前租户的会话）
+// 访问令牌需验签通过且所属会话仍然有效；刷新令牌需未轮换、未过期且会话有效
+// 任何无效情况都只返回 active=false，不透露原因
+func IntrospectToken(db *gorm.DB, cfg *config.Config, token, hint string) *Introspection {
+	inactive := &Introspection{Active: false}
+	if token == "" {
+		return inactive
+	}
+
+	lookups := []func(*gorm.DB, *config.Config, string) *Introspection{introspectAccessToken, introspectRefreshToken}
+	if hint == TokenTypeHintRefreshToken {
+		lookups[0], lookups[1] = lookups[1], lookups[0]
+	}
+	for _, lookup := range lookups {
+		if result := lookup(db, cfg, token); result != nil {
+			return result
+		}
+	}
+	return inactive
+}
+
+// introspectAccessToken 内省访问令牌，不是有效访问令牌时返回 nil
+func introspectAccessToken(db *gorm.DB, cfg *config.Config, token string) *Introspection {
+	claims, err := ValidateToken(token)
+	if err != nil {
+		return nil
+	}
+	session, err := GetSessionByToken(db, token)
+	if err != nil || session.TenantID != cfg.TenantID {
+		return nil
+	}
+
+	result := &Introspection{
+		Active:    true,
+		Scope:     claims.Scope,
+		ClientID:  claims.ClientID,
+		TokenType: "Bearer",
+		Sub:       claims.UserID,
+		Iss:       cfg.Issuer,
+		Jti:       claims.ID,
+		SessionID: session.ID,
+	}
+	if claims.ExpiresAt != nil {
+		result.Exp = claims.ExpiresAt.Unix()
+	}
+	if claims.IssuedAt != nil {
+		result.Iat = claims.IssuedAt.Unix()
+	}
+	return result
+}
+
+// introspectRefreshToken 内省刷新令牌，不是有效刷新令牌时返回 nil
+func introspectRefreshToken(db *gorm.DB, cfg *config.Config, token string) *Introspection {
+	record, session, err := findRefreshToken(db, token)
+	if err != nil || record.UsedAt != nil || session.TenantID != cfg.TenantID {
+		return nil
+	}
+
+	return &Introspection{
+		Active:    true,
+		Scope:     session.Scope,
+		ClientID:  session.ClientID,
+		TokenType: TokenTypeHintRefreshToken,
+		Exp:       record.ExpiresAt.Unix(),
+		Iat:       record.CreatedAt.Unix(),
+		Sub:       session.UserID,
+		Iss:       cfg.Issuer,
+		SessionID: session.ID,
+	}
+}
+
+// RevokeToken 按 RFC 7009 吊销令牌
+// 无论访问令牌还是刷新令牌，都吊销其所属会话（会话下的全部令牌随之失效）
+// 只能吊销签发给该客户端的令牌；令牌无效或不属于该客户端时静默忽略
+func RevokeToken(db *gorm.DB, client *models.Client, token, hint string) error {
+	if token == "" {
+		return nil
+	}
+
+	lookups := []func(*gorm.DB, string) *models.Session{accessTokenSession, refreshTokenSession}
+	if hint == TokenTypeHintRefreshToken {
+		lookups[0], lookups[1] = lookups[1], lookups[0]
+	}
+	var session *models.Session
+	for _, lookup := range lookups {
+		if session = lookup(db, token); session != nil {
+			break
+		}
+	}
+
+	if session == nil || session.ClientID != client.ClientID {
+		return nil
+	}
+	return RevokeSessions(db, session.ID)
+}
+
+// accessTokenSession 访问令牌所属的有效会话（访问令牌本身可以已过期）
+func accessTokenSession(db *gorm.DB, token string) *models.Session {
+	if _, err := ValidateTokenAllowExpired(token); err != nil {
+		return nil
+	}
+	session, err := GetSessionByToken(db, token)
+	if err != nil {
+		return nil
+	}
+	return session
+}
+
+// refreshTokenSession 刷新令牌所属的有效会话
+func refreshTokenSession(db *gorm.DB, token string) *models.Session {
+	_, session, err := findRefreshToken(db, token)
+	if err != nil {
+		return nil
+	}
+	return session
+}
+
+// findRefreshToken 查找未过期的刷新令牌及其有效会话
+func findRefreshToken(db *gorm.DB, token string) (*models.RefreshToken, *models.Session, error) {
+	var record models.RefreshToken
+	if err := db.Where("token_hash = ? AND expires_at > ?", hashToken(token), time.Now()).First(&record).Error; err != nil {
+		return nil, nil, err
+	}
+
+	var session models.Session
+	if err := db.Where("id = ? AND expires_at > ?", record.SessionID, time.Now()).First(&session).Error; err != nil {
+		return nil, nil, err
+	}
+	return &record, &session, nil
+}
@@ -8,45 +8,93 @@ import (
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 
+	"github.com/keenchase/auth-center/internal/config"
 	"github.com/keenchase/auth-center/internal/models"
 )
 
 const (
-	// TokenExpiration Token 有效期（7天）
-	TokenExpiration = 7 * 24 * time.Hour
+	// AccessTokenType 访问令牌的 typ 头（RFC 9068），与同一密钥签名的 ID Token 区分
+	AccessTokenType = "at+jwt"
+
+	// FirstPartyAudience auth-center 自身（第一方登录）签发的访问令牌的 aud
+	FirstPartyAudience = "auth-center"
 )
 
 // Claims JWT 声明
 type Claims struct {
-	UserID string `json:"userId"`
+	UserID    string `json:"userId"`
+	SessionID string `json:"sid,omitempty"`       // 所属会话
+	TenantID  string `json:"tid,omitempty"`       // 所属租户，迁移前签发的令牌为空（默认租户）
+	Scope     string `json:"scope,omitempty"`     // OIDC 授权范围
+	ClientID  string `json:"client_id,omitempty"` // OIDC 客户端
+	// 用户在该客户端内的角色与权限（签发时的快照，刷新令牌时更新）
+	Roles       []string   `json:"roles,omitempty"`
+	Permissions []string   `json:"permissions,omitempty"`
+	Orgs        []OrgClaim `json:"orgs,omitempty"` // 用户所在的组织及组织内角色
 	jwt.RegisteredClaims
 }
 
-// GenerateToken 生成 JWT Token
-func GenerateToken(userID string, secret string) (string, error) {
+// GenerateAccessToken 生成绑定会话的短期访问令牌，authz 不为空时写入角色与权限声明
+func GenerateAccessToken(userID, sessionID, tenantID, clientID, scope string, authz *Authorization, orgs []OrgClaim, ttl time.Duration) (string, error) {
 	now := time.Now()
+	audience := FirstPartyAudience
+	if clientID != "" {
+		audience = clientID
+	}
 	claims := Claims{
-		UserID: userID,
+		UserID:    userID,
+		SessionID: sessionID,
+		TenantID:  tenantID,
+		Scope:     scope,
+		ClientID:  clientID,
+		Orgs:      orgs,
 		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   userID,
+			Audience:  jwt.ClaimStrings{audience},
 			ID:        uuid.New().String(),
-			IssuedAt: jwt.NewNumericDate(now),
-			ExpiresAt: jwt.NewNumericDate(now.Add(TokenExpiration)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
 			NotBefore: jwt.NewNumericDate(now),
 		},
 	}
+	if authz != nil {
+		claims.Roles = authz.Roles
+		claims.Permissions = authz.Permissions
+	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(secret))
+	return signToken(AccessTokenType, claims)
 }
 
-// ValidateToken 验证 JWT Token
-func ValidateToken(tokenString string, secret string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok || token.Header["alg"] != "HS256" {
-			return nil, errors.New("无效的签名算法")
+// Tenant 令牌所属租户
+func (c *Claims) Tenant() string {
+	if c.TenantID == "" {
+		return config.DefaultTenantID
+	}
+	return c.TenantID
+}
+
+// FirstParty 是否为第一方令牌（未签发给业务系统客户端），只有第一方令牌可访问 auth-center 自身的接口
+// 迁移前签发的令牌没有 aud，按 client_id 判断
+func (c *Claims) FirstParty() bool {
+	if c.ClientID != "" {
+		return false
+	}
+	if len(c.Audience) == 0 {
+		return true
+	}
+	for _, aud := range c.Audience {
+		if aud == FirstPartyAudience {
+			return true
 		}
-		return []byte(secret), nil
-	})
+	}
+	return false
+}
+
+// ValidateToken 验证访问令牌（按 kid 选择公钥验签，拒绝 ID Token 等其他类型的令牌）
+func ValidateToken(tokenString string) (*Claims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, accessTokenKey,
+		jwt.WithValidMethods([]string{AlgRS256, AlgES256, AlgEdDSA, "HS256"}),
+	)
 
 	if err != nil {
 		return nil, err
@@ -59,42 +107,64 @@ func ValidateToken(tokenString string, secret string) (*Claims, error) {
 	return nil, errors.New("无效的 Token")
 }
 
-// CreateSession 创建会话
-func CreateSession(db *gorm.DB, userID string, token string, deviceInfo map[string]interface{}) (string, error) {
-	sessionID := uuid.New().String()
-	expiresAt := time.Now().Add(TokenExpiration)
-
-	var deviceInfoJSON *string
-	if deviceInfo != nil {
-		// 简化处理，实际应该序列化
-		deviceInfoStr := "{}"
-		deviceInfoJSON = &deviceInfoStr
+// ValidateTokenAllowExpired 验证签名但忽略过期时间
+// 仅用于以会话是否存活为准的场景（如 auth-center 自身的 SSO 登录态）
+func ValidateTokenAllowExpired(tokenString string) (*Claims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, accessTokenKey,
+		jwt.WithValidMethods([]string{AlgRS256, AlgES256, AlgEdDSA, "HS256"}),
+		jwt.WithoutClaimsValidation(),
+	)
+	if err != nil {
+		return nil, err
 	}
 
-	session := models.Session{
-		ID:         sessionID,
-		UserID:     userID,
-		Token:      token,
-		DeviceInfo: deviceInfoJSON,
-		ExpiresAt:  &expiresAt,
+	claims, ok := token.Claims.(*Claims)
+	if !ok || !token.Valid {
+		return nil, errors.New("无效的 Token")
 	}
+	return claims, nil
+}
 
-	if err := db.Create(&session).Error; err != nil {
-		return "", err
+// accessTokenKey 选择访问令牌的验签公钥：带 kid 的令牌必须是访问令牌类型（迁移期的旧 HS256 令牌没有 typ 区分）
+func accessTokenKey(token *jwt.Token) (interface{}, error) {
+	if kid, _ := token.Header["kid"].(string); kid != "" {
+		if typ, _ := token.Header["typ"].(string); typ != AccessTokenType {
+			return nil, errors.New("不是访问令牌")
+		}
 	}
+	return verificationKey(token)
+}
 
-	return sessionID, nil
+// sessionQuery 按 Token 定位会话：新 Token 通过 sid 声明，旧 Token 通过令牌摘要
+func sessionQuery(db *gorm.DB, token string) *gorm.DB {
+	if claims, err := ValidateTokenAllowExpired(token); err == nil && claims.SessionID != "" {
+		return db.Where("id = ?", claims.SessionID)
+	}
+	return db.Where("token_hash = ?", hashToken(token))
 }
 
-// DeleteSession 删除会话
+// DeleteSession 删除会话（其刷新令牌随之级联删除）
 func DeleteSession(db *gorm.DB, token string) error {
-	return db.Where("token = ?", token).Delete(&models.Session{}).Error
+	var ids []string
+	if err := sessionQuery(db, token).Model(&models.Session{}).Pluck("id", &ids).Error; err != nil {
+		return err
+	}
+	return RevokeSessions(db, ids...)
+}
+
+// GetSessionByToken 根据 Token 获取未过期的会话
+func GetSessionByToken(db *gorm.DB, token string) (*models.Session, error) {
+	var session models.Session
+	if err := sessionQuery(db, token).Where("expires_at > ?", time.Now()).First(&session).Error; err != nil {
+		return nil, err
+	}
+	return &session, nil
 }
 
 // GetUserByToken 根据 Token 获取用户
 func GetUserByToken(db *gorm.DB, token string) (*models.User, error) {
-	var session models.Session
-	if err := db.Where("token = ? AND expires_at > ?", token, time.Now()).First(&session).Error; err != nil {
+	session, err := GetSessionByToken(db, token)
+	if err != nil {
 		return nil, err
 	}
 
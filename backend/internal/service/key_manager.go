@@ -0,0 +1,348 @@
+package service
+
+import (
+	"crypto"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/keenchase/auth-center/internal/config"
+	"github.com/keenchase/auth-center/internal/models"
+)
+
+const (
+	// signingKeyLockID 签名密钥轮换的 Postgres advisory lock 标识（多副本互斥）
+	signingKeyLockID int64 = 0x61632d6b657973 // "ac-keys"
+
+	// keyReloadInterval 各副本从数据库重新加载密钥的周期
+	keyReloadInterval = time.Minute
+
+	// unknownKidReloadInterval 遇到未知 kid 时按需重新加载的最小间隔
+	unknownKidReloadInterval = 10 * time.Second
+)
+
+// KeyManager 签名密钥生命周期管理：加密存储、定期轮换、宽限期验签、退役
+type KeyManager struct {
+	db     *gorm.DB
+	cfg    *config.Config
+	box    *SecretBox
+	keySet *KeySet
+
+	mu         sync.Mutex
+	lastReload time.Time
+}
+
+// defaultKeyManager 进程内的密钥管理器，由 InitKeyManager 初始化
+var defaultKeyManager *KeyManager
+
+// DefaultKeyManager 返回进程内的密钥管理器
+func DefaultKeyManager() *KeyManager {
+	return defaultKeyManager
+}
+
+// InitKeyManager 初始化密钥管理器：确保存在签名密钥，加载到进程内密钥集合，并启动后台轮换
+func InitKeyManager(db *gorm.DB, cfg *config.Config) (*KeyManager, error) {
+	if defaultSecretBox == nil {
+		return nil, errors.New("静态加密密钥未初始化")
+	}
+	if cfg.JWTKeyGracePeriod < cfg.AccessTokenTTL {
+		log.Printf("警告: JWT_KEY_GRACE_PERIOD(%s) 小于访问令牌有效期(%s)，轮换后部分 Token 将提前失效", cfg.JWTKeyGracePeriod, cfg.AccessTokenTTL)
+	}
+
+	m := &KeyManager{
+		db:     db,
+		cfg:    cfg,
+		box:    defaultSecretBox,
+		keySet: defaultKeySet,
+	}
+
+	if cfg.JWTLegacyHS256 && !time.Now().Before(LegacyHS256Sunset) {
+		log.Printf("警告: 已过旧 HS256 Token 截止时间 %s，忽略 JWT_LEGACY_HS256", LegacyHS256Sunset.Format(time.RFC3339))
+	} else if cfg.JWTLegacyHS256 {
+		log.Printf("警告: JWT_LEGACY_HS256 已开启，%s 前仍接受 AUTH_CENTER_SECRET 签名的旧 Token", LegacyHS256Sunset.Format(time.RFC3339))
+		defaultKeySet.mu.Lock()
+		defaultKeySet.legacySecret = cfg.JWTSecret
+		defaultKeySet.mu.Unlock()
+	}
+
+	if err := m.ensureActiveKey(); err != nil {
+		return nil, err
+	}
+	if err := m.Reload(); err != nil {
+		return nil, err
+	}
+
+	defaultKeyManager = m
+	go m.run()
+	return m, nil
+}
+
+// ensureActiveKey 数据库中没有签名密钥时创建第一把
+// 若配置了 JWT_PRIVATE_KEY_FILE 则导入该私钥，保证已签发的 Token 继续有效
+func (m *KeyManager) ensureActiveKey() error {
+	return m.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", signingKeyLockID).Error; err != nil {
+			return err
+		}
+
+		var count int64
+		if err := tx.Model(&models.SigningKey{}).Where("status = ?", models.SigningKeyActive).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return nil
+		}
+
+		var key *SigningKey
+		var err error
+		if m.cfg.JWTPrivateKeyFile != "" {
+			key, err = LoadSigningKeyFile(m.cfg.JWTPrivateKeyFile)
+			log.Printf("导入 JWT_PRIVATE_KEY_FILE 作为初始签名密钥")
+		} else {
+			key, err = GenerateSigningKey(m.cfg.JWTSigningAlg)
+		}
+		if err != nil {
+			return err
+		}
+
+		record, err := m.sealKey(key)
+		if err != nil {
+			return err
+		}
+		return tx.Create(record).Error
+	})
+}
+
+// Rotate 生成新的签名密钥，当前密钥转为仅验签
+// revokePrevious 为 true 时（密钥泄露等紧急情况）旧密钥立即退役，不保留宽限期
+func (m *KeyManager) Rotate(revokePrevious bool) (*models.SigningKey, error) {
+	record, err := m.rotate(revokePrevious, false)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.Reload(); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// RotateIfDue 当前签名密钥超过轮换周期时轮换
+func (m *KeyManager) RotateIfDue() (bool, error) {
+	record, err := m.rotate(false, true)
+	if err != nil || record == nil {
+		return false, err
+	}
+	log.Printf("签名密钥已按计划轮换: kid=%s", record.Kid)
+	return true, m.Reload()
+}
+
+func (m *KeyManager) rotate(revokePrevious, onlyIfDue bool) (*models.SigningKey, error) {
+	if onlyIfDue {
+		due, err := m.rotationDue(m.db)
+		if err != nil || !due {
+			return nil, err
+		}
+	}
+
+	key, err := GenerateSigningKey(m.cfg.JWTSigningAlg)
+	if err != nil {
+		return nil, err
+	}
+	record, err := m.sealKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	err = m.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", signingKeyLockID).Error; err != nil {
+			return err
+		}
+
+		// 加锁后再判断一次是否到期，避免多个副本重复轮换
+		if onlyIfDue {
+			due, err := m.rotationDue(tx)
+			if err != nil {
+				return err
+			}
+			if !due {
+				record = nil
+				return nil
+			}
+		}
+
+		now := time.Now()
+		demote := map[string]interface{}{
+			"status":     models.SigningKeyVerify,
+			"rotated_at": now,
+			"retire_at":  now.Add(m.cfg.JWTKeyGracePeriod),
+		}
+		if revokePrevious {
+			demote = map[string]interface{}{
+				"status":          models.SigningKeyRetired,
+				"rotated_at":      now,
+				"retire_at":       now,
+				"retired_at":      now,
+				"private_key_enc": "",
+			}
+		}
+		if err := tx.Model(&models.SigningKey{}).Where("status = ?", models.SigningKeyActive).Updates(demote).Error; err != nil {
+			return err
+		}
+		return tx.Create(record).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("轮换签名密钥失败: %w", err)
+	}
+	return record, nil
+}
+
+// rotationDue 当前签名密钥是否已超过轮换周期
+func (m *KeyManager) rotationDue(db *gorm.DB) (bool, error) {
+	var current models.SigningKey
+	err := db.Where("status = ?", models.SigningKeyActive).Order("activated_at DESC").First(&current).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return time.Since(current.ActivatedAt) >= m.cfg.JWTKeyRotationInterval, nil
+}
+
+// RetireExpired 退役宽限期已结束的仅验签密钥（清除私钥材料）
+func (m *KeyManager) RetireExpired() (int64, error) {
+	now := time.Now()
+	result := m.db.Model(&models.SigningKey{}).
+		Where("status = ? AND retire_at <= ?", models.SigningKeyVerify, now).
+		Updates(map[string]interface{}{
+			"status":          models.SigningKeyRetired,
+			"retired_at":      now,
+			"private_key_enc": "",
+		})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	if result.RowsAffected > 0 {
+		return result.RowsAffected, m.Reload()
+	}
+	return 0, nil
+}
+
+// Reload 从数据库加载签名密钥与仅验签密钥到进程内密钥集合
+func (m *KeyManager) Reload() error {
+	var records []models.SigningKey
+	if err := m.db.Where("status IN ?", []string{models.SigningKeyActive, models.SigningKeyVerify}).
+		Order("activated_at DESC").
+		Find(&records).Error; err != nil {
+		return fmt.Errorf("加载签名密钥失败: %w", err)
+	}
+
+	var active *SigningKey
+	var verifyOnly []*SigningKey
+	for i := range records {
+		key, err := m.openKey(&records[i])
+		if err != nil {
+			log.Printf("警告: 签名密钥 %s 无法解密: %v", records[i].Kid, err)
+			continue
+		}
+		if records[i].Status == models.SigningKeyActive && active == nil {
+			active = key
+			continue
+		}
+		// 仅验签的密钥不在内存中保留私钥
+		verifyOnly = append(verifyOnly, &SigningKey{ID: key.ID, Algorithm: key.Algorithm, PublicKey: key.PublicKey})
+	}
+	if active == nil {
+		return errors.New("没有可用的签名密钥")
+	}
+
+	m.keySet.Replace(active, verifyOnly...)
+
+	m.mu.Lock()
+	m.lastReload = time.Now()
+	m.mu.Unlock()
+	return nil
+}
+
+// ListKeys 列出签名密钥（不含私钥）
+func (m *KeyManager) ListKeys() ([]models.SigningKey, error) {
+	var records []models.SigningKey
+	err := m.db.Omit("private_key_enc").Order("activated_at DESC").Find(&records).Error
+	return records, err
+}
+
+// reloadForUnknownKid 遇到未知 kid 时重新加载（其他副本刚轮换过），带限流
+func (m *KeyManager) reloadForUnknownKid() bool {
+	m.mu.Lock()
+	if time.Since(m.lastReload) < unknownKidReloadInterval {
+		m.mu.Unlock()
+		return false
+	}
+	m.lastReload = time.Now()
+	m.mu.Unlock()
+
+	if err := m.Reload(); err != nil {
+		log.Printf("警告: 重新加载签名密钥失败: %v", err)
+		return false
+	}
+	return true
+}
+
+// run 后台定期重新加载、按计划轮换、退役过期密钥
+func (m *KeyManager) run() {
+	ticker := time.NewTicker(keyReloadInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if _, err := m.RotateIfDue(); err != nil {
+			log.Printf("警告: 签名密钥轮换失败: %v", err)
+		}
+		if _, err := m.RetireExpired(); err != nil {
+			log.Printf("警告: 签名密钥退役失败: %v", err)
+		}
+		if err := m.Reload(); err != nil {
+			log.Printf("警告: 重新加载签名密钥失败: %v", err)
+		}
+	}
+}
+
+// sealKey 加密私钥，生成数据库记录
+func (m *KeyManager) sealKey(key *SigningKey) (*models.SigningKey, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(key.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("序列化签名密钥失败: %w", err)
+	}
+	enc, err := m.box.Seal(der, key.ID)
+	if err != nil {
+		return nil, fmt.Errorf("加密签名密钥失败: %w", err)
+	}
+	return &models.SigningKey{
+		Kid:           key.ID,
+		Algorithm:     key.Algorithm,
+		PrivateKeyEnc: enc,
+		Status:        models.SigningKeyActive,
+		ActivatedAt:   time.Now(),
+	}, nil
+}
+
+// openKey 解密数据库中的私钥
+func (m *KeyManager) openKey(record *models.SigningKey) (*SigningKey, error) {
+	der, err := m.box.Open(record.PrivateKeyEnc, record.Kid)
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := parsed.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("不支持的签名密钥类型")
+	}
+	return NewSigningKey(signer)
+}
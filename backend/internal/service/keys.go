@@ -0,0 +1,380 @@
+package service
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// 支持的签名算法
+const (
+	AlgRS256 = "RS256"
+	AlgES256 = "ES256"
+	AlgEdDSA = "EdDSA"
+)
+
+// SigningKey JWT 签名密钥（非对称）
+type SigningKey struct {
+	ID         string // kid（RFC 7638 公钥指纹）
+	Algorithm  string
+	PrivateKey crypto.Signer // 仅验签的密钥为 nil
+	PublicKey  crypto.PublicKey
+}
+
+// Method 返回对应的 JWT 签名方法
+func (k *SigningKey) Method() jwt.SigningMethod {
+	switch k.Algorithm {
+	case AlgES256:
+		return jwt.SigningMethodES256
+	case AlgEdDSA:
+		return jwt.SigningMethodEdDSA
+	default:
+		return jwt.SigningMethodRS256
+	}
+}
+
+// LegacyHS256Sunset 旧 HS256 Token 的最终截止时间：此后即使开启 JWT_LEGACY_HS256 也不再接受
+// 旧 Token 有效期为 7 天，迁移时签发的旧 Token 届时都已过期
+var LegacyHS256Sunset = time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+
+// KeySet 签名密钥集合：一个当前签名密钥 + 若干仅验签密钥
+type KeySet struct {
+	mu     sync.RWMutex
+	active *SigningKey
+	keys   map[string]*SigningKey
+
+	// legacySecret 非空时继续接受旧的 HS256 Token（无 kid），用于平滑迁移
+	legacySecret string
+}
+
+// NewKeySet 创建密钥集合
+func NewKeySet(active *SigningKey, verifyOnly ...*SigningKey) *KeySet {
+	ks := &KeySet{keys: make(map[string]*SigningKey)}
+	ks.Replace(active, verifyOnly...)
+	return ks
+}
+
+// Replace 原子地替换签名密钥与验签密钥
+func (ks *KeySet) Replace(active *SigningKey, verifyOnly ...*SigningKey) {
+	keys := make(map[string]*SigningKey, len(verifyOnly)+1)
+	if active != nil {
+		keys[active.ID] = active
+	}
+	for _, k := range verifyOnly {
+		keys[k.ID] = k
+	}
+
+	ks.mu.Lock()
+	ks.active = active
+	ks.keys = keys
+	ks.mu.Unlock()
+}
+
+// SigningKey 返回当前签名密钥
+func (ks *KeySet) SigningKey() (*SigningKey, error) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	if ks.active == nil || ks.active.PrivateKey == nil {
+		return nil, errors.New("签名密钥未初始化")
+	}
+	return ks.active, nil
+}
+
+// Key 根据 kid 查找验签密钥
+func (ks *KeySet) Key(kid string) (*SigningKey, bool) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	k, ok := ks.keys[kid]
+	return k, ok
+}
+
+// Keys 返回全部可验签的密钥
+func (ks *KeySet) Keys() []*SigningKey {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	result := make([]*SigningKey, 0, len(ks.keys))
+	if ks.active != nil {
+		result = append(result, ks.active)
+	}
+	for id, k := range ks.keys {
+		if ks.active == nil || id != ks.active.ID {
+			result = append(result, k)
+		}
+	}
+	return result
+}
+
+// defaultKeySet 进程内的签名密钥集合，由 KeyManager 从数据库加载
+var defaultKeySet = NewKeySet(nil)
+
+// DefaultKeySet 返回进程内的签名密钥集合
+func DefaultKeySet() *KeySet {
+	return defaultKeySet
+}
+
+// GenerateSigningKey 生成新的签名密钥
+func GenerateSigningKey(alg string) (*SigningKey, error) {
+	var signer crypto.Signer
+	var err error
+
+	switch alg {
+	case AlgRS256, "":
+		signer, err = rsa.GenerateKey(rand.Reader, 2048)
+	case AlgES256:
+		signer, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case AlgEdDSA:
+		_, signer, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return nil, fmt.Errorf("不支持的签名算法: %s", alg)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("生成签名密钥失败: %w", err)
+	}
+
+	return NewSigningKey(signer)
+}
+
+// LoadSigningKeyFile 从 PEM 文件加载签名私钥（PKCS#8 / PKCS#1 / SEC 1）
+func LoadSigningKeyFile(path string) (*SigningKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取签名密钥失败: %w", err)
+	}
+	return ParseSigningKeyPEM(data)
+}
+
+// ParseSigningKeyPEM 解析 PEM 格式的签名私钥
+func ParseSigningKeyPEM(data []byte) (*SigningKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("签名密钥不是有效的 PEM 格式")
+	}
+
+	var parsed interface{}
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		parsed, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("解析签名密钥失败: %w", err)
+	}
+
+	signer, ok := parsed.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("不支持的签名密钥类型")
+	}
+	return NewSigningKey(signer)
+}
+
+// NewSigningKey 根据私钥构建签名密钥（算法由密钥类型决定）
+func NewSigningKey(signer crypto.Signer) (*SigningKey, error) {
+	key := &SigningKey{
+		PrivateKey: signer,
+		PublicKey:  signer.Public(),
+	}
+
+	switch k := signer.(type) {
+	case *rsa.PrivateKey:
+		if k.N.BitLen() < 2048 {
+			return nil, errors.New("RSA 密钥长度不能小于 2048 位")
+		}
+		key.Algorithm = AlgRS256
+	case *ecdsa.PrivateKey:
+		if k.Curve != elliptic.P256() {
+			return nil, errors.New("ECDSA 密钥必须使用 P-256 曲线")
+		}
+		key.Algorithm = AlgES256
+	case ed25519.PrivateKey:
+		key.Algorithm = AlgEdDSA
+	default:
+		return nil, errors.New("不支持的签名密钥类型")
+	}
+
+	kid, err := keyThumbprint(key.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	key.ID = kid
+	return key, nil
+}
+
+// JWK JSON Web Key（公钥）
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JWKS JSON Web Key Set
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS 返回所有可验签公钥
+func (ks *KeySet) JWKS() JWKS {
+	keys := ks.Keys()
+	result := JWKS{Keys: make([]JWK, 0, len(keys))}
+	for _, k := range keys {
+		jwk, err := publicJWK(k.PublicKey)
+		if err != nil {
+			continue
+		}
+		jwk.Kid = k.ID
+		jwk.Use = "sig"
+		jwk.Alg = k.Algorithm
+		result.Keys = append(result.Keys, jwk)
+	}
+	return result
+}
+
+// publicJWK 将公钥编码为 JWK
+func publicJWK(pub crypto.PublicKey) (JWK, error) {
+	enc := base64.RawURLEncoding
+	switch k := pub.(type) {
+	case *rsa.PublicKey:
+		return JWK{
+			Kty: "RSA",
+			N:   enc.EncodeToString(k.N.Bytes()),
+			E:   enc.EncodeToString(big.NewInt(int64(k.E)).Bytes()),
+		}, nil
+	case *ecdsa.PublicKey:
+		size := (k.Curve.Params().BitSize + 7) / 8
+		return JWK{
+			Kty: "EC",
+			Crv: k.Curve.Params().Name,
+			X:   enc.EncodeToString(k.X.FillBytes(make([]byte, size))),
+			Y:   enc.EncodeToString(k.Y.FillBytes(make([]byte, size))),
+		}, nil
+	case ed25519.PublicKey:
+		return JWK{
+			Kty: "OKP",
+			Crv: "Ed25519",
+			X:   enc.EncodeToString(k),
+		}, nil
+	}
+	return JWK{}, errors.New("不支持的公钥类型")
+}
+
+// keyThumbprint 计算 RFC 7638 JWK 指纹，作为 kid
+func keyThumbprint(pub crypto.PublicKey) (string, error) {
+	jwk, err := publicJWK(pub)
+	if err != nil {
+		return "", err
+	}
+
+	// 必需成员按字典序排列
+	var members interface{}
+	switch jwk.Kty {
+	case "RSA":
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{jwk.E, jwk.Kty, jwk.N}
+	case "EC":
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		}{jwk.Crv, jwk.Kty, jwk.X, jwk.Y}
+	default:
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{jwk.Crv, jwk.Kty, jwk.X}
+	}
+
+	data, err := json.Marshal(members)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
+
+// signToken 使用当前签名密钥签名（写入 kid 与 typ 头）
+func signToken(typ string, claims jwt.Claims) (string, error) {
+	key, err := defaultKeySet.SigningKey()
+	if err != nil {
+		return "", err
+	}
+
+	token := jwt.NewWithClaims(key.Method(), claims)
+	token.Header["kid"] = key.ID
+	token.Header["typ"] = typ
+	return token.SignedString(key.PrivateKey)
+}
+
+// verificationKey 根据 Token 头中的 kid 选择验签公钥
+func verificationKey(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		// 迁移期：无 kid 的旧 HS256 Token
+		defaultKeySet.mu.RLock()
+		secret := defaultKeySet.legacySecret
+		defaultKeySet.mu.RUnlock()
+		if secret != "" && token.Method == jwt.SigningMethodHS256 && time.Now().Before(LegacyHS256Sunset) {
+			if err := checkLegacyClaims(token.Claims); err != nil {
+				return nil, err
+			}
+			return []byte(secret), nil
+		}
+		return nil, errors.New("缺少 kid")
+	}
+
+	key, ok := defaultKeySet.Key(kid)
+	if !ok && defaultKeyManager != nil && defaultKeyManager.reloadForUnknownKid() {
+		// 其他副本可能刚完成轮换
+		key, ok = defaultKeySet.Key(kid)
+	}
+	if !ok {
+		return nil, fmt.Errorf("未知的 kid: %s", kid)
+	}
+	if token.Method.Alg() != key.Algorithm {
+		return nil, errors.New("无效的签名算法")
+	}
+	return key.PublicKey, nil
+}
+
+// checkLegacyClaims 旧 HS256 Token 只有 userId 与标准时间声明
+// 共享密钥在各业务系统手中，带会话、租户、客户端等新声明的 HS256 Token 一定是伪造的
+func checkLegacyClaims(claims jwt.Claims) error {
+	c, ok := claims.(*Claims)
+	if !ok {
+		return errors.New("无效的 Token")
+	}
+	if c.SessionID != "" || c.TenantID != "" || c.ClientID != "" || c.Scope != "" ||
+		len(c.Roles) > 0 || len(c.Permissions) > 0 || len(c.Orgs) > 0 || len(c.Audience) > 0 {
+		return errors.New("旧 HS256 Token 不能携带会话、租户或客户端声明")
+	}
+	return nil
+}
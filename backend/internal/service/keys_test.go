@@ -0,0 +1,127 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/keenchase/auth-center/internal/config"
+	"github.com/keenchase/auth-center/internal/models"
+)
+
+// useTestKeySet 用新生成的签名密钥替换进程内的密钥集合，测试结束后恢复
+func useTestKeySet(t *testing.T, verifyOnly ...*SigningKey) *SigningKey {
+	t.Helper()
+	key, err := GenerateSigningKey(AlgES256)
+	if err != nil {
+		t.Fatalf("GenerateSigningKey: %v", err)
+	}
+	previous := defaultKeySet.Keys()
+	defaultKeySet.Replace(key, verifyOnly...)
+	t.Cleanup(func() {
+		if len(previous) == 0 {
+			defaultKeySet.Replace(nil)
+			return
+		}
+		defaultKeySet.Replace(previous[0], previous[1:]...)
+	})
+	return key
+}
+
+func TestKeySetRotationGracePeriod(t *testing.T) {
+	old := useTestKeySet(t)
+	oldToken, err := GenerateAccessToken("user-1", "session-1", "default", "", "", nil, nil, time.Minute)
+	if err != nil {
+		t.Fatalf("GenerateAccessToken: %v", err)
+	}
+
+	// 轮换：新密钥签名，旧密钥在宽限期内仅验签
+	next, err := GenerateSigningKey(AlgRS256)
+	if err != nil {
+		t.Fatalf("GenerateSigningKey: %v", err)
+	}
+	defaultKeySet.Replace(next, &SigningKey{ID: old.ID, Algorithm: old.Algorithm, PublicKey: old.PublicKey})
+
+	if _, err := ValidateToken(oldToken); err != nil {
+		t.Fatalf("宽限期内旧密钥签发的 Token 应有效: %v", err)
+	}
+	if active, err := defaultKeySet.SigningKey(); err != nil || active.ID != next.ID {
+		t.Fatalf("当前签名密钥 = %v, %v", active, err)
+	}
+	if len(defaultKeySet.JWKS().Keys) != 2 {
+		t.Fatal("JWKS 应同时公布新旧公钥")
+	}
+
+	// 退役：旧密钥不再验签
+	defaultKeySet.Replace(next)
+	if _, err := ValidateToken(oldToken); err == nil {
+		t.Fatal("旧密钥退役后其签发的 Token 应失效")
+	}
+}
+
+func TestKeyManagerRotate(t *testing.T) {
+	db := testDB(t)
+	if err := db.Where("1 = 1").Delete(&models.SigningKey{}).Error; err != nil {
+		t.Fatalf("清空签名密钥失败: %v", err)
+	}
+	m := &KeyManager{
+		db:     db,
+		cfg:    &config.Config{JWTSigningAlg: AlgES256, JWTKeyGracePeriod: time.Hour},
+		box:    newTestSecretBox(t),
+		keySet: NewKeySet(nil),
+	}
+	if err := m.ensureActiveKey(); err != nil {
+		t.Fatalf("ensureActiveKey: %v", err)
+	}
+	if err := m.Reload(); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	first, _ := m.keySet.SigningKey()
+
+	record, err := m.Rotate(false)
+	if err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+	if active, _ := m.keySet.SigningKey(); active.ID != record.Kid {
+		t.Fatalf("轮换后签名密钥 = %s, want %s", active.ID, record.Kid)
+	}
+	verifyKey, ok := m.keySet.Key(first.ID)
+	if !ok || verifyKey.PrivateKey != nil {
+		t.Fatal("旧密钥应保留为仅验签（不含私钥）")
+	}
+
+	var demoted models.SigningKey
+	if err := db.Where("kid = ?", first.ID).First(&demoted).Error; err != nil {
+		t.Fatalf("查询旧密钥: %v", err)
+	}
+	if demoted.Status != models.SigningKeyVerify || demoted.RetireAt == nil ||
+		demoted.RetireAt.Sub(time.Now()) < 59*time.Minute {
+		t.Fatalf("旧密钥状态 = %s, retire_at = %v", demoted.Status, demoted.RetireAt)
+	}
+
+	// 宽限期内不退役
+	if n, err := m.RetireExpired(); err != nil || n != 0 {
+		t.Fatalf("RetireExpired = %d, %v", n, err)
+	}
+
+	// 宽限期结束后退役并清除私钥
+	db.Model(&models.SigningKey{}).Where("kid = ?", first.ID).Update("retire_at", time.Now().Add(-time.Second))
+	if n, err := m.RetireExpired(); err != nil || n != 1 {
+		t.Fatalf("RetireExpired = %d, %v", n, err)
+	}
+	if _, ok := m.keySet.Key(first.ID); ok {
+		t.Fatal("退役的密钥不应再用于验签")
+	}
+	db.Where("kid = ?", first.ID).First(&demoted)
+	if demoted.Status != models.SigningKeyRetired || demoted.PrivateKeyEnc != "" {
+		t.Fatalf("退役密钥状态 = %s, 私钥是否清除 = %v", demoted.Status, demoted.PrivateKeyEnc == "")
+	}
+
+	// 紧急轮换：旧密钥立即退役
+	second := record.Kid
+	if _, err := m.Rotate(true); err != nil {
+		t.Fatalf("Rotate(true): %v", err)
+	}
+	if _, ok := m.keySet.Key(second); ok {
+		t.Fatal("紧急轮换后旧密钥应立即失效")
+	}
+}
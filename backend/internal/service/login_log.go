@@ -15,7 +15,7 @@ type LoginSourceItem struct {
 }
 
 // CreateLoginLog 记录用户登录流水（来源业务系统）
-func CreateLoginLog(db *gorm.DB, userID, callbackURL, loginMethod string) error {
+func CreateLoginLog(db *gorm.DB, tenantID, userID, callbackURL, loginMethod string) error {
 	sourceHost := parseHostFromCallbackURL(callbackURL)
 	if sourceHost == "" {
 		return nil // 无有效来源则不记录
@@ -23,6 +23,7 @@ func CreateLoginLog(db *gorm.DB, userID, callbackURL, loginMethod string) error
 
 	log := models.UserLoginLog{
 		UserID:      userID,
+		TenantID:    tenantID,
 		SourceHost:  sourceHost,
 		LoginMethod: loginMethod,
 	}
@@ -0,0 +1,271 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/keenchase/auth-center/internal/config"
+	"github.com/keenchase/auth-center/internal/models"
+)
+
+var (
+	// ErrMiniProgramDataInvalid encryptedData 无法解密或不属于当前小程序
+	ErrMiniProgramDataInvalid = errors.New("小程序加密数据无效")
+
+	// ErrMiniProgramSessionNotFound 用户没有可用的小程序 session_key（未通过小程序登录）
+	ErrMiniProgramSessionNotFound = errors.New("小程序会话不存在，请重新登录")
+)
+
+// miniProgramDataMaxAge 水印时间戳与本机时间允许的最大偏差：encryptedData 由小程序取得后立即提交，超出视为重放
+const miniProgramDataMaxAge = 5 * time.Minute
+
+func init() {
+	RegisterIdentityProvider(&miniProgramProvider{})
+}
+
+// Code2SessionResponse 小程序 jscode2session 响应
+type Code2SessionResponse struct {
+	OpenID     string `json:"openid"`
+	SessionKey string `json:"session_key"`
+	UnionID    string `json:"unionid"`
+}
+
+// miniProgramProvider 微信小程序登录（wx.login 的 code 经 jscode2session 换取 openid 与 session_key）
+// 小程序没有网页授权页，ProviderToken.AccessToken 为 session_key
+type miniProgramProvider struct{}
+
+// Name 登录方式标识
+func (p *miniProgramProvider) Name() string {
+	return models.LoginMethodWeChatMini
+}
+
+// AppID 当前租户配置的小程序 AppID
+func (p *miniProgramProvider) AppID(cfg *config.Config) string {
+	return cfg.WeChatMiniAppID
+}
+
+// AuthURL 小程序在客户端调用 wx.login 获取 code，没有授权页
+func (p *miniProgramProvider) AuthURL(cfg *config.Config, redirectURI, state string) string {
+	return ""
+}
+
+// ExchangeCode 调用 jscode2session 换取 openid、session_key（绑定开放平台时同时返回 unionid）
+func (p *miniProgramProvider) ExchangeCode(ctx context.Context, cfg *config.Config, code string) (*ProviderToken, error) {
+	params := url.Values{}
+	params.Set("appid", cfg.WeChatMiniAppID)
+	params.Set("secret", cfg.WeChatMiniSecret)
+	params.Set("js_code", code)
+	params.Set("grant_type", "authorization_code")
+
+	var result Code2SessionResponse
+	if err := wechatAPI.GetOnce(ctx, "/sns/jscode2session", params, &result); err != nil {
+		return nil, err
+	}
+
+	return &ProviderToken{
+		AccessToken: result.SessionKey,
+		Subject:     result.OpenID,
+		UnionID:     result.UnionID,
+	}, nil
+}
+
+// FetchIdentity 小程序没有服务端资料接口，昵称、头像等只能通过 encryptedData 获取
+func (p *miniProgramProvider) FetchIdentity(ctx context.Context, cfg *config.Config, token *ProviderToken) (*ExternalIdentity, error) {
+	return &ExternalIdentity{
+		Provider:    "wechat",
+		AppID:       cfg.WeChatMiniAppID,
+		Subject:     token.Subject,
+		UnionID:     token.UnionID,
+		AccountType: "miniapp",
+	}, nil
+}
+
+// MiniProgramLoginInput 小程序登录参数
+type MiniProgramLoginInput struct {
+	Code          string // wx.login 返回的 code
+	EncryptedData string // 可选，wx.getUserInfo 等返回的加密数据，jscode2session 未返回 unionid 时用于获取
+	IV            string
+}
+
+// LoginMiniProgram 小程序登录：jscode2session → 关联账号 → 保存 session_key → 更新最后登录时间（不签发令牌）
+// jscode2session 未返回 unionid 时，尝试用本次的 session_key 解密 encryptedData；仍然没有时只按 openid 关联账号
+func LoginMiniProgram(ctx context.Context, db *gorm.DB, cfg *config.Config, input MiniProgramLoginInput) (*models.User, error) {
+	provider := &miniProgramProvider{}
+	if provider.AppID(cfg) == "" {
+		return nil, fmt.Errorf("%w：%s", ErrIdentityProviderNotConfigured, provider.Name())
+	}
+
+	token, err := exchangeProviderCode(ctx, cfg, provider, input.Code)
+	if err != nil {
+		return nil, err
+	}
+	identity, err := provider.FetchIdentity(ctx, cfg, token)
+	if err != nil {
+		return nil, fmt.Errorf("获取用户资料失败: %w", err)
+	}
+
+	if input.EncryptedData != "" {
+		data, err := DecryptMiniProgramData(cfg.WeChatMiniAppID, token.AccessToken, input.EncryptedData, input.IV)
+		if err != nil {
+			return nil, err
+		}
+		applyMiniProgramProfile(identity, data)
+	}
+
+	user, err := LinkExternalIdentity(db, cfg, identity)
+	if err != nil {
+		return nil, fmt.Errorf("获取或创建用户失败: %w", err)
+	}
+
+	if err := saveMiniProgramSessionKey(db, cfg, identity, token.AccessToken); err != nil {
+		return nil, err
+	}
+
+	if err := UpdateLastLogin(db, user.UserID); err != nil {
+		// 记录错误但不中断登录流程
+		log.Printf("警告: 更新最后登录时间失败: %v", err)
+	}
+	return user, nil
+}
+
+// DecryptUserMiniProgramData 用用户最近一次小程序登录的 session_key 解密 encryptedData
+// 解密结果包含 unionId 时补全用户的 UnionID，包含昵称、头像时更新小程序账户资料
+func DecryptUserMiniProgramData(db *gorm.DB, cfg *config.Config, userID, encryptedData, iv string) (map[string]interface{}, error) {
+	if cfg.WeChatMiniAppID == "" {
+		return nil, fmt.Errorf("%w：%s", ErrIdentityProviderNotConfigured, models.LoginMethodWeChatMini)
+	}
+
+	var account models.UserAccount
+	err := db.Where("tenant_id = ? AND user_id = ? AND provider = ? AND app_id = ?",
+		cfg.TenantID, userID, "wechat", cfg.WeChatMiniAppID).
+		First(&account).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) || err == nil && account.SessionKey == "" {
+		return nil, ErrMiniProgramSessionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询用户账户失败: %w", err)
+	}
+
+	box := DefaultSecretBox()
+	if box == nil {
+		return nil, errors.New("静态加密未初始化")
+	}
+	sessionKey, err := box.Open(account.SessionKey, sessionKeyAAD(&account))
+	if err != nil {
+		return nil, fmt.Errorf("解密 session_key 失败: %w", err)
+	}
+
+	data, err := DecryptMiniProgramData(cfg.WeChatMiniAppID, string(sessionKey), encryptedData, iv)
+	if err != nil {
+		return nil, err
+	}
+
+	identity := &ExternalIdentity{}
+	applyMiniProgramProfile(identity, data)
+	if err := updateAccountProfile(db, &account, identity); err != nil {
+		return nil, err
+	}
+	if identity.UnionID != "" {
+		if err := attachUnionID(db, cfg.TenantID, userID, identity.UnionID); err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+// DecryptMiniProgramData 解密小程序 encryptedData（AES-128-CBC，密钥为 session_key），并校验水印中的 AppID 与时间戳
+func DecryptMiniProgramData(appID, sessionKey, encryptedData, iv string) (map[string]interface{}, error) {
+	key, err := base64.StdEncoding.DecodeString(sessionKey)
+	if err != nil || len(key) != aes.BlockSize {
+		return nil, fmt.Errorf("%w：session_key 格式错误", ErrMiniProgramDataInvalid)
+	}
+	ivBytes, err := base64.StdEncoding.DecodeString(iv)
+	if err != nil || len(ivBytes) != aes.BlockSize {
+		return nil, fmt.Errorf("%w：iv 格式错误", ErrMiniProgramDataInvalid)
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(encryptedData)
+	if err != nil || len(ciphertext) == 0 || len(ciphertext)%aes.BlockSize != 0 {
+		return nil, fmt.Errorf("%w：encryptedData 格式错误", ErrMiniProgramDataInvalid)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	plaintext := make([]byte, len(ciphertext))
+	cipher.NewCBCDecrypter(block, ivBytes).CryptBlocks(plaintext, ciphertext)
+
+	// PKCS#7 填充
+	padding := int(plaintext[len(plaintext)-1])
+	if padding == 0 || padding > aes.BlockSize || !bytes.Equal(plaintext[len(plaintext)-padding:], bytes.Repeat([]byte{byte(padding)}, padding)) {
+		return nil, fmt.Errorf("%w：解密失败", ErrMiniProgramDataInvalid)
+	}
+	plaintext = plaintext[:len(plaintext)-padding]
+
+	var data map[string]interface{}
+	if err := json.Unmarshal(plaintext, &data); err != nil {
+		return nil, fmt.Errorf("%w：解密结果不是 JSON", ErrMiniProgramDataInvalid)
+	}
+
+	watermark, _ := data["watermark"].(map[string]interface{})
+	if GetStringValue(watermark, "appid") != appID {
+		return nil, fmt.Errorf("%w：数据不属于当前小程序", ErrMiniProgramDataInvalid)
+	}
+	timestamp, _ := watermark["timestamp"].(float64)
+	if skew := time.Since(time.Unix(int64(timestamp), 0)); timestamp <= 0 || skew > miniProgramDataMaxAge || skew < -miniProgramDataMaxAge {
+		return nil, fmt.Errorf("%w：数据已过期，请重新获取", ErrMiniProgramDataInvalid)
+	}
+	return data, nil
+}
+
+// applyMiniProgramProfile 用解密数据补全身份中的 UnionID、昵称和头像
+func applyMiniProgramProfile(identity *ExternalIdentity, data map[string]interface{}) {
+	if unionID := GetStringValue(data, "unionId"); unionID != "" && identity.UnionID == "" {
+		identity.UnionID = unionID
+	}
+	if nickname := GetStringValue(data, "nickName"); nickname != "" {
+		identity.Nickname = nickname
+	}
+	if avatarURL := GetStringValue(data, "avatarUrl"); avatarURL != "" {
+		identity.AvatarURL = avatarURL
+	}
+}
+
+// saveMiniProgramSessionKey 加密保存小程序账户最新的 session_key（每次 wx.login 后旧的 session_key 失效）
+func saveMiniProgramSessionKey(db *gorm.DB, cfg *config.Config, identity *ExternalIdentity, sessionKey string) error {
+	var account models.UserAccount
+	if err := db.Where("tenant_id = ? AND provider = ? AND app_id = ? AND open_id = ?",
+		cfg.TenantID, identity.Provider, identity.AppID, identity.Subject).
+		First(&account).Error; err != nil {
+		return fmt.Errorf("查询用户账户失败: %w", err)
+	}
+
+	box := DefaultSecretBox()
+	if box == nil {
+		return errors.New("静态加密未初始化")
+	}
+	sealed, err := box.Seal([]byte(sessionKey), sessionKeyAAD(&account))
+	if err != nil {
+		return err
+	}
+	if err := db.Model(&account).Update("session_key", sealed).Error; err != nil {
+		return fmt.Errorf("保存 session_key 失败: %w", err)
+	}
+	return nil
+}
+
+// sessionKeyAAD 把 session_key 密文绑定到所属账户
+func sessionKeyAAD(account *models.UserAccount) string {
+	return "user_account:" + account.ID + ":session_key"
+}
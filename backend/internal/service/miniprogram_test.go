@@ -0,0 +1,94 @@
+package service
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+var (
+	testMiniSessionKey = base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{3}, 16))
+	testMiniIV         = base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{5}, 16))
+)
+
+// sealMiniProgramData 按小程序 encryptedData 的格式加密 plaintext，padding 为追加的填充字节
+func sealMiniProgramData(t *testing.T, plaintext, padding []byte) string {
+	t.Helper()
+	key, _ := base64.StdEncoding.DecodeString(testMiniSessionKey)
+	iv, _ := base64.StdEncoding.DecodeString(testMiniIV)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		t.Fatalf("aes.NewCipher: %v", err)
+	}
+	data := append(append([]byte{}, plaintext...), padding...)
+	if len(data)%aes.BlockSize != 0 {
+		t.Fatalf("填充后长度 %d 不是块大小的整数倍", len(data))
+	}
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(data, data)
+	return base64.StdEncoding.EncodeToString(data)
+}
+
+// miniProgramPlaintext 水印时间为 at 的解密结果
+func miniProgramPlaintext(at time.Time) []byte {
+	return []byte(fmt.Sprintf(`{"phoneNumber":"13800000000","watermark":{"appid":"wx-mini","timestamp":%d}}`, at.Unix()))
+}
+
+// pkcs7 plaintext 的 PKCS#7 填充
+func pkcs7(plaintext []byte) []byte {
+	n := aes.BlockSize - len(plaintext)%aes.BlockSize
+	return bytes.Repeat([]byte{byte(n)}, n)
+}
+
+func TestDecryptMiniProgramData(t *testing.T) {
+	plaintext := miniProgramPlaintext(time.Now())
+	encrypted := sealMiniProgramData(t, plaintext, pkcs7(plaintext))
+
+	data, err := DecryptMiniProgramData("wx-mini", testMiniSessionKey, encrypted, testMiniIV)
+	if err != nil {
+		t.Fatalf("DecryptMiniProgramData: %v", err)
+	}
+	if GetStringValue(data, "phoneNumber") != "13800000000" {
+		t.Fatalf("data = %v", data)
+	}
+}
+
+func TestDecryptMiniProgramDataRejects(t *testing.T) {
+	plaintext := miniProgramPlaintext(time.Now())
+	valid := sealMiniProgramData(t, plaintext, pkcs7(plaintext))
+	stale := miniProgramPlaintext(time.Now().Add(-miniProgramDataMaxAge - time.Minute))
+	future := miniProgramPlaintext(time.Now().Add(miniProgramDataMaxAge + time.Minute))
+	noTimestamp := []byte(`{"watermark":{"appid":"wx-mini"}}`)
+	badPadding := pkcs7(plaintext)
+	badPadding[0] ^= 0xff
+
+	tests := []struct {
+		name       string
+		appID      string
+		sessionKey string
+		encrypted  string
+		iv         string
+	}{
+		{"PKCS#7 填充错误", "wx-mini", testMiniSessionKey, sealMiniProgramData(t, plaintext, badPadding), testMiniIV},
+		{"水印 AppID 不符", "wx-other", testMiniSessionKey, valid, testMiniIV},
+		{"iv 长度错误", "wx-mini", testMiniSessionKey, valid, base64.StdEncoding.EncodeToString(make([]byte, 8))},
+		{"iv 不是 base64", "wx-mini", testMiniSessionKey, valid, "not base64"},
+		{"session_key 长度错误", "wx-mini", base64.StdEncoding.EncodeToString(make([]byte, 32)), valid, testMiniIV},
+		{"密文不是块大小的整数倍", "wx-mini", testMiniSessionKey, base64.StdEncoding.EncodeToString(make([]byte, 20)), testMiniIV},
+		{"水印时间戳过旧", "wx-mini", testMiniSessionKey, sealMiniProgramData(t, stale, pkcs7(stale)), testMiniIV},
+		{"水印时间戳超前", "wx-mini", testMiniSessionKey, sealMiniProgramData(t, future, pkcs7(future)), testMiniIV},
+		{"水印没有时间戳", "wx-mini", testMiniSessionKey, sealMiniProgramData(t, noTimestamp, pkcs7(noTimestamp)), testMiniIV},
+		{"没有水印", "wx-mini", testMiniSessionKey, sealMiniProgramData(t, []byte(`{"a":1}`), pkcs7([]byte(`{"a":1}`))), testMiniIV},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := DecryptMiniProgramData(tt.appID, tt.sessionKey, tt.encrypted, tt.iv); !errors.Is(err, ErrMiniProgramDataInvalid) {
+				t.Fatalf("err = %v, want ErrMiniProgramDataInvalid", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,370 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/keenchase/auth-center/internal/config"
+	"github.com/keenchase/auth-center/internal/models"
+)
+
+const (
+	// mpPushMaxSkew 推送时间戳与本机时间允许的最大偏差，超出视为重放
+	mpPushMaxSkew = 5 * time.Minute
+
+	// mpMessageRetention 已处理推送的保留时间：时间戳最多偏差 mpPushMaxSkew，两倍窗口后同一推送必然因时间戳被拒绝
+	mpMessageRetention = 2 * mpPushMaxSkew
+)
+
+// ErrMPMessageInvalid 公众号推送签名错误、无法解密或不属于当前公众号
+var ErrMPMessageInvalid = errors.New("公众号消息无效")
+
+func init() {
+	// 扫码关注登录在前：扫码后关注的新用户由扫码登录创建公众号账户，随后才能记录关注状态
+	RegisterMPEventHandler(handleQRLoginEvent, "subscribe", "SCAN")
+	RegisterMPEventHandler(handleFollowEvent, "subscribe", "unsubscribe", "SCAN")
+}
+
+// MPMessage 公众号推送的消息与事件（明文 XML）
+type MPMessage struct {
+	XMLName      xml.Name `xml:"xml"`
+	ToUserName   string   `xml:"ToUserName"`   // 公众号原始 ID
+	FromUserName string   `xml:"FromUserName"` // 粉丝 openid
+	CreateTime   int64    `xml:"CreateTime"`
+	MsgType      string   `xml:"MsgType"`
+	MsgID        int64    `xml:"MsgId"`    // 普通消息的消息 ID，事件没有
+	Event        string   `xml:"Event"`    // subscribe / unsubscribe / SCAN ...
+	EventKey     string   `xml:"EventKey"` // 扫带参数二维码时为场景值（关注事件带 qrscene_ 前缀）
+	Ticket       string   `xml:"Ticket"`
+}
+
+// MPPushParams 公众号推送 URL 上的签名参数
+type MPPushParams struct {
+	Signature    string // sha1(sort(token, timestamp, nonce))
+	Timestamp    string
+	Nonce        string
+	EncryptType  string // 安全模式 / 兼容模式为 aes，明文模式为空或 raw
+	MsgSignature string // 安全模式：sha1(sort(token, timestamp, nonce, Encrypt))
+}
+
+// Encrypted 是否为加密推送（安全模式 / 兼容模式），加密推送的被动回复也需要加密
+func (p MPPushParams) Encrypted() bool {
+	return p.EncryptType == "aes"
+}
+
+// CheckMPSignature 校验公众号推送的签名：sha1(sort(token, timestamp, nonce))
+func CheckMPSignature(token, signature, timestamp, nonce string) bool {
+	if token == "" || signature == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(mpSignature(token, timestamp, nonce)), []byte(signature)) == 1
+}
+
+// ReadMPMessage 校验签名与时间戳并解析公众号推送；加密推送校验 msg_signature 后解密（AES-256-CBC），并校验其中的 AppID
+// signature 不覆盖消息体，因此配置了 EncodingAESKey 时只接受加密推送（msg_signature 覆盖密文）
+func ReadMPMessage(cfg *config.Config, params MPPushParams, body []byte) (*MPMessage, error) {
+	if !CheckMPSignature(cfg.WeChatMPToken, params.Signature, params.Timestamp, params.Nonce) {
+		return nil, fmt.Errorf("%w：签名错误", ErrMPMessageInvalid)
+	}
+	timestamp, err := strconv.ParseInt(params.Timestamp, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w：时间戳无效", ErrMPMessageInvalid)
+	}
+	if skew := time.Since(time.Unix(timestamp, 0)); skew > mpPushMaxSkew || skew < -mpPushMaxSkew {
+		return nil, fmt.Errorf("%w：时间戳超出有效范围", ErrMPMessageInvalid)
+	}
+	if cfg.WeChatMPEncodingAESKey != "" && !params.Encrypted() {
+		return nil, fmt.Errorf("%w：已配置消息加解密密钥，拒绝未加密的推送", ErrMPMessageInvalid)
+	}
+
+	if params.Encrypted() {
+		var envelope struct {
+			Encrypt string `xml:"Encrypt"`
+		}
+		if err := xml.Unmarshal(body, &envelope); err != nil || envelope.Encrypt == "" {
+			return nil, fmt.Errorf("%w：缺少密文", ErrMPMessageInvalid)
+		}
+		expected := mpSignature(cfg.WeChatMPToken, params.Timestamp, params.Nonce, envelope.Encrypt)
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(params.MsgSignature)) != 1 {
+			return nil, fmt.Errorf("%w：消息签名错误", ErrMPMessageInvalid)
+		}
+		plaintext, err := decryptMPMessage(cfg, envelope.Encrypt)
+		if err != nil {
+			return nil, err
+		}
+		body = plaintext
+	}
+
+	var msg MPMessage
+	if err := xml.Unmarshal(body, &msg); err != nil {
+		return nil, fmt.Errorf("%w：解析 XML 失败", ErrMPMessageInvalid)
+	}
+	return &msg, nil
+}
+
+// MPEventHandler 公众号事件订阅者，返回被动回复的文本内容（为空表示不回复）
+// 返回错误时推送不记为已处理，微信重试时同一事件的订阅者会再次执行，订阅者须保证幂等
+type MPEventHandler func(ctx context.Context, db *gorm.DB, cfg *config.Config, msg *MPMessage) (string, error)
+
+var (
+	mpEventHandlersMu sync.RWMutex
+	mpEventHandlers   = make(map[string][]MPEventHandler)
+)
+
+// RegisterMPEventHandler 订阅公众号事件（subscribe / unsubscribe / SCAN ...，通常在 init 中调用）
+// 同一事件的订阅者按注册顺序执行
+func RegisterMPEventHandler(handler MPEventHandler, events ...string) {
+	mpEventHandlersMu.Lock()
+	defer mpEventHandlersMu.Unlock()
+	for _, event := range events {
+		mpEventHandlers[event] = append(mpEventHandlers[event], handler)
+	}
+}
+
+// HandleMPMessage 处理公众号推送，返回被动回复的文本内容（为空表示不回复）
+// 同一推送（微信重试或重放）只处理一次：已处理过的推送直接返回空回复；订阅者全部成功后才记录为已处理，
+// 有订阅者失败时返回错误，不记录，由微信重试
+func HandleMPMessage(ctx context.Context, db *gorm.DB, cfg *config.Config, msg *MPMessage) (string, error) {
+	key := mpMessageKey(cfg, msg)
+	var count int64
+	if err := db.Model(&models.WeChatMPMessage{}).
+		Where("message_key = ? AND expires_at > ?", key, time.Now()).
+		Count(&count).Error; err != nil {
+		return "", err
+	}
+	if count > 0 {
+		return "", nil
+	}
+
+	reply, err := dispatchMPMessage(ctx, db, cfg, msg)
+	if err != nil {
+		return "", err
+	}
+
+	record := models.WeChatMPMessage{MessageKey: key, ExpiresAt: time.Now().Add(mpMessageRetention)}
+	err = db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "message_key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"expires_at"}),
+	}).Create(&record).Error
+	if err != nil {
+		// 订阅者已处理成功，记录失败只影响去重，仍然回复
+		log.Printf("警告: 记录已处理的公众号推送失败: %v", err)
+	}
+	return reply, nil
+}
+
+// mpMessageKey 推送的去重键：普通消息按 MsgId，事件按 FromUserName + CreateTime（微信文档推荐的排重方式）
+func mpMessageKey(cfg *config.Config, msg *MPMessage) string {
+	if msg.MsgID != 0 {
+		return fmt.Sprintf("%s:msg:%d", cfg.WeChatMPAppID, msg.MsgID)
+	}
+	return fmt.Sprintf("%s:event:%s:%d", cfg.WeChatMPAppID, msg.FromUserName, msg.CreateTime)
+}
+
+// dispatchMPMessage 把公众号事件分发给订阅者，返回被动回复的文本内容（取第一个非空的回复）
+// 某个订阅者失败时其余订阅者仍然执行，返回所有错误
+func dispatchMPMessage(ctx context.Context, db *gorm.DB, cfg *config.Config, msg *MPMessage) (string, error) {
+	if msg.MsgType != "event" {
+		return "", nil
+	}
+
+	mpEventHandlersMu.RLock()
+	handlers := mpEventHandlers[msg.Event]
+	mpEventHandlersMu.RUnlock()
+
+	var reply string
+	var errs []error
+	for _, handler := range handlers {
+		content, err := handler(ctx, db, cfg, msg)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		if content != "" && reply == "" {
+			reply = content
+		}
+	}
+	return reply, errors.Join(errs...)
+}
+
+// MPTextReply 构造被动回复的文本消息 XML
+func MPTextReply(msg *MPMessage, content string) ([]byte, error) {
+	reply := struct {
+		XMLName      xml.Name `xml:"xml"`
+		ToUserName   cdata    `xml:"ToUserName"`
+		FromUserName cdata    `xml:"FromUserName"`
+		CreateTime   int64    `xml:"CreateTime"`
+		MsgType      cdata    `xml:"MsgType"`
+		Content      cdata    `xml:"Content"`
+	}{
+		ToUserName:   cdata{msg.FromUserName},
+		FromUserName: cdata{msg.ToUserName},
+		CreateTime:   time.Now().Unix(),
+		MsgType:      cdata{"text"},
+		Content:      cdata{content},
+	}
+	return xml.Marshal(reply)
+}
+
+// SealMPReply 加密被动回复（加密推送必须以加密消息回复）
+func SealMPReply(cfg *config.Config, params MPPushParams, reply []byte) ([]byte, error) {
+	encrypted, err := encryptMPMessage(cfg, reply)
+	if err != nil {
+		return nil, err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	envelope := struct {
+		XMLName      xml.Name `xml:"xml"`
+		Encrypt      cdata    `xml:"Encrypt"`
+		MsgSignature cdata    `xml:"MsgSignature"`
+		TimeStamp    string   `xml:"TimeStamp"`
+		Nonce        cdata    `xml:"Nonce"`
+	}{
+		Encrypt:      cdata{encrypted},
+		MsgSignature: cdata{mpSignature(cfg.WeChatMPToken, timestamp, params.Nonce, encrypted)},
+		TimeStamp:    timestamp,
+		Nonce:        cdata{params.Nonce},
+	}
+	return xml.Marshal(envelope)
+}
+
+// cdata 以 CDATA 输出的 XML 文本
+type cdata struct {
+	Value string `xml:",cdata"`
+}
+
+// mpSignature 公众号签名：参数字典序排序后拼接取 sha1
+func mpSignature(parts ...string) string {
+	sorted := append([]string(nil), parts...)
+	sort.Strings(sorted)
+	sum := sha1.Sum([]byte(strings.Join(sorted, "")))
+	return hex.EncodeToString(sum[:])
+}
+
+// mpAESKey 由 EncodingAESKey（43 位 Base64）得到 32 字节 AES 密钥
+func mpAESKey(encodingAESKey string) ([]byte, error) {
+	if len(encodingAESKey) != 43 {
+		return nil, fmt.Errorf("%w：未配置消息加解密密钥", ErrMPMessageInvalid)
+	}
+	key, err := base64.StdEncoding.DecodeString(encodingAESKey + "=")
+	if err != nil || len(key) != 32 {
+		return nil, fmt.Errorf("%w：消息加解密密钥格式错误", ErrMPMessageInvalid)
+	}
+	return key, nil
+}
+
+// validMPEncodingAESKey 校验 EncodingAESKey 的格式
+func validMPEncodingAESKey(encodingAESKey string) bool {
+	_, err := mpAESKey(encodingAESKey)
+	return err == nil
+}
+
+// decryptMPMessage 解密推送密文：明文为 16 字节随机串 + 4 字节消息长度（网络字节序）+ 消息 + AppID，PKCS#7 按 32 字节填充
+func decryptMPMessage(cfg *config.Config, encrypted string) ([]byte, error) {
+	key, err := mpAESKey(cfg.WeChatMPEncodingAESKey)
+	if err != nil {
+		return nil, err
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(encrypted)
+	if err != nil || len(ciphertext) == 0 || len(ciphertext)%aes.BlockSize != 0 {
+		return nil, fmt.Errorf("%w：密文格式错误", ErrMPMessageInvalid)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	plaintext := make([]byte, len(ciphertext))
+	cipher.NewCBCDecrypter(block, key[:aes.BlockSize]).CryptBlocks(plaintext, ciphertext)
+
+	padding := int(plaintext[len(plaintext)-1])
+	if padding == 0 || padding > 32 || padding > len(plaintext) ||
+		!bytes.Equal(plaintext[len(plaintext)-padding:], bytes.Repeat([]byte{byte(padding)}, padding)) {
+		return nil, fmt.Errorf("%w：解密失败", ErrMPMessageInvalid)
+	}
+	plaintext = plaintext[:len(plaintext)-padding]
+
+	if len(plaintext) < 20 {
+		return nil, fmt.Errorf("%w：解密失败", ErrMPMessageInvalid)
+	}
+	length := binary.BigEndian.Uint32(plaintext[16:20])
+	if uint64(length) > uint64(len(plaintext)-20) {
+		return nil, fmt.Errorf("%w：解密失败", ErrMPMessageInvalid)
+	}
+	message, appID := plaintext[20:20+length], plaintext[20+length:]
+	if string(appID) != cfg.WeChatMPAppID {
+		return nil, fmt.Errorf("%w：消息不属于当前公众号", ErrMPMessageInvalid)
+	}
+	return message, nil
+}
+
+// encryptMPMessage 加密被动回复，格式与推送密文相同
+func encryptMPMessage(cfg *config.Config, message []byte) (string, error) {
+	key, err := mpAESKey(cfg.WeChatMPEncodingAESKey)
+	if err != nil {
+		return "", err
+	}
+
+	plaintext := make([]byte, 20, 20+len(message)+len(cfg.WeChatMPAppID)+32)
+	if _, err := rand.Read(plaintext[:16]); err != nil {
+		return "", err
+	}
+	binary.BigEndian.PutUint32(plaintext[16:20], uint32(len(message)))
+	plaintext = append(plaintext, message...)
+	plaintext = append(plaintext, cfg.WeChatMPAppID...)
+	padding := 32 - len(plaintext)%32
+	plaintext = append(plaintext, bytes.Repeat([]byte{byte(padding)}, padding)...)
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", err
+	}
+	ciphertext := make([]byte, len(plaintext))
+	cipher.NewCBCEncrypter(block, key[:aes.BlockSize]).CryptBlocks(ciphertext, plaintext)
+	return base64.StdEncoding.EncodeToString(ciphertext), nil
+}
+
+// handleFollowEvent 公众号事件订阅者：记录公众号账户的关注状态（SCAN 说明用户已关注）
+// 从未登录过的粉丝没有账户，不做记录
+func handleFollowEvent(ctx context.Context, db *gorm.DB, cfg *config.Config, msg *MPMessage) (string, error) {
+	eventTime := time.Now()
+	if msg.CreateTime > 0 {
+		eventTime = time.Unix(msg.CreateTime, 0)
+	}
+
+	updates := map[string]interface{}{"subscribed": true}
+	switch msg.Event {
+	case "subscribe":
+		updates["subscribed_at"] = eventTime
+	case "unsubscribe":
+		updates["subscribed"] = false
+	}
+
+	err := db.Model(&models.UserAccount{}).
+		Where("tenant_id = ? AND provider = ? AND app_id = ? AND open_id = ?",
+			cfg.TenantID, "wechat", cfg.WeChatMPAppID, msg.FromUserName).
+		Updates(updates).Error
+	if err != nil {
+		return "", fmt.Errorf("更新公众号关注状态失败: %w", err)
+	}
+	return "", nil
+}
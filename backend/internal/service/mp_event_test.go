@@ -0,0 +1,247 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/keenchase/auth-center/internal/config"
+)
+
+const testMPMessage = `<xml><ToUserName><![CDATA[gh_test]]></ToUserName><FromUserName><![CDATA[openid-1]]></FromUserName>` +
+	`<CreateTime>1700000000</CreateTime><MsgType><![CDATA[event]]></MsgType><Event><![CDATA[subscribe]]></Event></xml>`
+
+// testMPConfig 测试用公众号配置，withAES 为 true 时为安全模式
+func testMPConfig(withAES bool) *config.Config {
+	cfg := &config.Config{WeChatMPAppID: "wx-test", WeChatMPToken: "mp-token"}
+	if withAES {
+		cfg.WeChatMPEncodingAESKey = base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{7}, 32))[:43]
+	}
+	return cfg
+}
+
+// testMPPush 构造一次签名正确的推送，nonce 每次不同
+func testMPPush(t *testing.T, cfg *config.Config, at time.Time, encrypted bool) (MPPushParams, []byte) {
+	t.Helper()
+	params := MPPushParams{
+		Timestamp: strconv.FormatInt(at.Unix(), 10),
+		Nonce:     fmt.Sprintf("%d", time.Now().UnixNano()),
+	}
+	params.Signature = mpSignature(cfg.WeChatMPToken, params.Timestamp, params.Nonce)
+	if !encrypted {
+		return params, []byte(testMPMessage)
+	}
+
+	ciphertext, err := encryptMPMessage(cfg, []byte(testMPMessage))
+	if err != nil {
+		t.Fatalf("encryptMPMessage: %v", err)
+	}
+	params.EncryptType = "aes"
+	params.MsgSignature = mpSignature(cfg.WeChatMPToken, params.Timestamp, params.Nonce, ciphertext)
+	return params, []byte("<xml><ToUserName><![CDATA[gh_test]]></ToUserName><Encrypt><![CDATA[" + ciphertext + "]]></Encrypt></xml>")
+}
+
+func TestMPMessageEncryptDecrypt(t *testing.T) {
+	cfg := testMPConfig(true)
+	ciphertext, err := encryptMPMessage(cfg, []byte(testMPMessage))
+	if err != nil {
+		t.Fatalf("encryptMPMessage: %v", err)
+	}
+	if raw, _ := base64.StdEncoding.DecodeString(ciphertext); len(raw)%32 != 0 {
+		t.Fatalf("密文长度 %d 应按 32 字节填充", len(raw))
+	}
+
+	plaintext, err := decryptMPMessage(cfg, ciphertext)
+	if err != nil || string(plaintext) != testMPMessage {
+		t.Fatalf("decryptMPMessage = %q, %v", plaintext, err)
+	}
+
+	other := testMPConfig(true)
+	other.WeChatMPAppID = "wx-other"
+	if _, err := decryptMPMessage(other, ciphertext); !errors.Is(err, ErrMPMessageInvalid) {
+		t.Fatalf("其他公众号的消息 err = %v", err)
+	}
+
+	raw, _ := base64.StdEncoding.DecodeString(ciphertext)
+	raw[len(raw)-1] ^= 0xff
+	if _, err := decryptMPMessage(cfg, base64.StdEncoding.EncodeToString(raw)); !errors.Is(err, ErrMPMessageInvalid) {
+		t.Fatalf("篡改的密文 err = %v", err)
+	}
+	if _, err := decryptMPMessage(cfg, "not base64"); !errors.Is(err, ErrMPMessageInvalid) {
+		t.Fatalf("格式错误的密文 err = %v", err)
+	}
+}
+
+func TestMPAESKey(t *testing.T) {
+	if !validMPEncodingAESKey(testMPConfig(true).WeChatMPEncodingAESKey) {
+		t.Fatal("43 位 EncodingAESKey 应有效")
+	}
+	for _, key := range []string{"", "short", testMPConfig(true).WeChatMPEncodingAESKey + "A"} {
+		if validMPEncodingAESKey(key) {
+			t.Fatalf("EncodingAESKey %q 应无效", key)
+		}
+	}
+}
+
+func TestReadMPMessage(t *testing.T) {
+	cfg := testMPConfig(false)
+	params, body := testMPPush(t, cfg, time.Now(), false)
+
+	msg, err := ReadMPMessage(cfg, params, body)
+	if err != nil {
+		t.Fatalf("ReadMPMessage: %v", err)
+	}
+	if msg.FromUserName != "openid-1" || msg.Event != "subscribe" {
+		t.Fatalf("msg = %+v", msg)
+	}
+}
+
+// testMPEvent 测试专用事件，订阅者按 testMPEventErr 返回结果并计数
+const testMPEvent = "test_dedup"
+
+var (
+	testMPEventCalls int
+	testMPEventErr   error
+)
+
+func init() {
+	RegisterMPEventHandler(func(ctx context.Context, db *gorm.DB, cfg *config.Config, msg *MPMessage) (string, error) {
+		testMPEventCalls++
+		return "ok", testMPEventErr
+	}, testMPEvent)
+}
+
+func TestMPMessageKey(t *testing.T) {
+	cfg := testMPConfig(false)
+	event := &MPMessage{FromUserName: "openid-1", CreateTime: 1700000000, MsgType: "event", Event: "subscribe"}
+	if got := mpMessageKey(cfg, event); got != "wx-test:event:openid-1:1700000000" {
+		t.Fatalf("事件去重键 = %q", got)
+	}
+	text := &MPMessage{FromUserName: "openid-1", CreateTime: 1700000000, MsgType: "text", MsgID: 42}
+	if got := mpMessageKey(cfg, text); got != "wx-test:msg:42" {
+		t.Fatalf("消息去重键 = %q", got)
+	}
+}
+
+func TestHandleMPMessageOnce(t *testing.T) {
+	db := testDB(t)
+	cfg := testMPConfig(false)
+	msg := &MPMessage{FromUserName: "openid-dedup", CreateTime: time.Now().Unix(), MsgType: "event", Event: testMPEvent}
+	testMPEventCalls, testMPEventErr = 0, errors.New("临时错误")
+	t.Cleanup(func() { testMPEventErr = nil })
+
+	// 订阅者失败时不记录，微信重试时再次处理
+	if _, err := HandleMPMessage(context.Background(), db, cfg, msg); err == nil {
+		t.Fatal("订阅者失败时应返回错误")
+	}
+	testMPEventErr = nil
+	reply, err := HandleMPMessage(context.Background(), db, cfg, msg)
+	if err != nil || reply != "ok" {
+		t.Fatalf("重试 = %q, %v", reply, err)
+	}
+	if testMPEventCalls != 2 {
+		t.Fatalf("订阅者执行 %d 次, want 2", testMPEventCalls)
+	}
+
+	// 处理成功后同一推送不再处理
+	reply, err = HandleMPMessage(context.Background(), db, cfg, msg)
+	if err != nil || reply != "" {
+		t.Fatalf("重复推送 = %q, %v", reply, err)
+	}
+	if testMPEventCalls != 2 {
+		t.Fatalf("重复推送后订阅者执行 %d 次, want 2", testMPEventCalls)
+	}
+}
+
+func TestReadMPMessageRejects(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(cfg *config.Config, params *MPPushParams) []byte
+	}{
+		{"签名错误", func(cfg *config.Config, params *MPPushParams) []byte {
+			p, body := testMPPush(t, cfg, time.Now(), false)
+			p.Signature = mpSignature("wrong-token", p.Timestamp, p.Nonce)
+			*params = p
+			return body
+		}},
+		{"时间戳过旧", func(cfg *config.Config, params *MPPushParams) []byte {
+			p, body := testMPPush(t, cfg, time.Now().Add(-mpPushMaxSkew-time.Minute), false)
+			*params = p
+			return body
+		}},
+		{"时间戳超前", func(cfg *config.Config, params *MPPushParams) []byte {
+			p, body := testMPPush(t, cfg, time.Now().Add(mpPushMaxSkew+time.Minute), false)
+			*params = p
+			return body
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testMPConfig(false)
+			var params MPPushParams
+			body := tt.mutate(cfg, &params)
+			if _, err := ReadMPMessage(cfg, params, body); !errors.Is(err, ErrMPMessageInvalid) {
+				t.Fatalf("err = %v", err)
+			}
+		})
+	}
+}
+
+func TestReadMPMessageEncrypted(t *testing.T) {
+	cfg := testMPConfig(true)
+
+	params, body := testMPPush(t, cfg, time.Now(), true)
+	msg, err := ReadMPMessage(cfg, params, body)
+	if err != nil || msg.FromUserName != "openid-1" {
+		t.Fatalf("ReadMPMessage = %+v, %v", msg, err)
+	}
+
+	// 配置了 EncodingAESKey 时拒绝明文推送（signature 不覆盖消息体）
+	params, body = testMPPush(t, cfg, time.Now(), false)
+	if _, err := ReadMPMessage(cfg, params, body); !errors.Is(err, ErrMPMessageInvalid) {
+		t.Fatalf("明文推送 err = %v", err)
+	}
+
+	// msg_signature 错误
+	params, body = testMPPush(t, cfg, time.Now(), true)
+	params.MsgSignature = mpSignature(cfg.WeChatMPToken, params.Timestamp, params.Nonce, "other")
+	if _, err := ReadMPMessage(cfg, params, body); !errors.Is(err, ErrMPMessageInvalid) {
+		t.Fatalf("消息签名错误 err = %v", err)
+	}
+}
+
+func TestSealMPReply(t *testing.T) {
+	cfg := testMPConfig(true)
+	params, _ := testMPPush(t, cfg, time.Now(), true)
+
+	sealed, err := SealMPReply(cfg, params, []byte("<xml>reply</xml>"))
+	if err != nil {
+		t.Fatalf("SealMPReply: %v", err)
+	}
+	// 回复与推送格式相同，可按推送的方式验签解密
+	var envelope struct {
+		Encrypt      string `xml:"Encrypt"`
+		MsgSignature string `xml:"MsgSignature"`
+		TimeStamp    string `xml:"TimeStamp"`
+		Nonce        string `xml:"Nonce"`
+	}
+	if err := xml.Unmarshal(sealed, &envelope); err != nil {
+		t.Fatalf("解析回复: %v", err)
+	}
+	if envelope.Nonce != params.Nonce ||
+		mpSignature(cfg.WeChatMPToken, envelope.TimeStamp, envelope.Nonce, envelope.Encrypt) != envelope.MsgSignature {
+		t.Fatal("回复的消息签名错误")
+	}
+	plaintext, err := decryptMPMessage(cfg, envelope.Encrypt)
+	if err != nil || string(plaintext) != "<xml>reply</xml>" {
+		t.Fatalf("解密回复 = %q, %v", plaintext, err)
+	}
+}
@@ -0,0 +1,48 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// listenNotifications 订阅 Postgres NOTIFY 通道，断线后自动重连（阻塞，需在 goroutine 中调用）
+// onConnected 在每次（重新）订阅成功后调用，用于弥补断线期间漏掉的事件
+func listenNotifications(databaseURL, channel string, onConnected func(), onNotify func(payload string)) {
+	backoff := time.Second
+	for {
+		err := listenNotificationsOnce(databaseURL, channel, func() {
+			backoff = time.Second
+			onConnected()
+		}, onNotify)
+		log.Printf("警告: %s 事件订阅中断: %v，%s 后重连", channel, err, backoff)
+		time.Sleep(backoff)
+		if backoff < time.Minute {
+			backoff *= 2
+		}
+	}
+}
+
+func listenNotificationsOnce(databaseURL, channel string, onConnected func(), onNotify func(payload string)) error {
+	ctx := context.Background()
+	conn, err := pgx.Connect(ctx, databaseURL)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(ctx)
+
+	if _, err := conn.Exec(ctx, "LISTEN "+channel); err != nil {
+		return err
+	}
+	onConnected()
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		onNotify(notification.Payload)
+	}
+}
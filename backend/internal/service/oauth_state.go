@@ -0,0 +1,74 @@
+package service
+
+import (
+	"crypto/subtle"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/keenchase/auth-center/internal/models"
+)
+
+// OAuthStateExpiration 微信登录 state 有效期
+const OAuthStateExpiration = 10 * time.Minute
+
+// ErrInvalidOAuthState state 无效、已使用、已过期或不属于当前浏览器
+var ErrInvalidOAuthState = errors.New("登录状态无效或已过期，请重新登录")
+
+// GenerateOAuthNonce 生成绑定浏览器的 nonce（写入 Cookie）
+func GenerateOAuthNonce() (string, error) {
+	return generateRandomToken(32)
+}
+
+// IssueOAuthState 签发微信登录 state
+// state 只是随机串，回调地址等信息保存在服务端，微信回传的 state 无法被篡改
+func IssueOAuthState(db *gorm.DB, nonce, flow, appID, callbackURL string) (string, error) {
+	state, err := generateRandomToken(16)
+	if err != nil {
+		return "", err
+	}
+
+	record := models.OAuthState{
+		StateHash:   hashToken(state),
+		NonceHash:   hashToken(nonce),
+		Flow:        flow,
+		AppID:       appID,
+		CallbackURL: callbackURL,
+		ExpiresAt:   time.Now().Add(OAuthStateExpiration),
+	}
+	if err := db.Create(&record).Error; err != nil {
+		return "", err
+	}
+	return state, nil
+}
+
+// ConsumeOAuthState 校验并作废 state（一次性），返回签发时绑定的信息
+// nonce 必须与签发时浏览器 Cookie 中的一致，flow / appID 必须与回调入口一致
+func ConsumeOAuthState(db *gorm.DB, state, nonce, flow, appID string) (*models.OAuthState, error) {
+	if state == "" || nonce == "" {
+		return nil, ErrInvalidOAuthState
+	}
+
+	// 先原子地作废（无论后续校验是否通过），防止被反复尝试
+	var record models.OAuthState
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("state_hash = ? AND used_at IS NULL", hashToken(state)).
+			First(&record).Error; err != nil {
+			return err
+		}
+		return tx.Model(&record).Update("used_at", time.Now()).Error
+	})
+	if err != nil {
+		return nil, ErrInvalidOAuthState
+	}
+
+	if time.Now().After(record.ExpiresAt) ||
+		subtle.ConstantTimeCompare([]byte(record.NonceHash), []byte(hashToken(nonce))) != 1 ||
+		record.Flow != flow || record.AppID != appID {
+		return nil, ErrInvalidOAuthState
+	}
+	return &record, nil
+}
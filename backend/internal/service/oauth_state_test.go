@@ -0,0 +1,73 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/keenchase/auth-center/internal/models"
+)
+
+func TestOAuthStateSingleUse(t *testing.T) {
+	db := testDB(t)
+	nonce, err := GenerateOAuthNonce()
+	if err != nil {
+		t.Fatalf("GenerateOAuthNonce: %v", err)
+	}
+	state, err := IssueOAuthState(db, nonce, "mp", "wx-app", "https://app.example.com/callback")
+	if err != nil {
+		t.Fatalf("IssueOAuthState: %v", err)
+	}
+
+	record, err := ConsumeOAuthState(db, state, nonce, "mp", "wx-app")
+	if err != nil || record.CallbackURL != "https://app.example.com/callback" {
+		t.Fatalf("ConsumeOAuthState = %v, %v", record, err)
+	}
+	if _, err := ConsumeOAuthState(db, state, nonce, "mp", "wx-app"); !errors.Is(err, ErrInvalidOAuthState) {
+		t.Fatalf("重复使用 err = %v", err)
+	}
+}
+
+func TestOAuthStateBinding(t *testing.T) {
+	db := testDB(t)
+	nonce, _ := GenerateOAuthNonce()
+
+	tests := []struct {
+		name               string
+		nonce, flow, appID string
+	}{
+		{"其他浏览器", "other-nonce", "mp", "wx-app"},
+		{"入口不一致", nonce, "open", "wx-app"},
+		{"AppID 不一致", nonce, "mp", "wx-other"},
+		{"缺少 nonce", "", "mp", "wx-app"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state, err := IssueOAuthState(db, nonce, "mp", "wx-app", "https://app.example.com/callback")
+			if err != nil {
+				t.Fatalf("IssueOAuthState: %v", err)
+			}
+			if _, err := ConsumeOAuthState(db, state, tt.nonce, tt.flow, tt.appID); !errors.Is(err, ErrInvalidOAuthState) {
+				t.Fatalf("err = %v", err)
+			}
+			// 校验失败的 state 同样作废，不能再用正确的参数重试
+			if _, err := ConsumeOAuthState(db, state, nonce, "mp", "wx-app"); !errors.Is(err, ErrInvalidOAuthState) {
+				t.Fatalf("校验失败后重试 err = %v", err)
+			}
+		})
+	}
+}
+
+func TestOAuthStateExpired(t *testing.T) {
+	db := testDB(t)
+	nonce, _ := GenerateOAuthNonce()
+	state, err := IssueOAuthState(db, nonce, "mp", "wx-app", "https://app.example.com/callback")
+	if err != nil {
+		t.Fatalf("IssueOAuthState: %v", err)
+	}
+	db.Model(&models.OAuthState{}).Where("state_hash = ?", hashToken(state)).Update("expires_at", time.Now().Add(-time.Second))
+
+	if _, err := ConsumeOAuthState(db, state, nonce, "mp", "wx-app"); !errors.Is(err, ErrInvalidOAuthState) {
+		t.Fatalf("err = %v", err)
+	}
+}
@@ -0,0 +1,321 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+
+	"github.com/keenchase/auth-center/internal/config"
+	"github.com/keenchase/auth-center/internal/models"
+)
+
+const (
+	// AuthorizeRequestExpiration 授权请求有效期（等待上游登录完成）
+	AuthorizeRequestExpiration = 10 * time.Minute
+
+	// AuthorizationCodeExpiration 授权码有效期
+	AuthorizationCodeExpiration = time.Minute
+
+	// IDTokenExpiration ID Token 有效期
+	IDTokenExpiration = time.Hour
+)
+
+// OAuthError OAuth 2.0 标准错误（RFC 6749 5.2）
+type OAuthError struct {
+	Code        string
+	Description string
+}
+
+func (e *OAuthError) Error() string {
+	return e.Code + ": " + e.Description
+}
+
+// NewOAuthError 创建 OAuth 错误
+func NewOAuthError(code, description string) *OAuthError {
+	return &OAuthError{Code: code, Description: description}
+}
+
+// GetClient 根据 client_id 获取租户内已启用的客户端
+func GetClient(db *gorm.DB, tenantID, clientID string) (*models.Client, error) {
+	var client models.Client
+	if err := db.Where("client_id = ? AND tenant_id = ? AND enabled", clientID, tenantID).First(&client).Error; err != nil {
+		return nil, err
+	}
+	return &client, nil
+}
+
+// AuthenticateClient 验证客户端凭证，客户端必须属于请求所属的租户
+// 公共客户端不允许携带密钥，机密客户端必须提供正确的密钥
+func AuthenticateClient(db *gorm.DB, tenantID, clientID, clientSecret string) (*models.Client, error) {
+	if clientID == "" {
+		return nil, NewOAuthError("invalid_client", "缺少 client_id")
+	}
+
+	client, err := GetClient(db, tenantID, clientID)
+	if err != nil {
+		return nil, NewOAuthError("invalid_client", "客户端不存在")
+	}
+
+	if client.IsPublic() {
+		if clientSecret != "" {
+			return nil, NewOAuthError("invalid_client", "公共客户端不应提供密钥")
+		}
+		return client, nil
+	}
+
+	if clientSecret == "" || bcrypt.CompareHashAndPassword([]byte(client.ClientSecretHash), []byte(clientSecret)) != nil {
+		return nil, NewOAuthError("invalid_client", "客户端认证失败")
+	}
+	return client, nil
+}
+
+// HasScope 判断空格分隔的 scope 中是否包含指定值
+func HasScope(scope, want string) bool {
+	for _, s := range strings.Fields(scope) {
+		if s == want {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateAuthorizeRequest 保存授权请求，等待上游登录完成
+// 授权请求绑定发起授权的浏览器 nonce，只有同一浏览器才能续接（防止登录 CSRF）
+func CreateAuthorizeRequest(db *gorm.DB, req *models.AuthorizeRequest, browserNonce string) error {
+	if browserNonce == "" {
+		return errors.New("授权请求必须绑定浏览器")
+	}
+	req.BrowserNonceHash = hashToken(browserNonce)
+	req.ExpiresAt = time.Now().Add(AuthorizeRequestExpiration)
+	return db.Create(req).Error
+}
+
+// GetAuthorizeRequest 获取当前浏览器发起的未过期授权请求
+func GetAuthorizeRequest(db *gorm.DB, id, browserNonce string) (*models.AuthorizeRequest, error) {
+	if browserNonce == "" {
+		return nil, gorm.ErrRecordNotFound
+	}
+	var req models.AuthorizeRequest
+	if err := db.Where("id = ? AND browser_nonce_hash = ? AND expires_at > ?", id, hashToken(browserNonce), time.Now()).
+		First(&req).Error; err != nil {
+		return nil, err
+	}
+	return &req, nil
+}
+
+// IssueAuthorizationCode 为已完成登录的授权请求签发授权码（授权请求随之失效）
+func IssueAuthorizationCode(db *gorm.DB, req *models.AuthorizeRequest, userID, loginMethod string, authTime time.Time, device *models.DeviceInfo) (string, error) {
+	code, err := generateRandomToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	authCode := models.AuthorizationCode{
+		CodeHash:            hashToken(code),
+		ClientID:            req.ClientID,
+		UserID:              userID,
+		RedirectURI:         req.RedirectURI,
+		Scope:               req.Scope,
+		Nonce:               req.Nonce,
+		CodeChallenge:       req.CodeChallenge,
+		CodeChallengeMethod: req.CodeChallengeMethod,
+		AuthTime:            authTime,
+		LoginMethod:         loginMethod,
+		DeviceInfo:          marshalDeviceInfo(device),
+		ExpiresAt:           time.Now().Add(AuthorizationCodeExpiration),
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&authCode).Error; err != nil {
+			return err
+		}
+		return tx.Where("id = ?", req.ID).Delete(&models.AuthorizeRequest{}).Error
+	})
+	if err != nil {
+		return "", err
+	}
+	return code, nil
+}
+
+// RedeemAuthorizationCode 兑换授权码（一次性）
+// 授权码被重复使用时，吊销第一次兑换时创建的会话（RFC 6749 4.1.2）
+func RedeemAuthorizationCode(db *gorm.DB, client *models.Client, code, redirectURI, codeVerifier string) (*models.AuthorizationCode, error) {
+	var authCode models.AuthorizationCode
+	if err := db.Where("code_hash = ?", hashToken(code)).First(&authCode).Error; err != nil {
+		return nil, NewOAuthError("invalid_grant", "授权码无效")
+	}
+
+	if authCode.UsedAt != nil {
+		if authCode.SessionID != nil {
+			RevokeSessions(db, *authCode.SessionID)
+		}
+		return nil, NewOAuthError("invalid_grant", "授权码已被使用")
+	}
+	if time.Now().After(authCode.ExpiresAt) {
+		return nil, NewOAuthError("invalid_grant", "授权码已过期")
+	}
+	if authCode.ClientID != client.ClientID {
+		return nil, NewOAuthError("invalid_grant", "授权码不属于该客户端")
+	}
+	if authCode.RedirectURI != redirectURI {
+		return nil, NewOAuthError("invalid_grant", "redirect_uri 不匹配")
+	}
+
+	// 公共客户端必须使用 PKCE；机密客户端若授权时提供了 code_challenge 也必须校验
+	if authCode.CodeChallenge != "" || client.IsPublic() {
+		if !VerifyPKCE(authCode.CodeChallenge, authCode.CodeChallengeMethod, codeVerifier) {
+			return nil, NewOAuthError("invalid_grant", "code_verifier 校验失败")
+		}
+	}
+
+	// 原子地标记为已使用，防止并发重复兑换
+	now := time.Now()
+	result := db.Model(&models.AuthorizationCode{}).
+		Where("code_hash = ? AND used_at IS NULL", authCode.CodeHash).
+		Update("used_at", now)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, NewOAuthError("invalid_grant", "授权码已被使用")
+	}
+	authCode.UsedAt = &now
+
+	return &authCode, nil
+}
+
+// BindAuthorizationCodeSession 记录授权码兑换出的会话，用于重复使用时吊销
+func BindAuthorizationCodeSession(db *gorm.DB, authCode *models.AuthorizationCode, sessionID string) error {
+	result := db.Model(&models.AuthorizationCode{}).
+		Where("code_hash = ?", authCode.CodeHash).
+		Update("session_id", sessionID)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// VerifyPKCE 校验 PKCE code_verifier（仅支持 S256）
+func VerifyPKCE(challenge, method, verifier string) bool {
+	if challenge == "" || verifier == "" || method != "S256" {
+		return false
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	expected := base64.RawURLEncoding.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(challenge)) == 1
+}
+
+// IDTokenClaims OIDC ID Token 声明
+type IDTokenClaims struct {
+	Nonce    string `json:"nonce,omitempty"`
+	AuthTime int64  `json:"auth_time"`
+	AtHash   string `json:"at_hash,omitempty"`
+	Name     string `json:"name,omitempty"`
+	Picture  string `json:"picture,omitempty"`
+	UnionID  string `json:"unionid,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// GenerateIDToken 生成 ID Token
+func GenerateIDToken(db *gorm.DB, cfg *config.Config, authCode *models.AuthorizationCode, accessToken string) (string, error) {
+	now := time.Now()
+	claims := IDTokenClaims{
+		Nonce:    authCode.Nonce,
+		AuthTime: authCode.AuthTime.Unix(),
+		AtHash:   accessTokenHash(accessToken),
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    cfg.Issuer,
+			Subject:   authCode.UserID,
+			Audience:  jwt.ClaimStrings{authCode.ClientID},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(IDTokenExpiration)),
+		},
+	}
+
+	if HasScope(authCode.Scope, "profile") {
+		info, err := GetUserInfoClaims(db, authCode.UserID, "openid profile")
+		if err != nil {
+			return "", err
+		}
+		claims.Name = GetStringValue(info, "name")
+		claims.Picture = GetStringValue(info, "picture")
+		claims.UnionID = GetStringValue(info, "unionid")
+	}
+
+	return signToken("JWT", claims)
+}
+
+// GetUserInfoClaims 按授权范围构建 OIDC UserInfo 声明
+func GetUserInfoClaims(db *gorm.DB, userID, scope string) (map[string]interface{}, error) {
+	var user models.User
+	if err := db.Preload("Accounts").Where("user_id = ?", userID).First(&user).Error; err != nil {
+		return nil, err
+	}
+
+	claims := map[string]interface{}{
+		"sub": user.UserID,
+	}
+
+	if HasScope(scope, "profile") {
+		if user.UnionID != "" {
+			claims["unionid"] = user.UnionID
+		}
+		if len(user.Accounts) > 0 {
+			claims["name"] = user.Accounts[0].Nickname
+			claims["nickname"] = user.Accounts[0].Nickname
+			claims["picture"] = user.Accounts[0].AvatarURL
+		}
+		claims["updated_at"] = user.UpdatedAt.Unix()
+	}
+	if HasScope(scope, "phone") && user.PhoneNumber != nil {
+		claims["phone_number"] = *user.PhoneNumber
+		claims["phone_number_verified"] = false
+	}
+	if HasScope(scope, "email") && user.Email != nil {
+		claims["email"] = *user.Email
+		claims["email_verified"] = false
+	}
+
+	return claims, nil
+}
+
+// accessTokenHash 计算 at_hash（签名算法对应摘要的左半部分，Ed25519 使用 SHA-512）
+func accessTokenHash(accessToken string) string {
+	var sum []byte
+	if key, err := defaultKeySet.SigningKey(); err == nil && key.Algorithm == AlgEdDSA {
+		h := sha512.Sum512([]byte(accessToken))
+		sum = h[:]
+	} else {
+		h := sha256.Sum256([]byte(accessToken))
+		sum = h[:]
+	}
+	return base64.RawURLEncoding.EncodeToString(sum[:len(sum)/2])
+}
+
+// hashToken 计算随机令牌的 SHA-256 摘要（数据库中只保存摘要）
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// generateRandomToken 生成 URL 安全的随机令牌
+func generateRandomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", errors.New("生成随机令牌失败")
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/keenchase/auth-center/internal/config"
+	"github.com/keenchase/auth-center/internal/models"
+)
+
+func TestVerifyPKCE(t *testing.T) {
+	// RFC 7636 附录 B 的示例
+	verifier := "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	challenge := "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+
+	sum := sha256.Sum256([]byte(verifier))
+	if got := base64.RawURLEncoding.EncodeToString(sum[:]); got != challenge {
+		t.Fatalf("示例 challenge 不一致: %s", got)
+	}
+
+	tests := []struct {
+		name                        string
+		challenge, method, verifier string
+		want                        bool
+	}{
+		{"S256 匹配", challenge, "S256", verifier, true},
+		{"verifier 不匹配", challenge, "S256", verifier + "x", false},
+		{"plain 不支持", verifier, "plain", verifier, false},
+		{"缺少 method", challenge, "", verifier, false},
+		{"缺少 challenge", "", "S256", verifier, false},
+		{"缺少 verifier", challenge, "S256", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := VerifyPKCE(tt.challenge, tt.method, tt.verifier); got != tt.want {
+				t.Fatalf("VerifyPKCE = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIDTokenRejectedAsAccessToken(t *testing.T) {
+	useTestKeySet(t)
+
+	accessToken, err := GenerateAccessToken("user-1", "session-1", "default", "client-1", "openid", nil, nil, time.Minute)
+	if err != nil {
+		t.Fatalf("GenerateAccessToken: %v", err)
+	}
+	if _, err := ValidateToken(accessToken); err != nil {
+		t.Fatalf("访问令牌应通过校验: %v", err)
+	}
+
+	// 不带 profile 范围时 GenerateIDToken 不访问数据库
+	idToken, err := GenerateIDToken(nil, &config.Config{Issuer: "https://auth.example.com"},
+		&models.AuthorizationCode{UserID: "user-1", ClientID: "client-1", Scope: "openid"}, accessToken)
+	if err != nil {
+		t.Fatalf("GenerateIDToken: %v", err)
+	}
+	if _, err := ValidateToken(idToken); err == nil {
+		t.Fatal("ID Token 不能作为访问令牌使用")
+	}
+}
+
+func TestClientTokenNotFirstParty(t *testing.T) {
+	useTestKeySet(t)
+
+	tests := []struct {
+		clientID string
+		want     bool
+	}{
+		{"", true},
+		{"client-1", false},
+	}
+	for _, tt := range tests {
+		token, err := GenerateAccessToken("user-1", "session-1", "default", tt.clientID, "", nil, nil, time.Minute)
+		if err != nil {
+			t.Fatalf("GenerateAccessToken: %v", err)
+		}
+		claims, err := ValidateToken(token)
+		if err != nil {
+			t.Fatalf("ValidateToken: %v", err)
+		}
+		if got := claims.FirstParty(); got != tt.want {
+			t.Fatalf("clientID %q: FirstParty = %v, want %v", tt.clientID, got, tt.want)
+		}
+	}
+}
+
+func TestBindAuthorizationCodeSessionUnknownCode(t *testing.T) {
+	db := testDB(t)
+
+	// 授权码不存在时绑定失败，调用方据此作废刚签发的令牌
+	err := BindAuthorizationCodeSession(db, &models.AuthorizationCode{CodeHash: hashToken("missing-code")}, "00000000-0000-0000-0000-000000000000")
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestAuthorizeRequestBoundToBrowser(t *testing.T) {
+	db := testDB(t)
+	client := models.Client{
+		ClientID:     "test-authorize-client",
+		TenantID:     config.DefaultTenantID,
+		Name:         "测试应用",
+		RedirectURIs: "https://app.example.com/callback",
+		Enabled:      true,
+	}
+	if err := db.Create(&client).Error; err != nil {
+		t.Fatalf("创建测试客户端失败: %v", err)
+	}
+
+	req := models.AuthorizeRequest{ClientID: client.ClientID, RedirectURI: "https://app.example.com/callback", Scope: "openid"}
+	if err := CreateAuthorizeRequest(db, &req, "browser-nonce"); err != nil {
+		t.Fatalf("CreateAuthorizeRequest: %v", err)
+	}
+
+	if got, err := GetAuthorizeRequest(db, req.ID, "browser-nonce"); err != nil || got.ID != req.ID {
+		t.Fatalf("同一浏览器续接 = %v, %v", got, err)
+	}
+	for _, nonce := range []string{"other-nonce", ""} {
+		if _, err := GetAuthorizeRequest(db, req.ID, nonce); !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Fatalf("nonce %q 续接 err = %v", nonce, err)
+		}
+	}
+	if err := CreateAuthorizeRequest(db, &models.AuthorizeRequest{ClientID: client.ClientID}, ""); err == nil {
+		t.Fatal("未绑定浏览器的授权请求应拒绝保存")
+	}
+}
@@ -0,0 +1,505 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/keenchase/auth-center/internal/models"
+)
+
+// 邀请有效期
+const (
+	DefaultInvitationExpiration = 7 * 24 * time.Hour
+	MaxInvitationExpiration     = 30 * 24 * time.Hour
+)
+
+var orgSlugPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{1,49}$`)
+
+var (
+	// ErrOrgNotFound 组织不存在
+	ErrOrgNotFound = errors.New("组织不存在")
+
+	// ErrInvalidOrgInput 组织配置不合法
+	ErrInvalidOrgInput = errors.New("组织配置无效")
+
+	// ErrOrgMemberNotFound 不是组织成员
+	ErrOrgMemberNotFound = errors.New("不是该组织的成员")
+
+	// ErrOrgForbidden 组织内角色不足以执行该操作
+	ErrOrgForbidden = errors.New("组织内权限不足")
+
+	// ErrLastOrgOwner 不能移除或降级组织的最后一位所有者
+	ErrLastOrgOwner = errors.New("不能移除或降级组织的最后一位所有者")
+
+	// ErrInvalidInvitation 邀请码无效、已撤销、已过期或已用完
+	ErrInvalidInvitation = errors.New("邀请码无效或已过期")
+)
+
+// OrgInput 创建/更新组织的参数
+type OrgInput struct {
+	Slug        string `json:"slug"` // 仅创建时使用
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	OwnerUserID string `json:"ownerUserId"` // 仅创建时使用：初始所有者
+}
+
+// OrgMembership 用户所在的组织及其角色
+type OrgMembership struct {
+	models.Organization
+	Role string `json:"role"`
+}
+
+// OrgMemberView 组织成员
+type OrgMemberView struct {
+	UserID    string    `json:"userId"`
+	Role      string    `json:"role"`
+	Nickname  string    `json:"nickname,omitempty"`
+	AvatarURL string    `json:"avatarUrl,omitempty"`
+	JoinedAt  time.Time `json:"joinedAt"`
+}
+
+// InvitationInput 创建邀请的参数
+type InvitationInput struct {
+	Role      string `json:"role"`      // admin | member，默认 member
+	MaxUses   int    `json:"maxUses"`   // 0 表示不限次数
+	ExpiresIn int    `json:"expiresIn"` // 秒，0 表示默认 7 天
+}
+
+// OrgClaim 写入访问令牌的组织声明
+type OrgClaim struct {
+	ID   string `json:"id"`
+	Slug string `json:"slug"`
+	Role string `json:"role"`
+}
+
+// ListOrgs 列出租户的全部组织
+func ListOrgs(db *gorm.DB, tenantID string) ([]models.Organization, error) {
+	var orgs []models.Organization
+	err := db.Where("tenant_id = ?", tenantID).Order("created_at").Find(&orgs).Error
+	return orgs, err
+}
+
+// GetOrg 获取组织
+func GetOrg(db *gorm.DB, orgID string) (*models.Organization, error) {
+	var org models.Organization
+	err := db.Where("id = ?", orgID).First(&org).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrOrgNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &org, nil
+}
+
+// OrgInTenant 组织是否属于该租户，不属于时返回 ErrOrgNotFound
+func OrgInTenant(db *gorm.DB, tenantID, orgID string) error {
+	if _, err := uuid.Parse(orgID); err != nil {
+		return ErrOrgNotFound
+	}
+	org, err := GetOrg(db, orgID)
+	if err != nil {
+		return err
+	}
+	if org.TenantID != tenantID {
+		return ErrOrgNotFound
+	}
+	return nil
+}
+
+// CreateOrg 在租户内创建组织，并将 OwnerUserID（同一租户的用户）设为所有者
+func CreateOrg(db *gorm.DB, tenantID string, input OrgInput) (*models.Organization, error) {
+	slug := strings.TrimSpace(input.Slug)
+	if !orgSlugPattern.MatchString(slug) {
+		return nil, fmt.Errorf("%w：slug 只能包含小写字母、数字和 -，长度 2-50", ErrInvalidOrgInput)
+	}
+	if err := requireUser(db, tenantID, input.OwnerUserID); err != nil {
+		return nil, err
+	}
+
+	org := models.Organization{TenantID: tenantID, Slug: slug}
+	if err := applyOrgInput(&org, input); err != nil {
+		return nil, err
+	}
+
+	var count int64
+	if err := db.Model(&models.Organization{}).Where("tenant_id = ? AND slug = ?", tenantID, slug).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, fmt.Errorf("%w：slug %s 已存在", ErrInvalidOrgInput, slug)
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&org).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.OrganizationMember{
+			OrgID:  org.ID,
+			UserID: input.OwnerUserID,
+			Role:   models.OrgRoleOwner,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &org, nil
+}
+
+// UpdateOrg 更新组织名称与描述（slug 不变）
+func UpdateOrg(db *gorm.DB, orgID string, input OrgInput) (*models.Organization, error) {
+	org, err := GetOrg(db, orgID)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyOrgInput(org, input); err != nil {
+		return nil, err
+	}
+	if err := db.Select("name", "description", "updated_at").Save(org).Error; err != nil {
+		return nil, err
+	}
+	return org, nil
+}
+
+// DeleteOrg 删除组织（成员与邀请级联删除）
+func DeleteOrg(db *gorm.DB, orgID string) error {
+	result := db.Where("id = ?", orgID).Delete(&models.Organization{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrOrgNotFound
+	}
+	return nil
+}
+
+// ListUserOrgs 列出用户所在的组织及其角色
+func ListUserOrgs(db *gorm.DB, userID string) ([]OrgMembership, error) {
+	var memberships []OrgMembership
+	err := db.Model(&models.Organization{}).
+		Select("organizations.*, organization_members.role").
+		Joins("JOIN organization_members ON organization_members.org_id = organizations.id").
+		Where("organization_members.user_id = ?", userID).
+		Order("organizations.name").
+		Scan(&memberships).Error
+	return memberships, err
+}
+
+// OrgMemberRole 用户在组织内的角色
+func OrgMemberRole(db *gorm.DB, orgID, userID string) (string, error) {
+	var member models.OrganizationMember
+	err := db.Where("org_id = ? AND user_id = ?", orgID, userID).First(&member).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", ErrOrgMemberNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+	return member.Role, nil
+}
+
+// ListOrgMembers 列出组织成员（昵称、头像取自任一微信账号）
+func ListOrgMembers(db *gorm.DB, orgID string) ([]OrgMemberView, error) {
+	if _, err := GetOrg(db, orgID); err != nil {
+		return nil, err
+	}
+
+	var members []models.OrganizationMember
+	if err := db.Where("org_id = ?", orgID).Order("created_at").Find(&members).Error; err != nil {
+		return nil, err
+	}
+	if len(members) == 0 {
+		return []OrgMemberView{}, nil
+	}
+
+	userIDs := make([]string, len(members))
+	for i := range members {
+		userIDs[i] = members[i].UserID
+	}
+	var accounts []models.UserAccount
+	if err := db.Where("user_id IN ? AND nickname <> ''", userIDs).Order("created_at").Find(&accounts).Error; err != nil {
+		return nil, err
+	}
+	profiles := make(map[string]models.UserAccount)
+	for _, account := range accounts {
+		if _, ok := profiles[account.UserID]; !ok {
+			profiles[account.UserID] = account
+		}
+	}
+
+	result := make([]OrgMemberView, len(members))
+	for i, member := range members {
+		profile := profiles[member.UserID]
+		result[i] = OrgMemberView{
+			UserID:    member.UserID,
+			Role:      member.Role,
+			Nickname:  profile.Nickname,
+			AvatarURL: profile.AvatarURL,
+			JoinedAt:  member.CreatedAt,
+		}
+	}
+	return result, nil
+}
+
+// AddOrgMember 直接将同一租户的用户加入组织（已是成员时更新角色），仅供平台管理员使用
+func AddOrgMember(db *gorm.DB, orgID, userID, role string) error {
+	org, err := GetOrg(db, orgID)
+	if err != nil {
+		return err
+	}
+	if err := requireUser(db, org.TenantID, userID); err != nil {
+		return err
+	}
+	return setOrgMemberRole(db, orgID, models.OrgRoleOwner, userID, role, false)
+}
+
+// SetOrgMemberRole 变更成员角色，actorRole 为操作者在组织内的角色（平台管理员视为所有者）
+// 只有所有者可以授予或变更所有者，不能降级最后一位所有者
+func SetOrgMemberRole(db *gorm.DB, orgID, actorRole, userID, role string) error {
+	return setOrgMemberRole(db, orgID, actorRole, userID, role, true)
+}
+
+// setOrgMemberRole 设置成员角色，mustBeMember 为 false 时非成员直接加入
+func setOrgMemberRole(db *gorm.DB, orgID, actorRole, userID, role string, mustBeMember bool) error {
+	if models.OrgRoleRank(role) == 0 {
+		return fmt.Errorf("%w：角色只能是 owner、admin 或 member", ErrInvalidOrgInput)
+	}
+
+	return withOrgLock(db, orgID, func(tx *gorm.DB) error {
+		current, err := OrgMemberRole(tx, orgID, userID)
+		if err != nil && (mustBeMember || !errors.Is(err, ErrOrgMemberNotFound)) {
+			return err
+		}
+		if !canManageOrgRole(actorRole, current) || !canManageOrgRole(actorRole, role) {
+			return ErrOrgForbidden
+		}
+		if current == models.OrgRoleOwner && role != models.OrgRoleOwner {
+			if err := ensureOtherOwner(tx, orgID, userID); err != nil {
+				return err
+			}
+		}
+
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "org_id"}, {Name: "user_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"role"}),
+		}).Create(&models.OrganizationMember{OrgID: orgID, UserID: userID, Role: role}).Error
+	})
+}
+
+// RemoveOrgMember 将成员移出组织；成员可以自行退出，不能移除最后一位所有者
+func RemoveOrgMember(db *gorm.DB, orgID, actorID, actorRole, userID string) error {
+	return withOrgLock(db, orgID, func(tx *gorm.DB) error {
+		current, err := OrgMemberRole(tx, orgID, userID)
+		if err != nil {
+			return err
+		}
+		if actorID != userID && !canManageOrgRole(actorRole, current) {
+			return ErrOrgForbidden
+		}
+		if current == models.OrgRoleOwner {
+			if err := ensureOtherOwner(tx, orgID, userID); err != nil {
+				return err
+			}
+		}
+		return tx.Where("org_id = ? AND user_id = ?", orgID, userID).Delete(&models.OrganizationMember{}).Error
+	})
+}
+
+// ListInvitations 列出组织的邀请（含已过期、已撤销）
+func ListInvitations(db *gorm.DB, orgID string) ([]models.OrganizationInvitation, error) {
+	var invitations []models.OrganizationInvitation
+	err := db.Where("org_id = ?", orgID).Order("created_at DESC").Find(&invitations).Error
+	return invitations, err
+}
+
+// CreateInvitation 创建组织邀请，返回明文邀请码（仅此一次）
+// 邀请的角色不能高于操作者自身
+func CreateInvitation(db *gorm.DB, orgID, actorID, actorRole string, input InvitationInput) (*models.OrganizationInvitation, string, error) {
+	if _, err := GetOrg(db, orgID); err != nil {
+		return nil, "", err
+	}
+
+	role := input.Role
+	if role == "" {
+		role = models.OrgRoleMember
+	}
+	if role != models.OrgRoleAdmin && role != models.OrgRoleMember {
+		return nil, "", fmt.Errorf("%w：邀请的角色只能是 admin 或 member", ErrInvalidOrgInput)
+	}
+	if models.OrgRoleRank(role) > models.OrgRoleRank(actorRole) {
+		return nil, "", ErrOrgForbidden
+	}
+	if input.MaxUses < 0 {
+		return nil, "", fmt.Errorf("%w：maxUses 不能为负数", ErrInvalidOrgInput)
+	}
+	expiresIn := DefaultInvitationExpiration
+	if input.ExpiresIn != 0 {
+		expiresIn = time.Duration(input.ExpiresIn) * time.Second
+		if expiresIn < time.Minute || expiresIn > MaxInvitationExpiration {
+			return nil, "", fmt.Errorf("%w：有效期须在 1 分钟到 30 天之间", ErrInvalidOrgInput)
+		}
+	}
+
+	code, err := generateRandomToken(16)
+	if err != nil {
+		return nil, "", err
+	}
+	invitation := models.OrganizationInvitation{
+		OrgID:     orgID,
+		CodeHash:  hashToken(code),
+		Role:      role,
+		ExpiresAt: time.Now().Add(expiresIn),
+	}
+	if input.MaxUses > 0 {
+		invitation.MaxUses = &input.MaxUses
+	}
+	if actorID != "" {
+		invitation.CreatedBy = &actorID
+	}
+	if err := db.Create(&invitation).Error; err != nil {
+		return nil, "", err
+	}
+	return &invitation, code, nil
+}
+
+// RevokeInvitation 撤销邀请
+func RevokeInvitation(db *gorm.DB, orgID, invitationID string) error {
+	result := db.Model(&models.OrganizationInvitation{}).
+		Where("id = ? AND org_id = ? AND revoked_at IS NULL", invitationID, orgID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrInvalidInvitation
+	}
+	return nil
+}
+
+// AcceptInvitation 用户兑换邀请码加入组织；已是成员时保持原角色且不消耗次数
+func AcceptInvitation(db *gorm.DB, userID, code string) (*OrgMembership, error) {
+	if code == "" {
+		return nil, ErrInvalidInvitation
+	}
+
+	var invitation models.OrganizationInvitation
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("code_hash = ? AND revoked_at IS NULL AND expires_at > ?", hashToken(code), time.Now()).
+			First(&invitation).Error; err != nil {
+			return ErrInvalidInvitation
+		}
+		if invitation.MaxUses != nil && invitation.Uses >= *invitation.MaxUses {
+			return ErrInvalidInvitation
+		}
+		// 只能加入用户所在租户的组织
+		userTenant := tx.Model(&models.User{}).Select("tenant_id").Where("user_id = ?", userID)
+		if err := tx.Where("id = ? AND tenant_id = (?)", invitation.OrgID, userTenant).
+			First(&models.Organization{}).Error; err != nil {
+			return ErrInvalidInvitation
+		}
+
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.OrganizationMember{
+			OrgID:  invitation.OrgID,
+			UserID: userID,
+			Role:   invitation.Role,
+		})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		return tx.Model(&invitation).Update("uses", gorm.Expr("uses + 1")).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	org, err := GetOrg(db, invitation.OrgID)
+	if err != nil {
+		return nil, err
+	}
+	role, err := OrgMemberRole(db, invitation.OrgID, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &OrgMembership{Organization: *org, Role: role}, nil
+}
+
+// UserOrgClaims 用户所在组织的令牌声明
+func UserOrgClaims(db *gorm.DB, userID string) ([]OrgClaim, error) {
+	claims := []OrgClaim{}
+	err := db.Model(&models.OrganizationMember{}).
+		Select("organizations.id, organizations.slug, organization_members.role").
+		Joins("JOIN organizations ON organizations.id = organization_members.org_id").
+		Where("organization_members.user_id = ?", userID).
+		Order("organizations.slug").
+		Scan(&claims).Error
+	return claims, err
+}
+
+// canManageOrgRole 操作者能否授予或变更该角色：所有者不受限，管理员只能管理 admin 及以下
+func canManageOrgRole(actorRole, role string) bool {
+	if actorRole == models.OrgRoleOwner {
+		return true
+	}
+	return actorRole == models.OrgRoleAdmin && role != models.OrgRoleOwner
+}
+
+// ensureOtherOwner 组织除该用户外还有其他所有者
+func ensureOtherOwner(tx *gorm.DB, orgID, userID string) error {
+	var others int64
+	if err := tx.Model(&models.OrganizationMember{}).
+		Where("org_id = ? AND role = ? AND user_id <> ?", orgID, models.OrgRoleOwner, userID).
+		Count(&others).Error; err != nil {
+		return err
+	}
+	if others == 0 {
+		return ErrLastOrgOwner
+	}
+	return nil
+}
+
+// withOrgLock 锁定组织行后执行成员变更，串行化同一组织的并发修改
+func withOrgLock(db *gorm.DB, orgID string, fn func(tx *gorm.DB) error) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var org models.Organization
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", orgID).First(&org).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrOrgNotFound
+		}
+		if err != nil {
+			return err
+		}
+		return fn(tx)
+	})
+}
+
+// requireUser 校验用户存在
+func requireUser(db *gorm.DB, tenantID, userID string) error {
+	if userID == "" {
+		return ErrUserNotFound
+	}
+	return UserInTenant(db, tenantID, userID)
+}
+
+// applyOrgInput 校验并写入组织名称与描述
+func applyOrgInput(org *models.Organization, input OrgInput) error {
+	name := strings.TrimSpace(input.Name)
+	if name == "" || utf8.RuneCountInString(name) > 100 {
+		return fmt.Errorf("%w：名称不能为空且不超过 100 个字符", ErrInvalidOrgInput)
+	}
+	if utf8.RuneCountInString(input.Description) > 255 {
+		return fmt.Errorf("%w：描述不能超过 255 个字符", ErrInvalidOrgInput)
+	}
+	org.Name = name
+	org.Description = input.Description
+	org.UpdatedAt = time.Now()
+	return nil
+}
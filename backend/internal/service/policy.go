@@ -0,0 +1,587 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/keenchase/auth-center/internal/models"
+)
+
+// 策略条件运算符
+const (
+	OperatorEq       = "eq"
+	OperatorNe       = "ne"
+	OperatorIn       = "in"       // 属性值在 value 列表中
+	OperatorNotIn    = "not_in"   // 属性值不在 value 列表中
+	OperatorContains = "contains" // 列表属性包含 value
+	OperatorGt       = "gt"
+	OperatorGte      = "gte"
+	OperatorLt       = "lt"
+	OperatorLte      = "lte"
+	OperatorExists   = "exists" // value 为 true 时要求属性存在，false 时要求不存在
+)
+
+// 策略判定结果
+const (
+	DecisionAllow = "allow"
+	DecisionDeny  = "deny"
+)
+
+// attributePrefixes 条件可引用的属性前缀（action 为完整属性名）
+var attributePrefixes = []string{"user.", "session.", "resource."}
+
+var (
+	// ErrPolicyNotFound 策略不存在
+	ErrPolicyNotFound = errors.New("策略不存在")
+
+	// ErrInvalidPolicyInput 策略配置不合法
+	ErrInvalidPolicyInput = errors.New("策略配置无效")
+)
+
+// PolicyInput 创建/更新策略的参数
+type PolicyInput struct {
+	ClientID    string                   `json:"clientId"` // 仅创建时使用
+	Name        string                   `json:"name"`
+	Description string                   `json:"description"`
+	Resource    string                   `json:"resource"`
+	Action      string                   `json:"action"`
+	Effect      string                   `json:"effect"`
+	Conditions  []models.PolicyCondition `json:"conditions"`
+	Enabled     *bool                    `json:"enabled"` // 为空时创建默认启用、更新保持不变
+}
+
+// PolicyView 对外展示的策略
+type PolicyView struct {
+	ID          string                   `json:"id"`
+	ClientID    string                   `json:"clientId"`
+	Name        string                   `json:"name"`
+	Description string                   `json:"description"`
+	Resource    string                   `json:"resource"`
+	Action      string                   `json:"action"`
+	Effect      string                   `json:"effect"`
+	Conditions  []models.PolicyCondition `json:"conditions"`
+	Enabled     bool                     `json:"enabled"`
+	CreatedAt   time.Time                `json:"createdAt"`
+	UpdatedAt   time.Time                `json:"updatedAt"`
+}
+
+// NewPolicyView 将策略记录转换为展示视图
+func NewPolicyView(policy *models.Policy) PolicyView {
+	return PolicyView{
+		ID:          policy.ID,
+		ClientID:    policy.ClientID,
+		Name:        policy.Name,
+		Description: policy.Description,
+		Resource:    policy.Resource,
+		Action:      policy.Action,
+		Effect:      policy.Effect,
+		Conditions:  parsePolicyConditions(policy.Conditions),
+		Enabled:     policy.Enabled,
+		CreatedAt:   policy.CreatedAt,
+		UpdatedAt:   policy.UpdatedAt,
+	}
+}
+
+// AuthzRequest 策略判定请求：主体属性 + 资源与操作
+type AuthzRequest struct {
+	ClientID   string
+	User       *models.User    // 需预加载 Accounts
+	Session    *models.Session // 可为空，此时 session.* 属性不存在
+	Resource   string
+	Action     string
+	Attributes map[string]interface{} // 资源属性，以 resource.<name> 引用
+}
+
+// DecisionReason 判定依据
+type DecisionReason struct {
+	Policy  string `json:"policy"`
+	Effect  string `json:"effect"`
+	Matched bool   `json:"matched"` // 条件是否全部满足
+	Message string `json:"message"`
+}
+
+// Decision 策略判定结果
+type Decision struct {
+	Allowed  bool             `json:"allowed"`
+	Decision string           `json:"decision"`
+	Reasons  []DecisionReason `json:"reasons"`
+}
+
+// ListPolicies 列出租户内业务系统的策略，clientID 为空时列出全部
+func ListPolicies(db *gorm.DB, tenantID, clientID string) ([]PolicyView, error) {
+	query := db.Where("client_id IN (?)", db.Model(&models.Client{}).Select("client_id").Where("tenant_id = ?", tenantID)).
+		Order("client_id, name")
+	if clientID != "" {
+		query = query.Where("client_id = ?", clientID)
+	}
+	var policies []models.Policy
+	if err := query.Find(&policies).Error; err != nil {
+		return nil, err
+	}
+	result := make([]PolicyView, len(policies))
+	for i := range policies {
+		result[i] = NewPolicyView(&policies[i])
+	}
+	return result, nil
+}
+
+// GetPolicy 获取策略详情
+func GetPolicy(db *gorm.DB, id string) (*PolicyView, error) {
+	policy, err := findPolicy(db, id)
+	if err != nil {
+		return nil, err
+	}
+	view := NewPolicyView(policy)
+	return &view, nil
+}
+
+// CreatePolicy 为租户内的业务系统创建策略
+func CreatePolicy(db *gorm.DB, tenantID string, input PolicyInput) (*PolicyView, error) {
+	if input.ClientID == "" {
+		return nil, fmt.Errorf("%w：缺少 clientId", ErrInvalidPolicyInput)
+	}
+	if err := ClientInTenant(db, tenantID, input.ClientID); err != nil {
+		return nil, err
+	}
+
+	policy := models.Policy{ClientID: input.ClientID, Enabled: true}
+	if err := applyPolicyInput(db, &policy, input); err != nil {
+		return nil, err
+	}
+	if err := db.Create(&policy).Error; err != nil {
+		return nil, err
+	}
+	view := NewPolicyView(&policy)
+	return &view, nil
+}
+
+// UpdatePolicy 更新策略（所属客户端不变）
+func UpdatePolicy(db *gorm.DB, id string, input PolicyInput) (*PolicyView, error) {
+	policy, err := findPolicy(db, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyPolicyInput(db, policy, input); err != nil {
+		return nil, err
+	}
+
+	if err := db.Select("name", "description", "resource", "action", "effect",
+		"conditions", "enabled", "updated_at").
+		Save(policy).Error; err != nil {
+		return nil, err
+	}
+	view := NewPolicyView(policy)
+	return &view, nil
+}
+
+// DeletePolicy 删除策略
+func DeletePolicy(db *gorm.DB, id string) error {
+	result := db.Where("id = ?", id).Delete(&models.Policy{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrPolicyNotFound
+	}
+	return nil
+}
+
+// SubjectAttributes 收集判定所需的主体属性（user.*、session.*）
+func SubjectAttributes(db *gorm.DB, clientID string, user *models.User, session *models.Session) (map[string]interface{}, error) {
+	authz, err := UserAuthorization(db, clientID, user.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	orgs, err := UserOrgClaims(db, user.UserID)
+	if err != nil {
+		return nil, err
+	}
+	orgIDs, orgSlugs := []interface{}{}, []interface{}{}
+	for _, org := range orgs {
+		orgIDs = append(orgIDs, org.ID)
+		orgSlugs = append(orgSlugs, org.Slug)
+	}
+
+	providers := []interface{}{}
+	accountTypes := []interface{}{}
+	departments := []interface{}{}
+	for _, account := range user.Accounts {
+		providers = appendUnique(providers, account.Provider)
+		accountTypes = appendUnique(accountTypes, account.Type)
+		for _, department := range account.DepartmentList() {
+			departments = appendUnique(departments, department.Name)
+		}
+	}
+
+	attrs := map[string]interface{}{
+		"user.id":           user.UserID,
+		"user.unionId":      user.UnionID,
+		"user.hasPassword":  user.PasswordHash != "",
+		"user.providers":    providers,
+		"user.accountTypes": accountTypes,
+		"user.departments":  departments,
+		"user.roles":        stringList(authz.Roles),
+		"user.permissions":  stringList(authz.Permissions),
+		"user.createdAt":    float64(user.CreatedAt.Unix()),
+		"user.orgs":         orgIDs,
+		"user.orgSlugs":     orgSlugs,
+	}
+	if user.PhoneNumber != nil {
+		attrs["user.phoneNumber"] = *user.PhoneNumber
+	}
+	if user.Email != nil {
+		attrs["user.email"] = *user.Email
+	}
+
+	if session != nil {
+		authTime := session.AuthenticatedAt()
+		attrs["session.id"] = session.ID
+		attrs["session.clientId"] = session.ClientID
+		attrs["session.loginMethod"] = session.LoginMethod
+		attrs["session.authTime"] = float64(authTime.Unix())
+		attrs["session.authAge"] = time.Since(authTime).Seconds()
+		if device := ParseDeviceInfo(session.DeviceInfo); device != nil {
+			attrs["session.ip"] = device.IP
+			attrs["session.deviceType"] = device.DeviceType
+			attrs["session.isWechatBrowser"] = device.IsWechatBrowser
+		}
+	}
+	return attrs, nil
+}
+
+// Authorize 按业务系统的策略判定是否允许：
+// 任一匹配的拒绝策略生效即拒绝，否则任一匹配的允许策略生效即允许，没有生效的策略时默认拒绝
+func Authorize(db *gorm.DB, req AuthzRequest) (*Decision, error) {
+	attrs, err := SubjectAttributes(db, req.ClientID, req.User, req.Session)
+	if err != nil {
+		return nil, err
+	}
+	attrs["action"] = req.Action
+	attrs["resource.name"] = req.Resource
+	for name, value := range req.Attributes {
+		if name != "name" {
+			attrs["resource."+name] = value
+		}
+	}
+
+	var policies []models.Policy
+	if err := db.Where("client_id = ? AND enabled", req.ClientID).Order("name").Find(&policies).Error; err != nil {
+		return nil, err
+	}
+
+	decision := &Decision{Decision: DecisionDeny, Reasons: []DecisionReason{}}
+	allowed, denied := false, false
+	for i := range policies {
+		policy := &policies[i]
+		if !matchPattern(policy.Resource, req.Resource) || !matchPattern(policy.Action, req.Action) {
+			continue
+		}
+
+		reason := DecisionReason{Policy: policy.Name, Effect: policy.Effect, Matched: true}
+		if failed := firstFailedCondition(parsePolicyConditions(policy.Conditions), attrs, policy.Effect); failed != nil {
+			reason.Matched = false
+			reason.Message = "条件不满足：" + describeCondition(failed)
+		} else if policy.Effect == models.PolicyEffectDeny {
+			denied = true
+			reason.Message = "策略拒绝"
+		} else {
+			allowed = true
+			reason.Message = "策略允许"
+		}
+		decision.Reasons = append(decision.Reasons, reason)
+	}
+
+	if allowed && !denied {
+		decision.Allowed = true
+		decision.Decision = DecisionAllow
+	}
+	if len(decision.Reasons) == 0 {
+		decision.Reasons = append(decision.Reasons, DecisionReason{Message: "没有匹配的策略，默认拒绝"})
+	}
+	return decision, nil
+}
+
+// PolicyInTenant 策略所属业务系统是否属于该租户，不属于时返回 ErrPolicyNotFound
+func PolicyInTenant(db *gorm.DB, tenantID, id string) error {
+	if _, err := uuid.Parse(id); err != nil {
+		return ErrPolicyNotFound
+	}
+	policy, err := findPolicy(db, id)
+	if err != nil {
+		return err
+	}
+	if err := ClientInTenant(db, tenantID, policy.ClientID); errors.Is(err, ErrClientNotFound) {
+		return ErrPolicyNotFound
+	} else if err != nil {
+		return err
+	}
+	return nil
+}
+
+// findPolicy 按 ID 获取策略
+func findPolicy(db *gorm.DB, id string) (*models.Policy, error) {
+	var policy models.Policy
+	err := db.Where("id = ?", id).First(&policy).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrPolicyNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// applyPolicyInput 校验并写入策略字段
+func applyPolicyInput(db *gorm.DB, policy *models.Policy, input PolicyInput) error {
+	name := strings.TrimSpace(input.Name)
+	if name == "" || utf8.RuneCountInString(name) > 100 {
+		return fmt.Errorf("%w：名称不能为空且不超过 100 个字符", ErrInvalidPolicyInput)
+	}
+	if utf8.RuneCountInString(input.Description) > 255 {
+		return fmt.Errorf("%w：描述不能超过 255 个字符", ErrInvalidPolicyInput)
+	}
+	resource, action := strings.TrimSpace(input.Resource), strings.TrimSpace(input.Action)
+	if resource == "" || len(resource) > 255 || action == "" || len(action) > 100 {
+		return fmt.Errorf("%w：resource 与 action 不能为空", ErrInvalidPolicyInput)
+	}
+	if input.Effect != models.PolicyEffectAllow && input.Effect != models.PolicyEffectDeny {
+		return fmt.Errorf("%w：effect 只能是 allow 或 deny", ErrInvalidPolicyInput)
+	}
+	for i := range input.Conditions {
+		if err := validateCondition(&input.Conditions[i]); err != nil {
+			return fmt.Errorf("%w：第 %d 个条件%s", ErrInvalidPolicyInput, i+1, err.Error())
+		}
+	}
+
+	var existing int64
+	query := db.Model(&models.Policy{}).Where("client_id = ? AND name = ?", policy.ClientID, name)
+	if policy.ID != "" {
+		query = query.Where("id <> ?", policy.ID)
+	}
+	if err := query.Count(&existing).Error; err != nil {
+		return err
+	}
+	if existing > 0 {
+		return fmt.Errorf("%w：策略 %s 已存在", ErrInvalidPolicyInput, name)
+	}
+
+	conditions := input.Conditions
+	if conditions == nil {
+		conditions = []models.PolicyCondition{}
+	}
+	data, err := json.Marshal(conditions)
+	if err != nil {
+		return err
+	}
+
+	policy.Name = name
+	policy.Description = input.Description
+	policy.Resource = resource
+	policy.Action = action
+	policy.Effect = input.Effect
+	policy.Conditions = string(data)
+	if input.Enabled != nil {
+		policy.Enabled = *input.Enabled
+	}
+	policy.UpdatedAt = time.Now()
+	return nil
+}
+
+// validateCondition 校验条件的属性、运算符与值类型
+func validateCondition(cond *models.PolicyCondition) error {
+	if !validAttribute(cond.Attribute) {
+		return fmt.Errorf("的属性 %q 无效（须为 action 或以 user.、session.、resource. 开头）", cond.Attribute)
+	}
+	if cond.ValueFrom != "" {
+		if cond.Value != nil {
+			return errors.New("不能同时指定 value 与 valueFrom")
+		}
+		if !validAttribute(cond.ValueFrom) {
+			return fmt.Errorf("的 valueFrom %q 无效", cond.ValueFrom)
+		}
+	}
+
+	switch cond.Operator {
+	case OperatorEq, OperatorNe, OperatorContains:
+		if cond.ValueFrom == "" && !isScalar(cond.Value) {
+			return errors.New("的 value 必须是字符串、数字或布尔值")
+		}
+	case OperatorIn, OperatorNotIn:
+		if _, ok := cond.Value.([]interface{}); !ok && cond.ValueFrom == "" {
+			return errors.New("的 value 必须是数组")
+		}
+	case OperatorGt, OperatorGte, OperatorLt, OperatorLte:
+		if _, ok := cond.Value.(float64); !ok && cond.ValueFrom == "" {
+			return errors.New("的 value 必须是数字")
+		}
+	case OperatorExists:
+		if _, ok := cond.Value.(bool); !ok || cond.ValueFrom != "" {
+			return errors.New("的 value 必须是布尔值")
+		}
+	default:
+		return fmt.Errorf("的运算符 %q 无效", cond.Operator)
+	}
+	return nil
+}
+
+// validAttribute 属性名是否可被条件引用
+func validAttribute(name string) bool {
+	if name == "action" {
+		return true
+	}
+	for _, prefix := range attributePrefixes {
+		if strings.HasPrefix(name, prefix) && len(name) > len(prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// firstFailedCondition 返回第一个不满足的条件，全部满足时返回 nil
+// 无法判定的条件（属性缺失、数值比较的类型不符）按策略效果从严处理：allow 策略视为不满足，deny 策略视为满足，
+// 调用方省略属性不能绕过拒绝策略
+func firstFailedCondition(conditions []models.PolicyCondition, attrs map[string]interface{}, effect string) *models.PolicyCondition {
+	for i := range conditions {
+		matched, decidable := evaluateCondition(&conditions[i], attrs)
+		if !decidable {
+			matched = effect == models.PolicyEffectDeny
+		}
+		if !matched {
+			return &conditions[i]
+		}
+	}
+	return nil
+}
+
+// evaluateCondition 判定单个条件；属性（或 valueFrom 引用的属性）不存在、数值比较的类型不符时 decidable 为 false（exists 除外）
+func evaluateCondition(cond *models.PolicyCondition, attrs map[string]interface{}) (matched, decidable bool) {
+	actual, ok := attrs[cond.Attribute]
+	if cond.Operator == OperatorExists {
+		want, _ := cond.Value.(bool)
+		return ok == want, true
+	}
+	if !ok {
+		return false, false
+	}
+
+	expected := cond.Value
+	if cond.ValueFrom != "" {
+		if expected, ok = attrs[cond.ValueFrom]; !ok {
+			return false, false
+		}
+	}
+
+	switch cond.Operator {
+	case OperatorEq:
+		return scalarEqual(actual, expected), true
+	case OperatorNe:
+		return !scalarEqual(actual, expected), true
+	case OperatorIn:
+		return listContains(expected, actual), true
+	case OperatorNotIn:
+		return !listContains(expected, actual), true
+	case OperatorContains:
+		return listContains(actual, expected), true
+	case OperatorGt, OperatorGte, OperatorLt, OperatorLte:
+		a, ok1 := actual.(float64)
+		b, ok2 := expected.(float64)
+		if !ok1 || !ok2 {
+			return false, false
+		}
+		switch cond.Operator {
+		case OperatorGt:
+			return a > b, true
+		case OperatorGte:
+			return a >= b, true
+		case OperatorLt:
+			return a < b, true
+		default:
+			return a <= b, true
+		}
+	}
+	return false, false
+}
+
+// describeCondition 条件的可读描述，用于判定依据
+func describeCondition(cond *models.PolicyCondition) string {
+	if cond.ValueFrom != "" {
+		return fmt.Sprintf("%s %s %s", cond.Attribute, cond.Operator, cond.ValueFrom)
+	}
+	value, _ := json.Marshal(cond.Value)
+	return fmt.Sprintf("%s %s %s", cond.Attribute, cond.Operator, value)
+}
+
+// matchPattern 资源/操作匹配：* 匹配全部，以 * 结尾时按前缀匹配，否则完全一致
+func matchPattern(pattern, value string) bool {
+	if strings.HasSuffix(pattern, "*") {
+		return strings.HasPrefix(value, strings.TrimSuffix(pattern, "*"))
+	}
+	return pattern == value
+}
+
+// parsePolicyConditions 解析 jsonb 中的条件列表
+func parsePolicyConditions(data string) []models.PolicyCondition {
+	conditions := []models.PolicyCondition{}
+	if data != "" {
+		_ = json.Unmarshal([]byte(data), &conditions)
+	}
+	return conditions
+}
+
+// isScalar 是否为 JSON 标量（字符串、数字、布尔）
+func isScalar(v interface{}) bool {
+	switch v.(type) {
+	case string, float64, bool:
+		return true
+	}
+	return false
+}
+
+// scalarEqual 标量比较，列表等非标量一律不相等
+func scalarEqual(a, b interface{}) bool {
+	return isScalar(a) && isScalar(b) && a == b
+}
+
+// listContains list 为数组且包含 item
+func listContains(list, item interface{}) bool {
+	values, ok := list.([]interface{})
+	if !ok {
+		return false
+	}
+	for _, v := range values {
+		if scalarEqual(v, item) {
+			return true
+		}
+	}
+	return false
+}
+
+// stringList 转换为条件求值使用的 []interface{}
+func stringList(values []string) []interface{} {
+	result := make([]interface{}, len(values))
+	for i, v := range values {
+		result[i] = v
+	}
+	return result
+}
+
+// appendUnique 追加不重复的非空字符串
+func appendUnique(list []interface{}, value string) []interface{} {
+	if value == "" {
+		return list
+	}
+	for _, v := range list {
+		if v == value {
+			return list
+		}
+	}
+	return append(list, value)
+}
@@ -0,0 +1,115 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/keenchase/auth-center/internal/models"
+)
+
+func TestEvaluateCondition(t *testing.T) {
+	attrs := map[string]interface{}{
+		"action":              "delete",
+		"user.id":             "u1",
+		"user.roles":          []interface{}{"editor", "viewer"},
+		"session.loginMethod": "wechat",
+		"resource.ownerId":    "u1",
+		"resource.size":       float64(10),
+		"resource.label":      "10",
+	}
+
+	tests := []struct {
+		name      string
+		cond      models.PolicyCondition
+		matched   bool
+		decidable bool
+	}{
+		{"eq", models.PolicyCondition{Attribute: "action", Operator: OperatorEq, Value: "delete"}, true, true},
+		{"ne", models.PolicyCondition{Attribute: "action", Operator: OperatorNe, Value: "delete"}, false, true},
+		{"in", models.PolicyCondition{Attribute: "session.loginMethod", Operator: OperatorIn, Value: []interface{}{"password", "wechat"}}, true, true},
+		{"not_in", models.PolicyCondition{Attribute: "session.loginMethod", Operator: OperatorNotIn, Value: []interface{}{"wechat"}}, false, true},
+		{"contains", models.PolicyCondition{Attribute: "user.roles", Operator: OperatorContains, Value: "editor"}, true, true},
+		{"contains 非列表", models.PolicyCondition{Attribute: "action", Operator: OperatorContains, Value: "delete"}, false, true},
+		{"gt", models.PolicyCondition{Attribute: "resource.size", Operator: OperatorGt, Value: float64(5)}, true, true},
+		{"lte", models.PolicyCondition{Attribute: "resource.size", Operator: OperatorLte, Value: float64(5)}, false, true},
+		{"valueFrom", models.PolicyCondition{Attribute: "resource.ownerId", Operator: OperatorEq, ValueFrom: "user.id"}, true, true},
+		{"exists", models.PolicyCondition{Attribute: "resource.ownerId", Operator: OperatorExists, Value: true}, true, true},
+		{"not exists", models.PolicyCondition{Attribute: "resource.missing", Operator: OperatorExists, Value: false}, true, true},
+		{"eq 不比较字符串与数字", models.PolicyCondition{Attribute: "resource.label", Operator: OperatorEq, Value: float64(10)}, false, true},
+		{"属性缺失", models.PolicyCondition{Attribute: "resource.missing", Operator: OperatorEq, Value: "x"}, false, false},
+		{"valueFrom 缺失", models.PolicyCondition{Attribute: "resource.ownerId", Operator: OperatorEq, ValueFrom: "resource.missing"}, false, false},
+		{"数值比较类型不符", models.PolicyCondition{Attribute: "resource.label", Operator: OperatorGt, Value: float64(5)}, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matched, decidable := evaluateCondition(&tt.cond, attrs)
+			if matched != tt.matched || decidable != tt.decidable {
+				t.Fatalf("evaluateCondition = (%v, %v), want (%v, %v)", matched, decidable, tt.matched, tt.decidable)
+			}
+		})
+	}
+}
+
+func TestFirstFailedConditionMissingAttribute(t *testing.T) {
+	// 拒绝"非本人删除"：调用方漏传 resource.ownerId 时拒绝策略仍然生效
+	conditions := []models.PolicyCondition{
+		{Attribute: "action", Operator: OperatorEq, Value: "delete"},
+		{Attribute: "resource.ownerId", Operator: OperatorNe, ValueFrom: "user.id"},
+	}
+	attrs := map[string]interface{}{"action": "delete", "user.id": "u1"}
+
+	if failed := firstFailedCondition(conditions, attrs, models.PolicyEffectDeny); failed != nil {
+		t.Fatalf("拒绝策略在属性缺失时应生效，失败条件: %s", describeCondition(failed))
+	}
+	if failed := firstFailedCondition(conditions, attrs, models.PolicyEffectAllow); failed != &conditions[1] {
+		t.Fatal("允许策略在属性缺失时不应生效")
+	}
+
+	// 可判定但不满足的条件对两种策略都不生效
+	attrs["action"] = "read"
+	if failed := firstFailedCondition(conditions, attrs, models.PolicyEffectDeny); failed != &conditions[0] {
+		t.Fatal("条件不满足时拒绝策略不应生效")
+	}
+}
+
+func TestValidateCondition(t *testing.T) {
+	tests := []struct {
+		name  string
+		cond  models.PolicyCondition
+		valid bool
+	}{
+		{"合法", models.PolicyCondition{Attribute: "user.roles", Operator: OperatorContains, Value: "admin"}, true},
+		{"valueFrom", models.PolicyCondition{Attribute: "resource.ownerId", Operator: OperatorEq, ValueFrom: "user.id"}, true},
+		{"未知属性前缀", models.PolicyCondition{Attribute: "env.ip", Operator: OperatorEq, Value: "x"}, false},
+		{"只有前缀", models.PolicyCondition{Attribute: "user.", Operator: OperatorEq, Value: "x"}, false},
+		{"value 与 valueFrom 同时指定", models.PolicyCondition{Attribute: "user.id", Operator: OperatorEq, Value: "x", ValueFrom: "resource.ownerId"}, false},
+		{"in 需要数组", models.PolicyCondition{Attribute: "action", Operator: OperatorIn, Value: "read"}, false},
+		{"gt 需要数字", models.PolicyCondition{Attribute: "resource.size", Operator: OperatorGt, Value: "5"}, false},
+		{"exists 需要布尔", models.PolicyCondition{Attribute: "resource.size", Operator: OperatorExists, Value: "yes"}, false},
+		{"未知运算符", models.PolicyCondition{Attribute: "action", Operator: "regex", Value: ".*"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateCondition(&tt.cond); (err == nil) != tt.valid {
+				t.Fatalf("validateCondition = %v, want valid %v", err, tt.valid)
+			}
+		})
+	}
+}
+
+func TestMatchPattern(t *testing.T) {
+	tests := []struct {
+		pattern, value string
+		want           bool
+	}{
+		{"*", "documents", true},
+		{"documents", "documents", true},
+		{"documents", "documents/1", false},
+		{"documents/*", "documents/1", true},
+		{"documents/*", "reports/1", false},
+	}
+	for _, tt := range tests {
+		if got := matchPattern(tt.pattern, tt.value); got != tt.want {
+			t.Errorf("matchPattern(%q, %q) = %v, want %v", tt.pattern, tt.value, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,329 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/keenchase/auth-center/internal/config"
+	"github.com/keenchase/auth-center/internal/models"
+)
+
+const (
+	// QRLoginExpiration 扫码关注登录的有效期（同时是带参数二维码的有效期）
+	QRLoginExpiration = 5 * time.Minute
+
+	// qrLoginScenePrefix 带参数二维码的场景值前缀，场景值为 login_<扫码登录 ID>
+	qrLoginScenePrefix = "login_"
+
+	// qrLoginChannel 扫码完成事件的 Postgres NOTIFY 通道（公众号推送与 PC 轮询可能落在不同副本）
+	qrLoginChannel = "auth_center_qr_login"
+
+	// qrLoginRecheckInterval 等待期间重新查库的间隔（NOTIFY 丢失时兜底）
+	qrLoginRecheckInterval = 3 * time.Second
+
+	// qrLoginRateWindow 按 IP 限制创建扫码登录的统计窗口
+	qrLoginRateWindow = time.Minute
+)
+
+var (
+	// ErrQRLoginNotFound 扫码登录不存在、已过期、已使用、轮询令牌不匹配或不是当前浏览器创建的
+	ErrQRLoginNotFound = errors.New("扫码登录不存在或已过期，请刷新二维码")
+
+	// ErrQRLoginRateLimited 同一 IP 创建扫码登录过于频繁（每次创建都会调用微信接口生成二维码）
+	ErrQRLoginRateLimited = errors.New("创建二维码过于频繁，请稍后再试")
+)
+
+// QRLoginTicket 创建扫码登录的结果（轮询令牌只返回一次）
+type QRLoginTicket struct {
+	ID        string `json:"id"`
+	PollToken string `json:"pollToken"`
+	QRCodeURL string `json:"qrCodeUrl"` // 二维码图片地址
+	ExpiresIn int    `json:"expiresIn"`
+}
+
+// CreateQRLogin 创建扫码关注登录：生成公众号临时带参数二维码，用户扫码或关注后由公众号事件完成登录
+// 扫码登录绑定创建它的浏览器 nonce，只有同一浏览器能完成登录；同一 IP 每分钟最多创建 cfg.QRLoginRateLimit 个
+func CreateQRLogin(ctx context.Context, db *gorm.DB, cfg *config.Config, callbackURL, clientIP, browserNonce string) (*QRLoginTicket, error) {
+	if cfg.WeChatMPAppID == "" {
+		return nil, fmt.Errorf("%w：%s", ErrIdentityProviderNotConfigured, models.LoginMethodWeChatMPQR)
+	}
+	if browserNonce == "" {
+		return nil, errors.New("扫码登录必须绑定浏览器")
+	}
+
+	pollToken, err := generateRandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	record := models.QRLogin{
+		TenantID:         cfg.TenantID,
+		PollTokenHash:    hashToken(pollToken),
+		BrowserNonceHash: hashToken(browserNonce),
+		ClientIP:         clientIP,
+		AppID:            cfg.WeChatMPAppID,
+		CallbackURL:      callbackURL,
+		Status:           models.QRLoginPending,
+		ExpiresAt:        time.Now().Add(QRLoginExpiration),
+	}
+	if err := createRateLimitedQRLogin(db, cfg.QRLoginRateLimit, &record); err != nil {
+		return nil, err
+	}
+
+	ticket, err := createMPQRCode(ctx, db, cfg, qrLoginScenePrefix+record.ID, int(QRLoginExpiration.Seconds()))
+	if err != nil {
+		return nil, fmt.Errorf("创建二维码失败: %w", err)
+	}
+
+	return &QRLoginTicket{
+		ID:        record.ID,
+		PollToken: pollToken,
+		QRCodeURL: "https://mp.weixin.qq.com/cgi-bin/showqrcode?ticket=" + url.QueryEscape(ticket),
+		ExpiresIn: int(QRLoginExpiration.Seconds()),
+	}, nil
+}
+
+// createRateLimitedQRLogin 同一 IP 在统计窗口内未超过 limit 时保存扫码登录（limit <= 0 表示不限制）
+// 多副本按 IP 加事务级 advisory lock 串行计数，事务内不调用微信接口
+func createRateLimitedQRLogin(db *gorm.DB, limit int, record *models.QRLogin) error {
+	if limit <= 0 {
+		return db.Create(record).Error
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", "qr_login:"+record.ClientIP).Error; err != nil {
+			return err
+		}
+		var count int64
+		if err := tx.Model(&models.QRLogin{}).
+			Where("client_ip = ? AND created_at > ?", record.ClientIP, time.Now().Add(-qrLoginRateWindow)).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if count >= int64(limit) {
+			return ErrQRLoginRateLimited
+		}
+		return tx.Create(record).Error
+	})
+}
+
+// ConfirmQRLogin 处理公众号扫码/关注事件：场景值为扫码登录时识别用户并标记为已扫码
+// 场景值不是扫码登录时返回 false
+func ConfirmQRLogin(ctx context.Context, db *gorm.DB, cfg *config.Config, scene, openID string) (bool, error) {
+	id, ok := strings.CutPrefix(scene, qrLoginScenePrefix)
+	if !ok {
+		return false, nil
+	}
+	if _, err := uuid.Parse(id); err != nil {
+		return true, ErrQRLoginNotFound
+	}
+
+	var record models.QRLogin
+	err := db.Where("id = ? AND tenant_id = ? AND app_id = ? AND status = ? AND expires_at > ?",
+		id, cfg.TenantID, cfg.WeChatMPAppID, models.QRLoginPending, time.Now()).
+		First(&record).Error
+	if err != nil {
+		return true, ErrQRLoginNotFound
+	}
+
+	identity, err := fetchMPFollower(ctx, db, cfg, openID)
+	if err != nil {
+		return true, fmt.Errorf("获取用户资料失败: %w", err)
+	}
+	user, err := LinkExternalIdentity(db, cfg, identity)
+	if err != nil {
+		return true, fmt.Errorf("获取或创建用户失败: %w", err)
+	}
+
+	now := time.Now()
+	result := db.Model(&models.QRLogin{}).
+		Where("id = ? AND status = ? AND expires_at > ?", record.ID, models.QRLoginPending, now).
+		Updates(map[string]interface{}{
+			"status":     models.QRLoginConfirmed,
+			"user_id":    user.UserID,
+			"scanned_at": now,
+		})
+	if result.Error != nil {
+		return true, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return true, ErrQRLoginNotFound
+	}
+
+	if err := UpdateLastLogin(db, user.UserID); err != nil {
+		// 记录错误但不中断登录流程
+		log.Printf("警告: 更新最后登录时间失败: %v", err)
+	}
+
+	qrLoginWaiters.wake(record.ID)
+	if err := db.Exec("SELECT pg_notify(?, ?)", qrLoginChannel, record.ID).Error; err != nil {
+		log.Printf("警告: 广播扫码登录事件失败: %v", err)
+	}
+	return true, nil
+}
+
+// handleQRLoginEvent 公众号事件订阅者：扫描带参数二维码（SCAN）或扫码后关注（subscribe，场景值带 qrscene_ 前缀）时完成扫码登录
+func handleQRLoginEvent(ctx context.Context, db *gorm.DB, cfg *config.Config, msg *MPMessage) (string, error) {
+	scene := msg.EventKey
+	if msg.Event == "subscribe" {
+		scene = strings.TrimPrefix(scene, "qrscene_")
+	}
+	if scene == "" {
+		return "", nil
+	}
+
+	handled, err := ConfirmQRLogin(ctx, db, cfg, scene, msg.FromUserName)
+	switch {
+	case !handled:
+		return "", nil
+	case errors.Is(err, ErrQRLoginNotFound):
+		return "二维码已过期，请在电脑上刷新后重新扫码", nil
+	case errors.Is(err, ErrWeChatUnionIDMissing):
+		return "登录失败：" + err.Error(), nil
+	case err != nil:
+		// 临时错误（数据库、微信接口）交给微信重试
+		return "", fmt.Errorf("扫码登录失败: %w", err)
+	}
+	return "扫码成功，请在电脑上继续操作", nil
+}
+
+// WaitQRLogin 等待扫码结果（长轮询），返回状态：pending / confirmed / consumed / expired
+// 超时或请求取消时返回 pending，由前端重新发起
+func WaitQRLogin(ctx context.Context, db *gorm.DB, tenantID, id, pollToken string, timeout time.Duration) (string, error) {
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+
+	for {
+		// 先订阅再查库，避免错过查库与等待之间到达的事件
+		wake := qrLoginWaiters.subscribe(id)
+		status, err := qrLoginStatus(db, tenantID, id, pollToken)
+		if err != nil || status != models.QRLoginPending {
+			qrLoginWaiters.unsubscribe(id, wake)
+			return status, err
+		}
+
+		select {
+		case <-wake:
+		case <-time.After(qrLoginRecheckInterval):
+		case <-deadline.C:
+			qrLoginWaiters.unsubscribe(id, wake)
+			return models.QRLoginPending, nil
+		case <-ctx.Done():
+			qrLoginWaiters.unsubscribe(id, wake)
+			return models.QRLoginPending, nil
+		}
+		qrLoginWaiters.unsubscribe(id, wake)
+	}
+}
+
+// ConsumeQRLogin 作废已扫码的扫码登录（一次性），返回识别出的用户与回调地址
+// 只有创建扫码登录的浏览器（browserNonce 一致）能完成登录，他人的扫码结果无法注入当前浏览器
+func ConsumeQRLogin(db *gorm.DB, tenantID, id, pollToken, browserNonce string) (*models.QRLogin, error) {
+	if _, err := uuid.Parse(id); err != nil || pollToken == "" || browserNonce == "" {
+		return nil, ErrQRLoginNotFound
+	}
+
+	var record models.QRLogin
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND tenant_id = ? AND poll_token_hash = ? AND browser_nonce_hash = ? AND status = ? AND expires_at > ?",
+				id, tenantID, hashToken(pollToken), hashToken(browserNonce), models.QRLoginConfirmed, time.Now()).
+			First(&record).Error; err != nil {
+			return err
+		}
+		return tx.Model(&record).Update("status", models.QRLoginConsumed).Error
+	})
+	if err != nil || record.UserID == nil {
+		return nil, ErrQRLoginNotFound
+	}
+	return &record, nil
+}
+
+// qrLoginStatus 查询扫码登录状态，过期未扫码的为 expired
+func qrLoginStatus(db *gorm.DB, tenantID, id, pollToken string) (string, error) {
+	if _, err := uuid.Parse(id); err != nil || pollToken == "" {
+		return "", ErrQRLoginNotFound
+	}
+
+	var record models.QRLogin
+	err := db.Where("id = ? AND tenant_id = ? AND poll_token_hash = ?", id, tenantID, hashToken(pollToken)).
+		First(&record).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", ErrQRLoginNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+	if record.Status != models.QRLoginConsumed && time.Now().After(record.ExpiresAt) {
+		return "expired", nil
+	}
+	return record.Status, nil
+}
+
+// InitQRLoginNotifier 订阅其他副本的扫码完成事件，唤醒本副本上等待的长轮询
+func InitQRLoginNotifier(cfg *config.Config) {
+	go listenNotifications(cfg.DatabaseURL, qrLoginChannel, qrLoginWaiters.wakeAll, qrLoginWaiters.wake)
+}
+
+// qrLoginWaiterSet 本副本上正在等待扫码结果的长轮询
+type qrLoginWaiterSet struct {
+	mu      sync.Mutex
+	waiters map[string][]chan struct{}
+}
+
+var qrLoginWaiters = &qrLoginWaiterSet{waiters: make(map[string][]chan struct{})}
+
+func (s *qrLoginWaiterSet) subscribe(id string) chan struct{} {
+	ch := make(chan struct{})
+	s.mu.Lock()
+	s.waiters[id] = append(s.waiters[id], ch)
+	s.mu.Unlock()
+	return ch
+}
+
+func (s *qrLoginWaiterSet) unsubscribe(id string, ch chan struct{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	waiters := s.waiters[id]
+	for i, waiter := range waiters {
+		if waiter == ch {
+			waiters = append(waiters[:i], waiters[i+1:]...)
+			break
+		}
+	}
+	if len(waiters) == 0 {
+		delete(s.waiters, id)
+	} else {
+		s.waiters[id] = waiters
+	}
+}
+
+// wake 唤醒等待该扫码登录的长轮询（每个通道只关闭一次）
+func (s *qrLoginWaiterSet) wake(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, ch := range s.waiters[id] {
+		close(ch)
+	}
+	delete(s.waiters, id)
+}
+
+// wakeAll 重新订阅后唤醒全部长轮询，弥补断线期间漏掉的事件
+func (s *qrLoginWaiterSet) wakeAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, waiters := range s.waiters {
+		for _, ch := range waiters {
+			close(ch)
+		}
+		delete(s.waiters, id)
+	}
+}
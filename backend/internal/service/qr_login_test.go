@@ -0,0 +1,66 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/keenchase/auth-center/internal/config"
+	"github.com/keenchase/auth-center/internal/models"
+)
+
+// newTestQRLogin 构造待保存的扫码登录
+func newTestQRLogin(clientIP, browserNonce, pollToken string) *models.QRLogin {
+	return &models.QRLogin{
+		TenantID:         config.DefaultTenantID,
+		PollTokenHash:    hashToken(pollToken),
+		BrowserNonceHash: hashToken(browserNonce),
+		ClientIP:         clientIP,
+		AppID:            "wx-test",
+		CallbackURL:      "https://app.example.com/callback",
+		Status:           models.QRLoginPending,
+		ExpiresAt:        time.Now().Add(QRLoginExpiration),
+	}
+}
+
+func TestCreateQRLoginRateLimit(t *testing.T) {
+	db := testDB(t)
+
+	for i := 0; i < 3; i++ {
+		if err := createRateLimitedQRLogin(db, 3, newTestQRLogin("203.0.113.7", "nonce", "poll")); err != nil {
+			t.Fatalf("第 %d 次创建: %v", i+1, err)
+		}
+	}
+	if err := createRateLimitedQRLogin(db, 3, newTestQRLogin("203.0.113.7", "nonce", "poll")); !errors.Is(err, ErrQRLoginRateLimited) {
+		t.Fatalf("超过限制 err = %v", err)
+	}
+	// 其他 IP 不受影响
+	if err := createRateLimitedQRLogin(db, 3, newTestQRLogin("203.0.113.8", "nonce", "poll")); err != nil {
+		t.Fatalf("其他 IP 创建: %v", err)
+	}
+}
+
+func TestConsumeQRLoginBoundToBrowser(t *testing.T) {
+	db := testDB(t)
+	userID := createTestUser(t, db)
+
+	record := newTestQRLogin("203.0.113.7", "browser-nonce", "poll-token")
+	record.Status = models.QRLoginConfirmed
+	record.UserID = &userID
+	if err := db.Create(record).Error; err != nil {
+		t.Fatalf("创建扫码登录失败: %v", err)
+	}
+
+	for _, nonce := range []string{"other-nonce", ""} {
+		if _, err := ConsumeQRLogin(db, config.DefaultTenantID, record.ID, "poll-token", nonce); !errors.Is(err, ErrQRLoginNotFound) {
+			t.Fatalf("nonce %q 完成登录 err = %v", nonce, err)
+		}
+	}
+	got, err := ConsumeQRLogin(db, config.DefaultTenantID, record.ID, "poll-token", "browser-nonce")
+	if err != nil || *got.UserID != userID {
+		t.Fatalf("ConsumeQRLogin = %v, %v", got, err)
+	}
+	if _, err := ConsumeQRLogin(db, config.DefaultTenantID, record.ID, "poll-token", "browser-nonce"); !errors.Is(err, ErrQRLoginNotFound) {
+		t.Fatalf("重复完成登录 err = %v", err)
+	}
+}
@@ -0,0 +1,487 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/keenchase/auth-center/internal/config"
+	"github.com/keenchase/auth-center/internal/models"
+)
+
+// rbacLockID 修改超级管理员时的 Postgres advisory lock 标识（防止并发移除最后一位超级管理员）
+const rbacLockID int64 = 0x61632d72626163 // "ac-rbac"
+
+var (
+	// ErrUserNotFound 用户不存在
+	ErrUserNotFound = errors.New("用户不存在")
+
+	// ErrRoleNotFound 角色不存在
+	ErrRoleNotFound = errors.New("角色不存在")
+
+	// ErrPermissionNotFound 权限不存在
+	ErrPermissionNotFound = errors.New("权限不存在")
+
+	// ErrInvalidPermissionInput 权限配置不合法
+	ErrInvalidPermissionInput = errors.New("权限配置无效")
+
+	// ErrInvalidRoleInput 角色配置不合法
+	ErrInvalidRoleInput = errors.New("角色配置无效")
+
+	// ErrSystemRole 内置角色不可修改或删除
+	ErrSystemRole = errors.New("内置角色不可修改或删除")
+
+	// ErrLastSuperAdmin 不能移除最后一位超级管理员
+	ErrLastSuperAdmin = errors.New("不能移除最后一位超级管理员")
+
+	// ErrSharedRole auth-center 自身的角色为各租户共用，只能在默认租户修改
+	ErrSharedRole = errors.New("auth-center 的角色为各租户共用，只能在默认租户修改")
+)
+
+// RoleInput 创建/更新角色的参数
+type RoleInput struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+// RoleView 角色及其权限
+type RoleView struct {
+	models.Role
+	Permissions []string `json:"permissions"`
+}
+
+// Authorization 用户在某个客户端内的角色与权限
+type Authorization struct {
+	ClientID    string   `json:"clientId"`
+	Roles       []string `json:"roles"`
+	Permissions []string `json:"permissions"`
+}
+
+// HasPermission 权限列表中是否包含指定权限（* 表示全部权限）
+func HasPermission(permissions []string, want string) bool {
+	for _, p := range permissions {
+		if p == want || p == models.PermissionAll {
+			return true
+		}
+	}
+	return false
+}
+
+// UserPermissions 用户在客户端内通过角色获得的全部权限（clientID 为空表示 auth-center 自身）
+func UserPermissions(db *gorm.DB, clientID, userID string) ([]string, error) {
+	var permissions []string
+	err := db.Model(&models.RolePermission{}).
+		Distinct("role_permissions.permission").
+		Joins("JOIN user_roles ON user_roles.role_id = role_permissions.role_id").
+		Where("user_roles.user_id = ? AND role_permissions.client_id = ?", userID, clientID).
+		Order("role_permissions.permission").
+		Pluck("role_permissions.permission", &permissions).Error
+	return permissions, err
+}
+
+// UserRoleNames 用户在客户端内拥有的角色名称
+func UserRoleNames(db *gorm.DB, clientID, userID string) ([]string, error) {
+	var names []string
+	err := db.Model(&models.Role{}).
+		Joins("JOIN user_roles ON user_roles.role_id = roles.id").
+		Where("user_roles.user_id = ? AND roles.client_id = ?", userID, clientID).
+		Order("roles.name").
+		Pluck("roles.name", &names).Error
+	return names, err
+}
+
+// UserAuthorization 用户在客户端内的角色与权限
+func UserAuthorization(db *gorm.DB, clientID, userID string) (*Authorization, error) {
+	roles, err := UserRoleNames(db, clientID, userID)
+	if err != nil {
+		return nil, err
+	}
+	permissions, err := UserPermissions(db, clientID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if roles == nil {
+		roles = []string{}
+	}
+	if permissions == nil {
+		permissions = []string{}
+	}
+	return &Authorization{ClientID: clientID, Roles: roles, Permissions: permissions}, nil
+}
+
+// UserHasPermission 用户在客户端内是否拥有指定权限
+func UserHasPermission(db *gorm.DB, clientID, userID, permission string) (bool, error) {
+	permissions, err := UserPermissions(db, clientID, userID)
+	if err != nil {
+		return false, err
+	}
+	return HasPermission(permissions, permission), nil
+}
+
+// ListPermissions 列出客户端定义的全部权限
+func ListPermissions(db *gorm.DB, clientID string) ([]models.Permission, error) {
+	if err := requireClient(db, clientID); err != nil {
+		return nil, err
+	}
+	var permissions []models.Permission
+	err := db.Where("client_id = ?", clientID).Order("code").Find(&permissions).Error
+	return permissions, err
+}
+
+// CreatePermission 为业务系统定义权限（auth-center 自身的权限是内置的，不能新增）
+func CreatePermission(db *gorm.DB, clientID, code, description string) (*models.Permission, error) {
+	if clientID == "" {
+		return nil, fmt.Errorf("%w：auth-center 的权限为内置权限", ErrInvalidPermissionInput)
+	}
+	if err := requireClient(db, clientID); err != nil {
+		return nil, err
+	}
+
+	code = strings.TrimSpace(code)
+	if code == "" || len(code) > 100 || strings.ContainsAny(code, " \t\r\n/") {
+		return nil, fmt.Errorf("%w：编码不能为空、不超过 100 个字符且不能包含空白或 /", ErrInvalidPermissionInput)
+	}
+	if utf8.RuneCountInString(description) > 255 {
+		return nil, fmt.Errorf("%w：描述不能超过 255 个字符", ErrInvalidPermissionInput)
+	}
+
+	permission := models.Permission{ClientID: clientID, Code: code, Description: description}
+	result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&permission)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, fmt.Errorf("%w：权限 %s 已存在", ErrInvalidPermissionInput, code)
+	}
+	return &permission, nil
+}
+
+// DeletePermission 删除业务系统的权限（角色中的该权限随之移除）
+func DeletePermission(db *gorm.DB, clientID, code string) error {
+	if clientID == "" {
+		return fmt.Errorf("%w：auth-center 的权限为内置权限", ErrInvalidPermissionInput)
+	}
+	result := db.Where("client_id = ? AND code = ?", clientID, code).Delete(&models.Permission{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrPermissionNotFound
+	}
+	return nil
+}
+
+// ListRoles 列出客户端的全部角色及其权限
+func ListRoles(db *gorm.DB, clientID string) ([]RoleView, error) {
+	if err := requireClient(db, clientID); err != nil {
+		return nil, err
+	}
+	var roles []models.Role
+	if err := db.Where("client_id = ?", clientID).Order("created_at").Find(&roles).Error; err != nil {
+		return nil, err
+	}
+	return roleViews(db, roles)
+}
+
+// CreateRole 在客户端内创建角色
+func CreateRole(db *gorm.DB, clientID string, input RoleInput) (*RoleView, error) {
+	if err := requireClient(db, clientID); err != nil {
+		return nil, err
+	}
+	role := models.Role{ClientID: clientID}
+	if err := applyRoleInput(db, &role, input); err != nil {
+		return nil, err
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&role).Error; err != nil {
+			return err
+		}
+		return setRolePermissions(tx, &role, input.Permissions)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &RoleView{Role: role, Permissions: input.Permissions}, nil
+}
+
+// UpdateRole 更新角色名称、描述与权限（全量替换）
+func UpdateRole(db *gorm.DB, tenantID, roleID string, input RoleInput) (*RoleView, error) {
+	role, err := manageableRole(db, tenantID, roleID)
+	if err != nil {
+		return nil, err
+	}
+	if role.IsSystem {
+		return nil, ErrSystemRole
+	}
+	if err := applyRoleInput(db, role, input); err != nil {
+		return nil, err
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Select("name", "description", "updated_at").Save(role).Error; err != nil {
+			return err
+		}
+		return setRolePermissions(tx, role, input.Permissions)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &RoleView{Role: *role, Permissions: input.Permissions}, nil
+}
+
+// DeleteRole 删除角色（用户角色级联删除）
+func DeleteRole(db *gorm.DB, tenantID, roleID string) error {
+	role, err := manageableRole(db, tenantID, roleID)
+	if err != nil {
+		return err
+	}
+	if role.IsSystem {
+		return ErrSystemRole
+	}
+	return db.Delete(role).Error
+}
+
+// ListUserRoles 列出用户拥有的全部角色（含各业务系统的角色）
+func ListUserRoles(db *gorm.DB, userID string) ([]RoleView, error) {
+	var roles []models.Role
+	if err := db.Joins("JOIN user_roles ON user_roles.role_id = roles.id").
+		Where("user_roles.user_id = ?", userID).
+		Order("roles.client_id, roles.name").
+		Find(&roles).Error; err != nil {
+		return nil, err
+	}
+	return roleViews(db, roles)
+}
+
+// AssignRole 为租户内的用户授予该租户可见的角色（已拥有时不报错）
+func AssignRole(db *gorm.DB, tenantID, userID, roleID, grantedBy string) error {
+	if err := UserInTenant(db, tenantID, userID); err != nil {
+		return err
+	}
+	if _, err := TenantRole(db, tenantID, roleID); err != nil {
+		return err
+	}
+
+	userRole := models.UserRole{UserID: userID, RoleID: roleID}
+	if grantedBy != "" {
+		userRole.GrantedBy = &grantedBy
+	}
+	return db.Clauses(clause.OnConflict{DoNothing: true}).Create(&userRole).Error
+}
+
+// RevokeRole 移除用户的角色，不能移除租户内最后一位超级管理员
+func RevokeRole(db *gorm.DB, tenantID, userID, roleID string) error {
+	role, err := TenantRole(db, tenantID, roleID)
+	if err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if role.ClientID == "" && role.Name == models.RoleSuperAdmin {
+			if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", rbacLockID).Error; err != nil {
+				return err
+			}
+			var others int64
+			if err := tx.Model(&models.UserRole{}).
+				Joins("JOIN users ON users.user_id = user_roles.user_id").
+				Where("user_roles.role_id = ? AND user_roles.user_id <> ? AND users.tenant_id = ?", roleID, userID, tenantID).
+				Count(&others).Error; err != nil {
+				return err
+			}
+			if others == 0 {
+				return ErrLastSuperAdmin
+			}
+		}
+		return tx.Where("user_id = ? AND role_id = ?", userID, roleID).Delete(&models.UserRole{}).Error
+	})
+}
+
+// EnsureBootstrapAdmin 租户内还没有任何超级管理员时，将 UnionID 对应的该租户用户设为超级管理员
+func EnsureBootstrapAdmin(db *gorm.DB, tenantID, unionID string) error {
+	if unionID == "" {
+		return nil
+	}
+
+	var role models.Role
+	if err := db.Where("client_id = '' AND name = ?", models.RoleSuperAdmin).First(&role).Error; err != nil {
+		return fmt.Errorf("内置超级管理员角色不存在: %w", err)
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", rbacLockID).Error; err != nil {
+			return err
+		}
+		var holders int64
+		if err := tx.Model(&models.UserRole{}).
+			Joins("JOIN users ON users.user_id = user_roles.user_id").
+			Where("user_roles.role_id = ? AND users.tenant_id = ?", role.ID, tenantID).
+			Count(&holders).Error; err != nil {
+			return err
+		}
+		if holders > 0 {
+			return nil
+		}
+
+		var user models.User
+		err := tx.Where("tenant_id = ? AND union_id = ?", tenantID, unionID).First(&user).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Printf("租户 %s 的初始超级管理员尚未登录，首次登录后授予超级管理员", tenantID)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		log.Printf("授予租户 %s 的初始超级管理员: %s", tenantID, user.UserID)
+		return tx.Create(&models.UserRole{UserID: user.UserID, RoleID: role.ID}).Error
+	})
+}
+
+// findRole 按 ID 获取角色
+func findRole(db *gorm.DB, roleID string) (*models.Role, error) {
+	var role models.Role
+	err := db.Where("id = ?", roleID).First(&role).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrRoleNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+// TenantRole 获取租户可见的角色：auth-center 自身的角色各租户共用，业务系统的角色只属于该系统所在租户
+func TenantRole(db *gorm.DB, tenantID, roleID string) (*models.Role, error) {
+	role, err := findRole(db, roleID)
+	if err != nil {
+		return nil, err
+	}
+	if role.ClientID == "" {
+		return role, nil
+	}
+	if err := ClientInTenant(db, tenantID, role.ClientID); errors.Is(err, ErrClientNotFound) {
+		return nil, ErrRoleNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	return role, nil
+}
+
+// manageableRole 获取租户可修改的角色（共用角色只能在默认租户修改）
+func manageableRole(db *gorm.DB, tenantID, roleID string) (*models.Role, error) {
+	role, err := TenantRole(db, tenantID, roleID)
+	if err != nil {
+		return nil, err
+	}
+	if role.ClientID == "" && tenantID != config.DefaultTenantID {
+		return nil, ErrSharedRole
+	}
+	return role, nil
+}
+
+// requireClient 校验客户端存在（clientID 为空表示 auth-center 自身）
+func requireClient(db *gorm.DB, clientID string) error {
+	if clientID == "" {
+		return nil
+	}
+	_, err := findClientRecord(db, clientID)
+	return err
+}
+
+// roleViews 批量查询角色的权限
+func roleViews(db *gorm.DB, roles []models.Role) ([]RoleView, error) {
+	result := make([]RoleView, len(roles))
+	if len(roles) == 0 {
+		return result, nil
+	}
+
+	ids := make([]string, len(roles))
+	for i := range roles {
+		ids[i] = roles[i].ID
+	}
+	var rows []models.RolePermission
+	if err := db.Where("role_id IN ?", ids).Order("permission").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	byRole := make(map[string][]string)
+	for _, row := range rows {
+		byRole[row.RoleID] = append(byRole[row.RoleID], row.Permission)
+	}
+
+	for i := range roles {
+		permissions := byRole[roles[i].ID]
+		if permissions == nil {
+			permissions = []string{}
+		}
+		result[i] = RoleView{Role: roles[i], Permissions: permissions}
+	}
+	return result, nil
+}
+
+// setRolePermissions 全量替换角色的权限
+func setRolePermissions(tx *gorm.DB, role *models.Role, permissions []string) error {
+	if err := tx.Where("role_id = ?", role.ID).Delete(&models.RolePermission{}).Error; err != nil {
+		return err
+	}
+	if len(permissions) == 0 {
+		return nil
+	}
+	rows := make([]models.RolePermission, len(permissions))
+	for i, p := range permissions {
+		rows[i] = models.RolePermission{RoleID: role.ID, ClientID: role.ClientID, Permission: p}
+	}
+	return tx.Create(&rows).Error
+}
+
+// applyRoleInput 校验并写入角色字段，权限必须是同一客户端内已存在的权限且不重复
+func applyRoleInput(db *gorm.DB, role *models.Role, input RoleInput) error {
+	name := strings.TrimSpace(input.Name)
+	if name == "" || utf8.RuneCountInString(name) > 100 {
+		return fmt.Errorf("%w：名称不能为空且不超过 100 个字符", ErrInvalidRoleInput)
+	}
+	if utf8.RuneCountInString(input.Description) > 255 {
+		return fmt.Errorf("%w：描述不能超过 255 个字符", ErrInvalidRoleInput)
+	}
+
+	var existing int64
+	query := db.Model(&models.Role{}).Where("client_id = ? AND name = ?", role.ClientID, name)
+	if role.ID != "" {
+		query = query.Where("id <> ?", role.ID)
+	}
+	if err := query.Count(&existing).Error; err != nil {
+		return err
+	}
+	if existing > 0 {
+		return fmt.Errorf("%w：角色 %s 已存在", ErrInvalidRoleInput, name)
+	}
+
+	seen := make(map[string]bool, len(input.Permissions))
+	for _, p := range input.Permissions {
+		if seen[p] {
+			return fmt.Errorf("%w：权限 %s 重复", ErrInvalidRoleInput, p)
+		}
+		seen[p] = true
+	}
+	if len(input.Permissions) > 0 {
+		var known int64
+		if err := db.Model(&models.Permission{}).Where("client_id = ? AND code IN ?", role.ClientID, input.Permissions).Count(&known).Error; err != nil {
+			return err
+		}
+		if int(known) != len(input.Permissions) {
+			return fmt.Errorf("%w：包含不存在的权限", ErrInvalidRoleInput)
+		}
+	}
+
+	role.Name = name
+	role.Description = input.Description
+	role.UpdatedAt = time.Now()
+	return nil
+}
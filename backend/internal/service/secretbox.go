@@ -0,0 +1,102 @@
+package service
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/keenchase/auth-center/internal/config"
+)
+
+// SecretBox 敏感数据静态加密（AES-256-GCM）
+type SecretBox struct {
+	aead cipher.AEAD
+}
+
+// NewSecretBox 使用 32 字节密钥创建 SecretBox
+func NewSecretBox(key []byte) (*SecretBox, error) {
+	if len(key) != 32 {
+		return nil, errors.New("加密密钥长度必须为 32 字节")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &SecretBox{aead: aead}, nil
+}
+
+// Seal 加密并返回 base64(nonce || ciphertext)；aad 用于把密文绑定到所属记录
+func (b *SecretBox) Seal(plaintext []byte, aad string) (string, error) {
+	nonce := make([]byte, b.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := b.aead.Seal(nonce, nonce, plaintext, []byte(aad))
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Open 解密 Seal 的输出
+func (b *SecretBox) Open(encoded string, aad string) ([]byte, error) {
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("密文格式错误: %w", err)
+	}
+	if len(data) < b.aead.NonceSize() {
+		return nil, errors.New("密文长度错误")
+	}
+	nonce, ciphertext := data[:b.aead.NonceSize()], data[b.aead.NonceSize():]
+	plaintext, err := b.aead.Open(nil, nonce, ciphertext, []byte(aad))
+	if err != nil {
+		return nil, errors.New("解密失败")
+	}
+	return plaintext, nil
+}
+
+// defaultSecretBox 进程内的静态加密器，由 InitSecretBox 初始化
+var defaultSecretBox *SecretBox
+
+// DefaultSecretBox 返回进程内的静态加密器
+func DefaultSecretBox() *SecretBox {
+	return defaultSecretBox
+}
+
+// InitSecretBox 初始化静态加密密钥
+// 生产环境必须配置独立的 DATA_ENCRYPTION_KEY（base64 编码的 32 字节），
+// 不能从 AUTH_CENTER_SECRET 派生，否则轮换该密钥会导致已加密数据无法解密
+func InitSecretBox(cfg *config.Config) error {
+	var key []byte
+	if cfg.DataEncryptionKey != "" {
+		decoded, err := base64.StdEncoding.DecodeString(cfg.DataEncryptionKey)
+		if err != nil {
+			return fmt.Errorf("DATA_ENCRYPTION_KEY 不是有效的 base64: %w", err)
+		}
+		key = decoded
+	} else {
+		if cfg.Environment == "production" || cfg.JWTSecret == "" {
+			return errors.New("未配置 DATA_ENCRYPTION_KEY")
+		}
+		derived, err := hkdf.Key(sha256.New, []byte(cfg.JWTSecret), nil, "auth-center data encryption", 32)
+		if err != nil {
+			return err
+		}
+		key = derived
+		log.Printf("警告: 未配置 DATA_ENCRYPTION_KEY，从 AUTH_CENTER_SECRET 派生加密密钥（仅限开发环境）")
+	}
+
+	box, err := NewSecretBox(key)
+	if err != nil {
+		return err
+	}
+	defaultSecretBox = box
+	return nil
+}
@@ -0,0 +1,71 @@
+package service
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+)
+
+// newTestSecretBox 使用随机密钥创建 SecretBox
+func newTestSecretBox(t *testing.T) *SecretBox {
+	t.Helper()
+	key := make([]byte, 32)
+	rand.Read(key)
+	box, err := NewSecretBox(key)
+	if err != nil {
+		t.Fatalf("NewSecretBox: %v", err)
+	}
+	return box
+}
+
+func TestSecretBoxRoundTrip(t *testing.T) {
+	box := newTestSecretBox(t)
+	plaintext := []byte("wechat-app-secret")
+
+	sealed, err := box.Seal(plaintext, "kid-1")
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+	opened, err := box.Open(sealed, "kid-1")
+	if err != nil || !bytes.Equal(opened, plaintext) {
+		t.Fatalf("Open = %q, %v", opened, err)
+	}
+
+	// 每次加密使用随机 nonce
+	if again, _ := box.Seal(plaintext, "kid-1"); again == sealed {
+		t.Fatal("相同明文两次加密的密文不应相同")
+	}
+}
+
+func TestSecretBoxRejectsTampering(t *testing.T) {
+	box := newTestSecretBox(t)
+	sealed, err := box.Seal([]byte("secret"), "kid-1")
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+
+	if _, err := box.Open(sealed, "kid-2"); err == nil {
+		t.Fatal("aad 不一致时应解密失败")
+	}
+	if _, err := newTestSecretBox(t).Open(sealed, "kid-1"); err == nil {
+		t.Fatal("密钥不一致时应解密失败")
+	}
+
+	tampered := []byte(sealed)
+	tampered[len(tampered)/2] ^= 1
+	if _, err := box.Open(string(tampered), "kid-1"); err == nil {
+		t.Fatal("篡改的密文应解密失败")
+	}
+	if _, err := box.Open("AAAA", "kid-1"); err == nil {
+		t.Fatal("过短的密文应解密失败")
+	}
+	if _, err := box.Open("not base64!", "kid-1"); err == nil {
+		t.Fatal("非 base64 密文应解密失败")
+	}
+}
+
+func TestNewSecretBoxKeyLength(t *testing.T) {
+	if _, err := NewSecretBox(make([]byte, 16)); err == nil {
+		t.Fatal("非 32 字节密钥应被拒绝")
+	}
+}
@@ -0,0 +1,169 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+
+	"github.com/keenchase/auth-center/internal/models"
+)
+
+const (
+	// sessionTouchInterval last_seen_at 的最小更新间隔
+	sessionTouchInterval = time.Minute
+
+	// maxSessionNameLength 设备名称最大长度（字符）
+	maxSessionNameLength = 100
+)
+
+var (
+	// ErrSessionNotFound 会话不存在或不属于当前用户
+	ErrSessionNotFound = errors.New("会话不存在")
+
+	// ErrInvalidSessionName 设备名称不合法
+	ErrInvalidSessionName = errors.New("设备名称不能超过 100 个字符")
+)
+
+// SessionView 对外展示的会话（不含可用的令牌）
+type SessionView struct {
+	ID          string             `json:"id"`
+	Name        string             `json:"name,omitempty"`
+	MaskedToken string             `json:"maskedToken"`
+	Device      *models.DeviceInfo `json:"device,omitempty"`
+	IP          string             `json:"ip,omitempty"`
+	App         string             `json:"app,omitempty"`
+	ClientID    string             `json:"clientId,omitempty"`
+	Current     bool               `json:"current"`
+	CreatedAt   time.Time          `json:"createdAt"`
+	LastSeenAt  *time.Time         `json:"lastSeenAt,omitempty"`
+	ExpiresAt   *time.Time         `json:"expiresAt"`
+}
+
+// NewSessionView 将会话记录转换为展示视图
+func NewSessionView(session *models.Session, currentSessionID string) SessionView {
+	view := SessionView{
+		ID:          session.ID,
+		MaskedToken: "****" + session.TokenHint,
+		ClientID:    session.ClientID,
+		App:         session.ClientID,
+		Current:     session.ID == currentSessionID,
+		CreatedAt:   session.CreatedAt,
+		LastSeenAt:  session.LastSeenAt,
+		ExpiresAt:   session.ExpiresAt,
+	}
+	if session.Name != nil {
+		view.Name = *session.Name
+	}
+	if device := ParseDeviceInfo(session.DeviceInfo); device != nil {
+		view.Device = device
+		view.IP = device.IP
+		if device.App != "" {
+			view.App = device.App
+		}
+	}
+	return view
+}
+
+// ParseDeviceInfo 解析 sessions.device_info，为空或格式错误时返回 nil
+func ParseDeviceInfo(raw *string) *models.DeviceInfo {
+	if raw == nil || *raw == "" {
+		return nil
+	}
+	var device models.DeviceInfo
+	if err := json.Unmarshal([]byte(*raw), &device); err != nil {
+		return nil
+	}
+	if device == (models.DeviceInfo{}) {
+		return nil
+	}
+	return &device
+}
+
+// ListSessions 列出用户的有效会话（最近使用的在前）
+func ListSessions(db *gorm.DB, userID, currentSessionID string) ([]SessionView, error) {
+	var sessions []models.Session
+	if err := db.Where("user_id = ? AND expires_at > ?", userID, time.Now()).
+		Order("COALESCE(last_seen_at, created_at) DESC").
+		Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+
+	result := make([]SessionView, len(sessions))
+	for i := range sessions {
+		result[i] = NewSessionView(&sessions[i], currentSessionID)
+	}
+	return result, nil
+}
+
+// RevokeUserSession 吊销用户自己的某个会话
+func RevokeUserSession(db *gorm.DB, userID, sessionID string) error {
+	var count int64
+	if err := db.Model(&models.Session{}).Where("id = ? AND user_id = ?", sessionID, userID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrSessionNotFound
+	}
+	return RevokeSessions(db, sessionID)
+}
+
+// RevokeOtherSessions 吊销用户除当前会话外的全部会话，返回吊销数量
+func RevokeOtherSessions(db *gorm.DB, userID, currentSessionID string) (int, error) {
+	var ids []string
+	if err := db.Model(&models.Session{}).
+		Where("user_id = ? AND id <> ?", userID, currentSessionID).
+		Pluck("id", &ids).Error; err != nil {
+		return 0, err
+	}
+	if err := RevokeSessions(db, ids...); err != nil {
+		return 0, err
+	}
+	return len(ids), nil
+}
+
+// RenameSession 为用户自己的会话设置设备名称，name 为空时清除
+func RenameSession(db *gorm.DB, userID, sessionID, name string) error {
+	name = strings.TrimSpace(name)
+	if utf8.RuneCountInString(name) > maxSessionNameLength {
+		return ErrInvalidSessionName
+	}
+
+	var value interface{}
+	if name != "" {
+		value = name
+	}
+	result := db.Model(&models.Session{}).
+		Where("id = ? AND user_id = ?", sessionID, userID).
+		Update("name", value)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// touchSession 节流更新会话的最近使用时间
+func touchSession(db *gorm.DB, sessionID string) {
+	now := time.Now()
+	db.Model(&models.Session{}).
+		Where("id = ? AND (last_seen_at IS NULL OR last_seen_at < ?)", sessionID, now.Add(-sessionTouchInterval)).
+		Update("last_seen_at", now)
+}
+
+// RevokeAllSessions 吊销用户的全部会话（管理员操作），返回吊销数量
+func RevokeAllSessions(db *gorm.DB, userID string) (int, error) {
+	var ids []string
+	if err := db.Model(&models.Session{}).Where("user_id = ?", userID).Pluck("id", &ids).Error; err != nil {
+		return 0, err
+	}
+	if err := RevokeSessions(db, ids...); err != nil {
+		return 0, err
+	}
+	return len(ids), nil
+}
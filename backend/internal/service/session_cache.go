@@ -0,0 +1,154 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/keenchase/auth-center/internal/cache"
+	"github.com/keenchase/auth-center/internal/config"
+	"github.com/keenchase/auth-center/internal/models"
+)
+
+const (
+	// sessionRevokedChannel 会话吊销事件的 Postgres NOTIFY 通道
+	sessionRevokedChannel = "auth_center_session_revoked"
+
+	// sessionNotifyBatch 单条 NOTIFY 携带的会话数（payload 上限 8000 字节）
+	sessionNotifyBatch = 100
+
+	// sessionRevokedMarker 缓存中表示会话已失效的值
+	sessionRevokedMarker = "-"
+)
+
+// ErrSessionRevoked 会话已登出、吊销或过期
+var ErrSessionRevoked = errors.New("会话已失效")
+
+// SessionCache 会话状态缓存：sid → userID（或失效标记）
+// 进程内模式下，其他副本的吊销事件通过 Postgres LISTEN/NOTIFY 实时同步
+type SessionCache struct {
+	cache cache.Cache
+	ttl   time.Duration
+}
+
+// defaultSessionCache 进程内的会话状态缓存，由 InitSessionCache 初始化；未初始化时每次都查数据库
+var defaultSessionCache *SessionCache
+
+// InitSessionCache 初始化会话状态缓存
+func InitSessionCache(cfg *config.Config) error {
+	sc := &SessionCache{ttl: cfg.SessionCacheTTL}
+
+	switch cfg.SessionCache {
+	case "redis":
+		if cfg.RedisURL == "" {
+			return errors.New("SESSION_CACHE=redis 时必须配置 REDIS_URL")
+		}
+		r, err := cache.NewRedis(cfg.RedisURL, "auth-center:session:")
+		if err != nil {
+			return err
+		}
+		sc.cache = r
+	case "memory", "":
+		sc.cache = cache.NewLRU(cfg.SessionCacheSize)
+		go listenSessionRevocations(cfg.DatabaseURL, sc)
+	default:
+		return fmt.Errorf("不支持的 SESSION_CACHE: %s", cfg.SessionCache)
+	}
+
+	defaultSessionCache = sc
+	return nil
+}
+
+// CheckSession 检查会话是否有效，返回会话所属用户
+func CheckSession(db *gorm.DB, sessionID string) (string, error) {
+	sc := defaultSessionCache
+	if sc != nil {
+		if value, ok := sc.cache.Get(sessionID); ok {
+			if value == sessionRevokedMarker {
+				return "", ErrSessionRevoked
+			}
+			return value, nil
+		}
+	}
+
+	var session models.Session
+	err := db.Select("id", "user_id", "expires_at").
+		Where("id = ? AND expires_at > ?", sessionID, time.Now()).
+		First(&session).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		if sc != nil {
+			sc.cache.Set(sessionID, sessionRevokedMarker, sc.ttl)
+		}
+		return "", ErrSessionRevoked
+	}
+	if err != nil {
+		return "", err
+	}
+
+	// 缓存未命中时顺带更新最近使用时间（频率受缓存有效期限制）
+	touchSession(db, sessionID)
+
+	if sc != nil {
+		ttl := sc.ttl
+		if session.ExpiresAt != nil && time.Until(*session.ExpiresAt) < ttl {
+			ttl = time.Until(*session.ExpiresAt)
+		}
+		// 查询之后会话可能刚被吊销（本副本或其他副本的 NOTIFY 已写入失效标记）：
+		// 只在没有缓存条目时写入，写入失败时以缓存中的失效标记为准，不能用查询结果覆盖它
+		if !sc.cache.Add(sessionID, session.UserID, ttl) {
+			if value, ok := sc.cache.Get(sessionID); ok && value == sessionRevokedMarker {
+				return "", ErrSessionRevoked
+			}
+		}
+	}
+	return session.UserID, nil
+}
+
+// RevokeSessions 删除会话（刷新令牌级联删除），并立即让所有副本的缓存失效
+func RevokeSessions(db *gorm.DB, sessionIDs ...string) error {
+	if len(sessionIDs) == 0 {
+		return nil
+	}
+	if err := db.Where("id IN ?", sessionIDs).Delete(&models.Session{}).Error; err != nil {
+		return err
+	}
+	invalidateSessions(db, sessionIDs...)
+	return nil
+}
+
+// invalidateSessions 在本副本缓存中写入失效标记并广播吊销事件
+func invalidateSessions(db *gorm.DB, sessionIDs ...string) {
+	if sc := defaultSessionCache; sc != nil {
+		sc.markRevoked(sessionIDs...)
+	}
+
+	for start := 0; start < len(sessionIDs); start += sessionNotifyBatch {
+		end := start + sessionNotifyBatch
+		if end > len(sessionIDs) {
+			end = len(sessionIDs)
+		}
+		payload := strings.Join(sessionIDs[start:end], ",")
+		if err := db.Exec("SELECT pg_notify(?, ?)", sessionRevokedChannel, payload).Error; err != nil {
+			log.Printf("警告: 广播会话吊销事件失败: %v", err)
+		}
+	}
+}
+
+// markRevoked 写入失效标记（而不是删除条目），并发的 CheckSession 不会再用吊销前的查询结果填充缓存
+func (sc *SessionCache) markRevoked(sessionIDs ...string) {
+	for _, id := range sessionIDs {
+		sc.cache.Set(id, sessionRevokedMarker, sc.ttl)
+	}
+}
+
+// listenSessionRevocations 订阅其他副本的会话吊销事件
+// 重连期间可能漏掉事件，因此每次（重新）订阅成功后清空本地缓存
+func listenSessionRevocations(databaseURL string, sc *SessionCache) {
+	listenNotifications(databaseURL, sessionRevokedChannel, sc.cache.Purge, func(payload string) {
+		sc.markRevoked(strings.Split(payload, ",")...)
+	})
+}
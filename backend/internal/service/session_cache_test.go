@@ -0,0 +1,105 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/keenchase/auth-center/internal/cache"
+	"github.com/keenchase/auth-center/internal/config"
+	"github.com/keenchase/auth-center/internal/models"
+)
+
+// interleavedCache 在 CheckSession 写入查询结果之前执行 beforeAdd，模拟查询与写缓存之间发生的吊销
+type interleavedCache struct {
+	cache.Cache
+	beforeAdd func()
+}
+
+func (c *interleavedCache) Add(key, value string, ttl time.Duration) bool {
+	if c.beforeAdd != nil {
+		c.beforeAdd()
+		c.beforeAdd = nil
+	}
+	return c.Cache.Add(key, value, ttl)
+}
+
+// useTestSessionCache 替换进程内的会话状态缓存，测试结束时恢复
+func useTestSessionCache(t *testing.T, c cache.Cache) *SessionCache {
+	t.Helper()
+	previous := defaultSessionCache
+	sc := &SessionCache{cache: c, ttl: time.Minute}
+	defaultSessionCache = sc
+	t.Cleanup(func() { defaultSessionCache = previous })
+	return sc
+}
+
+func TestCheckSessionRevokedDuringLookup(t *testing.T) {
+	db := testDB(t)
+	useTestKeySet(t)
+	cfg := &config.Config{TenantID: config.DefaultTenantID, AccessTokenTTL: time.Minute, RefreshTokenTTL: time.Hour}
+	userID := createTestUser(t, db)
+
+	tests := []struct {
+		name   string
+		revoke func(sc *SessionCache, sessionID string)
+	}{
+		{"本副本吊销", func(sc *SessionCache, sessionID string) {
+			if err := RevokeSessions(db, sessionID); err != nil {
+				t.Fatalf("RevokeSessions: %v", err)
+			}
+		}},
+		{"其他副本的吊销事件", func(sc *SessionCache, sessionID string) {
+			// 会话在其他副本被删除，NOTIFY 先于本副本写缓存到达
+			if err := db.Where("id = ?", sessionID).Delete(&models.Session{}).Error; err != nil {
+				t.Fatalf("删除会话失败: %v", err)
+			}
+			sc.markRevoked(sessionID)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens, err := IssueTokens(db, cfg, userID, TokenOptions{LoginMethod: models.LoginMethodPassword})
+			if err != nil {
+				t.Fatalf("IssueTokens: %v", err)
+			}
+			c := &interleavedCache{Cache: cache.NewLRU(100)}
+			sc := useTestSessionCache(t, c)
+			c.beforeAdd = func() { tt.revoke(sc, tokens.SessionID) }
+
+			if _, err := CheckSession(db, tokens.SessionID); !errors.Is(err, ErrSessionRevoked) {
+				t.Fatalf("查询期间吊销 err = %v, want ErrSessionRevoked", err)
+			}
+			// 缓存中保留失效标记，后续请求不再放行
+			if _, err := CheckSession(db, tokens.SessionID); !errors.Is(err, ErrSessionRevoked) {
+				t.Fatalf("再次检查 err = %v, want ErrSessionRevoked", err)
+			}
+		})
+	}
+}
+
+func TestCheckSessionCachesValidSession(t *testing.T) {
+	db := testDB(t)
+	useTestKeySet(t)
+	cfg := &config.Config{TenantID: config.DefaultTenantID, AccessTokenTTL: time.Minute, RefreshTokenTTL: time.Hour}
+	userID := createTestUser(t, db)
+	sc := useTestSessionCache(t, cache.NewLRU(100))
+
+	tokens, err := IssueTokens(db, cfg, userID, TokenOptions{LoginMethod: models.LoginMethodPassword})
+	if err != nil {
+		t.Fatalf("IssueTokens: %v", err)
+	}
+	if got, err := CheckSession(db, tokens.SessionID); err != nil || got != userID {
+		t.Fatalf("CheckSession = %q, %v", got, err)
+	}
+	if value, ok := sc.cache.Get(tokens.SessionID); !ok || value != userID {
+		t.Fatalf("缓存 = %q, %v", value, ok)
+	}
+
+	if err := RevokeSessions(db, tokens.SessionID); err != nil {
+		t.Fatalf("RevokeSessions: %v", err)
+	}
+	if _, err := CheckSession(db, tokens.SessionID); !errors.Is(err, ErrSessionRevoked) {
+		t.Fatalf("吊销后 err = %v", err)
+	}
+}
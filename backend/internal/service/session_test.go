@@ -0,0 +1,38 @@
+package service
+
+import (
+	"os"
+	"testing"
+)
+
+func TestLegacySessionTokenBackfill(t *testing.T) {
+	db := testDB(t)
+	userID := createTestUser(t, db)
+	migration, err := os.ReadFile("../../migrations/007_hash_session_tokens.up.sql")
+	if err != nil {
+		t.Fatalf("读取迁移失败: %v", err)
+	}
+
+	// 还原 007 之前的表结构：明文令牌列，尚未回填摘要
+	if err := db.Exec("ALTER TABLE sessions ADD COLUMN token VARCHAR(500); ALTER TABLE sessions ALTER COLUMN token_hash DROP NOT NULL").Error; err != nil {
+		t.Fatalf("还原旧表结构失败: %v", err)
+	}
+	const token = "legacy-opaque-session-token-0123456789"
+	if err := db.Exec("INSERT INTO sessions (user_id, token, expires_at, created_at) VALUES (?, ?, NOW() + INTERVAL '1 hour', NOW())",
+		userID, token).Error; err != nil {
+		t.Fatalf("创建旧会话失败: %v", err)
+	}
+
+	if err := db.Exec(string(migration)).Error; err != nil {
+		t.Fatalf("执行 007 迁移失败: %v", err)
+	}
+
+	// 回填后按令牌摘要定位会话，令牌末尾 8 位保留为提示
+	session, err := GetSessionByToken(db, token)
+	if err != nil {
+		t.Fatalf("GetSessionByToken: %v", err)
+	}
+	if session.UserID != userID || session.TokenHash != hashToken(token) || session.TokenHint != token[len(token)-8:] {
+		t.Fatalf("session = %+v", session)
+	}
+}
@@ -0,0 +1,577 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/keenchase/auth-center/internal/config"
+	"github.com/keenchase/auth-center/internal/models"
+)
+
+const (
+	// tenantsChangedChannel 租户配置变更事件的 Postgres NOTIFY 通道
+	tenantsChangedChannel = "auth_center_tenants_changed"
+
+	// tenantReloadInterval 各副本定期全量重新加载租户的周期（NOTIFY 丢失时兜底）
+	tenantReloadInterval = time.Minute
+)
+
+var (
+	// ErrTenantNotFound 租户不存在或已停用
+	ErrTenantNotFound = errors.New("租户不存在")
+
+	// ErrInvalidTenantInput 租户配置不合法
+	ErrInvalidTenantInput = errors.New("租户配置无效")
+)
+
+var (
+	tenantIDPattern   = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{1,49}$`)
+	tenantHostPattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9.-]*[a-z0-9])?(:[0-9]{1,5})?$`)
+)
+
+// tenantSet 已启用租户的快照
+type tenantSet struct {
+	byID   map[string]*models.Tenant
+	byHost map[string]*models.Tenant
+
+	// configs 租户 ID → 解密后的租户配置，随快照一起替换（租户变更后自然失效）
+	configs sync.Map
+}
+
+func newTenantSet(tenants []models.Tenant) *tenantSet {
+	set := &tenantSet{
+		byID:   make(map[string]*models.Tenant, len(tenants)),
+		byHost: make(map[string]*models.Tenant),
+	}
+	for i := range tenants {
+		tenant := &tenants[i]
+		set.byID[tenant.ID] = tenant
+		for _, host := range tenant.HostList() {
+			set.byHost[host] = tenant
+		}
+	}
+	return set
+}
+
+// TenantRegistry 已启用租户的进程内缓存，租户解析不再逐请求查库
+// 管理接口修改租户后通过 Postgres NOTIFY 通知所有副本重新加载
+type TenantRegistry struct {
+	db  *gorm.DB
+	cfg *config.Config // 环境变量配置，租户配置以此为基础
+
+	mu  sync.RWMutex
+	set *tenantSet
+}
+
+// defaultTenantRegistry 进程内的租户缓存，由 InitTenantRegistry 初始化；未初始化时每次都查数据库
+var defaultTenantRegistry *TenantRegistry
+
+// InitTenantRegistry 加载租户，并订阅变更事件、启动定期重新加载
+func InitTenantRegistry(db *gorm.DB, cfg *config.Config) error {
+	r := &TenantRegistry{db: db, cfg: cfg}
+	if err := r.Reload(); err != nil {
+		return err
+	}
+	defaultTenantRegistry = r
+
+	go listenNotifications(cfg.DatabaseURL, tenantsChangedChannel, r.reloadLogged, func(string) { r.reloadLogged() })
+	go func() {
+		ticker := time.NewTicker(tenantReloadInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			r.reloadLogged()
+		}
+	}()
+	return nil
+}
+
+// Reload 从数据库重新加载已启用的租户
+func (r *TenantRegistry) Reload() error {
+	set, err := loadTenantSet(r.db)
+	if err != nil {
+		return fmt.Errorf("加载租户失败: %w", err)
+	}
+	r.mu.Lock()
+	r.set = set
+	r.mu.Unlock()
+	return nil
+}
+
+func (r *TenantRegistry) reloadLogged() {
+	if err := r.Reload(); err != nil {
+		log.Printf("警告: %v", err)
+	}
+}
+
+func loadTenantSet(db *gorm.DB) (*tenantSet, error) {
+	var tenants []models.Tenant
+	if err := db.Where("enabled").Find(&tenants).Error; err != nil {
+		return nil, err
+	}
+	return newTenantSet(tenants), nil
+}
+
+// enabledTenants 返回已启用租户的快照
+func enabledTenants(db *gorm.DB) (*tenantSet, error) {
+	if r := defaultTenantRegistry; r != nil {
+		r.mu.RLock()
+		defer r.mu.RUnlock()
+		return r.set, nil
+	}
+	return loadTenantSet(db)
+}
+
+// tenantsChanged 租户变更后立即重新加载本副本，并通知其他副本
+func tenantsChanged(db *gorm.DB) {
+	if r := defaultTenantRegistry; r != nil {
+		r.reloadLogged()
+	}
+	if err := db.Exec("SELECT pg_notify(?, '')", tenantsChangedChannel).Error; err != nil {
+		log.Printf("警告: 广播租户变更事件失败: %v", err)
+	}
+}
+
+// ResolveTenant 解析请求所属的租户
+// 指定了租户标识（路径 /t/:tenant）时按标识查找；否则按请求域名匹配租户登记的域名，都未匹配时为默认租户
+func ResolveTenant(db *gorm.DB, tenantID, host string) (*models.Tenant, error) {
+	set, err := enabledTenants(db)
+	if err != nil {
+		return nil, err
+	}
+
+	if tenantID != "" {
+		if tenant := set.byID[tenantID]; tenant != nil {
+			return tenant, nil
+		}
+		return nil, ErrTenantNotFound
+	}
+
+	host = strings.ToLower(host)
+	if tenant := set.byHost[host]; tenant != nil {
+		return tenant, nil
+	}
+	if hostname, _, err := net.SplitHostPort(host); err == nil {
+		if tenant := set.byHost[hostname]; tenant != nil {
+			return tenant, nil
+		}
+	}
+	if tenant := set.byID[config.DefaultTenantID]; tenant != nil {
+		return tenant, nil
+	}
+	return nil, ErrTenantNotFound
+}
+
+// LoadTenantConfig 租户的配置，解密结果缓存在当前租户快照中，不再逐请求解密租户密钥
+// 返回的配置为多个请求共享，调用方不能修改
+func LoadTenantConfig(db *gorm.DB, tenant *models.Tenant) (*config.Config, error) {
+	r := defaultTenantRegistry
+	if r == nil {
+		return TenantConfig(config.Load(), tenant)
+	}
+	r.mu.RLock()
+	set := r.set
+	r.mu.RUnlock()
+
+	// 解析租户后快照可能已被替换：只缓存当前快照中的租户
+	current := set.byID[tenant.ID]
+	if current == nil {
+		return TenantConfig(r.cfg, tenant)
+	}
+	if cached, ok := set.configs.Load(tenant.ID); ok {
+		return cached.(*config.Config), nil
+	}
+	tenantCfg, err := TenantConfig(r.cfg, current)
+	if err != nil {
+		return nil, err
+	}
+	set.configs.Store(tenant.ID, tenantCfg)
+	return tenantCfg, nil
+}
+
+// TenantConfig 租户的配置：微信凭证与初始管理员取自租户登记
+// 默认租户未登记时沿用环境变量；其他租户不会继承环境变量中的凭证与迁移期回调域名白名单，
+// OIDC issuer 为 <AUTH_CENTER_ISSUER>/t/<租户标识>
+func TenantConfig(cfg *config.Config, tenant *models.Tenant) (*config.Config, error) {
+	tenantCfg := *cfg
+	tenantCfg.TenantID = tenant.ID
+	tenantCfg.AdminWeChatOpenID = tenantAdminUnionID(cfg, tenant)
+	if tenant.ID != config.DefaultTenantID {
+		tenantCfg.Issuer = strings.TrimRight(cfg.Issuer, "/") + "/t/" + tenant.ID
+		tenantCfg.WeChatAppID, tenantCfg.WeChatAppSecret = "", ""
+		tenantCfg.WeChatMPAppID, tenantCfg.WeChatMPSecret, tenantCfg.WeChatMPToken = "", "", ""
+		tenantCfg.WeChatMPEncodingAESKey = ""
+		tenantCfg.WeChatMiniAppID, tenantCfg.WeChatMiniSecret = "", ""
+		tenantCfg.WeComCorpID, tenantCfg.WeComAgentID, tenantCfg.WeComSecret = "", "", ""
+		tenantCfg.AllowedCallbackDomains = ""
+	}
+
+	if tenant.WeChatAppID != "" {
+		secret, err := openTenantSecret(tenant, "wechat_app_secret", tenant.WeChatAppSecret)
+		if err != nil {
+			return nil, err
+		}
+		tenantCfg.WeChatAppID, tenantCfg.WeChatAppSecret = tenant.WeChatAppID, secret
+	}
+	if tenant.WeChatMPAppID != "" {
+		secret, err := openTenantSecret(tenant, "wechat_mp_secret", tenant.WeChatMPSecret)
+		if err != nil {
+			return nil, err
+		}
+		tenantCfg.WeChatMPAppID, tenantCfg.WeChatMPSecret, tenantCfg.WeChatMPToken = tenant.WeChatMPAppID, secret, ""
+		tenantCfg.WeChatMPEncodingAESKey = ""
+		if tenant.WeChatMPToken != "" {
+			token, err := openTenantSecret(tenant, "wechat_mp_token", tenant.WeChatMPToken)
+			if err != nil {
+				return nil, err
+			}
+			tenantCfg.WeChatMPToken = token
+		}
+		if tenant.WeChatMPAESKey != "" {
+			aesKey, err := openTenantSecret(tenant, "wechat_mp_encoding_aes_key", tenant.WeChatMPAESKey)
+			if err != nil {
+				return nil, err
+			}
+			tenantCfg.WeChatMPEncodingAESKey = aesKey
+		}
+	}
+	if tenant.WeChatMiniAppID != "" {
+		secret, err := openTenantSecret(tenant, "wechat_mini_secret", tenant.WeChatMiniSecret)
+		if err != nil {
+			return nil, err
+		}
+		tenantCfg.WeChatMiniAppID, tenantCfg.WeChatMiniSecret = tenant.WeChatMiniAppID, secret
+	}
+	if tenant.WeComCorpID != "" {
+		secret, err := openTenantSecret(tenant, "wecom_secret", tenant.WeComSecret)
+		if err != nil {
+			return nil, err
+		}
+		tenantCfg.WeComCorpID, tenantCfg.WeComAgentID, tenantCfg.WeComSecret = tenant.WeComCorpID, tenant.WeComAgentID, secret
+	}
+	return &tenantCfg, nil
+}
+
+// EnsureTenantAdmins 为每个已启用租户授予初始超级管理员（租户内还没有超级管理员时）
+func EnsureTenantAdmins(db *gorm.DB, cfg *config.Config) error {
+	set, err := enabledTenants(db)
+	if err != nil {
+		return err
+	}
+	for _, tenant := range set.byID {
+		if err := EnsureBootstrapAdmin(db, tenant.ID, tenantAdminUnionID(cfg, tenant)); err != nil {
+			return fmt.Errorf("租户 %s: %w", tenant.ID, err)
+		}
+	}
+	return nil
+}
+
+// UserInTenant 用户是否属于该租户，不属于时返回 ErrUserNotFound
+func UserInTenant(db *gorm.DB, tenantID, userID string) error {
+	if _, err := uuid.Parse(userID); err != nil {
+		return ErrUserNotFound
+	}
+	var count int64
+	if err := db.Model(&models.User{}).Where("user_id = ? AND tenant_id = ?", userID, tenantID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// TenantInput 创建/更新租户的参数
+type TenantInput struct {
+	ID               string   `json:"id"` // 仅创建时使用
+	Name             string   `json:"name"`
+	Hosts            []string `json:"hosts"`
+	WeChatAppID      string   `json:"wechatAppId"`
+	WeChatAppSecret  string   `json:"wechatAppSecret"` // 为空时保持不变
+	WeChatMPAppID    string   `json:"wechatMpAppId"`
+	WeChatMPSecret   string   `json:"wechatMpSecret"`         // 为空时保持不变
+	WeChatMPToken    string   `json:"wechatMpToken"`          // 消息推送令牌，为空时保持不变
+	WeChatMPAESKey   string   `json:"wechatMpEncodingAesKey"` // 消息加解密密钥，为空时保持不变
+	WeChatMiniAppID  string   `json:"wechatMiniAppId"`
+	WeChatMiniSecret string   `json:"wechatMiniSecret"` // 为空时保持不变
+	WeComCorpID      string   `json:"wecomCorpId"`
+	WeComAgentID     string   `json:"wecomAgentId"`
+	WeComSecret      string   `json:"wecomSecret"` // 为空时保持不变
+	AdminUnionID     string   `json:"adminUnionId"`
+	Enabled          *bool    `json:"enabled"` // 为空时创建默认启用、更新保持不变
+}
+
+// TenantView 对外展示的租户（不含微信密钥）
+type TenantView struct {
+	ID              string    `json:"id"`
+	Name            string    `json:"name"`
+	Hosts           []string  `json:"hosts"`
+	WeChatAppID     string    `json:"wechatAppId"`
+	WeChatMPAppID   string    `json:"wechatMpAppId"`
+	WeChatMiniAppID string    `json:"wechatMiniAppId"`
+	WeComCorpID     string    `json:"wecomCorpId"`
+	WeComAgentID    string    `json:"wecomAgentId"`
+	AdminUnionID    string    `json:"adminUnionId"`
+	Enabled         bool      `json:"enabled"`
+	CreatedAt       time.Time `json:"createdAt"`
+	UpdatedAt       time.Time `json:"updatedAt"`
+}
+
+// NewTenantView 将租户记录转换为展示视图
+func NewTenantView(tenant *models.Tenant) TenantView {
+	return TenantView{
+		ID:              tenant.ID,
+		Name:            tenant.Name,
+		Hosts:           tenant.HostList(),
+		WeChatAppID:     tenant.WeChatAppID,
+		WeChatMPAppID:   tenant.WeChatMPAppID,
+		WeChatMiniAppID: tenant.WeChatMiniAppID,
+		WeComCorpID:     tenant.WeComCorpID,
+		WeComAgentID:    tenant.WeComAgentID,
+		AdminUnionID:    tenant.AdminUnionID,
+		Enabled:         tenant.Enabled,
+		CreatedAt:       tenant.CreatedAt,
+		UpdatedAt:       tenant.UpdatedAt,
+	}
+}
+
+// ListTenants 列出全部租户（含已停用）
+func ListTenants(db *gorm.DB) ([]TenantView, error) {
+	var tenants []models.Tenant
+	if err := db.Order("created_at").Find(&tenants).Error; err != nil {
+		return nil, err
+	}
+	result := make([]TenantView, len(tenants))
+	for i := range tenants {
+		result[i] = NewTenantView(&tenants[i])
+	}
+	return result, nil
+}
+
+// GetTenantView 获取租户详情（含已停用）
+func GetTenantView(db *gorm.DB, tenantID string) (*TenantView, error) {
+	tenant, err := findTenantRecord(db, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	view := NewTenantView(tenant)
+	return &view, nil
+}
+
+// CreateTenant 创建租户
+func CreateTenant(db *gorm.DB, input TenantInput) (*TenantView, error) {
+	tenantID := strings.TrimSpace(input.ID)
+	if !tenantIDPattern.MatchString(tenantID) {
+		return nil, fmt.Errorf("%w：租户标识只能包含小写字母、数字和 -，长度 2-50", ErrInvalidTenantInput)
+	}
+
+	var count int64
+	if err := db.Model(&models.Tenant{}).Where("id = ?", tenantID).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, fmt.Errorf("%w：租户 %s 已存在", ErrInvalidTenantInput, tenantID)
+	}
+
+	tenant := models.Tenant{ID: tenantID, Enabled: true}
+	if err := applyTenantInput(db, &tenant, input); err != nil {
+		return nil, err
+	}
+	if err := db.Create(&tenant).Error; err != nil {
+		return nil, err
+	}
+	tenantsChanged(db)
+
+	view := NewTenantView(&tenant)
+	return &view, nil
+}
+
+// UpdateTenant 更新租户配置（租户标识不变）
+func UpdateTenant(db *gorm.DB, tenantID string, input TenantInput) (*TenantView, error) {
+	tenant, err := findTenantRecord(db, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyTenantInput(db, tenant, input); err != nil {
+		return nil, err
+	}
+
+	if err := db.Select("name", "hosts", "wechat_app_id", "wechat_app_secret", "wechat_mp_app_id",
+		"wechat_mp_secret", "wechat_mp_token", "wechat_mp_encoding_aes_key", "wechat_mini_app_id", "wechat_mini_secret",
+		"wecom_corp_id", "wecom_agent_id", "wecom_secret", "admin_union_id", "enabled", "updated_at").
+		Save(tenant).Error; err != nil {
+		return nil, err
+	}
+	tenantsChanged(db)
+
+	// 新设置的初始管理员可能已经登录过
+	if err := EnsureBootstrapAdmin(db, tenant.ID, tenant.AdminUnionID); err != nil {
+		log.Printf("警告: 租户 %s 初始化超级管理员失败: %v", tenant.ID, err)
+	}
+
+	view := NewTenantView(tenant)
+	return &view, nil
+}
+
+// findTenantRecord 按 ID 获取租户（含已停用）
+func findTenantRecord(db *gorm.DB, tenantID string) (*models.Tenant, error) {
+	var tenant models.Tenant
+	err := db.Where("id = ?", tenantID).First(&tenant).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrTenantNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &tenant, nil
+}
+
+// applyTenantInput 校验并写入可编辑字段，微信密钥加密保存
+func applyTenantInput(db *gorm.DB, tenant *models.Tenant, input TenantInput) error {
+	name := strings.TrimSpace(input.Name)
+	if name == "" || len(name) > 255 {
+		return fmt.Errorf("%w：名称不能为空且不超过 255 个字符", ErrInvalidTenantInput)
+	}
+
+	hosts := make([]string, 0, len(input.Hosts))
+	for _, raw := range input.Hosts {
+		host := strings.ToLower(strings.TrimSpace(raw))
+		if !tenantHostPattern.MatchString(host) {
+			return fmt.Errorf("%w：域名 %s 只能包含域名和端口", ErrInvalidTenantInput, raw)
+		}
+		var count int64
+		if err := db.Model(&models.Tenant{}).
+			Where("id <> ? AND ? = ANY(string_to_array(hosts, ' '))", tenant.ID, host).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return fmt.Errorf("%w：域名 %s 已被其他租户使用", ErrInvalidTenantInput, host)
+		}
+		hosts = append(hosts, host)
+	}
+
+	if input.Enabled != nil && !*input.Enabled && tenant.ID == config.DefaultTenantID {
+		return fmt.Errorf("%w：默认租户不能停用", ErrInvalidTenantInput)
+	}
+
+	appSecret, err := sealTenantSecret(tenant, "wechat_app_secret", strings.TrimSpace(input.WeChatAppID),
+		strings.TrimSpace(input.WeChatAppSecret), tenant.WeChatAppSecret)
+	if err != nil {
+		return err
+	}
+	mpSecret, err := sealTenantSecret(tenant, "wechat_mp_secret", strings.TrimSpace(input.WeChatMPAppID),
+		strings.TrimSpace(input.WeChatMPSecret), tenant.WeChatMPSecret)
+	if err != nil {
+		return err
+	}
+	miniSecret, err := sealTenantSecret(tenant, "wechat_mini_secret", strings.TrimSpace(input.WeChatMiniAppID),
+		strings.TrimSpace(input.WeChatMiniSecret), tenant.WeChatMiniSecret)
+	if err != nil {
+		return err
+	}
+	mpToken, err := sealOptionalTenantSecret(tenant, "wechat_mp_token", strings.TrimSpace(input.WeChatMPAppID),
+		strings.TrimSpace(input.WeChatMPToken), tenant.WeChatMPToken)
+	if err != nil {
+		return err
+	}
+	mpAESKey := strings.TrimSpace(input.WeChatMPAESKey)
+	if mpAESKey != "" && !validMPEncodingAESKey(mpAESKey) {
+		return fmt.Errorf("%w：公众号消息加解密密钥应为 43 位字符", ErrInvalidTenantInput)
+	}
+	mpAESKey, err = sealOptionalTenantSecret(tenant, "wechat_mp_encoding_aes_key", strings.TrimSpace(input.WeChatMPAppID),
+		mpAESKey, tenant.WeChatMPAESKey)
+	if err != nil {
+		return err
+	}
+	wecomCorpID, wecomAgentID := strings.TrimSpace(input.WeComCorpID), strings.TrimSpace(input.WeComAgentID)
+	if wecomCorpID != "" && wecomAgentID == "" {
+		return fmt.Errorf("%w：设置企业微信 CorpID 时必须提供应用 AgentID", ErrInvalidTenantInput)
+	}
+	wecomSecret, err := sealTenantSecret(tenant, "wecom_secret", wecomCorpID,
+		strings.TrimSpace(input.WeComSecret), tenant.WeComSecret)
+	if err != nil {
+		return err
+	}
+
+	tenant.Name = name
+	tenant.Hosts = strings.Join(hosts, " ")
+	tenant.WeChatAppID, tenant.WeChatAppSecret = strings.TrimSpace(input.WeChatAppID), appSecret
+	tenant.WeChatMPAppID, tenant.WeChatMPSecret = strings.TrimSpace(input.WeChatMPAppID), mpSecret
+	tenant.WeChatMPToken, tenant.WeChatMPAESKey = mpToken, mpAESKey
+	tenant.WeChatMiniAppID, tenant.WeChatMiniSecret = strings.TrimSpace(input.WeChatMiniAppID), miniSecret
+	tenant.WeComCorpID, tenant.WeComSecret = wecomCorpID, wecomSecret
+	tenant.WeComAgentID = ""
+	if wecomCorpID != "" {
+		tenant.WeComAgentID = wecomAgentID
+	}
+	tenant.AdminUnionID = strings.TrimSpace(input.AdminUnionID)
+	if input.Enabled != nil {
+		tenant.Enabled = *input.Enabled
+	}
+	tenant.UpdatedAt = time.Now()
+	return nil
+}
+
+// sealTenantSecret 加密新的微信密钥；未提供新密钥时保留原密文，清空 AppID 时一并清空
+func sealTenantSecret(tenant *models.Tenant, field, appID, secret, current string) (string, error) {
+	if appID == "" {
+		return "", nil
+	}
+	if secret == "" {
+		if current == "" {
+			return "", fmt.Errorf("%w：设置微信 AppID 时必须提供对应的密钥", ErrInvalidTenantInput)
+		}
+		return current, nil
+	}
+	box := DefaultSecretBox()
+	if box == nil {
+		return "", errors.New("静态加密未初始化")
+	}
+	return box.Seal([]byte(secret), tenantSecretAAD(tenant, field))
+}
+
+// sealOptionalTenantSecret 加密可选的微信凭证：未提供时保留原密文（可以为空），清空 AppID 时一并清空
+func sealOptionalTenantSecret(tenant *models.Tenant, field, appID, secret, current string) (string, error) {
+	if appID == "" {
+		return "", nil
+	}
+	if secret == "" {
+		return current, nil
+	}
+	return sealTenantSecret(tenant, field, appID, secret, current)
+}
+
+// openTenantSecret 解密租户的微信密钥
+func openTenantSecret(tenant *models.Tenant, field, sealed string) (string, error) {
+	box := DefaultSecretBox()
+	if box == nil {
+		return "", errors.New("静态加密未初始化")
+	}
+	secret, err := box.Open(sealed, tenantSecretAAD(tenant, field))
+	if err != nil {
+		return "", fmt.Errorf("解密租户 %s 的微信密钥失败: %w", tenant.ID, err)
+	}
+	return string(secret), nil
+}
+
+// tenantSecretAAD 把密文绑定到租户与字段，防止被复制到其他租户或字段使用
+func tenantSecretAAD(tenant *models.Tenant, field string) string {
+	return "tenant:" + tenant.ID + ":" + field
+}
+
+// tenantAdminUnionID 租户的初始超级管理员，默认租户未登记时沿用 ADMIN_WECHAT_OPENID
+func tenantAdminUnionID(cfg *config.Config, tenant *models.Tenant) string {
+	if tenant.AdminUnionID == "" && tenant.ID == config.DefaultTenantID {
+		return cfg.AdminWeChatOpenID
+	}
+	return tenant.AdminUnionID
+}
@@ -0,0 +1,52 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/keenchase/auth-center/internal/config"
+	"github.com/keenchase/auth-center/internal/models"
+)
+
+// useTestTenants 用给定的租户替换进程内的租户缓存，测试结束后恢复
+func useTestTenants(t *testing.T, tenants ...models.Tenant) {
+	t.Helper()
+	previous := defaultTenantRegistry
+	defaultTenantRegistry = &TenantRegistry{set: newTenantSet(tenants)}
+	t.Cleanup(func() { defaultTenantRegistry = previous })
+}
+
+func TestResolveTenant(t *testing.T) {
+	useTestTenants(t,
+		models.Tenant{ID: config.DefaultTenantID, Enabled: true},
+		models.Tenant{ID: "acme", Hosts: "acme.example.com login.acme.com:8443", Enabled: true},
+	)
+
+	tests := []struct {
+		name     string
+		tenantID string
+		host     string
+		want     string
+	}{
+		{"路径指定租户", "acme", "os.crazyaigc.com", "acme"},
+		{"路径优先于域名", config.DefaultTenantID, "acme.example.com", config.DefaultTenantID},
+		{"按域名", "", "acme.example.com", "acme"},
+		{"域名不区分大小写", "", "ACME.example.com", "acme"},
+		{"域名带端口", "", "acme.example.com:443", "acme"},
+		{"登记了端口的域名", "", "login.acme.com:8443", "acme"},
+		{"未登记的域名为默认租户", "", "os.crazyaigc.com", config.DefaultTenantID},
+		{"相同后缀的域名为默认租户", "", "evil-acme.example.com", config.DefaultTenantID},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tenant, err := ResolveTenant(nil, tt.tenantID, tt.host)
+			if err != nil || tenant.ID != tt.want {
+				t.Fatalf("ResolveTenant(%q, %q) = %v, %v, want %s", tt.tenantID, tt.host, tenant, err, tt.want)
+			}
+		})
+	}
+
+	if _, err := ResolveTenant(nil, "unknown", "acme.example.com"); !errors.Is(err, ErrTenantNotFound) {
+		t.Fatalf("路径指定不存在的租户 err = %v", err)
+	}
+}
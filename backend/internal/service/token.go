@@ -0,0 +1,225 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/keenchase/auth-center/internal/config"
+	"github.com/keenchase/auth-center/internal/models"
+)
+
+var (
+	// ErrInvalidRefreshToken 刷新令牌无效或已过期
+	ErrInvalidRefreshToken = errors.New("刷新令牌无效或已过期")
+
+	// ErrRefreshTokenReused 已轮换的刷新令牌再次出现，整个会话已吊销
+	ErrRefreshTokenReused = errors.New("刷新令牌被重复使用，会话已吊销")
+)
+
+// TokenOptions 签发令牌时的附加信息
+type TokenOptions struct {
+	ClientID    string // OIDC 客户端，第一方登录为空
+	Scope       string
+	DeviceInfo  *models.DeviceInfo
+	LoginMethod string    // 登录方式，见 models.LoginMethod*
+	AuthTime    time.Time // 实际完成登录的时间，为零值时即会话创建时间
+}
+
+// TokenPair 访问令牌 + 刷新令牌
+type TokenPair struct {
+	UserID       string
+	SessionID    string
+	AccessToken  string
+	RefreshToken string
+	Scope        string
+	ExpiresIn    int // 访问令牌有效期（秒）
+}
+
+// IssueTokens 创建会话并签发访问令牌与刷新令牌
+func IssueTokens(db *gorm.DB, cfg *config.Config, userID string, opts TokenOptions) (*TokenPair, error) {
+	// 会话与令牌归属用户所在的租户
+	var user models.User
+	if err := db.Select("user_id", "tenant_id").Where("user_id = ?", userID).First(&user).Error; err != nil {
+		return nil, err
+	}
+
+	sessionID := uuid.New().String()
+	accessTTL, refreshTTL := tokenTTLs(db, cfg, opts.ClientID)
+	authz, orgs, err := accessTokenContext(db, opts.ClientID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	accessToken, err := GenerateAccessToken(userID, sessionID, user.TenantID, opts.ClientID, opts.Scope, authz, orgs, accessTTL)
+	if err != nil {
+		return nil, err
+	}
+	refreshToken, err := generateRandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(refreshTTL)
+	session := models.Session{
+		ID:          sessionID,
+		UserID:      userID,
+		TenantID:    user.TenantID,
+		TokenHash:   hashToken(accessToken),
+		TokenHint:   tokenHint(accessToken),
+		ClientID:    opts.ClientID,
+		Scope:       opts.Scope,
+		DeviceInfo:  marshalDeviceInfo(opts.DeviceInfo),
+		ExpiresAt:   &expiresAt,
+		LastSeenAt:  &now,
+		LoginMethod: opts.LoginMethod,
+	}
+	if !opts.AuthTime.IsZero() {
+		session.AuthTime = &opts.AuthTime
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&session).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.RefreshToken{
+			SessionID: sessionID,
+			TokenHash: hashToken(refreshToken),
+			ExpiresAt: expiresAt,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		UserID:       userID,
+		SessionID:    sessionID,
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		Scope:        opts.Scope,
+		ExpiresIn:    int(accessTTL.Seconds()),
+	}, nil
+}
+
+// RefreshTokens 使用刷新令牌换取新的令牌对（刷新令牌随之轮换）
+// 已轮换过的刷新令牌再次出现说明可能被窃取，立即吊销整个会话
+func RefreshTokens(db *gorm.DB, cfg *config.Config, refreshToken, clientID string) (*TokenPair, error) {
+	var record models.RefreshToken
+	if err := db.Where("token_hash = ?", hashToken(refreshToken)).First(&record).Error; err != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	if record.UsedAt != nil {
+		revokeSessionFamily(db, record.SessionID)
+		return nil, ErrRefreshTokenReused
+	}
+	if time.Now().After(record.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	var session models.Session
+	if err := db.Where("id = ? AND expires_at > ?", record.SessionID, time.Now()).First(&session).Error; err != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+	if session.ClientID != clientID || session.TenantID != cfg.TenantID {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	accessTTL, refreshTTL := tokenTTLs(db, cfg, session.ClientID)
+	authz, orgs, err := accessTokenContext(db, session.ClientID, session.UserID)
+	if err != nil {
+		return nil, err
+	}
+	accessToken, err := GenerateAccessToken(session.UserID, session.ID, session.TenantID, session.ClientID, session.Scope, authz, orgs, accessTTL)
+	if err != nil {
+		return nil, err
+	}
+	newRefreshToken, err := generateRandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(refreshTTL)
+	err = db.Transaction(func(tx *gorm.DB) error {
+		// 原子地标记旧令牌已使用，并发的重复刷新只有一个能成功
+		result := tx.Model(&models.RefreshToken{}).
+			Where("id = ? AND used_at IS NULL", record.ID).
+			Update("used_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrRefreshTokenReused
+		}
+
+		if err := tx.Create(&models.RefreshToken{
+			SessionID: session.ID,
+			TokenHash: hashToken(newRefreshToken),
+			ParentID:  &record.ID,
+			ExpiresAt: expiresAt,
+		}).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&models.Session{}).Where("id = ?", session.ID).Updates(map[string]interface{}{
+			"token_hash":   hashToken(accessToken),
+			"token_hint":   tokenHint(accessToken),
+			"expires_at":   expiresAt,
+			"last_seen_at": now,
+		}).Error
+	})
+	if errors.Is(err, ErrRefreshTokenReused) {
+		revokeSessionFamily(db, session.ID)
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		UserID:       session.UserID,
+		SessionID:    session.ID,
+		AccessToken:  accessToken,
+		RefreshToken: newRefreshToken,
+		Scope:        session.Scope,
+		ExpiresIn:    int(accessTTL.Seconds()),
+	}, nil
+}
+
+// accessTokenContext 写入访问令牌的授权声明：组织对所有令牌写入，角色与权限只有签发给业务系统的令牌携带
+func accessTokenContext(db *gorm.DB, clientID, userID string) (*Authorization, []OrgClaim, error) {
+	orgs, err := UserOrgClaims(db, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if clientID == "" {
+		return nil, orgs, nil
+	}
+	authz, err := UserAuthorization(db, clientID, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return authz, orgs, nil
+}
+
+// tokenHint 令牌末尾 8 位，用于在会话列表中辨认
+func tokenHint(token string) string {
+	if len(token) <= 8 {
+		return ""
+	}
+	return token[len(token)-8:]
+}
+
+// revokeSessionFamily 吊销会话及其全部刷新令牌
+func revokeSessionFamily(db *gorm.DB, sessionID string) {
+	log.Printf("警告: 检测到刷新令牌重放，吊销会话 %s", sessionID)
+	if err := RevokeSessions(db, sessionID); err != nil {
+		log.Printf("警告: 吊销会话失败: %v", err)
+	}
+}
@@ -0,0 +1,68 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/keenchase/auth-center/internal/config"
+	"github.com/keenchase/auth-center/internal/models"
+)
+
+// createTestUser 在默认租户下创建测试用户
+func createTestUser(t *testing.T, db *gorm.DB) string {
+	t.Helper()
+	user := models.User{TenantID: config.DefaultTenantID}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("创建测试用户失败: %v", err)
+	}
+	return user.UserID
+}
+
+func TestRefreshTokenRotationAndReuse(t *testing.T) {
+	db := testDB(t)
+	useTestKeySet(t)
+	cfg := &config.Config{TenantID: config.DefaultTenantID, AccessTokenTTL: time.Minute, RefreshTokenTTL: time.Hour}
+	userID := createTestUser(t, db)
+
+	first, err := IssueTokens(db, cfg, userID, TokenOptions{LoginMethod: models.LoginMethodPassword})
+	if err != nil {
+		t.Fatalf("IssueTokens: %v", err)
+	}
+
+	second, err := RefreshTokens(db, cfg, first.RefreshToken, "")
+	if err != nil {
+		t.Fatalf("RefreshTokens: %v", err)
+	}
+	if second.SessionID != first.SessionID || second.RefreshToken == first.RefreshToken {
+		t.Fatal("刷新应在同一会话内轮换刷新令牌")
+	}
+
+	// 客户端不一致的刷新被拒绝，且不消耗刷新令牌
+	if _, err := RefreshTokens(db, cfg, second.RefreshToken, "other-client"); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("客户端不一致 err = %v", err)
+	}
+
+	// 已轮换的刷新令牌再次出现：吊销整个会话，最新的刷新令牌也随之失效
+	if _, err := RefreshTokens(db, cfg, first.RefreshToken, ""); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("重放 err = %v, want ErrRefreshTokenReused", err)
+	}
+	if _, err := RefreshTokens(db, cfg, second.RefreshToken, ""); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("会话吊销后 err = %v, want ErrInvalidRefreshToken", err)
+	}
+	var count int64
+	db.Model(&models.Session{}).Where("id = ?", first.SessionID).Count(&count)
+	if count != 0 {
+		t.Fatal("检测到重放后会话应被删除")
+	}
+}
+
+func TestRefreshTokenUnknown(t *testing.T) {
+	db := testDB(t)
+	cfg := &config.Config{TenantID: config.DefaultTenantID}
+	if _, err := RefreshTokens(db, cfg, "unknown", ""); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("err = %v", err)
+	}
+}
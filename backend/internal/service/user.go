@@ -9,10 +9,10 @@ import (
 	"gorm.io/gorm"
 )
 
-// VerifyPassword 验证密码
-func VerifyPassword(db *gorm.DB, phoneNumber, password string) (*models.User, error) {
+// VerifyPassword 验证租户内用户的密码
+func VerifyPassword(db *gorm.DB, tenantID, phoneNumber, password string) (*models.User, error) {
 	var user models.User
-	if err := db.Where("phone_number = ?", phoneNumber).First(&user).Error; err != nil {
+	if err := db.Where("tenant_id = ? AND phone_number = ?", tenantID, phoneNumber).First(&user).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, nil // 用户不存在
 		}
@@ -48,19 +48,19 @@ func SetPhonePassword(db *gorm.DB, userID, phoneNumber, password string) error {
 	}).Error
 }
 
-// GetUsers 获取用户列表（分页）
-func GetUsers(db *gorm.DB, page, pageSize int) ([]map[string]interface{}, int64, error) {
+// GetUsers 获取租户内的用户列表（分页）
+func GetUsers(db *gorm.DB, tenantID string, page, pageSize int) ([]map[string]interface{}, int64, error) {
 	var users []models.User
 	var total int64
 
 	// 计算总数
-	if err := db.Model(&models.User{}).Count(&total).Error; err != nil {
+	if err := db.Model(&models.User{}).Where("tenant_id = ?", tenantID).Count(&total).Error; err != nil {
 		return nil, 0, err
 	}
 
 	// 分页查询用户，预加载账号和会话信息
 	offset := (page - 1) * pageSize
-	if err := db.Preload("Accounts").Preload("Sessions").Limit(pageSize).Offset(offset).Find(&users).Error; err != nil {
+	if err := db.Preload("Accounts").Preload("Sessions").Where("tenant_id = ?", tenantID).Limit(pageSize).Offset(offset).Find(&users).Error; err != nil {
 		return nil, 0, err
 	}
 
@@ -90,15 +90,9 @@ func GetUsers(db *gorm.DB, page, pageSize int) ([]map[string]interface{}, int64,
 		}
 
 		// 构建会话信息列表
-		sessions := make([]map[string]interface{}, len(user.Sessions))
-		for j, sess := range user.Sessions {
-			sessions[j] = map[string]interface{}{
-				"id":         sess.ID,
-				"token":      sess.Token,
-				"deviceInfo": sess.DeviceInfo,
-				"expiresAt":  sess.ExpiresAt,
-				"createdAt":  sess.CreatedAt,
-			}
+		sessions := make([]SessionView, len(user.Sessions))
+		for j := range user.Sessions {
+			sessions[j] = NewSessionView(&user.Sessions[j], "")
 		}
 
 		// 检查登录方式
@@ -137,25 +131,3 @@ func GetUsers(db *gorm.DB, page, pageSize int) ([]map[string]interface{}, int64,
 
 	return result, total, nil
 }
-
-// GetSessions 获取用户的所有会话
-func GetSessions(db *gorm.DB, userID string) ([]map[string]interface{}, error) {
-	var sessions []models.Session
-
-	if err := db.Where("user_id = ?", userID).Order("created_at DESC").Find(&sessions).Error; err != nil {
-		return nil, err
-	}
-
-	result := make([]map[string]interface{}, len(sessions))
-	for i, sess := range sessions {
-		result[i] = map[string]interface{}{
-			"id":         sess.ID,
-			"token":      sess.Token,
-			"deviceInfo": sess.DeviceInfo,
-			"expiresAt":  sess.ExpiresAt,
-			"createdAt":  sess.CreatedAt,
-		}
-	}
-
-	return result, nil
-}
@@ -1,17 +1,52 @@
 package service
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
-	"io"
-	"net/http"
 	"net/url"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
 
 	"github.com/keenchase/auth-center/internal/config"
 	"github.com/keenchase/auth-center/internal/models"
-	"gorm.io/gorm"
+	"github.com/keenchase/auth-center/internal/wechat"
+)
+
+// ErrWeChatUnionIDMissing 微信未返回 unionid（应用未绑定到微信开放平台）
+var ErrWeChatUnionIDMissing = errors.New("无法获取用户唯一标识，请确保应用已绑定到微信开放平台")
+
+// wechatAPI / wecomAPI 微信与企业微信服务端接口客户端，InitWeChatClients 按配置重新创建
+var (
+	wechatAPI = wechat.NewClient(wechat.Config{BaseURL: wechat.DefaultBaseURL})
+	wecomAPI  = wechat.NewClient(wechat.Config{BaseURL: wechat.DefaultWeComBaseURL})
 )
 
+// InitWeChatClients 按配置创建微信与企业微信接口客户端（基础地址、超时、重试次数）
+func InitWeChatClients(cfg *config.Config) {
+	retries := cfg.WeChatAPIRetries
+	if retries == 0 {
+		retries = -1 // 配置为 0 表示不重试
+	}
+	wechatAPI = wechat.NewClient(wechat.Config{
+		BaseURL:    cfg.WeChatAPIBaseURL,
+		Timeout:    cfg.WeChatAPITimeout,
+		MaxRetries: retries,
+	})
+	wecomAPI = wechat.NewClient(wechat.Config{
+		BaseURL:    cfg.WeComAPIBaseURL,
+		Timeout:    cfg.WeChatAPITimeout,
+		MaxRetries: retries,
+	})
+}
+
+func init() {
+	RegisterIdentityProvider(&wechatProvider{name: models.LoginMethodWeChatOpen, accountType: "web"})
+	RegisterIdentityProvider(&wechatProvider{name: models.LoginMethodWeChatMP, accountType: "mp", mp: true})
+}
+
 // WeChatOAuthResponse 微信 OAuth 响应
 type WeChatOAuthResponse struct {
 	AccessToken  string `json:"access_token"`
@@ -20,244 +55,203 @@ type WeChatOAuthResponse struct {
 	OpenID       string `json:"openid"`
 	Scope        string `json:"scope"`
 	UnionID      string `json:"unionid"`
-	ErrCode      int    `json:"errcode"`
-	ErrMsg       string `json:"errmsg"`
 }
 
-// GetWeChatAccessToken 获取微信 Access Token
-func GetWeChatAccessToken(cfg *config.Config, code string, isMP bool) (*WeChatOAuthResponse, error) {
-	var appID, appSecret string
+// wechatProvider 微信网页登录：开放平台扫码（wechat_open）与公众号网页授权（wechat_mp）
+// 两者使用同一套 sns 接口，只是 AppID 与授权页不同
+type wechatProvider struct {
+	name        string
+	accountType string
+	mp          bool
+}
 
-	if isMP {
-		appID = cfg.WeChatMPAppID
-		appSecret = cfg.WeChatMPSecret
-	} else {
-		appID = cfg.WeChatAppID
-		appSecret = cfg.WeChatAppSecret
+// Name 登录方式标识
+func (p *wechatProvider) Name() string {
+	return p.name
+}
+
+// AppID 当前租户配置的开放平台网站应用或公众号 AppID
+func (p *wechatProvider) AppID(cfg *config.Config) string {
+	if p.mp {
+		return cfg.WeChatMPAppID
 	}
+	return cfg.WeChatAppID
+}
 
-	// 构建请求 URL
-	apiURL := "https://api.weixin.qq.com/sns/oauth2/access_token"
-	if isMP {
-		apiURL = "https://api.weixin.qq.com/sns/oauth2/access_token" // 公众号使用相同接口
+// secret 与 AppID 对应的密钥
+func (p *wechatProvider) secret(cfg *config.Config) string {
+	if p.mp {
+		return cfg.WeChatMPSecret
 	}
+	return cfg.WeChatAppSecret
+}
 
+// AuthURL 公众号为网页授权（snsapi_userinfo），开放平台为扫码登录（snsapi_login）
+func (p *wechatProvider) AuthURL(cfg *config.Config, redirectURI, state string) string {
+	endpoint, scope := "https://open.weixin.qq.com/connect/qrconnect", "snsapi_login"
+	if p.mp {
+		endpoint, scope = "https://open.weixin.qq.com/connect/oauth2/authorize", "snsapi_userinfo"
+	}
+	return fmt.Sprintf(
+		"%s?appid=%s&redirect_uri=%s&response_type=code&scope=%s&state=%s#wechat_redirect",
+		endpoint,
+		p.AppID(cfg),
+		url.QueryEscape(redirectURI),
+		scope,
+		url.QueryEscape(state),
+	)
+}
+
+// ExchangeCode 获取微信 Access Token（公众号与开放平台使用相同接口）
+func (p *wechatProvider) ExchangeCode(ctx context.Context, cfg *config.Config, code string) (*ProviderToken, error) {
 	params := url.Values{}
-	params.Set("appid", appID)
-	params.Set("secret", appSecret)
+	params.Set("appid", p.AppID(cfg))
+	params.Set("secret", p.secret(cfg))
 	params.Set("code", code)
 	params.Set("grant_type", "authorization_code")
 
-	// 发送请求
-	resp, err := http.Get(apiURL + "?" + params.Encode())
-	if err != nil {
-		return nil, fmt.Errorf("请求微信 API 失败: %w", err)
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("读取响应失败: %w", err)
-	}
-
 	var result WeChatOAuthResponse
-	if err := json.Unmarshal(body, &result); err != nil {
-		return nil, fmt.Errorf("解析响应失败: %w", err)
+	if err := wechatAPI.GetOnce(ctx, "/sns/oauth2/access_token", params, &result); err != nil {
+		return nil, err
 	}
 
-	if result.ErrCode != 0 {
-		return nil, fmt.Errorf("微信 API 错误: %s", result.ErrMsg)
-	}
-
-	return &result, nil
+	return &ProviderToken{
+		AccessToken: result.AccessToken,
+		Subject:     result.OpenID,
+		UnionID:     result.UnionID,
+	}, nil
 }
 
-// GetWeChatUserInfo 获取微信用户信息
-func GetWeChatUserInfo(accessToken, openID string, isMP bool) (map[string]interface{}, error) {
-	// 公众号和开放平台都需要调用 userinfo 接口获取用户信息（昵称、头像等）
-	apiURL := "https://api.weixin.qq.com/sns/userinfo"
+// FetchIdentity 获取微信用户信息（昵称、头像等）
+// 开放平台的 unionid 在 access_token 响应中，公众号的在 userinfo 响应中
+func (p *wechatProvider) FetchIdentity(ctx context.Context, cfg *config.Config, token *ProviderToken) (*ExternalIdentity, error) {
 	params := url.Values{}
-	params.Set("access_token", accessToken)
-	params.Set("openid", openID)
+	params.Set("access_token", token.AccessToken)
+	params.Set("openid", token.Subject)
 	params.Set("lang", "zh_CN")
 
-	resp, err := http.Get(apiURL + "?" + params.Encode())
-	if err != nil {
-		return nil, fmt.Errorf("请求微信 API 失败: %w", err)
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("读取响应失败: %w", err)
+	var userInfo map[string]interface{}
+	if err := wechatAPI.Get(ctx, "/sns/userinfo", params, &userInfo); err != nil {
+		return nil, err
 	}
 
-	var result map[string]interface{}
-	if err := json.Unmarshal(body, &result); err != nil {
-		return nil, fmt.Errorf("解析响应失败: %w", err)
+	unionID := token.UnionID
+	if unionID == "" {
+		unionID = GetStringValue(userInfo, "unionid")
 	}
-
-	if result["errcode"] != nil && result["errcode"].(float64) != 0 {
-		return nil, fmt.Errorf("微信 API 错误: %v", result["errmsg"])
+	if unionID == "" {
+		return nil, ErrWeChatUnionIDMissing
 	}
 
-	return result, nil
+	return &ExternalIdentity{
+		Provider:    "wechat",
+		AppID:       p.AppID(cfg),
+		Subject:     token.Subject,
+		UnionID:     unionID,
+		AccountType: p.accountType,
+		Nickname:    GetStringValue(userInfo, "nickname"),
+		AvatarURL:   GetStringValue(userInfo, "headimgurl"),
+		Raw:         userInfo,
+	}, nil
 }
 
-// FindOrCreateUserByUnionID 根据 UnionID 查找或创建用户
-func FindOrCreateUserByUnionID(db *gorm.DB, unionID string, openID, appID, accountType string, userInfo map[string]interface{}) (*models.User, error) {
-	// 先查找用户
-	var user models.User
-	err := db.Where("union_id = ?", unionID).First(&user).Error
-
-	if err == gorm.ErrRecordNotFound {
-		// 用户不存在，创建新用户
-		// 让数据库自动生成 UUID（通过 gen_random_uuid()）
-		user = models.User{
-			UnionID: unionID,
-		}
-
-		if err := db.Create(&user).Error; err != nil {
-			return nil, fmt.Errorf("创建用户失败: %w", err)
-		}
+// createMPQRCode 创建公众号临时带参数二维码（字符串场景值），返回换取二维码图片的 ticket
+func createMPQRCode(ctx context.Context, db *gorm.DB, cfg *config.Config, scene string, expireSeconds int) (string, error) {
+	accessToken, err := mpAccessToken(ctx, db, cfg)
+	if err != nil {
+		return "", err
 	}
 
-	// 创建或更新用户账户
-	var account models.UserAccount
-	err = db.Where(
-		"user_id = ? AND provider = ? AND app_id = ? AND open_id = ?",
-		user.UserID, "wechat", appID, openID,
-	).First(&account).Error
-
-	if err == gorm.ErrRecordNotFound {
-		// 账户不存在，创建新账户
-		account = models.UserAccount{
-			UserID:    user.UserID,
-			Provider:  "wechat",
-			AppID:     appID,
-			OpenID:    openID,
-			Type:      accountType,
-			Nickname:  GetStringValue(userInfo, "nickname"),
-			AvatarURL: GetStringValue(userInfo, "headimgurl"),
-		}
-
-		if err := db.Create(&account).Error; err != nil {
-			return nil, fmt.Errorf("创建用户账户失败: %w", err)
-		}
-	} else if err == nil {
-		// 账户已存在，更新昵称和头像
-		updates := map[string]interface{}{}
-		if nickname := GetStringValue(userInfo, "nickname"); nickname != "" {
-			updates["nickname"] = nickname
-		}
-		if avatarURL := GetStringValue(userInfo, "headimgurl"); avatarURL != "" {
-			updates["avatar_url"] = avatarURL
-		}
-		if len(updates) > 0 {
-			if err := db.Model(&account).Updates(updates).Error; err != nil {
-				return nil, fmt.Errorf("更新用户账户失败: %w", err)
-			}
-		}
+	request := map[string]interface{}{
+		"expire_seconds": expireSeconds,
+		"action_name":    "QR_STR_SCENE",
+		"action_info": map[string]interface{}{
+			"scene": map[string]string{"scene_str": scene},
+		},
 	}
-
-	return &user, nil
-}
-
-// GetStringValue 从 map 中安全地获取字符串值
-func GetStringValue(m map[string]interface{}, key string) string {
-	if val, ok := m[key]; ok {
-		if str, ok := val.(string); ok {
-			return str
-		}
+	var result struct {
+		Ticket string `json:"ticket"`
 	}
-	return ""
-}
-
-// CompleteWeChatLoginResult 完成微信登录的结果
-type CompleteWeChatLoginResult struct {
-	UserID string
-	Token  string
+	if err := wechatAPI.Post(ctx, "/cgi-bin/qrcode/create", url.Values{"access_token": {accessToken}}, request, &result); err != nil {
+		return "", err
+	}
+	return result.Ticket, nil
 }
 
-// CompleteWeChatLogin 完成微信登录流程
-// 1. 获取微信 Access Token
-// 2. 获取用户信息
-// 3. 创建或更新用户
-// 4. 生成 JWT Token
-// 5. 创建会话
-// 6. 更新最后登录时间
-func CompleteWeChatLogin(db *gorm.DB, cfg *config.Config, code string, isMP bool) (*CompleteWeChatLoginResult, error) {
-	// 1. 获取微信 Access Token
-	wxResp, err := GetWeChatAccessToken(cfg, code, isMP)
+// fetchMPFollower 获取公众号粉丝的身份（unionid 仅在公众号绑定开放平台时返回）
+func fetchMPFollower(ctx context.Context, db *gorm.DB, cfg *config.Config, openID string) (*ExternalIdentity, error) {
+	accessToken, err := mpAccessToken(ctx, db, cfg)
 	if err != nil {
-		return nil, fmt.Errorf("获取微信 Access Token 失败: %w", err)
+		return nil, err
 	}
 
-	// 2. 获取用户信息（仅公众号需要调用此接口）
-	userInfo, err := GetWeChatUserInfo(wxResp.AccessToken, wxResp.OpenID, isMP)
-	if err != nil {
-		return nil, fmt.Errorf("获取用户信息失败: %w", err)
-	}
+	params := url.Values{}
+	params.Set("access_token", accessToken)
+	params.Set("openid", openID)
+	params.Set("lang", "zh_CN")
 
-	// 3. 提取 UnionID（注意：公众号和开放平台的 unionid 获取位置不同）
-	var unionID string
-	if isMP {
-		// 公众号：unionid 在 userinfo 响应中
-		unionID = GetStringValue(userInfo, "unionid")
-	} else {
-		// 开放平台：unionid 在 access_token 响应中
-		unionID = wxResp.UnionID
+	var userInfo map[string]interface{}
+	if err := wechatAPI.Get(ctx, "/cgi-bin/user/info", params, &userInfo); err != nil {
+		return nil, err
 	}
 
-	// 验证 unionID 不为空
+	unionID := GetStringValue(userInfo, "unionid")
 	if unionID == "" {
-		return nil, fmt.Errorf("无法获取用户唯一标识，请确保应用已绑定到微信开放平台")
+		return nil, ErrWeChatUnionIDMissing
 	}
+	return &ExternalIdentity{
+		Provider:    "wechat",
+		AppID:       cfg.WeChatMPAppID,
+		Subject:     openID,
+		UnionID:     unionID,
+		AccountType: "mp",
+		Nickname:    GetStringValue(userInfo, "nickname"),
+		AvatarURL:   GetStringValue(userInfo, "headimgurl"),
+		Raw:         userInfo,
+	}, nil
+}
 
-	// 4. 获取或创建用户
-	appID := cfg.WeChatAppID
-	if isMP {
-		appID = cfg.WeChatMPAppID
-	}
+// upstreamToken 缓存的上游接口调用凭证
+type upstreamToken struct {
+	value     string
+	expiresAt time.Time
+}
 
-	accountType := "web"
-	if isMP {
-		accountType = "mp"
-	}
+// upstreamTokenCache 上游接口调用凭证（企业微信的 access_token）的进程内缓存
+// 公众号凭证由中控服务统一保存与刷新（见 wechat_credential.go）
+// 凭证有效期一般为 2 小时，提前 5 分钟刷新
+type upstreamTokenCache struct {
+	mu      sync.Mutex
+	entries map[string]upstreamToken
+}
 
-	user, err := FindOrCreateUserByUnionID(
-		db,
-		unionID,
-		wxResp.OpenID,
-		appID,
-		accountType,
-		userInfo,
-	)
-	if err != nil {
-		return nil, fmt.Errorf("获取或创建用户失败: %w", err)
-	}
+var upstreamTokens = &upstreamTokenCache{entries: make(map[string]upstreamToken)}
 
-	// 5. 生成 JWT Token
-	token, err := GenerateToken(user.UserID, cfg.JWTSecret)
-	if err != nil {
-		return nil, fmt.Errorf("生成 Token 失败: %w", err)
+// get 返回未过期的缓存凭证，否则调用 fetch 获取（fetch 返回凭证与有效期秒数）
+func (c *upstreamTokenCache) get(key string, fetch func() (string, int, error)) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if entry, ok := c.entries[key]; ok && time.Now().Before(entry.expiresAt) {
+		return entry.value, nil
 	}
 
-	// 6. 创建会话
-	_, err = CreateSession(db, user.UserID, token, nil)
+	value, expiresIn, err := fetch()
 	if err != nil {
-		return nil, fmt.Errorf("创建会话失败: %w", err)
+		return "", err
 	}
-
-	// 7. 更新最后登录时间
-	if err := UpdateLastLogin(db, user.UserID); err != nil {
-		// 记录错误但不中断登录流程
-		fmt.Printf("警告: 更新最后登录时间失败: %v\n", err)
+	c.entries[key] = upstreamToken{
+		value:     value,
+		expiresAt: time.Now().Add(time.Duration(expiresIn)*time.Second - 5*time.Minute),
 	}
+	return value, nil
+}
 
-	// 8. 返回结果
-	return &CompleteWeChatLoginResult{
-		UserID: user.UserID,
-		Token:  token,
-	}, nil
+// GetStringValue 从 map 中安全地获取字符串值
+func GetStringValue(m map[string]interface{}, key string) string {
+	if val, ok := m[key]; ok {
+		if str, ok := val.(string); ok {
+			return str
+		}
+	}
+	return ""
 }
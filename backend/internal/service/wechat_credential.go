@@ -0,0 +1,390 @@
+package service
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/keenchase/auth-center/internal/config"
+	"github.com/keenchase/auth-center/internal/models"
+	"github.com/keenchase/auth-center/internal/wechat"
+)
+
+// wechatCredentialRefreshBefore 凭证到期前多久刷新
+// 微信保证刷新后 5 分钟内新旧凭证都可用，业务系统按返回的 expiresIn 缓存即可平滑切换
+const wechatCredentialRefreshBefore = 10 * time.Minute
+
+const (
+	// wechatCredentialLeaseTTL 刷新租约的有效期，须长于一次刷新（含 jsapi_ticket 换 access_token 重试）的耗时；持有者崩溃时租约到期后由其他副本接手
+	wechatCredentialLeaseTTL = time.Minute
+
+	// wechatCredentialLeasePoll 等待其他副本刷新时重新检查的间隔
+	wechatCredentialLeasePoll = 200 * time.Millisecond
+)
+
+// WeChatCredential 中控服务对外提供的公众号接口凭证
+type WeChatCredential struct {
+	AppID     string `json:"appId"`
+	Value     string `json:"value"`
+	ExpiresIn int    `json:"expiresIn"` // 建议缓存的秒数（到中控服务下次刷新为止）
+}
+
+// JSSDKConfig 前端 wx.config 所需的参数
+type JSSDKConfig struct {
+	AppID     string `json:"appId"`
+	Timestamp int64  `json:"timestamp"`
+	NonceStr  string `json:"nonceStr"`
+	Signature string `json:"signature"`
+}
+
+// MPAccessToken 获取公众号 access_token（中控服务）
+func MPAccessToken(ctx context.Context, db *gorm.DB, cfg *config.Config) (*WeChatCredential, error) {
+	return mpCredential(ctx, db, cfg, models.WeChatCredentialAccessToken, "")
+}
+
+// RefreshMPAccessToken 业务系统发现 access_token 已失效（如被其他程序刷新）时上报
+// 仅当当前凭证仍是上报的凭证时才重新获取，多个业务系统同时上报只刷新一次
+func RefreshMPAccessToken(ctx context.Context, db *gorm.DB, cfg *config.Config, invalidToken string) (*WeChatCredential, error) {
+	return mpCredential(ctx, db, cfg, models.WeChatCredentialAccessToken, invalidToken)
+}
+
+// MPJSAPITicket 获取公众号 jsapi_ticket（中控服务）
+func MPJSAPITicket(ctx context.Context, db *gorm.DB, cfg *config.Config) (*WeChatCredential, error) {
+	return mpCredential(ctx, db, cfg, models.WeChatCredentialJSAPITicket, "")
+}
+
+// SignJSSDK 计算 JS-SDK 签名：sha1(jsapi_ticket=...&noncestr=...&timestamp=...&url=...)
+// url 为当前网页地址（不含 # 及其后部分）；nonceStr、timestamp 为空时自动生成
+func SignJSSDK(ctx context.Context, db *gorm.DB, cfg *config.Config, pageURL, nonceStr string, timestamp int64) (*JSSDKConfig, error) {
+	// 前端签名时使用 location.href.split('#')[0]，这里只去掉 # 部分，不做其他规范化
+	pageURL, _, _ = strings.Cut(pageURL, "#")
+	if nonceStr == "" {
+		var err error
+		if nonceStr, err = generateRandomToken(12); err != nil {
+			return nil, err
+		}
+	}
+	if timestamp == 0 {
+		timestamp = time.Now().Unix()
+	}
+
+	ticket, err := MPJSAPITicket(ctx, db, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &JSSDKConfig{
+		AppID:     ticket.AppID,
+		Timestamp: timestamp,
+		NonceStr:  nonceStr,
+		Signature: jssdkSignature(ticket.Value, nonceStr, timestamp, pageURL),
+	}, nil
+}
+
+// jssdkSignature 按参数名字典序拼接后取 sha1（参数值不做 URL 转义）
+func jssdkSignature(ticket, nonceStr string, timestamp int64, pageURL string) string {
+	sum := sha1.Sum([]byte("jsapi_ticket=" + ticket +
+		"&noncestr=" + nonceStr +
+		"&timestamp=" + strconv.FormatInt(timestamp, 10) +
+		"&url=" + pageURL))
+	return hex.EncodeToString(sum[:])
+}
+
+// RefreshWeChatCredentials 后台任务：提前刷新各租户公众号即将到期的凭证
+// access_token 始终保持有效；jsapi_ticket 只在有业务系统使用过（已保存）时刷新
+func RefreshWeChatCredentials(ctx context.Context, db *gorm.DB, cfg *config.Config) (int, error) {
+	set, err := enabledTenants(db)
+	if err != nil {
+		return 0, err
+	}
+
+	refreshed, seen := 0, make(map[string]bool)
+	for _, tenant := range set.byID {
+		if ctx.Err() != nil {
+			return refreshed, ctx.Err()
+		}
+		tenantCfg, err := TenantConfig(cfg, tenant)
+		if err != nil {
+			log.Printf("警告: 加载租户 %s 配置失败: %v", tenant.ID, err)
+			continue
+		}
+		if tenantCfg.WeChatMPAppID == "" || seen[tenantCfg.WeChatMPAppID] {
+			continue
+		}
+		seen[tenantCfg.WeChatMPAppID] = true
+
+		kinds := []string{models.WeChatCredentialAccessToken}
+		var count int64
+		if err := db.Model(&models.WeChatCredential{}).
+			Where("app_id = ? AND kind = ?", tenantCfg.WeChatMPAppID, models.WeChatCredentialJSAPITicket).
+			Count(&count).Error; err != nil {
+			return refreshed, err
+		}
+		if count > 0 {
+			kinds = append(kinds, models.WeChatCredentialJSAPITicket)
+		}
+
+		for _, kind := range kinds {
+			current, err := loadWeChatCredential(db, tenantCfg.WeChatMPAppID, kind)
+			if err == nil && wechatCredentialFresh(current) {
+				continue
+			}
+			if _, err := mpCredential(ctx, db, tenantCfg, kind, ""); err != nil {
+				log.Printf("警告: 刷新公众号 %s 的 %s 失败: %v", tenantCfg.WeChatMPAppID, kind, err)
+				continue
+			}
+			refreshed++
+		}
+	}
+	return refreshed, nil
+}
+
+// mpAccessToken 获取公众号 access_token 的值（auth-center 自身调用公众号接口时使用）
+func mpAccessToken(ctx context.Context, db *gorm.DB, cfg *config.Config) (string, error) {
+	credential, err := MPAccessToken(ctx, db, cfg)
+	if err != nil {
+		return "", err
+	}
+	return credential.Value, nil
+}
+
+// mpCredential 返回未临近到期的凭证；否则领取该凭证的刷新租约后调用微信接口，多副本只有一个副本刷新
+// invalid 不为空时表示调用方发现该凭证已失效：当前凭证仍是它时强制刷新
+// 调用微信接口期间不持有事务与锁：领取租约、保存凭证各自是一个短事务
+func mpCredential(ctx context.Context, db *gorm.DB, cfg *config.Config, kind, invalid string) (*WeChatCredential, error) {
+	if cfg.WeChatMPAppID == "" {
+		return nil, fmt.Errorf("%w：%s", ErrIdentityProviderNotConfigured, models.LoginMethodWeChatMP)
+	}
+	appID := cfg.WeChatMPAppID
+
+	if current, err := loadWeChatCredential(db, appID, kind); err == nil && wechatCredentialFresh(current) && current.Value != invalid {
+		return newWeChatCredential(current), nil
+	}
+
+	for {
+		current, holder, err := claimWeChatCredentialLease(db, appID, kind, invalid)
+		if err != nil {
+			return nil, err
+		}
+		if holder != "" {
+			return refreshWeChatCredential(ctx, db, cfg, kind, holder)
+		}
+		// 其他副本正在刷新：旧凭证还没过期且不是调用方上报失效的凭证时直接使用，否则等待刷新结果
+		if current != nil && time.Now().Before(current.ExpiresAt) && current.Value != invalid {
+			return newWeChatCredential(current), nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wechatCredentialLeasePoll):
+		}
+	}
+}
+
+// claimWeChatCredentialLease 在 advisory lock 保护的短事务中检查凭证并领取刷新租约
+// 返回当前保存的凭证（可能已临近到期或为空）；需要刷新且领取成功时返回租约持有者标识，租约被其他副本持有时为空
+func claimWeChatCredentialLease(db *gorm.DB, appID, kind, invalid string) (*models.WeChatCredential, string, error) {
+	var current *models.WeChatCredential
+	var holder string
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", wechatCredentialLockID(appID, kind)).Error; err != nil {
+			return err
+		}
+
+		// 等锁期间其他副本可能已经刷新
+		record, err := loadWeChatCredential(tx, appID, kind)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if err == nil {
+			current = record
+			if wechatCredentialFresh(record) && record.Value != invalid {
+				return nil
+			}
+		}
+
+		now := time.Now()
+		token, err := generateRandomToken(16)
+		if err != nil {
+			return err
+		}
+		// 没有租约或租约已过期（持有者崩溃）时领取
+		result := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "app_id"}, {Name: "kind"}},
+			DoUpdates: clause.AssignmentColumns([]string{"holder", "lease_until"}),
+			Where:     clause.Where{Exprs: []clause.Expression{clause.Expr{SQL: "wechat_credential_leases.lease_until < ?", Vars: []interface{}{now}}}},
+		}).Create(&models.WeChatCredentialLease{
+			AppID:      appID,
+			Kind:       kind,
+			Holder:     token,
+			LeaseUntil: now.Add(wechatCredentialLeaseTTL),
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected > 0 {
+			holder = token
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	return current, holder, nil
+}
+
+// refreshWeChatCredential 持有租约时向微信获取新凭证并保存，完成后释放租约
+func refreshWeChatCredential(ctx context.Context, db *gorm.DB, cfg *config.Config, kind, holder string) (*WeChatCredential, error) {
+	appID := cfg.WeChatMPAppID
+	release := func(tx *gorm.DB) error {
+		return tx.Where("app_id = ? AND kind = ? AND holder = ?", appID, kind, holder).
+			Delete(&models.WeChatCredentialLease{}).Error
+	}
+
+	value, expiresIn, err := fetchMPCredential(ctx, db, cfg, kind)
+	if err != nil {
+		// 释放租约，其他副本可以立即重试
+		if releaseErr := release(db); releaseErr != nil {
+			log.Printf("警告: 释放公众号凭证刷新租约失败: %v", releaseErr)
+		}
+		return nil, err
+	}
+
+	now := time.Now()
+	record := &models.WeChatCredential{
+		AppID:     appID,
+		Kind:      kind,
+		Value:     value,
+		ExpiresAt: now.Add(time.Duration(expiresIn) * time.Second),
+		UpdatedAt: now,
+	}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := saveWeChatCredential(tx, record); err != nil {
+			return err
+		}
+		return release(tx)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return newWeChatCredential(record), nil
+}
+
+// fetchMPCredential 向微信获取新的凭证，返回凭证与有效期秒数
+func fetchMPCredential(ctx context.Context, db *gorm.DB, cfg *config.Config, kind string) (string, int, error) {
+	var result struct {
+		AccessToken string `json:"access_token"`
+		Ticket      string `json:"ticket"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+
+	if kind != models.WeChatCredentialJSAPITicket {
+		params := url.Values{}
+		params.Set("grant_type", "client_credential")
+		params.Set("appid", cfg.WeChatMPAppID)
+		params.Set("secret", cfg.WeChatMPSecret)
+		if err := wechatAPI.Get(ctx, "/cgi-bin/token", params, &result); err != nil {
+			return "", 0, err
+		}
+		return result.AccessToken, result.ExpiresIn, nil
+	}
+
+	accessToken, err := mpAccessToken(ctx, db, cfg)
+	if err != nil {
+		return "", 0, err
+	}
+	params := url.Values{}
+	params.Set("access_token", accessToken)
+	params.Set("type", "jsapi")
+	err = wechatAPI.Get(ctx, "/cgi-bin/ticket/getticket", params, &result)
+	if wechat.IsCode(err, wechat.CodeInvalidCredential, wechat.CodeAccessTokenExpired) {
+		// access_token 被其他程序刷新或提前失效：换新的 access_token 再试一次
+		credential, refreshErr := RefreshMPAccessToken(ctx, db, cfg, accessToken)
+		if refreshErr != nil {
+			return "", 0, refreshErr
+		}
+		params.Set("access_token", credential.Value)
+		err = wechatAPI.Get(ctx, "/cgi-bin/ticket/getticket", params, &result)
+	}
+	if err != nil {
+		return "", 0, err
+	}
+	return result.Ticket, result.ExpiresIn, nil
+}
+
+// loadWeChatCredential 读取并解密保存的凭证
+func loadWeChatCredential(db *gorm.DB, appID, kind string) (*models.WeChatCredential, error) {
+	var record models.WeChatCredential
+	if err := db.Where("app_id = ? AND kind = ?", appID, kind).First(&record).Error; err != nil {
+		return nil, err
+	}
+	box := DefaultSecretBox()
+	if box == nil {
+		return nil, errors.New("静态加密未初始化")
+	}
+	value, err := box.Open(record.Value, wechatCredentialAAD(appID, kind))
+	if err != nil {
+		return nil, fmt.Errorf("解密公众号凭证失败: %w", err)
+	}
+	record.Value = string(value)
+	return &record, nil
+}
+
+// saveWeChatCredential 加密保存凭证（覆盖旧凭证）
+func saveWeChatCredential(db *gorm.DB, record *models.WeChatCredential) error {
+	box := DefaultSecretBox()
+	if box == nil {
+		return errors.New("静态加密未初始化")
+	}
+	sealed, err := box.Seal([]byte(record.Value), wechatCredentialAAD(record.AppID, record.Kind))
+	if err != nil {
+		return err
+	}
+	row := *record
+	row.Value = sealed
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "app_id"}, {Name: "kind"}},
+		DoUpdates: clause.AssignmentColumns([]string{"value", "expires_at", "updated_at"}),
+	}).Create(&row).Error
+}
+
+// wechatCredentialFresh 凭证是否还没到刷新时间
+func wechatCredentialFresh(record *models.WeChatCredential) bool {
+	return time.Now().Before(record.ExpiresAt.Add(-wechatCredentialRefreshBefore))
+}
+
+// newWeChatCredential 转换为对外的凭证，有效期截止到下次刷新
+func newWeChatCredential(record *models.WeChatCredential) *WeChatCredential {
+	expiresIn := int(time.Until(record.ExpiresAt.Add(-wechatCredentialRefreshBefore)).Seconds())
+	if expiresIn < 0 {
+		expiresIn = 0
+	}
+	return &WeChatCredential{
+		AppID:     record.AppID,
+		Value:     record.Value,
+		ExpiresIn: expiresIn,
+	}
+}
+
+// wechatCredentialAAD 把凭证密文绑定到所属公众号与凭证类型
+func wechatCredentialAAD(appID, kind string) string {
+	return "wechat_credential:" + appID + ":" + kind
+}
+
+// wechatCredentialLockID 凭证刷新的 advisory lock 标识
+func wechatCredentialLockID(appID, kind string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte("auth-center:wechat_credential:" + appID + ":" + kind))
+	return int64(h.Sum64())
+}
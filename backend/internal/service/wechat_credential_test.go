@@ -0,0 +1,106 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/keenchase/auth-center/internal/config"
+	"github.com/keenchase/auth-center/internal/models"
+)
+
+func TestJSSDKSignature(t *testing.T) {
+	// 微信 JS-SDK 说明文档附录 1 中的示例
+	got := jssdkSignature(
+		"sM4AOVdWfPE4DxkXGEs8VMCPGGVi4C3VM0P37wVUCFvkVAy_90u5h9nbSlYy3-Sl-HhTdfl2fzFy1AOcHKP7qg",
+		"Wm3WZYTPz0wzccnW",
+		1414587457,
+		"http://mp.weixin.qq.com?params=value",
+	)
+	if want := "0f9de62fce790f9a083d5c99e95740ceb90c27ed"; got != want {
+		t.Fatalf("signature = %s, want %s", got, want)
+	}
+}
+
+func TestFindClientByPageURL(t *testing.T) {
+	useTestClients(t, models.Client{
+		ClientID:       "client-1",
+		TenantID:       config.DefaultTenantID,
+		RedirectURIs:   "https://app.example.com/auth/callback",
+		AllowedOrigins: "https://www.example.com",
+		Enabled:        true,
+	})
+
+	tests := []struct {
+		name    string
+		pageURL string
+		found   bool
+	}{
+		{"CORS 来源", "https://www.example.com/pay?id=1", true},
+		{"回调地址的域名", "https://app.example.com/orders", true},
+		{"未登记的域名", "https://evil.com/page", false},
+		{"相同后缀的域名", "https://evil-app.example.com/page", false},
+		{"协议降级", "http://www.example.com/pay", false},
+		{"相对地址", "/pay", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := FindClientByPageURL(nil, config.DefaultTenantID, tt.pageURL)
+			if tt.found != (err == nil) || (!tt.found && !errors.Is(err, ErrClientNotFound)) {
+				t.Fatalf("FindClientByPageURL(%q) err = %v, want found %v", tt.pageURL, err, tt.found)
+			}
+		})
+	}
+
+	if _, err := FindClientByPageURL(nil, "other-tenant", "https://www.example.com/pay"); !errors.Is(err, ErrClientNotFound) {
+		t.Fatalf("其他租户 err = %v", err)
+	}
+}
+
+func TestClaimWeChatCredentialLease(t *testing.T) {
+	db := testDB(t)
+	previous := defaultSecretBox
+	defaultSecretBox = newTestSecretBox(t)
+	t.Cleanup(func() { defaultSecretBox = previous })
+	const appID, kind = "wx-lease-test", models.WeChatCredentialAccessToken
+
+	// 没有凭证时领取租约，租约未到期前其他副本领取不到
+	current, holder, err := claimWeChatCredentialLease(db, appID, kind, "")
+	if err != nil || current != nil || holder == "" {
+		t.Fatalf("首次领取 = %v, %q, %v", current, holder, err)
+	}
+	if _, other, err := claimWeChatCredentialLease(db, appID, kind, ""); err != nil || other != "" {
+		t.Fatalf("租约持有期间领取 = %q, %v", other, err)
+	}
+
+	// 持有者崩溃：租约到期后可以接手
+	if err := db.Model(&models.WeChatCredentialLease{}).Where("app_id = ? AND kind = ?", appID, kind).
+		Update("lease_until", time.Now().Add(-time.Second)).Error; err != nil {
+		t.Fatalf("使租约过期失败: %v", err)
+	}
+	_, holder, err = claimWeChatCredentialLease(db, appID, kind, "")
+	if err != nil || holder == "" {
+		t.Fatalf("租约到期后领取 = %q, %v", holder, err)
+	}
+
+	// 保存凭证并释放租约后，新鲜的凭证直接返回，不再领取租约
+	now := time.Now()
+	if err := saveWeChatCredential(db, &models.WeChatCredential{
+		AppID: appID, Kind: kind, Value: "token-1", ExpiresAt: now.Add(2 * time.Hour), UpdatedAt: now,
+	}); err != nil {
+		t.Fatalf("saveWeChatCredential: %v", err)
+	}
+	if err := db.Where("app_id = ? AND kind = ? AND holder = ?", appID, kind, holder).
+		Delete(&models.WeChatCredentialLease{}).Error; err != nil {
+		t.Fatalf("释放租约失败: %v", err)
+	}
+	current, holder, err = claimWeChatCredentialLease(db, appID, kind, "")
+	if err != nil || current == nil || current.Value != "token-1" || holder != "" {
+		t.Fatalf("新鲜凭证 = %v, %q, %v", current, holder, err)
+	}
+
+	// 调用方上报失效时强制刷新
+	if _, holder, err := claimWeChatCredentialLease(db, appID, kind, "token-1"); err != nil || holder == "" {
+		t.Fatalf("上报失效后领取 = %q, %v", holder, err)
+	}
+}
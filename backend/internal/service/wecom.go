@@ -0,0 +1,169 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+
+	"github.com/keenchase/auth-center/internal/config"
+	"github.com/keenchase/auth-center/internal/models"
+)
+
+// ErrWeComNotMember 登录者不是企业成员（企业微信只返回了 openid）
+var ErrWeComNotMember = errors.New("仅限企业成员登录")
+
+func init() {
+	RegisterIdentityProvider(&wecomProvider{name: models.LoginMethodWeCom})
+	RegisterIdentityProvider(&wecomProvider{name: models.LoginMethodWeComQR, qr: true})
+}
+
+// wecomProvider 企业微信自建应用登录：企业微信内网页授权（wecom）与浏览器扫码登录（wecom_qr）
+// 两者都换得成员 userid，账户以 provider=wecom、app_id=CorpID、open_id=userid 登记，因此同一成员两种方式登录为同一账户
+type wecomProvider struct {
+	name string
+	qr   bool
+}
+
+// Name 登录方式标识
+func (p *wecomProvider) Name() string {
+	return p.name
+}
+
+// AppID 当前租户配置的企业 CorpID（未配置应用 AgentID 时视为未配置）
+func (p *wecomProvider) AppID(cfg *config.Config) string {
+	if cfg.WeComAgentID == "" {
+		return ""
+	}
+	return cfg.WeComCorpID
+}
+
+// AuthURL 扫码登录为企业微信 Web 登录页，企业微信内为 OAuth2 网页授权（snsapi_base，静默授权）
+func (p *wecomProvider) AuthURL(cfg *config.Config, redirectURI, state string) string {
+	if p.qr {
+		params := url.Values{}
+		params.Set("login_type", "CorpApp")
+		params.Set("appid", cfg.WeComCorpID)
+		params.Set("agentid", cfg.WeComAgentID)
+		params.Set("redirect_uri", redirectURI)
+		params.Set("state", state)
+		return "https://login.work.weixin.qq.com/wwlogin/sso/login?" + params.Encode()
+	}
+	return fmt.Sprintf(
+		"https://open.weixin.qq.com/connect/oauth2/authorize?appid=%s&redirect_uri=%s&response_type=code&scope=snsapi_base&state=%s&agentid=%s#wechat_redirect",
+		cfg.WeComCorpID,
+		url.QueryEscape(redirectURI),
+		url.QueryEscape(state),
+		url.QueryEscape(cfg.WeComAgentID),
+	)
+}
+
+// ExchangeCode 用授权码换取成员 userid（两种登录方式使用同一接口）
+func (p *wecomProvider) ExchangeCode(ctx context.Context, cfg *config.Config, code string) (*ProviderToken, error) {
+	accessToken, err := wecomAccessToken(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	params := url.Values{}
+	params.Set("access_token", accessToken)
+	params.Set("code", code)
+
+	var result struct {
+		UserID string `json:"userid"`
+		OpenID string `json:"openid"`
+	}
+	if err := wecomAPI.GetOnce(ctx, "/cgi-bin/auth/getuserinfo", params, &result); err != nil {
+		return nil, err
+	}
+	if result.UserID == "" {
+		return nil, ErrWeComNotMember
+	}
+
+	return &ProviderToken{
+		AccessToken: accessToken,
+		Subject:     result.UserID,
+	}, nil
+}
+
+// FetchIdentity 读取成员资料（姓名、头像），WECOM_SYNC_DEPARTMENTS 开启时同步所属部门
+// 企业成员没有微信 UnionID，只按 CorpID + userid 关联账户
+func (p *wecomProvider) FetchIdentity(ctx context.Context, cfg *config.Config, token *ProviderToken) (*ExternalIdentity, error) {
+	params := url.Values{}
+	params.Set("access_token", token.AccessToken)
+	params.Set("userid", token.Subject)
+
+	var member struct {
+		Name       string `json:"name"`
+		Avatar     string `json:"avatar"`
+		Department []int  `json:"department"`
+	}
+	if err := wecomAPI.Get(ctx, "/cgi-bin/user/get", params, &member); err != nil {
+		return nil, err
+	}
+
+	identity := &ExternalIdentity{
+		Provider:    "wecom",
+		AppID:       cfg.WeComCorpID,
+		Subject:     token.Subject,
+		AccountType: "wecom",
+		Nickname:    member.Name,
+		AvatarURL:   member.Avatar,
+	}
+	if cfg.WeComSyncDepartments {
+		departments, err := wecomDepartments(ctx, token.AccessToken, member.Department)
+		if err != nil {
+			return nil, fmt.Errorf("同步部门失败: %w", err)
+		}
+		identity.Departments = departments
+	}
+	return identity, nil
+}
+
+// wecomDepartments 查询部门名称，按成员所属部门的顺序返回
+func wecomDepartments(ctx context.Context, accessToken string, ids []int) ([]models.Department, error) {
+	departments := make([]models.Department, 0, len(ids))
+	if len(ids) == 0 {
+		return departments, nil
+	}
+
+	params := url.Values{}
+	params.Set("access_token", accessToken)
+
+	var result struct {
+		Department []struct {
+			ID   int    `json:"id"`
+			Name string `json:"name"`
+		} `json:"department"`
+	}
+	if err := wecomAPI.Get(ctx, "/cgi-bin/department/list", params, &result); err != nil {
+		return nil, err
+	}
+
+	names := make(map[int]string, len(result.Department))
+	for _, department := range result.Department {
+		names[department.ID] = department.Name
+	}
+	for _, id := range ids {
+		departments = append(departments, models.Department{ID: id, Name: names[id]})
+	}
+	return departments, nil
+}
+
+// wecomAccessToken 获取自建应用的 access_token（按 CorpID + AgentID 缓存）
+func wecomAccessToken(ctx context.Context, cfg *config.Config) (string, error) {
+	return upstreamTokens.get("wecom:"+cfg.WeComCorpID+":"+cfg.WeComAgentID, func() (string, int, error) {
+		params := url.Values{}
+		params.Set("corpid", cfg.WeComCorpID)
+		params.Set("corpsecret", cfg.WeComSecret)
+
+		var result struct {
+			AccessToken string `json:"access_token"`
+			ExpiresIn   int    `json:"expires_in"`
+		}
+		if err := wecomAPI.Get(ctx, "/cgi-bin/gettoken", params, &result); err != nil {
+			return "", 0, err
+		}
+		return result.AccessToken, result.ExpiresIn, nil
+	})
+}
@@ -0,0 +1,58 @@
+package wechat
+
+import (
+	"sync"
+	"time"
+)
+
+// breaker 熔断器：连续失败达到阈值后打开，冷却期内拒绝请求；冷却期过后放行一个探测请求，成功则关闭
+type breaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+// allow 是否放行本次请求；probe 表示放行的是半开状态下的探测请求，结束时须原样传给 release / record
+func (b *breaker) allow() (probe, ok bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failures < b.threshold {
+		return false, true
+	}
+	if time.Now().Before(b.openUntil) || b.probing {
+		return false, false
+	}
+	b.probing = true
+	return true, true
+}
+
+// release 放弃本次请求（调用方取消）：不计入结果；探测请求释放探测名额
+// 熔断前发出的其他请求被取消时不能释放名额，否则会同时放行多个探测请求
+func (b *breaker) release(probe bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if probe {
+		b.probing = false
+	}
+}
+
+// record 记录请求结果，探测请求同时释放探测名额
+func (b *breaker) record(probe, failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if probe {
+		b.probing = false
+	}
+	if !failed {
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.failures >= b.threshold {
+		b.openUntil = time.Now().Add(b.cooldown)
+	}
+}
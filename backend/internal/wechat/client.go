@@ -0,0 +1,225 @@
+// Package wechat 微信 / 企业微信服务端 API 客户端
+// 单次请求超时、临时错误重试、连续失败熔断，基础地址可配置（测试时可指向本地模拟服务）
+package wechat
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultBaseURL 微信公众平台 / 开放平台 / 小程序接口地址
+	DefaultBaseURL = "https://api.weixin.qq.com"
+
+	// DefaultWeComBaseURL 企业微信接口地址
+	DefaultWeComBaseURL = "https://qyapi.weixin.qq.com"
+
+	// maxResponseBytes 响应体大小上限
+	maxResponseBytes = 1 << 20
+)
+
+// Config 客户端配置，零值字段使用默认值
+type Config struct {
+	BaseURL          string        // 默认 DefaultBaseURL
+	Timeout          time.Duration // 单次请求超时，默认 5 秒
+	MaxRetries       int           // 临时错误的最大重试次数，默认 2，负数表示不重试
+	RetryBackoff     time.Duration // 首次重试前的等待时间（之后每次翻倍），默认 200 毫秒
+	BreakerThreshold int           // 连续失败多少次后熔断，默认 5
+	BreakerCooldown  time.Duration // 熔断冷却时间，默认 30 秒
+	HTTPClient       *http.Client  // 默认 http.DefaultClient
+}
+
+// Client 微信服务端 API 客户端，可并发使用
+type Client struct {
+	baseURL      string
+	timeout      time.Duration
+	maxRetries   int
+	retryBackoff time.Duration
+	httpClient   *http.Client
+	breaker      *breaker
+}
+
+// NewClient 创建客户端
+func NewClient(cfg Config) *Client {
+	c := &Client{
+		baseURL:      strings.TrimRight(cfg.BaseURL, "/"),
+		timeout:      cfg.Timeout,
+		maxRetries:   cfg.MaxRetries,
+		retryBackoff: cfg.RetryBackoff,
+		httpClient:   cfg.HTTPClient,
+		breaker:      &breaker{threshold: cfg.BreakerThreshold, cooldown: cfg.BreakerCooldown},
+	}
+	if c.baseURL == "" {
+		c.baseURL = DefaultBaseURL
+	}
+	if c.timeout <= 0 {
+		c.timeout = 5 * time.Second
+	}
+	if c.maxRetries == 0 {
+		c.maxRetries = 2
+	} else if c.maxRetries < 0 {
+		c.maxRetries = 0
+	}
+	if c.retryBackoff <= 0 {
+		c.retryBackoff = 200 * time.Millisecond
+	}
+	if c.httpClient == nil {
+		c.httpClient = http.DefaultClient
+	}
+	if c.breaker.threshold <= 0 {
+		c.breaker.threshold = 5
+	}
+	if c.breaker.cooldown <= 0 {
+		c.breaker.cooldown = 30 * time.Second
+	}
+	return c
+}
+
+// Get 调用 GET 接口，errcode 不为 0 时返回 *Error，否则把响应解析到 out
+func (c *Client) Get(ctx context.Context, path string, params url.Values, out interface{}) error {
+	return c.do(ctx, http.MethodGet, path, params, nil, out, c.maxRetries)
+}
+
+// GetOnce 同 Get，但不重试；用于一次性授权码换取等非幂等接口
+// （超时的请求可能已在微信侧消费了 code，重试只会得到 40163）
+func (c *Client) GetOnce(ctx context.Context, path string, params url.Values, out interface{}) error {
+	return c.do(ctx, http.MethodGet, path, params, nil, out, 0)
+}
+
+// Post 以 JSON 请求体调用 POST 接口（params 为查询参数，如 access_token），响应处理同 Get
+func (c *Client) Post(ctx context.Context, path string, params url.Values, request interface{}, out interface{}) error {
+	payload, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	return c.do(ctx, http.MethodPost, path, params, payload, out, c.maxRetries)
+}
+
+// do 发送请求：临时错误按指数退避最多重试 maxRetries 次，网络错误、5xx 与系统繁忙计入熔断；
+// 调用方取消时不计入熔断结果
+func (c *Client) do(ctx context.Context, method, path string, params url.Values, payload []byte, out interface{}, maxRetries int) error {
+	probe, ok := c.breaker.allow()
+	if !ok {
+		return ErrCircuitOpen
+	}
+
+	var err error
+	for attempt := 0; ; attempt++ {
+		err = c.send(ctx, method, path, params, payload, out)
+		if ctx.Err() != nil {
+			c.breaker.release(probe)
+			return err
+		}
+		if !retryable(err) || attempt >= maxRetries {
+			break
+		}
+		timer := time.NewTimer(c.retryBackoff << attempt)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			c.breaker.release(probe)
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	var unavailable *unavailableError
+	c.breaker.record(probe, errors.As(err, &unavailable) || ErrorCode(err) == CodeSystemBusy)
+	return err
+}
+
+// send 发送一次请求并解析响应
+func (c *Client) send(ctx context.Context, method, path string, params url.Values, payload []byte, out interface{}) error {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	endpoint := c.baseURL + path
+	if len(params) > 0 {
+		endpoint += "?" + params.Encode()
+	}
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, body)
+	if err != nil {
+		return err
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		// 调用方取消不算微信接口故障
+		if ctxErr := context.Cause(ctx); ctxErr != nil && !errors.Is(ctxErr, context.DeadlineExceeded) {
+			return ctxErr
+		}
+		return &unavailableError{err: err}
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBytes))
+	if err != nil {
+		return &unavailableError{err: fmt.Errorf("读取响应失败: %w", err)}
+	}
+	if resp.StatusCode >= 500 {
+		return &unavailableError{err: fmt.Errorf("HTTP %d", resp.StatusCode)}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("请求微信 API 失败: HTTP %d", resp.StatusCode)
+	}
+	return decode(data, out)
+}
+
+// decode 检查 errcode（数字或字符串均可），为 0 或不存在时把响应解析到 out
+func decode(data []byte, out interface{}) error {
+	var envelope struct {
+		ErrCode json.RawMessage `json:"errcode"`
+		ErrMsg  json.RawMessage `json:"errmsg"`
+	}
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return fmt.Errorf("解析响应失败: %w", err)
+	}
+
+	if raw := strings.Trim(string(envelope.ErrCode), `"`); raw != "" && raw != "null" {
+		code, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("解析响应失败: errcode %s", envelope.ErrCode)
+		}
+		if code != 0 {
+			var message string
+			if json.Unmarshal(envelope.ErrMsg, &message) != nil {
+				message = string(envelope.ErrMsg)
+			}
+			return &Error{Code: code, Message: message}
+		}
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("解析响应失败: %w", err)
+	}
+	return nil
+}
+
+// retryable 是否值得重试：网络错误、5xx、系统繁忙、频率限制
+func retryable(err error) bool {
+	var unavailable *unavailableError
+	if errors.As(err, &unavailable) {
+		return true
+	}
+	var apiErr *Error
+	return errors.As(err, &apiErr) && apiErr.Temporary()
+}
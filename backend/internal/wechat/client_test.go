@@ -0,0 +1,210 @@
+package wechat
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestClient 指向本地模拟服务的客户端，重试等待与熔断冷却缩短以加快测试
+func newTestClient(t *testing.T, handler http.HandlerFunc) (*Client, *httptest.Server) {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	c := NewClient(Config{
+		BaseURL:          srv.URL,
+		Timeout:          time.Second,
+		MaxRetries:       2,
+		RetryBackoff:     time.Millisecond,
+		BreakerThreshold: 2,
+		BreakerCooldown:  50 * time.Millisecond,
+	})
+	return c, srv
+}
+
+func TestDecodeErrCode(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		code int
+	}{
+		{"数字", `{"errcode":40029,"errmsg":"invalid code"}`, CodeInvalidCode},
+		{"字符串", `{"errcode":"40163","errmsg":"code been used"}`, CodeCodeUsed},
+		{"零", `{"errcode":0,"errmsg":"ok","openid":"o1"}`, 0},
+		{"不存在", `{"openid":"o1"}`, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out struct {
+				OpenID string `json:"openid"`
+			}
+			err := decode([]byte(tt.body), &out)
+			if got := ErrorCode(err); got != tt.code {
+				t.Fatalf("errcode = %d, want %d (err: %v)", got, tt.code, err)
+			}
+			if tt.code == 0 && (err != nil || out.OpenID != "o1") {
+				t.Fatalf("decode = %v, openid %q", err, out.OpenID)
+			}
+		})
+	}
+
+	if err := decode([]byte(`{"errcode":"abc"}`), nil); err == nil || ErrorCode(err) != 0 {
+		t.Fatalf("非法 errcode 应返回解析错误，got %v", err)
+	}
+}
+
+func TestGetRetriesTemporaryErrors(t *testing.T) {
+	var calls atomic.Int32
+	c, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.Write([]byte(`{"errcode":-1,"errmsg":"system error"}`))
+			return
+		}
+		w.Write([]byte(`{"openid":"o1"}`))
+	})
+
+	var out struct {
+		OpenID string `json:"openid"`
+	}
+	if err := c.Get(context.Background(), "/sns/userinfo", nil, &out); err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if calls.Load() != 3 || out.OpenID != "o1" {
+		t.Fatalf("calls = %d, openid = %q", calls.Load(), out.OpenID)
+	}
+}
+
+func TestGetDoesNotRetryPermanentErrors(t *testing.T) {
+	var calls atomic.Int32
+	c, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Write([]byte(`{"errcode":40029,"errmsg":"invalid code"}`))
+	})
+
+	err := c.Get(context.Background(), "/sns/userinfo", nil, nil)
+	if !IsCode(err, CodeInvalidCode) || calls.Load() != 1 {
+		t.Fatalf("err = %v, calls = %d", err, calls.Load())
+	}
+}
+
+func TestGetOnceDoesNotRetry(t *testing.T) {
+	var calls atomic.Int32
+	c, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+	})
+
+	err := c.GetOnce(context.Background(), "/sns/oauth2/access_token", nil, nil)
+	var unavailable *unavailableError
+	if !errors.As(err, &unavailable) || calls.Load() != 1 {
+		t.Fatalf("err = %v, calls = %d", err, calls.Load())
+	}
+}
+
+func TestBreakerOpensAndRecovers(t *testing.T) {
+	var healthy atomic.Bool
+	var calls atomic.Int32
+	c, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(`{"errcode":0}`))
+	})
+	ctx := context.Background()
+
+	// 连续失败达到阈值后熔断
+	for i := 0; i < 2; i++ {
+		if err := c.GetOnce(ctx, "/cgi-bin/token", nil, nil); err == nil {
+			t.Fatal("期望 5xx 失败")
+		}
+	}
+	before := calls.Load()
+	if err := c.GetOnce(ctx, "/cgi-bin/token", nil, nil); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("熔断期间 err = %v", err)
+	}
+	if calls.Load() != before {
+		t.Fatal("熔断期间不应请求微信接口")
+	}
+
+	// 冷却后放行一个探测请求，探测失败则重新熔断
+	time.Sleep(60 * time.Millisecond)
+	if err := c.GetOnce(ctx, "/cgi-bin/token", nil, nil); err == nil || errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("探测请求 err = %v", err)
+	}
+	if err := c.GetOnce(ctx, "/cgi-bin/token", nil, nil); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("探测失败后 err = %v", err)
+	}
+
+	// 探测成功则关闭
+	healthy.Store(true)
+	time.Sleep(60 * time.Millisecond)
+	if err := c.GetOnce(ctx, "/cgi-bin/token", nil, nil); err != nil {
+		t.Fatalf("探测请求 err = %v", err)
+	}
+	if err := c.GetOnce(ctx, "/cgi-bin/token", nil, nil); err != nil {
+		t.Fatalf("关闭后 err = %v", err)
+	}
+}
+
+func TestCanceledRequestNotRecorded(t *testing.T) {
+	c, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	})
+
+	// 失败一次后取消的请求既不清零也不累加失败计数
+	c.breaker.record(false, true)
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	if err := c.Get(ctx, "/cgi-bin/token", nil, nil); !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v", err)
+	}
+	if c.breaker.failures != 1 {
+		t.Fatalf("failures = %d, want 1", c.breaker.failures)
+	}
+
+	// 半开状态下取消的探测请求释放探测名额
+	c.breaker.record(false, true)
+	c.breaker.openUntil = time.Now().Add(-time.Millisecond)
+	ctx, cancel = context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	if err := c.Get(ctx, "/cgi-bin/token", nil, nil); !errors.Is(err, context.Canceled) {
+		t.Fatalf("探测请求 err = %v", err)
+	}
+	if probe, ok := c.breaker.allow(); !probe || !ok {
+		t.Fatal("取消的探测请求应释放探测名额")
+	}
+}
+
+func TestBreakerProbeReleasedOnlyByProbe(t *testing.T) {
+	b := &breaker{threshold: 1, cooldown: time.Minute}
+	b.record(false, true)
+	b.openUntil = time.Now().Add(-time.Millisecond)
+
+	probe, ok := b.allow()
+	if !probe || !ok {
+		t.Fatalf("冷却期过后 allow = %v, %v, want 探测请求", probe, ok)
+	}
+	if _, ok := b.allow(); ok {
+		t.Fatal("探测期间应拒绝其他请求")
+	}
+
+	// 熔断前发出的请求被取消或失败，不释放探测名额
+	b.release(false)
+	b.record(false, true)
+	b.openUntil = time.Now().Add(-time.Millisecond)
+	if _, ok := b.allow(); ok {
+		t.Fatal("非探测请求结束后不应放行第二个探测请求")
+	}
+
+	// 探测请求成功后关闭熔断
+	b.record(true, false)
+	if probe, ok := b.allow(); probe || !ok || b.failures != 0 {
+		t.Fatalf("探测成功后 allow = %v, %v, failures = %d", probe, ok, b.failures)
+	}
+}
@@ -0,0 +1,70 @@
+package wechat
+
+import (
+	"errors"
+	"fmt"
+)
+
+// 微信接口错误码（errcode）
+const (
+	CodeSystemBusy         = -1    // 系统繁忙，稍后重试
+	CodeInvalidCredential  = 40001 // access_token 无效或不是最新的
+	CodeInvalidCode        = 40029 // 授权码无效
+	CodeCodeUsed           = 40163 // 授权码已被使用
+	CodeAccessTokenExpired = 42001 // access_token 已过期
+	CodeFreqLimit          = 45009 // 接口调用超过频率限制
+)
+
+// ErrCircuitOpen 微信接口连续失败，熔断冷却期内直接失败，不再请求
+var ErrCircuitOpen = errors.New("微信接口暂时不可用，请稍后重试")
+
+// Error 微信接口返回的错误（errcode 不为 0）
+type Error struct {
+	Code    int
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("微信 API 错误: %d %s", e.Code, e.Message)
+}
+
+// Temporary 是否为可重试的临时错误（系统繁忙、频率限制）
+func (e *Error) Temporary() bool {
+	return e.Code == CodeSystemBusy || e.Code == CodeFreqLimit
+}
+
+// ErrorCode 返回 err 中的微信错误码，不是微信接口错误时返回 0
+func ErrorCode(err error) int {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr.Code
+	}
+	return 0
+}
+
+// IsCode err 是否为指定错误码之一的微信接口错误
+func IsCode(err error, codes ...int) bool {
+	code := ErrorCode(err)
+	if code == 0 {
+		return false
+	}
+	for _, c := range codes {
+		if code == c {
+			return true
+		}
+	}
+	return false
+}
+
+// unavailableError 网络错误或 5xx 响应（可重试，计入熔断）
+type unavailableError struct {
+	err error
+}
+
+func (e *unavailableError) Error() string {
+	return "请求微信 API 失败: " + e.err.Error()
+}
+
+func (e *unavailableError) Unwrap() error {
+	return e.err
+}
@@ -0,0 +1,41 @@
+-- 基础表：用户、登录账户与会话（后续迁移在此基础上演进），新建数据库时先执行本迁移
+-- 已有数据库的表由早期版本创建，IF NOT EXISTS 保证重复执行无副作用
+-- 需要 PostgreSQL 13+（gen_random_uuid），更早的版本须先 CREATE EXTENSION pgcrypto
+-- Date: 2026-10-17
+
+CREATE TABLE IF NOT EXISTS users (
+  user_id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  union_id VARCHAR(255) UNIQUE,                          -- 016 改为租户内唯一
+  phone_number VARCHAR(255) UNIQUE,
+  password_hash VARCHAR(255),
+  email VARCHAR(255) UNIQUE,
+  last_login_at TIMESTAMP WITH TIME ZONE,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+  updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS user_accounts (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id UUID NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+  provider VARCHAR(50) NOT NULL,
+  app_id VARCHAR(100) NOT NULL,
+  open_id VARCHAR(255) NOT NULL,
+  type VARCHAR(20) NOT NULL,
+  nickname VARCHAR(255),
+  avatar_url TEXT,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+  UNIQUE (provider, app_id, open_id)                     -- 016 改为租户内唯一
+);
+
+CREATE INDEX IF NOT EXISTS user_accounts_user_id_idx ON user_accounts(user_id);
+
+CREATE TABLE IF NOT EXISTS sessions (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id UUID NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+  token VARCHAR(500) UNIQUE NOT NULL,                    -- 007 改为只保存摘要
+  device_info JSONB,
+  expires_at TIMESTAMP WITHOUT TIME ZONE NOT NULL,
+  created_at TIMESTAMP WITHOUT TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS sessions_user_id_idx ON sessions(user_id);
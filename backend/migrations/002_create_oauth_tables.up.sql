@@ -0,0 +1,43 @@
+-- OIDC 授权服务：已注册客户端、授权请求与授权码
+-- Date: 2026-10-17
+
+CREATE TABLE IF NOT EXISTS clients (
+  client_id VARCHAR(100) PRIMARY KEY,
+  client_secret_hash VARCHAR(255),           -- bcrypt；为空表示公共客户端（必须使用 PKCE）
+  name VARCHAR(255) NOT NULL,
+  redirect_uris TEXT NOT NULL,               -- 空格分隔，精确匹配
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+  updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS oauth_authorize_requests (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  client_id VARCHAR(100) NOT NULL REFERENCES clients(client_id) ON DELETE CASCADE,
+  redirect_uri TEXT NOT NULL,
+  scope VARCHAR(500) NOT NULL,
+  state VARCHAR(500),
+  nonce VARCHAR(500),
+  code_challenge VARCHAR(128),
+  code_challenge_method VARCHAR(10),
+  expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS oauth_authorization_codes (
+  code_hash VARCHAR(64) PRIMARY KEY,         -- SHA-256(code)，不保存明文
+  client_id VARCHAR(100) NOT NULL REFERENCES clients(client_id) ON DELETE CASCADE,
+  user_id UUID NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+  redirect_uri TEXT NOT NULL,
+  scope VARCHAR(500) NOT NULL,
+  nonce VARCHAR(500),
+  code_challenge VARCHAR(128),
+  code_challenge_method VARCHAR(10),
+  auth_time TIMESTAMP WITH TIME ZONE NOT NULL,
+  session_id UUID,
+  expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+  used_at TIMESTAMP WITH TIME ZONE,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX oauth_authorize_requests_expires_at_idx ON oauth_authorize_requests(expires_at);
+CREATE INDEX oauth_authorization_codes_expires_at_idx ON oauth_authorization_codes(expires_at);
@@ -0,0 +1,16 @@
+-- JWT 签名密钥：私钥加密存储，支持定期轮换与宽限期验签
+-- Date: 2026-10-17
+
+CREATE TABLE IF NOT EXISTS signing_keys (
+  kid VARCHAR(64) PRIMARY KEY,               -- RFC 7638 公钥指纹
+  algorithm VARCHAR(10) NOT NULL,            -- RS256 | ES256 | EdDSA
+  private_key_enc TEXT NOT NULL,             -- AES-256-GCM 加密的 PKCS#8 私钥，退役后清空
+  status VARCHAR(20) NOT NULL,               -- active | verify | retired
+  activated_at TIMESTAMP WITH TIME ZONE NOT NULL,
+  rotated_at TIMESTAMP WITH TIME ZONE,       -- 转为仅验签的时间
+  retire_at TIMESTAMP WITH TIME ZONE,        -- 宽限期结束时间
+  retired_at TIMESTAMP WITH TIME ZONE,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX signing_keys_status_idx ON signing_keys(status);
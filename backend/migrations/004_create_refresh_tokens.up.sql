@@ -0,0 +1,19 @@
+-- 短期访问令牌 + 轮换刷新令牌
+-- sessions 代表一个刷新令牌家族：expires_at 随每次刷新顺延，被删除时家族内的刷新令牌级联删除
+-- Date: 2026-10-17
+
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS client_id VARCHAR(100) NOT NULL DEFAULT '';
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS scope VARCHAR(500) NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  session_id UUID NOT NULL REFERENCES sessions(id) ON DELETE CASCADE,
+  token_hash VARCHAR(64) NOT NULL UNIQUE,    -- SHA-256(refresh_token)，不保存明文
+  parent_id UUID,                            -- 轮换前的刷新令牌
+  expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+  used_at TIMESTAMP WITH TIME ZONE,          -- 已轮换；再次出现即视为重放
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX refresh_tokens_session_id_idx ON refresh_tokens(session_id);
+CREATE INDEX refresh_tokens_expires_at_idx ON refresh_tokens(expires_at);
@@ -0,0 +1,10 @@
+-- 会话管理："在哪些设备上登录"页面
+-- name: 用户为设备设置的名称；last_seen_at: 最近一次使用（鉴权时节流更新）
+-- Date: 2026-10-17
+
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS name VARCHAR(100);
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS last_seen_at TIMESTAMP WITHOUT TIME ZONE;
+
+UPDATE sessions SET last_seen_at = created_at WHERE last_seen_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS sessions_user_id_idx ON sessions(user_id);
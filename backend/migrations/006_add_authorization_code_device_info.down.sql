@@ -0,0 +1 @@
+ALTER TABLE oauth_authorization_codes DROP COLUMN IF EXISTS device_info;
@@ -0,0 +1,4 @@
+-- 授权码记录用户浏览器的设备信息（令牌端点由客户端服务端调用，无法取得用户的 IP / User-Agent）
+-- Date: 2026-10-17
+
+ALTER TABLE oauth_authorization_codes ADD COLUMN IF NOT EXISTS device_info JSONB;
@@ -0,0 +1,9 @@
+-- 明文令牌无法恢复：回滚后旧会话只能通过 sid 声明定位，不带 sid 的旧 Token 需要重新登录
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS token VARCHAR(500);
+UPDATE sessions SET token = 'revoked:' || id::text WHERE token IS NULL;
+ALTER TABLE sessions ALTER COLUMN token SET NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS sessions_token_key ON sessions(token);
+
+DROP INDEX IF EXISTS sessions_token_hash_key;
+ALTER TABLE sessions DROP COLUMN IF EXISTS token_hint;
+ALTER TABLE sessions DROP COLUMN IF EXISTS token_hash;
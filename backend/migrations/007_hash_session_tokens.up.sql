@@ -0,0 +1,18 @@
+-- 会话令牌只保存 SHA-256 摘要，数据库泄露不再等于账号被接管
+-- token_hint 保留令牌末尾 8 位，仅用于在会话列表中辨认
+-- 需要 PostgreSQL 11+（sha256 函数）
+-- Date: 2026-10-17
+
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS token_hash VARCHAR(64);
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS token_hint VARCHAR(16);
+
+UPDATE sessions
+SET token_hash = encode(sha256(convert_to(token, 'UTF8')), 'hex'),
+    token_hint = right(token, 8)
+WHERE token_hash IS NULL;
+
+ALTER TABLE sessions ALTER COLUMN token_hash SET NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS sessions_token_hash_key ON sessions(token_hash);
+
+-- 删除明文令牌列（其唯一索引随之删除）
+ALTER TABLE sessions DROP COLUMN IF EXISTS token;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS sessions_expires_at_idx;
+DROP TABLE IF EXISTS scheduled_jobs;
@@ -0,0 +1,17 @@
+-- 后台任务最近一次运行状态（多副本共享，由 advisory lock 保证同一任务只有一个副本在运行）
+-- Date: 2026-10-17
+
+CREATE TABLE IF NOT EXISTS scheduled_jobs (
+  name VARCHAR(100) PRIMARY KEY,
+  last_started_at TIMESTAMP WITH TIME ZONE,
+  last_finished_at TIMESTAMP WITH TIME ZONE,
+  last_status VARCHAR(20),                   -- success | failed
+  last_result TEXT,                          -- 运行结果摘要
+  last_error TEXT,
+  last_duration_ms BIGINT,
+  last_instance VARCHAR(255),                -- 执行任务的副本
+  updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- 会话清理按过期时间扫描
+CREATE INDEX IF NOT EXISTS sessions_expires_at_idx ON sessions(expires_at);
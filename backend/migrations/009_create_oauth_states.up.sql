@@ -0,0 +1,15 @@
+-- 微信登录 state：短的不透明随机串，绑定浏览器 nonce（Cookie）、回调地址、AppID 与过期时间，一次性使用
+-- Date: 2026-10-17
+
+CREATE TABLE IF NOT EXISTS oauth_states (
+  state_hash VARCHAR(64) PRIMARY KEY,        -- SHA-256(state)，不保存明文
+  nonce_hash VARCHAR(64) NOT NULL,           -- SHA-256(浏览器 Cookie 中的 nonce)
+  flow VARCHAR(20) NOT NULL,                 -- mp（公众号）| open（开放平台）
+  app_id VARCHAR(100) NOT NULL,
+  callback_url TEXT NOT NULL,
+  expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+  used_at TIMESTAMP WITH TIME ZONE,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX oauth_states_expires_at_idx ON oauth_states(expires_at);
@@ -0,0 +1,16 @@
+-- 登录交换码：微信登录完成后只把一次性交换码带回业务系统，由业务系统服务端凭客户端密钥换取令牌
+-- Date: 2026-10-17
+
+CREATE TABLE IF NOT EXISTS exchange_codes (
+  code_hash VARCHAR(64) PRIMARY KEY,         -- SHA-256(code)，不保存明文
+  client_id VARCHAR(100) NOT NULL DEFAULT '', -- 兑换方客户端，空字符串表示 auth-center 自身页面
+  user_id UUID NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+  callback_url TEXT NOT NULL,
+  login_method VARCHAR(50) NOT NULL,
+  device_info JSONB,                         -- 登录时用户浏览器的设备信息
+  expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+  used_at TIMESTAMP WITH TIME ZONE,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX exchange_codes_expires_at_idx ON exchange_codes(expires_at);
@@ -0,0 +1,6 @@
+ALTER TABLE clients DROP COLUMN IF EXISTS enabled;
+ALTER TABLE clients DROP COLUMN IF EXISTS logo_url;
+ALTER TABLE clients DROP COLUMN IF EXISTS refresh_token_ttl;
+ALTER TABLE clients DROP COLUMN IF EXISTS access_token_ttl;
+ALTER TABLE clients DROP COLUMN IF EXISTS allowed_login_methods;
+ALTER TABLE clients DROP COLUMN IF EXISTS allowed_origins;
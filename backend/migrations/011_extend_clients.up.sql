@@ -0,0 +1,10 @@
+-- 客户端（业务系统）登记：CORS 来源、允许的登录方式、令牌有效期、Logo 与启用状态
+-- 取代 ALLOWED_CALLBACK_DOMAINS / ALLOWED_ORIGINS 环境变量，新增业务系统无需重新部署
+-- Date: 2026-10-17
+
+ALTER TABLE clients ADD COLUMN IF NOT EXISTS allowed_origins TEXT NOT NULL DEFAULT '';       -- 空格分隔，如 https://pixel.crazyaigc.com
+ALTER TABLE clients ADD COLUMN IF NOT EXISTS allowed_login_methods TEXT NOT NULL DEFAULT ''; -- 空格分隔：wechat_mp wechat_open password，空表示不限
+ALTER TABLE clients ADD COLUMN IF NOT EXISTS access_token_ttl INTEGER;                      -- 秒，为空时使用 ACCESS_TOKEN_TTL
+ALTER TABLE clients ADD COLUMN IF NOT EXISTS refresh_token_ttl INTEGER;                     -- 秒，为空时使用 REFRESH_TOKEN_TTL
+ALTER TABLE clients ADD COLUMN IF NOT EXISTS logo_url TEXT;
+ALTER TABLE clients ADD COLUMN IF NOT EXISTS enabled BOOLEAN NOT NULL DEFAULT TRUE;
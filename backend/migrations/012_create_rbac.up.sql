@@ -0,0 +1,51 @@
+-- 基于角色的访问控制：角色、权限与用户角色，取代单一的 ADMIN_WECHAT_OPENID 管理员
+-- 初始超级管理员在服务启动时按 ADMIN_WECHAT_OPENID 授予（仅当还没有任何超级管理员时）
+-- Date: 2026-10-17
+
+CREATE TABLE IF NOT EXISTS permissions (
+  code VARCHAR(100) PRIMARY KEY,             -- 如 users:read；* 表示全部权限
+  description VARCHAR(255) NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS roles (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  name VARCHAR(100) NOT NULL UNIQUE,
+  description VARCHAR(255) NOT NULL DEFAULT '',
+  is_system BOOLEAN NOT NULL DEFAULT FALSE,  -- 内置角色不可删除、不可修改权限
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+  updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS role_permissions (
+  role_id UUID NOT NULL REFERENCES roles(id) ON DELETE CASCADE,
+  permission VARCHAR(100) NOT NULL REFERENCES permissions(code) ON DELETE CASCADE,
+  PRIMARY KEY (role_id, permission)
+);
+
+CREATE TABLE IF NOT EXISTS user_roles (
+  user_id UUID NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+  role_id UUID NOT NULL REFERENCES roles(id) ON DELETE CASCADE,
+  granted_by UUID,                           -- 授予者，为空表示系统初始化
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+  PRIMARY KEY (user_id, role_id)
+);
+
+CREATE INDEX user_roles_role_id_idx ON user_roles(role_id);
+
+INSERT INTO permissions (code, description) VALUES
+  ('*', '全部权限'),
+  ('users:read', '查看用户'),
+  ('users:write', '修改用户资料与登录方式'),
+  ('sessions:revoke', '吊销用户会话'),
+  ('clients:manage', '管理客户端（业务系统）'),
+  ('roles:manage', '管理角色与用户角色'),
+  ('keys:manage', '管理签名密钥'),
+  ('jobs:read', '查看后台任务')
+ON CONFLICT (code) DO NOTHING;
+
+INSERT INTO roles (name, description, is_system) VALUES ('super_admin', '超级管理员', TRUE)
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission)
+SELECT id, '*' FROM roles WHERE name = 'super_admin'
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,15 @@
+DELETE FROM roles WHERE client_id <> '';
+DELETE FROM permissions WHERE client_id <> '';
+
+ALTER TABLE roles DROP CONSTRAINT IF EXISTS roles_client_id_name_key;
+ALTER TABLE roles ADD CONSTRAINT roles_name_key UNIQUE (name);
+
+ALTER TABLE role_permissions DROP CONSTRAINT IF EXISTS role_permissions_permission_fkey;
+ALTER TABLE permissions DROP CONSTRAINT IF EXISTS permissions_pkey;
+ALTER TABLE permissions ADD PRIMARY KEY (code);
+ALTER TABLE role_permissions ADD CONSTRAINT role_permissions_permission_fkey
+  FOREIGN KEY (permission) REFERENCES permissions(code) ON DELETE CASCADE;
+
+ALTER TABLE role_permissions DROP COLUMN IF EXISTS client_id;
+ALTER TABLE roles DROP COLUMN IF EXISTS client_id;
+ALTER TABLE permissions DROP COLUMN IF EXISTS client_id;
@@ -0,0 +1,18 @@
+-- 应用级角色与权限：权限、角色归属某个客户端（业务系统），client_id 为空表示 auth-center 自身
+-- 业务系统的角色与权限写入该客户端的访问令牌，并可通过 /api/authz 接口查询
+-- Date: 2026-10-17
+
+ALTER TABLE permissions ADD COLUMN IF NOT EXISTS client_id VARCHAR(100) NOT NULL DEFAULT '';
+ALTER TABLE roles ADD COLUMN IF NOT EXISTS client_id VARCHAR(100) NOT NULL DEFAULT '';
+ALTER TABLE role_permissions ADD COLUMN IF NOT EXISTS client_id VARCHAR(100) NOT NULL DEFAULT '';
+
+-- 权限编码只在同一客户端内唯一
+ALTER TABLE role_permissions DROP CONSTRAINT IF EXISTS role_permissions_permission_fkey;
+ALTER TABLE permissions DROP CONSTRAINT IF EXISTS permissions_pkey;
+ALTER TABLE permissions ADD PRIMARY KEY (client_id, code);
+ALTER TABLE role_permissions ADD CONSTRAINT role_permissions_permission_fkey
+  FOREIGN KEY (client_id, permission) REFERENCES permissions(client_id, code) ON DELETE CASCADE;
+
+-- 角色名称只在同一客户端内唯一
+ALTER TABLE roles DROP CONSTRAINT IF EXISTS roles_name_key;
+ALTER TABLE roles ADD CONSTRAINT roles_client_id_name_key UNIQUE (client_id, name);
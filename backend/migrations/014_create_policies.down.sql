@@ -0,0 +1,5 @@
+DELETE FROM permissions WHERE client_id = '' AND code = 'policies:manage';
+DROP TABLE IF EXISTS policies;
+ALTER TABLE oauth_authorization_codes DROP COLUMN IF EXISTS login_method;
+ALTER TABLE sessions DROP COLUMN IF EXISTS auth_time;
+ALTER TABLE sessions DROP COLUMN IF EXISTS login_method;
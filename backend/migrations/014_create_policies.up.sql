@@ -0,0 +1,25 @@
+-- 基于属性的访问策略：业务系统的资源/操作按策略（条件全部满足时允许或拒绝）判定，拒绝优先
+-- 会话记录登录方式与认证时间，供策略条件使用
+-- Date: 2026-10-17
+
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS login_method VARCHAR(20) NOT NULL DEFAULT '';
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS auth_time TIMESTAMP WITHOUT TIME ZONE;  -- 用户实际完成登录的时间，为空时以 created_at 为准
+ALTER TABLE oauth_authorization_codes ADD COLUMN IF NOT EXISTS login_method VARCHAR(20) NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS policies (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  client_id VARCHAR(100) NOT NULL REFERENCES clients(client_id) ON DELETE CASCADE,
+  name VARCHAR(100) NOT NULL,
+  description VARCHAR(255) NOT NULL DEFAULT '',
+  resource VARCHAR(255) NOT NULL,            -- 资源，* 结尾表示前缀匹配
+  action VARCHAR(100) NOT NULL,              -- 操作，* 结尾表示前缀匹配
+  effect VARCHAR(10) NOT NULL CHECK (effect IN ('allow', 'deny')),
+  conditions JSONB NOT NULL DEFAULT '[]',    -- 条件列表，全部满足时策略生效
+  enabled BOOLEAN NOT NULL DEFAULT TRUE,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+  updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+  UNIQUE (client_id, name)
+);
+
+INSERT INTO permissions (client_id, code, description) VALUES ('', 'policies:manage', '管理访问策略')
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,4 @@
+DELETE FROM permissions WHERE client_id = '' AND code = 'orgs:manage';
+DROP TABLE IF EXISTS organization_invitations;
+DROP TABLE IF EXISTS organization_members;
+DROP TABLE IF EXISTS organizations;
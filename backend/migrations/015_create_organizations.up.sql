@@ -0,0 +1,39 @@
+-- 组织（企业客户）：成员及组织内角色（owner/admin/member），通过邀请码加入
+-- Date: 2026-10-17
+
+CREATE TABLE IF NOT EXISTS organizations (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  slug VARCHAR(50) NOT NULL UNIQUE,          -- 组织标识，小写字母、数字与 -
+  name VARCHAR(100) NOT NULL,
+  description VARCHAR(255) NOT NULL DEFAULT '',
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+  updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS organization_members (
+  org_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+  user_id UUID NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+  role VARCHAR(20) NOT NULL CHECK (role IN ('owner', 'admin', 'member')),
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+  PRIMARY KEY (org_id, user_id)
+);
+
+CREATE INDEX organization_members_user_id_idx ON organization_members(user_id);
+
+CREATE TABLE IF NOT EXISTS organization_invitations (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  org_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+  code_hash VARCHAR(64) NOT NULL UNIQUE,     -- SHA-256(邀请码)，不保存明文
+  role VARCHAR(20) NOT NULL CHECK (role IN ('admin', 'member')),
+  max_uses INTEGER,                          -- 为空表示不限次数
+  uses INTEGER NOT NULL DEFAULT 0,
+  created_by UUID,
+  expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+  revoked_at TIMESTAMP WITH TIME ZONE,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX organization_invitations_org_id_idx ON organization_invitations(org_id);
+
+INSERT INTO permissions (client_id, code, description) VALUES ('', 'orgs:manage', '管理全部组织及其成员')
+ON CONFLICT DO NOTHING;
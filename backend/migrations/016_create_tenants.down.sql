@@ -0,0 +1,28 @@
+-- 回滚前需先删除非默认租户的数据，否则恢复全局唯一约束会失败
+DELETE FROM permissions WHERE client_id = '' AND code = 'tenants:manage';
+
+DROP INDEX IF EXISTS clients_tenant_id_idx;
+DROP INDEX IF EXISTS user_login_log_tenant_id_idx;
+DROP INDEX IF EXISTS sessions_tenant_id_idx;
+
+ALTER TABLE organizations DROP CONSTRAINT IF EXISTS organizations_tenant_id_slug_key;
+ALTER TABLE organizations ADD CONSTRAINT organizations_slug_key UNIQUE (slug);
+
+ALTER TABLE user_accounts DROP CONSTRAINT IF EXISTS user_accounts_tenant_id_provider_app_id_open_id_key;
+ALTER TABLE user_accounts ADD CONSTRAINT user_accounts_provider_app_id_open_id_key UNIQUE (provider, app_id, open_id);
+
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_tenant_id_email_key;
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_tenant_id_phone_number_key;
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_tenant_id_union_id_key;
+ALTER TABLE users ADD CONSTRAINT users_email_key UNIQUE (email);
+ALTER TABLE users ADD CONSTRAINT users_phone_number_key UNIQUE (phone_number);
+ALTER TABLE users ADD CONSTRAINT users_union_id_key UNIQUE (union_id);
+
+ALTER TABLE organizations DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE clients DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE user_login_log DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE sessions DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE user_accounts DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE users DROP COLUMN IF EXISTS tenant_id;
+
+DROP TABLE IF EXISTS tenants;
@@ -0,0 +1,51 @@
+-- 多租户：同一部署服务多个品牌，每个租户有独立的微信凭证、客户端、用户、会话与登录流水
+-- 已有数据归入默认租户 default；请求按路径 /t/:tenant 或租户登记的域名解析所属租户
+-- Date: 2026-10-17
+
+CREATE TABLE IF NOT EXISTS tenants (
+  id VARCHAR(50) PRIMARY KEY,                            -- 租户标识，小写字母、数字与 -
+  name VARCHAR(255) NOT NULL,
+  hosts TEXT NOT NULL DEFAULT '',                        -- 空格分隔的域名，如 auth.brand.com
+  wechat_app_id VARCHAR(100) NOT NULL DEFAULT '',        -- 微信开放平台
+  wechat_app_secret TEXT NOT NULL DEFAULT '',            -- 加密存储（DATA_ENCRYPTION_KEY）
+  wechat_mp_app_id VARCHAR(100) NOT NULL DEFAULT '',     -- 微信公众号
+  wechat_mp_secret TEXT NOT NULL DEFAULT '',             -- 加密存储（DATA_ENCRYPTION_KEY）
+  admin_union_id VARCHAR(255) NOT NULL DEFAULT '',       -- 初始超级管理员的微信 UnionID
+  enabled BOOLEAN NOT NULL DEFAULT TRUE,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+  updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- 默认租户的微信凭证与初始管理员为空时沿用环境变量
+INSERT INTO tenants (id, name) VALUES ('default', '默认租户') ON CONFLICT DO NOTHING;
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(50) NOT NULL DEFAULT 'default' REFERENCES tenants(id);
+ALTER TABLE user_accounts ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(50) NOT NULL DEFAULT 'default' REFERENCES tenants(id);
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(50) NOT NULL DEFAULT 'default' REFERENCES tenants(id);
+ALTER TABLE user_login_log ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(50) NOT NULL DEFAULT 'default' REFERENCES tenants(id);
+ALTER TABLE clients ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(50) NOT NULL DEFAULT 'default' REFERENCES tenants(id);
+ALTER TABLE organizations ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(50) NOT NULL DEFAULT 'default' REFERENCES tenants(id);
+
+-- UnionID、手机号、邮箱只在同一租户内唯一（不同品牌的用户池互不相干）
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_union_id_key;
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_phone_number_key;
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_email_key;
+ALTER TABLE users ADD CONSTRAINT users_tenant_id_union_id_key UNIQUE (tenant_id, union_id);
+ALTER TABLE users ADD CONSTRAINT users_tenant_id_phone_number_key UNIQUE (tenant_id, phone_number);
+ALTER TABLE users ADD CONSTRAINT users_tenant_id_email_key UNIQUE (tenant_id, email);
+
+-- 多个租户可能共用同一个微信应用
+ALTER TABLE user_accounts DROP CONSTRAINT IF EXISTS user_accounts_provider_app_id_open_id_key;
+ALTER TABLE user_accounts ADD CONSTRAINT user_accounts_tenant_id_provider_app_id_open_id_key
+  UNIQUE (tenant_id, provider, app_id, open_id);
+
+-- 组织标识只在同一租户内唯一
+ALTER TABLE organizations DROP CONSTRAINT IF EXISTS organizations_slug_key;
+ALTER TABLE organizations ADD CONSTRAINT organizations_tenant_id_slug_key UNIQUE (tenant_id, slug);
+
+CREATE INDEX IF NOT EXISTS sessions_tenant_id_idx ON sessions(tenant_id);
+CREATE INDEX IF NOT EXISTS user_login_log_tenant_id_idx ON user_login_log(tenant_id);
+CREATE INDEX IF NOT EXISTS clients_tenant_id_idx ON clients(tenant_id);
+
+INSERT INTO permissions (client_id, code, description) VALUES ('', 'tenants:manage', '管理租户（仅默认租户）')
+ON CONFLICT DO NOTHING;
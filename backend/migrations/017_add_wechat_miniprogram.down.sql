@@ -0,0 +1,4 @@
+ALTER TABLE user_accounts DROP COLUMN IF EXISTS session_key;
+
+ALTER TABLE tenants DROP COLUMN IF EXISTS wechat_mini_secret;
+ALTER TABLE tenants DROP COLUMN IF EXISTS wechat_mini_app_id;
@@ -0,0 +1,11 @@
+-- 微信小程序登录：租户登记小程序凭证，小程序账户保存加密的 session_key（用于解密 encryptedData）
+-- 小程序未绑定开放平台时拿不到 unionid，此类用户的 union_id 为 NULL，之后通过解密数据补全
+-- Date: 2026-10-17
+
+ALTER TABLE tenants ADD COLUMN IF NOT EXISTS wechat_mini_app_id VARCHAR(100) NOT NULL DEFAULT '';  -- 微信小程序
+ALTER TABLE tenants ADD COLUMN IF NOT EXISTS wechat_mini_secret TEXT NOT NULL DEFAULT '';          -- 加密存储（DATA_ENCRYPTION_KEY）
+
+ALTER TABLE user_accounts ADD COLUMN IF NOT EXISTS session_key TEXT NOT NULL DEFAULT '';          -- 加密存储（DATA_ENCRYPTION_KEY）
+
+-- 历史数据中空字符串的 union_id 统一为 NULL，避免与租户内唯一约束冲突
+UPDATE users SET union_id = NULL WHERE union_id = '';
@@ -0,0 +1,5 @@
+ALTER TABLE user_accounts DROP COLUMN IF EXISTS departments;
+
+ALTER TABLE tenants DROP COLUMN IF EXISTS wecom_secret;
+ALTER TABLE tenants DROP COLUMN IF EXISTS wecom_agent_id;
+ALTER TABLE tenants DROP COLUMN IF EXISTS wecom_corp_id;
@@ -0,0 +1,9 @@
+-- 企业微信员工登录：租户登记企业微信自建应用凭证，企业微信账户以 provider=wecom、app_id=CorpID、open_id=userid 登记
+-- 开启部门同步时，成员所属部门保存在账户上（[{"id": 1, "name": "研发部"}]）
+-- Date: 2026-10-17
+
+ALTER TABLE tenants ADD COLUMN IF NOT EXISTS wecom_corp_id VARCHAR(100) NOT NULL DEFAULT '';  -- 企业微信 CorpID
+ALTER TABLE tenants ADD COLUMN IF NOT EXISTS wecom_agent_id VARCHAR(50) NOT NULL DEFAULT '';  -- 自建应用 AgentID
+ALTER TABLE tenants ADD COLUMN IF NOT EXISTS wecom_secret TEXT NOT NULL DEFAULT '';           -- 加密存储（DATA_ENCRYPTION_KEY）
+
+ALTER TABLE user_accounts ADD COLUMN IF NOT EXISTS departments JSONB;                        -- 企业微信成员所属部门，未同步时为 NULL
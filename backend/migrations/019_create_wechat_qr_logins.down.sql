@@ -0,0 +1,3 @@
+ALTER TABLE tenants DROP COLUMN IF EXISTS wechat_mp_token;
+
+DROP TABLE IF EXISTS wechat_qr_logins;
@@ -0,0 +1,20 @@
+-- 扫码关注公众号登录：PC 页面展示公众号临时带参数二维码，用户扫码/关注后由公众号事件识别身份
+-- 仅保存轮询令牌的哈希；租户登记公众号消息推送令牌（校验推送签名）
+-- Date: 2026-10-17
+
+CREATE TABLE IF NOT EXISTS wechat_qr_logins (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),        -- 同时用作二维码场景值 login_<id>
+  tenant_id VARCHAR(50) NOT NULL REFERENCES tenants(id),
+  poll_token_hash VARCHAR(64) NOT NULL,                  -- SHA-256(轮询令牌)
+  app_id VARCHAR(100) NOT NULL,                          -- 公众号 AppID
+  callback_url TEXT NOT NULL,
+  status VARCHAR(20) NOT NULL,                           -- pending | confirmed | consumed
+  user_id UUID REFERENCES users(user_id) ON DELETE CASCADE,
+  expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+  scanned_at TIMESTAMP WITH TIME ZONE,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS wechat_qr_logins_expires_at_idx ON wechat_qr_logins(expires_at);
+
+ALTER TABLE tenants ADD COLUMN IF NOT EXISTS wechat_mp_token TEXT NOT NULL DEFAULT '';  -- 加密存储（DATA_ENCRYPTION_KEY）
@@ -0,0 +1,4 @@
+ALTER TABLE user_accounts DROP COLUMN IF EXISTS subscribed_at;
+ALTER TABLE user_accounts DROP COLUMN IF EXISTS subscribed;
+
+ALTER TABLE tenants DROP COLUMN IF EXISTS wechat_mp_encoding_aes_key;
@@ -0,0 +1,7 @@
+-- 公众号消息服务器：租户登记消息加解密密钥（安全模式），公众号账户记录关注状态（由 subscribe / unsubscribe 事件维护）
+-- Date: 2026-10-17
+
+ALTER TABLE tenants ADD COLUMN IF NOT EXISTS wechat_mp_encoding_aes_key TEXT NOT NULL DEFAULT '';  -- 加密存储（DATA_ENCRYPTION_KEY）
+
+ALTER TABLE user_accounts ADD COLUMN IF NOT EXISTS subscribed BOOLEAN NOT NULL DEFAULT false;    -- 是否关注公众号
+ALTER TABLE user_accounts ADD COLUMN IF NOT EXISTS subscribed_at TIMESTAMP WITH TIME ZONE;         -- 最近一次关注时间
@@ -0,0 +1,3 @@
+ALTER TABLE clients DROP COLUMN IF EXISTS wechat_token_access;
+
+DROP TABLE IF EXISTS wechat_credentials;
@@ -0,0 +1,14 @@
+-- 公众号中控服务：auth-center 统一刷新并保存公众号 access_token 与 jsapi_ticket，业务系统通过接口获取
+-- 客户端需登记 wechat_token_access 才能获取凭证
+-- Date: 2026-10-17
+
+CREATE TABLE IF NOT EXISTS wechat_credentials (
+  app_id VARCHAR(100) NOT NULL,                          -- 公众号 AppID
+  kind VARCHAR(20) NOT NULL,                             -- access_token | jsapi_ticket
+  value TEXT NOT NULL,                                   -- 加密存储（DATA_ENCRYPTION_KEY）
+  expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+  updated_at TIMESTAMP WITH TIME ZONE NOT NULL,
+  PRIMARY KEY (app_id, kind)
+);
+
+ALTER TABLE clients ADD COLUMN IF NOT EXISTS wechat_token_access BOOLEAN NOT NULL DEFAULT FALSE;  -- 允许获取公众号接口凭证
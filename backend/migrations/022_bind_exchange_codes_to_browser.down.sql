@@ -0,0 +1 @@
+ALTER TABLE exchange_codes DROP COLUMN IF EXISTS browser_nonce_hash;
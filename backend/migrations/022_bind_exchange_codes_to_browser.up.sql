@@ -0,0 +1,8 @@
+-- 不绑定客户端的交换码只用于 OIDC 授权续接（/oauth/authorize/resume），并绑定发起登录的浏览器 nonce
+-- 其余交换码一律由机密客户端凭密钥兑换
+-- Date: 2026-10-17
+
+ALTER TABLE exchange_codes ADD COLUMN IF NOT EXISTS browser_nonce_hash VARCHAR(64);  -- 浏览器 nonce 的 SHA-256
+
+-- 旧版签发的未绑定交换码（有效期只有 1 分钟）直接作废
+DELETE FROM exchange_codes WHERE client_id = '' AND browser_nonce_hash IS NULL;
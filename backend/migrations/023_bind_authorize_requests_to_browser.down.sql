@@ -0,0 +1 @@
+ALTER TABLE oauth_authorize_requests DROP COLUMN IF EXISTS browser_nonce_hash;
@@ -0,0 +1,7 @@
+-- OIDC 授权请求绑定发起授权的浏览器（auth_center_login_nonce Cookie），续接时校验，防止登录 CSRF
+-- Date: 2026-10-17
+
+ALTER TABLE oauth_authorize_requests ADD COLUMN IF NOT EXISTS browser_nonce_hash VARCHAR(64);  -- 浏览器 nonce 的 SHA-256
+
+-- 未绑定浏览器的旧授权请求（有效期只有 10 分钟）直接作废
+DELETE FROM oauth_authorize_requests WHERE browser_nonce_hash IS NULL;
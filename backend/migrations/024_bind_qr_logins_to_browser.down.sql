@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS wechat_qr_logins_client_ip_created_at_idx;
+
+ALTER TABLE wechat_qr_logins DROP COLUMN IF EXISTS client_ip;
+ALTER TABLE wechat_qr_logins DROP COLUMN IF EXISTS browser_nonce_hash;
@@ -0,0 +1,10 @@
+-- 扫码关注登录绑定创建它的浏览器（auth_center_login_nonce Cookie），完成登录时校验；按创建者 IP 限流
+-- Date: 2026-10-17
+
+ALTER TABLE wechat_qr_logins ADD COLUMN IF NOT EXISTS browser_nonce_hash VARCHAR(64);  -- 浏览器 nonce 的 SHA-256
+ALTER TABLE wechat_qr_logins ADD COLUMN IF NOT EXISTS client_ip VARCHAR(45);           -- 创建者 IP
+
+CREATE INDEX IF NOT EXISTS wechat_qr_logins_client_ip_created_at_idx ON wechat_qr_logins(client_ip, created_at);
+
+-- 未绑定浏览器的旧扫码登录（有效期只有 5 分钟）直接作废
+DELETE FROM wechat_qr_logins WHERE browser_nonce_hash IS NULL AND status <> 'consumed';
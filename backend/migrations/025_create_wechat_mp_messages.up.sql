@@ -0,0 +1,11 @@
+-- 公众号推送去重：订阅者处理成功后记录推送，微信重试或重放的同一推送不再处理（多副本共享）
+-- 普通消息按 MsgId 去重，事件按 FromUserName + CreateTime 去重
+-- Date: 2026-10-17
+
+CREATE TABLE IF NOT EXISTS wechat_mp_messages (
+  message_key VARCHAR(255) PRIMARY KEY,                  -- AppID + MsgId，或 AppID + FromUserName + CreateTime
+  expires_at TIMESTAMP WITH TIME ZONE NOT NULL,          -- 超出推送时间戳的有效范围后由清理任务删除
+  created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS wechat_mp_messages_expires_at_idx ON wechat_mp_messages(expires_at);
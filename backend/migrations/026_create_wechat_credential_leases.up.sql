@@ -0,0 +1,10 @@
+-- 公众号凭证刷新租约：领取租约的副本调用微信接口，调用期间不持有数据库事务与锁
+-- Date: 2026-10-17
+
+CREATE TABLE IF NOT EXISTS wechat_credential_leases (
+  app_id VARCHAR(100) NOT NULL,                          -- 公众号 AppID
+  kind VARCHAR(20) NOT NULL,                             -- access_token | jsapi_ticket
+  holder VARCHAR(64) NOT NULL,                           -- 持有者随机标识
+  lease_until TIMESTAMP WITH TIME ZONE NOT NULL,         -- 到期后其他副本可以接手
+  PRIMARY KEY (app_id, kind)
+);
@@ -54,8 +54,10 @@ import { zhCN } from 'date-fns/locale'
 interface DeviceInfo {
   userAgent?: string
   ip?: string
-  platform?: string
+  browser?: string
+  os?: string
   deviceType?: string
+  app?: string
 }
 
 interface UserAccount {
@@ -71,8 +73,12 @@ interface UserAccount {
 
 interface Session {
   id: string
-  token: string
-  deviceInfo: DeviceInfo | null
+  name?: string
+  maskedToken: string
+  device?: DeviceInfo
+  ip?: string
+  app?: string
+  lastSeenAt?: string
   expiresAt: string
   createdAt: string
 }
@@ -1172,7 +1178,7 @@ export default function AdminDashboard() {
                                 {isExpired ? '已过期' : '活跃中'}
                               </Typography>
                               <Typography variant="caption" color="text.secondary">
-                                {session.deviceInfo?.ip || '-'} · {format(new Date(session.expiresAt), 'MM-dd HH:mm', { locale: zhCN })} 过期
+                                {session.ip || '-'} · {format(new Date(session.expiresAt), 'MM-dd HH:mm', { locale: zhCN })} 过期
                               </Typography>
                             </Box>
                           </AccordionSummary>
@@ -1182,16 +1188,13 @@ export default function AdminDashboard() {
                                 <Typography variant="caption" color="text.secondary">Token</Typography>
                                 <Box sx={{ display: 'flex', alignItems: 'center', gap: 0.5 }}>
                                   <Typography variant="body2" sx={{ fontFamily: 'monospace', fontSize: '0.7rem' }}>
-                                    {session.token.substring(0, 36)}...
+                                    {session.maskedToken}
                                   </Typography>
-                                  <IconButton size="small" onClick={() => copyToClipboard(session.token, `token-${session.id}`)}>
-                                    <ContentCopyIcon sx={{ fontSize: 14 }} />
-                                  </IconButton>
                                 </Box>
                               </Box>
                               <Box>
                                 <Typography variant="caption" color="text.secondary">平台</Typography>
-                                <Typography variant="body2">{session.deviceInfo?.platform || '-'}</Typography>
+                                <Typography variant="body2">{[session.device?.os, session.device?.browser].filter(Boolean).join(' · ') || '-'}</Typography>
                               </Box>
                               <Box>
                                 <Typography variant="caption" color="text.secondary">创建时间</Typography>